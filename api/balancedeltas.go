@@ -0,0 +1,84 @@
+package api
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// GetBalanceDeltas returns the net change of the confirmed balance of the address in each block of the range from-to
+// in which the address has a transaction, the deltas are ordered from the oldest block and computed from the index;
+// negative to means the best block
+func (w *Worker) GetBalanceDeltas(address string, from, to int) (*BalanceDeltas, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotate(err, "GetBestBlock")
+	}
+	if to < 0 || to > int(bestHeight) {
+		to = int(bestHeight)
+	}
+	if from > to {
+		return nil, NewAPIError("Invalid block range", true)
+	}
+	r := &BalanceDeltas{
+		AddrStr:    address,
+		FromHeight: uint32(from),
+		ToHeight:   uint32(to),
+		Deltas:     []BalanceDelta{},
+	}
+	var total big.Int
+	// the transactions are returned from the newest, the deltas are built in reverse order
+	err = w.db.GetAddrDescTransactions(addrDesc, uint32(from), uint32(to), func(txid string, height uint32, indexes []int32) error {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+			return nil
+		}
+		if len(r.Deltas) == 0 || r.Deltas[len(r.Deltas)-1].Height != height {
+			r.Deltas = append(r.Deltas, BalanceDelta{
+				Height:   height,
+				Time:     int64(w.is.GetBlockTime(height)),
+				DeltaSat: &Amount{},
+			})
+		}
+		bd := &r.Deltas[len(r.Deltas)-1]
+		bd.Txs++
+		delta := (*big.Int)(bd.DeltaSat)
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+				if int(index) < len(ta.Inputs) {
+					delta.Sub(delta, &ta.Inputs[index].ValueSat)
+					total.Sub(&total, &ta.Inputs[index].ValueSat)
+				}
+			} else if int(index) < len(ta.Outputs) {
+				delta.Add(delta, &ta.Outputs[index].ValueSat)
+				total.Add(&total, &ta.Outputs[index].ValueSat)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescTransactions %v", addrDesc)
+	}
+	for i, j := 0, len(r.Deltas)-1; i < j; i, j = i+1, j-1 {
+		r.Deltas[i], r.Deltas[j] = r.Deltas[j], r.Deltas[i]
+	}
+	r.TotalDeltaSat = (*Amount)(&total)
+	glog.Info("GetBalanceDeltas ", address, ", blocks ", from, "-", to, ", count ", len(r.Deltas), ", ", time.Since(start))
+	return r, nil
+}
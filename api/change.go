@@ -0,0 +1,204 @@
+package api
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// heuristic detection of the change output of a transaction, i.e. the output returning the funds back to the sender
+// each heuristic points at the output which it considers to be the change or abstains,
+// the votes of the heuristics are weighted and the output with the highest score is the best guess of the change
+// the detection is only a hint, the guesses of the heuristics can be easily fooled by the wallets
+const (
+	// changeRoundDigits is the minimal number of trailing zeros of the value in satoshis considered a round payment
+	changeRoundDigits = 4
+	// changeMinConfidence is the minimal confidence of the guess of the change output
+	changeMinConfidence = 0.25
+)
+
+// changeHeuristic returns the index of the output which the heuristic considers to be the change or -1 if it abstains
+type changeHeuristic struct {
+	name   string
+	weight float64
+	detect func(tx *Tx) int
+}
+
+// changeHeuristics are all the available heuristics, selected by the -changeheuristics option
+var changeHeuristics = []changeHeuristic{
+	{name: "addressreuse", weight: 0.5, detect: changeByAddressReuse},
+	{name: "roundnumber", weight: 0.3, detect: changeByRoundNumber},
+	{name: "scripttype", weight: 0.2, detect: changeByScriptType},
+}
+
+// selectChangeHeuristics returns the heuristics of the given names
+func selectChangeHeuristics(names []string) ([]changeHeuristic, error) {
+	var r []changeHeuristic
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		found := false
+		for _, h := range changeHeuristics {
+			if h.name == n {
+				r = append(r, h)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("Unknown change heuristic %v", n)
+		}
+	}
+	return r, nil
+}
+
+// changeCandidates returns the indexes of the outputs which can be the change, i.e. the outputs with value to an address
+// nil is returned if the change is not detectable, i.e. for coinbase transactions and transactions with less than two candidates
+func changeCandidates(tx *Tx) []int {
+	for i := range tx.Vin {
+		if tx.Vin[i].Coinbase != "" {
+			return nil
+		}
+	}
+	var candidates []int
+	for i := range tx.Vout {
+		v := tx.Vout[i].ValueSat
+		if v == nil || (*big.Int)(v).Sign() <= 0 || !tx.Vout[i].IsAddress || len(tx.Vout[i].Addresses) == 0 {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	if len(candidates) < 2 {
+		return nil
+	}
+	return candidates
+}
+
+// onlyMatching returns the candidate for which match is true if there is exactly one such candidate, otherwise -1
+func onlyMatching(candidates []int, match func(i int) bool) int {
+	r := -1
+	for _, i := range candidates {
+		if match(i) {
+			if r >= 0 {
+				return -1
+			}
+			r = i
+		}
+	}
+	return r
+}
+
+// changeByAddressReuse points at the only output sent back to an address of the inputs
+func changeByAddressReuse(tx *Tx) int {
+	inputs := make(map[string]struct{}, len(tx.Vin))
+	for i := range tx.Vin {
+		if tx.Vin[i].IsAddress && len(tx.Vin[i].Addresses) > 0 {
+			inputs[strings.Join(tx.Vin[i].Addresses, ",")] = struct{}{}
+		}
+	}
+	return onlyMatching(changeCandidates(tx), func(i int) bool {
+		_, found := inputs[strings.Join(tx.Vout[i].Addresses, ",")]
+		return found
+	})
+}
+
+var roundValueUnit = new(big.Int).Exp(big.NewInt(10), big.NewInt(changeRoundDigits), nil)
+
+// isRoundValue returns true if the value in satoshis has at least changeRoundDigits trailing zeros
+func isRoundValue(v *Amount) bool {
+	var m big.Int
+	return m.Mod((*big.Int)(v), roundValueUnit).Sign() == 0
+}
+
+// changeByRoundNumber points at the only output with a value which is not round, the payments are usually round numbers
+func changeByRoundNumber(tx *Tx) int {
+	return onlyMatching(changeCandidates(tx), func(i int) bool {
+		return !isRoundValue(tx.Vout[i].ValueSat)
+	})
+}
+
+// outputScriptType returns the type of the standard output script or empty string if the type is not recognized
+func outputScriptType(addrDesc bchain.AddressDescriptor) string {
+	switch {
+	case len(addrDesc) == 25 && addrDesc[0] == 0x76 && addrDesc[1] == 0xa9 && addrDesc[2] == 0x14 && addrDesc[23] == 0x88 && addrDesc[24] == 0xac:
+		return "p2pkh"
+	case len(addrDesc) == 23 && addrDesc[0] == 0xa9 && addrDesc[1] == 0x14 && addrDesc[22] == 0x87:
+		return "p2sh"
+	case len(addrDesc) == 22 && addrDesc[0] == 0x00 && addrDesc[1] == 0x14:
+		return "p2wpkh"
+	case len(addrDesc) == 34 && addrDesc[0] == 0x00 && addrDesc[1] == 0x20:
+		return "p2wsh"
+	case len(addrDesc) == 34 && addrDesc[0] == 0x51 && addrDesc[1] == 0x20:
+		return "p2tr"
+	}
+	return ""
+}
+
+// changeByScriptType points at the only output of the same script type as all the inputs, the wallets send the change to their own type of script
+func changeByScriptType(tx *Tx) int {
+	var inputType string
+	for i := range tx.Vin {
+		t := outputScriptType(tx.Vin[i].AddrDesc)
+		if t == "" || (inputType != "" && t != inputType) {
+			return -1
+		}
+		inputType = t
+	}
+	if inputType == "" {
+		return -1
+	}
+	return onlyMatching(changeCandidates(tx), func(i int) bool {
+		return outputScriptType(tx.Vout[i].AddrDesc) == inputType
+	})
+}
+
+// detectChange returns the index of the best guess of the change output and the confidence of the guess between 0 and 1,
+// the confidence is the weight of the heuristics pointing at the output reduced by the weight of the heuristics pointing elsewhere,
+// relative to the weight of all the heuristics; -1 is returned if there is no guess with at least changeMinConfidence
+// the coinjoin transactions are not considered, the change of their participants cannot be told apart
+func detectChange(tx *Tx, heuristics []changeHeuristic) (int, float64) {
+	if len(heuristics) == 0 || changeCandidates(tx) == nil {
+		return -1, 0
+	}
+	if likely, _ := detectCoinJoin(tx); likely {
+		return -1, 0
+	}
+	var total, voted float64
+	votes := make(map[int]float64)
+	for _, h := range heuristics {
+		total += h.weight
+		if i := h.detect(tx); i >= 0 {
+			votes[i] += h.weight
+			voted += h.weight
+		}
+	}
+	best := -1
+	for i, v := range votes {
+		if best < 0 || v > votes[best] || (v == votes[best] && i < best) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return -1, 0
+	}
+	confidence := (2*votes[best] - voted) / total
+	if confidence < changeMinConfidence {
+		return -1, 0
+	}
+	return best, confidence
+}
+
+// setChange sets the best guess of the change output of the transaction and its confidence
+func (w *Worker) setChange(tx *Tx) {
+	if len(w.changeHeuristics) == 0 || w.chainType != bchain.ChainBitcoinType {
+		return
+	}
+	if i, confidence := detectChange(tx, w.changeHeuristics); i >= 0 {
+		tx.ChangeIndex = &i
+		tx.ChangeConfidence = confidence
+	}
+}
@@ -0,0 +1,177 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const (
+	testP2PKH  = "76a914" + "1111111111111111111111111111111111111111" + "88ac"
+	testP2WPKH = "0014" + "2222222222222222222222222222222222222222"
+	testP2TR   = "5120" + "3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+type testChangeIO struct {
+	address string
+	script  string
+	value   int64
+}
+
+func testChangeTx(inputs []testChangeIO, outputs []testChangeIO) *Tx {
+	tx := &Tx{
+		Vin:  make([]Vin, len(inputs)),
+		Vout: make([]Vout, len(outputs)),
+	}
+	for i, in := range inputs {
+		tx.Vin[i].N = i
+		tx.Vin[i].Addresses = []string{in.address}
+		tx.Vin[i].IsAddress = true
+		tx.Vin[i].AddrDesc, _ = hex.DecodeString(in.script)
+		tx.Vin[i].ValueSat = (*Amount)(big.NewInt(in.value))
+	}
+	for i, out := range outputs {
+		tx.Vout[i].N = i
+		tx.Vout[i].Addresses = []string{out.address}
+		tx.Vout[i].IsAddress = true
+		tx.Vout[i].AddrDesc, _ = hex.DecodeString(out.script)
+		tx.Vout[i].ValueSat = (*Amount)(big.NewInt(out.value))
+	}
+	return tx
+}
+
+var (
+	// payment of 0.01 to a p2pkh address with the change back to the p2wpkh address of the input
+	testChangeObvious = testChangeTx(
+		[]testChangeIO{{"in1", testP2WPKH, 5000000}},
+		[]testChangeIO{{"payee", testP2PKH, 1000000}, {"in1", testP2WPKH, 3987654}},
+	)
+	// the heuristics disagree, the round output goes back to the input address
+	testChangeConflict = testChangeTx(
+		[]testChangeIO{{"in1", testP2WPKH, 5000000}},
+		[]testChangeIO{{"payee", testP2WPKH, 1234567}, {"in1", testP2PKH, 3760000}},
+	)
+	// equal-output coinjoin, all the outputs of the participants go back to fresh addresses of the same type
+	testChangeCoinJoin = testChangeTx(
+		[]testChangeIO{{"in1", testP2TR, 10300000}, {"in2", testP2TR, 10200000}, {"in3", testP2TR, 10100000}},
+		[]testChangeIO{{"out1", testP2TR, 10000000}, {"out2", testP2TR, 10000000}, {"out3", testP2TR, 10000000}, {"in1", testP2TR, 287654}},
+	)
+	// coinbase with two outputs
+	testChangeCoinbase = func() *Tx {
+		tx := testChangeTx(
+			[]testChangeIO{{"", "", 0}},
+			[]testChangeIO{{"miner", testP2WPKH, 625001234}, {"pool", testP2WPKH, 10000000}},
+		)
+		tx.Vin[0].Coinbase = "03bf1e15"
+		return tx
+	}()
+)
+
+func Test_changeHeuristics(t *testing.T) {
+	tests := []struct {
+		name   string
+		detect func(tx *Tx) int
+		tx     *Tx
+		want   int
+	}{
+		{name: "address reuse", detect: changeByAddressReuse, tx: testChangeObvious, want: 1},
+		{name: "address reuse conflict", detect: changeByAddressReuse, tx: testChangeConflict, want: 1},
+		{name: "address reuse coinbase", detect: changeByAddressReuse, tx: testChangeCoinbase, want: -1},
+		{
+			name:   "address reuse all outputs to inputs",
+			detect: changeByAddressReuse,
+			tx:     testChangeTx([]testChangeIO{{"in1", testP2WPKH, 3000}, {"in2", testP2WPKH, 3000}}, []testChangeIO{{"in1", testP2WPKH, 2000}, {"in2", testP2WPKH, 3500}}),
+			want:   -1,
+		},
+		{name: "round number", detect: changeByRoundNumber, tx: testChangeObvious, want: 1},
+		{name: "round number conflict", detect: changeByRoundNumber, tx: testChangeConflict, want: 0},
+		{
+			name:   "round number no round output",
+			detect: changeByRoundNumber,
+			tx:     testChangeTx([]testChangeIO{{"in1", testP2WPKH, 5000000}}, []testChangeIO{{"a", testP2WPKH, 1234567}, {"b", testP2WPKH, 3760001}}),
+			want:   -1,
+		},
+		{name: "script type", detect: changeByScriptType, tx: testChangeObvious, want: 1},
+		{name: "script type conflict", detect: changeByScriptType, tx: testChangeConflict, want: 0},
+		{name: "script type all outputs of the same type", detect: changeByScriptType, tx: testChangeCoinJoin, want: -1},
+		{
+			name:   "script type mixed inputs",
+			detect: changeByScriptType,
+			tx:     testChangeTx([]testChangeIO{{"in1", testP2WPKH, 3000}, {"in2", testP2PKH, 3000}}, []testChangeIO{{"a", testP2WPKH, 2000}, {"b", testP2TR, 3500}}),
+			want:   -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.detect(tt.tx); got != tt.want {
+				t.Errorf("detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_detectChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		heuristics     string
+		tx             *Tx
+		wantIndex      int
+		wantConfidence float64
+	}{
+		{
+			name:           "obvious change",
+			heuristics:     "addressreuse,roundnumber,scripttype",
+			tx:             testChangeObvious,
+			wantIndex:      1,
+			wantConfidence: 1,
+		},
+		{
+			name:           "obvious change by round number only",
+			heuristics:     "roundnumber",
+			tx:             testChangeObvious,
+			wantIndex:      1,
+			wantConfidence: 1,
+		},
+		{
+			name:       "coinjoin",
+			heuristics: "addressreuse,roundnumber,scripttype",
+			tx:         testChangeCoinJoin,
+			wantIndex:  -1,
+		},
+		{
+			name:       "conflicting heuristics",
+			heuristics: "addressreuse,roundnumber,scripttype",
+			tx:         testChangeConflict,
+			wantIndex:  -1,
+		},
+		{
+			name:       "coinbase",
+			heuristics: "addressreuse,roundnumber,scripttype",
+			tx:         testChangeCoinbase,
+			wantIndex:  -1,
+		},
+		{
+			name:      "no heuristics",
+			tx:        testChangeObvious,
+			wantIndex: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heuristics, err := selectChangeHeuristics(strings.Split(tt.heuristics, ","))
+			if err != nil {
+				t.Fatal(err)
+			}
+			index, confidence := detectChange(tt.tx, heuristics)
+			if index != tt.wantIndex || confidence != tt.wantConfidence {
+				t.Errorf("detectChange() = %v, %v, want %v, %v", index, confidence, tt.wantIndex, tt.wantConfidence)
+			}
+		})
+	}
+	if _, err := selectChangeHeuristics([]string{"roundnumber", "unknown"}); err == nil {
+		t.Error("selectChangeHeuristics() expected error for unknown heuristic")
+	}
+}
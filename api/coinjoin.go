@@ -0,0 +1,54 @@
+package api
+
+import "math/big"
+
+// heuristic detection of the equal-output coinjoin transactions (Whirlpool, Wasabi, JoinMarket and similar)
+// the participants of such a transaction receive outputs of the same value, which cannot be linked to their inputs;
+// the number of the outputs of the most frequent value is the anonymity set of the transaction
+// the heuristic is only a hint, a batched payment of equal amounts from many inputs is flagged as well
+const (
+	// coinJoinMinAnonymitySet is the minimal number of outputs with the same value
+	coinJoinMinAnonymitySet = 3
+	// coinJoinMinInputs is the minimal number of inputs, a coinjoin needs at least two participants
+	coinJoinMinInputs = 2
+)
+
+// detectCoinJoin returns true if the transaction looks like an equal-output coinjoin and the size of its anonymity set,
+// i.e. the number of outputs with the most frequent value; the transaction is considered a coinjoin if
+//   - the anonymity set has at least coinJoinMinAnonymitySet outputs
+//   - the transaction has at least coinJoinMinInputs inputs and at least as many inputs as the size of the anonymity set,
+//     each participant contributes at least one input
+//
+// the outputs with zero value (e.g. OP_RETURN) and the coinbase transactions are not considered
+func detectCoinJoin(tx *Tx) (bool, int) {
+	if len(tx.Vin) < coinJoinMinInputs || len(tx.Vout) < coinJoinMinAnonymitySet {
+		return false, 0
+	}
+	for i := range tx.Vin {
+		if tx.Vin[i].Coinbase != "" {
+			return false, 0
+		}
+	}
+	counts := make(map[string]int, len(tx.Vout))
+	anonymitySet := 0
+	for i := range tx.Vout {
+		v := tx.Vout[i].ValueSat
+		if v == nil || (*big.Int)(v).Sign() <= 0 {
+			continue
+		}
+		s := v.String()
+		counts[s]++
+		if counts[s] > anonymitySet {
+			anonymitySet = counts[s]
+		}
+	}
+	if anonymitySet < coinJoinMinAnonymitySet || len(tx.Vin) < anonymitySet {
+		return false, 0
+	}
+	return true, anonymitySet
+}
+
+// setCoinJoin sets the coinjoin flag and the anonymity set of the transaction
+func setCoinJoin(tx *Tx) {
+	tx.CoinJoinLikely, tx.CoinJoinAnonymitySet = detectCoinJoin(tx)
+}
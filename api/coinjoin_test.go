@@ -0,0 +1,87 @@
+//go:build unittest
+
+package api
+
+import (
+	"math/big"
+	"testing"
+)
+
+func testCoinJoinTx(inputs int, coinbase bool, outputs ...int64) *Tx {
+	tx := &Tx{
+		Vin:  make([]Vin, inputs),
+		Vout: make([]Vout, len(outputs)),
+	}
+	for i := range tx.Vin {
+		tx.Vin[i].N = i
+		tx.Vin[i].ValueSat = (*Amount)(big.NewInt(20000000))
+	}
+	if coinbase && inputs > 0 {
+		tx.Vin[0].Coinbase = "03bf1e15"
+	}
+	for i, v := range outputs {
+		tx.Vout[i].N = i
+		tx.Vout[i].ValueSat = (*Amount)(big.NewInt(v))
+	}
+	return tx
+}
+
+func Test_detectCoinJoin(t *testing.T) {
+	tests := []struct {
+		name             string
+		tx               *Tx
+		wantLikely       bool
+		wantAnonymitySet int
+	}{
+		{
+			name:             "coinjoin with 5 equal outputs and change",
+			tx:               testCoinJoinTx(6, false, 10000000, 10000000, 10000000, 4567, 10000000, 10000000, 1234567),
+			wantLikely:       true,
+			wantAnonymitySet: 5,
+		},
+		{
+			name:             "whirlpool 5x5",
+			tx:               testCoinJoinTx(5, false, 5000000, 5000000, 5000000, 5000000, 5000000),
+			wantLikely:       true,
+			wantAnonymitySet: 5,
+		},
+		{
+			name:             "minimal anonymity set",
+			tx:               testCoinJoinTx(3, false, 10000000, 10000000, 10000000),
+			wantLikely:       true,
+			wantAnonymitySet: 3,
+		},
+		{
+			name: "normal payment with change",
+			tx:   testCoinJoinTx(2, false, 10000000, 29990000),
+		},
+		{
+			name: "two equal outputs",
+			tx:   testCoinJoinTx(2, false, 10000000, 10000000, 19990000),
+		},
+		{
+			name: "batched payout from one input",
+			tx:   testCoinJoinTx(1, false, 10000000, 10000000, 10000000, 10000000),
+		},
+		{
+			name: "fewer inputs than equal outputs",
+			tx:   testCoinJoinTx(3, false, 10000000, 10000000, 10000000, 10000000),
+		},
+		{
+			name: "zero value outputs",
+			tx:   testCoinJoinTx(3, false, 0, 0, 0, 10000000),
+		},
+		{
+			name: "coinbase",
+			tx:   testCoinJoinTx(3, true, 10000000, 10000000, 10000000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			likely, anonymitySet := detectCoinJoin(tt.tx)
+			if likely != tt.wantLikely || anonymitySet != tt.wantAnonymitySet {
+				t.Errorf("detectCoinJoin() = %v, %v, want %v, %v", likely, anonymitySet, tt.wantLikely, tt.wantAnonymitySet)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// limits of the difficulty history, each point requires the block header from the backend
+const (
+	defaultDifficultyInterval = 144
+	defaultDifficultyPoints   = 100
+	maxDifficultyPoints       = 500
+)
+
+// difficultyFromBits converts the compact target of the block header to the difficulty the same way as Bitcoin Core,
+// i.e. relative to the target 0x1d00ffff
+func difficultyFromBits(bits uint32) float64 {
+	mantissa := bits & 0x00ffffff
+	if mantissa == 0 {
+		return 0
+	}
+	shift := (bits >> 24) & 0xff
+	diff := float64(0x0000ffff) / float64(mantissa)
+	for ; shift < 29; shift++ {
+		diff *= 256
+	}
+	for ; shift > 29; shift-- {
+		diff /= 256
+	}
+	return diff
+}
+
+// hashrateFromDifficulty estimates the hashrate in hashes per second, on average difficulty*2^32 hashes are needed to find a block
+func hashrateFromDifficulty(difficulty float64, targetBlockTime time.Duration) float64 {
+	return difficulty * math.Exp2(32) / targetBlockTime.Seconds()
+}
+
+// GetDifficultyHistory returns the difficulty and the estimated hashrate of the blocks sampled every interval blocks
+// from the height to down to the height from, the points are ordered from the oldest; negative to means the best block,
+// negative from means defaultDifficultyPoints points
+func (w *Worker) GetDifficultyHistory(from, to, interval int) (*DifficultyHistory, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	targetBlockTime, err := w.chainParser.GetTargetBlockTime()
+	if err != nil {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if interval == 0 {
+		interval = defaultDifficultyInterval
+	}
+	if interval < 0 {
+		return nil, NewAPIError("Invalid interval", true)
+	}
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if to < 0 || to > int(bestHeight) {
+		to = int(bestHeight)
+	}
+	if from < 0 {
+		from = to - (defaultDifficultyPoints-1)*interval
+		if from < 0 {
+			from = 0
+		}
+	}
+	if from > to {
+		return nil, NewAPIError("Invalid block range", true)
+	}
+	points := (to-from)/interval + 1
+	if points > maxDifficultyPoints {
+		return nil, NewAPIError(fmt.Sprintf("Too many points, the limit is %d, increase the interval", maxDifficultyPoints), true)
+	}
+	r := &DifficultyHistory{
+		TargetBlockTime: int(targetBlockTime / time.Second),
+		Interval:        interval,
+		Points:          make([]DifficultyPoint, points),
+	}
+	for i := range r.Points {
+		// the points are aligned to the height to
+		height := uint32(to - (points-1-i)*interval)
+		bi, err := w.db.GetBlockInfo(height)
+		if err != nil {
+			return nil, err
+		}
+		if bi == nil {
+			return nil, NewAPIError(fmt.Sprintf("Block %d not found", height), true)
+		}
+		headerHex, err := w.chain.GetBlockHeaderHex(bi.Hash)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHeaderHex %v", bi.Hash)
+		}
+		header, err := hex.DecodeString(headerHex)
+		if err != nil {
+			return nil, errors.Annotatef(err, "block %v", bi.Hash)
+		}
+		// the coins with a different header format are not supported
+		if len(header) != wire.MaxBlockHeaderPayload {
+			return nil, NewAPIError("Not supported", true)
+		}
+		var h wire.BlockHeader
+		if err = h.Deserialize(bytes.NewReader(header)); err != nil {
+			return nil, errors.Annotatef(err, "block %v", bi.Hash)
+		}
+		difficulty := difficultyFromBits(h.Bits)
+		r.Points[i] = DifficultyPoint{
+			Height:     height,
+			Time:       bi.Time,
+			Difficulty: difficulty,
+			Hashrate:   hashrateFromDifficulty(difficulty, targetBlockTime),
+		}
+	}
+	glog.Info("GetDifficultyHistory ", from, "-", to, ", interval ", interval, ", ", points, " points, ", time.Since(start))
+	return r, nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// minimal length of a run of printable characters considered a part of the coinbase message
+const minCoinbaseMessageRun = 4
+
+// scriptPushes returns the data pushed by the script, the parsing stops at the first opcode which does not push data
+func scriptPushes(script []byte) [][]byte {
+	var pushes [][]byte
+	for i := 0; i < len(script); {
+		op := script[i]
+		i++
+		var l int
+		switch {
+		case op >= 0x01 && op <= 0x4b:
+			l = int(op)
+		case op == 0x4c && i+1 <= len(script):
+			l = int(script[i])
+			i++
+		case op == 0x4d && i+2 <= len(script):
+			l = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == 0x4e && i+4 <= len(script):
+			l = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		default:
+			return pushes
+		}
+		if l < 0 || i+l > len(script) {
+			return pushes
+		}
+		pushes = append(pushes, script[i:i+l])
+		i += l
+	}
+	return pushes
+}
+
+// coinbaseMessage returns the printable ASCII texts contained in the data pushed by the coinbase script, separated by a space
+func coinbaseMessage(script []byte) string {
+	var runs []string
+	for _, p := range scriptPushes(script) {
+		start := -1
+		for i := 0; i <= len(p); i++ {
+			if i < len(p) && p[i] >= 0x20 && p[i] < 0x7f {
+				if start < 0 {
+					start = i
+				}
+				continue
+			}
+			if start >= 0 && i-start >= minCoinbaseMessageRun {
+				runs = append(runs, strings.TrimSpace(string(p[start:i])))
+			}
+			start = -1
+		}
+	}
+	return strings.Join(runs, " ")
+}
+
+// GetGenesisInfo returns the hash and the time of the genesis block of the chain and the message contained in its coinbase
+func (w *Worker) GetGenesisInfo() (*GenesisInfo, error) {
+	w.genesisInfoMux.Lock()
+	defer w.genesisInfoMux.Unlock()
+	// the genesis block never changes
+	if w.genesisInfo != nil {
+		return w.genesisInfo, nil
+	}
+	start := time.Now()
+	hash, err := w.chain.GetBlockHash(0)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockHash 0")
+	}
+	block, err := w.chain.GetBlock(hash, 0)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlock %v", hash)
+	}
+	r := &GenesisInfo{
+		Hash: hash,
+		Time: block.Time,
+	}
+	if w.chainType == bchain.ChainBitcoinType && len(block.Txs) > 0 && len(block.Txs[0].Vin) > 0 {
+		r.CoinbaseTxid = block.Txs[0].Txid
+		r.CoinbaseScript = block.Txs[0].Vin[0].Coinbase
+		script, err := hex.DecodeString(r.CoinbaseScript)
+		if err != nil {
+			return nil, errors.Annotatef(err, "coinbase %v", r.CoinbaseTxid)
+		}
+		r.CoinbaseMessage = coinbaseMessage(script)
+	}
+	w.genesisInfo = r
+	glog.Info("GetGenesisInfo ", hash, ", ", time.Since(start))
+	return r, nil
+}
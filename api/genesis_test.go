@@ -0,0 +1,53 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_coinbaseMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "bitcoin genesis",
+			script: "04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73",
+			want:   "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks",
+		},
+		{
+			name:   "text after non-push opcode",
+			script: "03bf1e1504aede765b726567696f6e312f50726f6a65637420425443506f6f6c2f01000001bf7e000000000000",
+			want:   "",
+		},
+		{
+			name:   "OP_PUSHDATA1 with binary data",
+			script: "4c0a00012f736c7573682f00",
+			want:   "/slush/",
+		},
+		{
+			name:   "truncated push",
+			script: "0a2f736c7573682f",
+			want:   "",
+		},
+		{
+			name:   "empty",
+			script: "",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := hex.DecodeString(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := coinbaseMessage(script); got != tt.want {
+				t.Errorf("coinbaseMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// medianTimeSpan is the number of the preceding blocks of which the median time past is computed (BIP113)
+const medianTimeSpan = 11
+
+// medianTimePast returns the median of the times of the medianTimeSpan blocks preceding the block at the height,
+// it is the time against which the time locks of the transactions in the block are checked (BIP113)
+// near the genesis block the median of the available preceding blocks is returned, 0 for the genesis block
+func medianTimePast(height uint32, blockTime func(height uint32) (int64, error)) (int64, error) {
+	times := make([]int64, 0, medianTimeSpan)
+	for h := height; h > 0 && len(times) < medianTimeSpan; h-- {
+		t, err := blockTime(h - 1)
+		if err != nil {
+			return 0, err
+		}
+		times = append(times, t)
+	}
+	if len(times) == 0 {
+		return 0, nil
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2], nil
+}
+
+// getMedianTimePast returns the median time past of the block at the height computed from the times of the blocks in the index
+func (w *Worker) getMedianTimePast(height uint32) (int64, error) {
+	return medianTimePast(height, func(h uint32) (int64, error) {
+		bi, err := w.db.GetBlockInfo(h)
+		if err != nil {
+			return 0, err
+		}
+		if bi == nil {
+			return 0, errors.Errorf("Block %d not found", h)
+		}
+		return bi.Time, nil
+	})
+}
@@ -0,0 +1,70 @@
+//go:build unittest
+
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_medianTimePast(t *testing.T) {
+	// mock chain of headers, the times are not monotonic as allowed by the consensus rules
+	headerTimes := []int64{
+		1000, 1600, 1200, 1900, 1300, 1800, 1100, 2000, 1500, 1700,
+		1400, 2100, 900, 2200, 2300,
+	}
+	blockTime := func(height uint32) (int64, error) {
+		if int(height) >= len(headerTimes) {
+			return 0, errors.New("block not found")
+		}
+		return headerTimes[height], nil
+	}
+	tests := []struct {
+		name   string
+		height uint32
+		want   int64
+	}{
+		{
+			name:   "genesis",
+			height: 0,
+			want:   0,
+		},
+		{
+			name:   "one preceding block",
+			height: 1,
+			want:   1000,
+		},
+		{
+			// times 1000, 1600, 1200, 1900
+			name:   "even number of preceding blocks",
+			height: 4,
+			want:   1600,
+		},
+		{
+			// times of the blocks 0-10: 1000, 1100, ..., 2000
+			name:   "eleven preceding blocks",
+			height: 11,
+			want:   1500,
+		},
+		{
+			// times of the blocks 4-14: 1300, 1800, 1100, 2000, 1500, 1700, 1400, 2100, 900, 2200, 2300
+			name:   "only last eleven blocks",
+			height: 15,
+			want:   1700,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := medianTimePast(tt.height, blockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("medianTimePast() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := medianTimePast(17, blockTime); err == nil {
+		t.Error("medianTimePast() expected error for missing block")
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// memoFromScript returns the OP_RETURN payload of the script as text
+// if it consists only of printable ASCII characters, otherwise empty string
+func memoFromScript(script []byte) string {
+	payload := db.GetOpReturnPayload(script)
+	if len(payload) == 0 {
+		return ""
+	}
+	for _, c := range payload {
+		if c < 0x20 || c > 0x7e {
+			return ""
+		}
+	}
+	return string(payload)
+}
+
+// setMemo sets the memo of the transaction to the text of its first OP_RETURN output with printable payload
+func (w *Worker) setMemo(tx *Tx) {
+	if !w.is.TxMemo || w.chainType != bchain.ChainBitcoinType {
+		return
+	}
+	for i := range tx.Vout {
+		if memo := memoFromScript(tx.Vout[i].AddrDesc); memo != "" {
+			tx.Memo = memo
+			return
+		}
+	}
+}
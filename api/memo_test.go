@@ -0,0 +1,58 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_memoFromScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "ASCII text",
+			script: "6a0c48656c6c6f20776f726c6421",
+			want:   "Hello world!",
+		},
+		{
+			name:   "ASCII text in OP_PUSHDATA1",
+			script: "6a4c0b626c6f636b626f6f6b2031",
+			want:   "blockbook 1",
+		},
+		{
+			name:   "binary payload",
+			script: "6a072020f1686f6a20",
+			want:   "",
+		},
+		{
+			name:   "text with control characters",
+			script: "6a0848690a7468657265",
+			want:   "",
+		},
+		{
+			name:   "empty OP_RETURN",
+			script: "6a",
+			want:   "",
+		},
+		{
+			name:   "not OP_RETURN",
+			script: "76a914010d39800f86122416e28f485029acf77507169288ac",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := hex.DecodeString(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := memoFromScript(script); got != tt.want {
+				t.Errorf("memoFromScript() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
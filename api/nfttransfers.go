@@ -0,0 +1,39 @@
+package api
+
+import (
+	"math/big"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+// getEthereumNftTransfers returns the transfers of the ERC721 and ERC1155 tokens as one item per transferred token id,
+// an ERC1155 TransferBatch is split to the individual tokens, the amount of an ERC721 token is always 1
+func getEthereumNftTransfers(transfers bchain.TokenTransfers) []NftTransfer {
+	var nfts []NftTransfer
+	for _, t := range transfers {
+		switch t.Type {
+		case bchain.NonFungibleToken:
+			nfts = append(nfts, NftTransfer{
+				Type:     bchain.EthereumTokenTypeMap[t.Type],
+				Contract: t.Contract,
+				From:     t.From,
+				To:       t.To,
+				TokenId:  (*Amount)(&t.Value),
+				Amount:   (*Amount)(big.NewInt(1)),
+			})
+		case bchain.MultiToken:
+			for i := range t.MultiTokenValues {
+				v := &t.MultiTokenValues[i]
+				nfts = append(nfts, NftTransfer{
+					Type:     bchain.EthereumTokenTypeMap[t.Type],
+					Contract: t.Contract,
+					From:     t.From,
+					To:       t.To,
+					TokenId:  (*Amount)(&v.Id),
+					Amount:   (*Amount)(&v.Value),
+				})
+			}
+		}
+	}
+	return nfts
+}
@@ -0,0 +1,128 @@
+//go:build unittest
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+)
+
+func Test_getEthereumNftTransfers(t *testing.T) {
+	erc20Transfer := &bchain.RpcLog{
+		Address: "0x4af4114F73d1c1C903aC9E0361b379D1291808A2",
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			"0x000000000000000000000000d1cfb5e0a9ac5f6a8a4ab2e9c3f29d9e0d5bd9a1",
+			"0x0000000000000000000000006f44cceb49b4a5812d54b6f494fc2febf25511ed",
+		},
+		Data: "0x0000000000000000000000000000000000000000000000000000000000000064",
+	}
+	erc721Transfer := &bchain.RpcLog{
+		Address: "0x5689b918D34C038901870105A6C7fc24744D31eB",
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			"0x0000000000000000000000000a206d4d5ff79cb5069def7fe3598421cff09391",
+			"0x0000000000000000000000006a016d7eec560549ffa0fbdb7f15c2b27302087f",
+			"0x0000000000000000000000000000000000000000000000000000000000001396",
+		},
+		Data: "0x",
+	}
+	erc1155TransferSingle := &bchain.RpcLog{
+		Address: "0x6Fd712E3A5B556654044608F9129040A4839E36c",
+		Topics: []string{
+			"0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62",
+			"0x0000000000000000000000009248a6048a58db9f0212dc7cd85ee8741128be72",
+			"0x000000000000000000000000a3950b823cb063dd9afc0d27f35008b805b3ed53",
+			"0x0000000000000000000000004392faf3bb96b5694ecc6ef64726f61cdd4bb0ec",
+		},
+		Data: "0x00000000000000000000000000000000000000000000000000000000000000960000000000000000000000000000000000000000000000000000000000000011",
+	}
+	erc1155TransferBatch := &bchain.RpcLog{
+		Address: "0x6c42C26a081c2F509F8bb68fb7Ac3062311cCfB7",
+		Topics: []string{
+			"0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb",
+			"0x0000000000000000000000005dc6288b35e0807a3d6feb89b3a2ff4ab773168e",
+			"0x0000000000000000000000000000000000000000000000000000000000000000",
+			"0x0000000000000000000000005dc6288b35e0807a3d6feb89b3a2ff4ab773168e",
+		},
+		Data: "0x000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a0000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000006f0000000000000000000000000000000000000000000000000000000000000076a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000a",
+	}
+	type nft struct {
+		typeName bchain.TokenTypeName
+		contract string
+		from     string
+		to       string
+		tokenId  string
+		amount   string
+	}
+	tests := []struct {
+		name string
+		logs []*bchain.RpcLog
+		want []nft
+	}{
+		{
+			name: "ERC20 transfer is not NFT",
+			logs: []*bchain.RpcLog{erc20Transfer},
+		},
+		{
+			name: "ERC721 Transfer",
+			logs: []*bchain.RpcLog{erc721Transfer},
+			want: []nft{
+				{bchain.ERC771TokenType, "0x5689b918D34C038901870105A6C7fc24744D31eB", "0x0a206d4d5ff79cb5069def7fe3598421cff09391", "0x6a016d7eec560549ffa0fbdb7f15c2b27302087f", "5014", "1"},
+			},
+		},
+		{
+			name: "ERC1155 TransferSingle",
+			logs: []*bchain.RpcLog{erc1155TransferSingle},
+			want: []nft{
+				{bchain.ERC1155TokenType, "0x6Fd712E3A5B556654044608F9129040A4839E36c", "0xa3950b823cb063dd9afc0d27f35008b805b3ed53", "0x4392faf3bb96b5694ecc6ef64726f61cdd4bb0ec", "150", "17"},
+			},
+		},
+		{
+			name: "ERC1155 TransferBatch",
+			logs: []*bchain.RpcLog{erc1155TransferBatch},
+			want: []nft{
+				{bchain.ERC1155TokenType, "0x6c42C26a081c2F509F8bb68fb7Ac3062311cCfB7", "0x0000000000000000000000000000000000000000", "0x5dc6288b35e0807a3d6feb89b3a2ff4ab773168e", "1776", "1"},
+				{bchain.ERC1155TokenType, "0x6c42C26a081c2F509F8bb68fb7Ac3062311cCfB7", "0x0000000000000000000000000000000000000000", "0x5dc6288b35e0807a3d6feb89b3a2ff4ab773168e", "1898", "10"},
+			},
+		},
+		{
+			name: "mixed transfers",
+			logs: []*bchain.RpcLog{erc20Transfer, erc721Transfer, erc1155TransferBatch},
+			want: []nft{
+				{bchain.ERC771TokenType, "0x5689b918D34C038901870105A6C7fc24744D31eB", "0x0a206d4d5ff79cb5069def7fe3598421cff09391", "0x6a016d7eec560549ffa0fbdb7f15c2b27302087f", "5014", "1"},
+				{bchain.ERC1155TokenType, "0x6c42C26a081c2F509F8bb68fb7Ac3062311cCfB7", "0x0000000000000000000000000000000000000000", "0x5dc6288b35e0807a3d6feb89b3a2ff4ab773168e", "1776", "1"},
+				{bchain.ERC1155TokenType, "0x6c42C26a081c2F509F8bb68fb7Ac3062311cCfB7", "0x0000000000000000000000000000000000000000", "0x5dc6288b35e0807a3d6feb89b3a2ff4ab773168e", "1898", "10"},
+			},
+		},
+	}
+	parser := eth.NewEthereumParser(1, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &bchain.Tx{
+				CoinSpecificData: bchain.EthereumSpecificData{
+					Receipt: &bchain.RpcReceipt{Logs: tt.logs},
+				},
+			}
+			transfers, err := parser.EthereumTypeGetTokenTransfersFromTx(tx)
+			if err != nil {
+				t.Fatalf("EthereumTypeGetTokenTransfersFromTx error %v", err)
+			}
+			got := getEthereumNftTransfers(transfers)
+			if len(got) != len(tt.want) {
+				t.Fatalf("getEthereumNftTransfers = %+v, want %+v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				// the addresses could have different case
+				if g.Type != w.typeName || !strings.EqualFold(g.Contract, w.contract) || !strings.EqualFold(g.From, w.from) || !strings.EqualFold(g.To, w.to) ||
+					g.TokenId.String() != w.tokenId || g.Amount.String() != w.amount {
+					t.Errorf("getEthereumNftTransfers %d = %v %v %v %v %v %v, want %+v", i, g.Type, g.Contract, g.From, g.To, g.TokenId, g.Amount, w)
+				}
+			}
+		})
+	}
+}
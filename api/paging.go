@@ -0,0 +1,44 @@
+package api
+
+// indexPager selects the items of one page while an index is iterated, without collecting all the items first;
+// the iteration can be stopped after the first item following the page, the total number of pages is then not known
+type indexPager struct {
+	page        int
+	itemsOnPage int
+	count       int
+}
+
+// newIndexPager returns the pager of the zero based page
+func newIndexPager(page int, itemsOnPage int) *indexPager {
+	if itemsOnPage <= 0 {
+		itemsOnPage = 1
+	}
+	return &indexPager{page: page, itemsOnPage: itemsOnPage}
+}
+
+// next registers the next item of the index and returns if the item is in the page
+// and if the iteration can be stopped because the item follows the page
+func (p *indexPager) next() (inPage bool, stop bool) {
+	from := p.page * p.itemsOnPage
+	to := from + p.itemsOnPage
+	i := p.count
+	p.count++
+	return i >= from && i < to, i >= to
+}
+
+// paging returns the Paging of the iterated index, TotalPages is -1 if the iteration was stopped after the page
+func (p *indexPager) paging() Paging {
+	pg := Paging{
+		ItemsOnPage: p.itemsOnPage,
+		Page:        p.page + 1,
+	}
+	if p.count > (p.page+1)*p.itemsOnPage {
+		pg.TotalPages = -1
+	} else {
+		pg.TotalPages = (p.count + p.itemsOnPage - 1) / p.itemsOnPage
+		if pg.TotalPages == 0 {
+			pg.TotalPages = 1
+		}
+	}
+	return pg
+}
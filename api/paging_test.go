@@ -0,0 +1,87 @@
+//go:build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_indexPager(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       int
+		page        int
+		itemsOnPage int
+		wantItems   []int
+		wantPaging  Paging
+	}{
+		{
+			name:        "empty index",
+			items:       0,
+			page:        0,
+			itemsOnPage: 10,
+			wantPaging:  Paging{Page: 1, TotalPages: 1, ItemsOnPage: 10},
+		},
+		{
+			name:        "single page",
+			items:       3,
+			page:        0,
+			itemsOnPage: 10,
+			wantItems:   []int{0, 1, 2},
+			wantPaging:  Paging{Page: 1, TotalPages: 1, ItemsOnPage: 10},
+		},
+		{
+			name:        "first page of more",
+			items:       25,
+			page:        0,
+			itemsOnPage: 10,
+			wantItems:   []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+			wantPaging:  Paging{Page: 1, TotalPages: -1, ItemsOnPage: 10},
+		},
+		{
+			name:        "last page",
+			items:       25,
+			page:        2,
+			itemsOnPage: 10,
+			wantItems:   []int{20, 21, 22, 23, 24},
+			wantPaging:  Paging{Page: 3, TotalPages: 3, ItemsOnPage: 10},
+		},
+		{
+			name:        "exactly full last page",
+			items:       20,
+			page:        1,
+			itemsOnPage: 10,
+			wantItems:   []int{10, 11, 12, 13, 14, 15, 16, 17, 18, 19},
+			wantPaging:  Paging{Page: 2, TotalPages: 2, ItemsOnPage: 10},
+		},
+		{
+			name:        "page beyond the index",
+			items:       5,
+			page:        3,
+			itemsOnPage: 10,
+			wantPaging:  Paging{Page: 4, TotalPages: 1, ItemsOnPage: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newIndexPager(tt.page, tt.itemsOnPage)
+			var got []int
+			for i := 0; i < tt.items; i++ {
+				inPage, stop := p.next()
+				if stop {
+					break
+				}
+				if inPage {
+					got = append(got, i)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantItems) {
+				t.Errorf("items = %v, want %v", got, tt.wantItems)
+			}
+			if pg := p.paging(); pg != tt.wantPaging {
+				t.Errorf("paging() = %+v, want %+v", pg, tt.wantPaging)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// partially signed bitcoin transaction (BIP174)
+// only the parts needed to add the previous outputs to the inputs are parsed, the other records are kept as they are
+
+var psbtMagic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// record key types
+const (
+	psbtGlobalUnsignedTx    = 0x00
+	psbtInputNonWitnessUtxo = 0x00
+	psbtInputWitnessUtxo    = 0x01
+	psbtInputRedeemScript   = 0x04
+	psbtInputWitnessScript  = 0x05
+)
+
+const psbtMaxRecordSize = 4000000
+const psbtMaxInOuts = 3000
+
+type psbtRecord struct {
+	key   []byte
+	value []byte
+}
+
+type psbtMap []psbtRecord
+
+// get returns the value of the record with the key consisting only of the key type
+func (m psbtMap) get(keyType byte) []byte {
+	for i := range m {
+		if len(m[i].key) == 1 && m[i].key[0] == keyType {
+			return m[i].value
+		}
+	}
+	return nil
+}
+
+func (m *psbtMap) set(keyType byte, value []byte) {
+	for i := range *m {
+		if len((*m)[i].key) == 1 && (*m)[i].key[0] == keyType {
+			(*m)[i].value = value
+			return
+		}
+	}
+	*m = append(*m, psbtRecord{key: []byte{keyType}, value: value})
+}
+
+type psbt struct {
+	global  psbtMap
+	tx      wire.MsgTx
+	inputs  []psbtMap
+	outputs []psbtMap
+}
+
+func readPsbtMap(r io.Reader) (psbtMap, error) {
+	var m psbtMap
+	for {
+		key, err := wire.ReadVarBytes(r, 0, psbtMaxRecordSize, "key")
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			return m, nil
+		}
+		value, err := wire.ReadVarBytes(r, 0, psbtMaxRecordSize, "value")
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, psbtRecord{key: key, value: value})
+	}
+}
+
+func writePsbtMap(w io.Writer, m psbtMap) error {
+	for i := range m {
+		if err := wire.WriteVarBytes(w, 0, m[i].key); err != nil {
+			return err
+		}
+		if err := wire.WriteVarBytes(w, 0, m[i].value); err != nil {
+			return err
+		}
+	}
+	return wire.WriteVarInt(w, 0, 0)
+}
+
+func parsePsbt(data []byte) (*psbt, error) {
+	if !bytes.HasPrefix(data, psbtMagic) {
+		return nil, errors.New("missing magic bytes")
+	}
+	r := bytes.NewReader(data[len(psbtMagic):])
+	var p psbt
+	var err error
+	if p.global, err = readPsbtMap(r); err != nil {
+		return nil, errors.Annotatef(err, "global")
+	}
+	unsignedTx := p.global.get(psbtGlobalUnsignedTx)
+	if unsignedTx == nil {
+		return nil, errors.New("missing unsigned transaction")
+	}
+	if err = p.tx.DeserializeNoWitness(bytes.NewReader(unsignedTx)); err != nil {
+		return nil, errors.Annotatef(err, "unsigned transaction")
+	}
+	if len(p.tx.TxIn) > psbtMaxInOuts || len(p.tx.TxOut) > psbtMaxInOuts {
+		return nil, errors.New("too many inputs or outputs")
+	}
+	p.inputs = make([]psbtMap, len(p.tx.TxIn))
+	for i := range p.inputs {
+		if p.inputs[i], err = readPsbtMap(r); err != nil {
+			return nil, errors.Annotatef(err, "input %d", i)
+		}
+	}
+	p.outputs = make([]psbtMap, len(p.tx.TxOut))
+	for i := range p.outputs {
+		if p.outputs[i], err = readPsbtMap(r); err != nil {
+			return nil, errors.Annotatef(err, "output %d", i)
+		}
+	}
+	return &p, nil
+}
+
+func (p *psbt) serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(psbtMagic)
+	if err := writePsbtMap(&buf, p.global); err != nil {
+		return nil, err
+	}
+	for _, m := range p.inputs {
+		if err := writePsbtMap(&buf, m); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range p.outputs {
+		if err := writePsbtMap(&buf, m); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// isWitnessProgram returns true if the script is a segwit output script of any witness version
+func isWitnessProgram(script []byte) bool {
+	if len(script) < 4 || len(script) > 42 {
+		return false
+	}
+	if script[0] != 0x00 && (script[0] < 0x51 || script[0] > 0x60) {
+		return false
+	}
+	return int(script[1]) == len(script)-2
+}
+
+func isP2SH(script []byte) bool {
+	return len(script) == 23 && script[0] == 0xa9 && script[1] == 0x14 && script[22] == 0x87
+}
+
+// isPsbtSegwitInput returns true if the input spends a segwit output, including P2SH embedded ones,
+// which are recognized by the redeem script or the witness script in the input record
+func isPsbtSegwitInput(script []byte, input psbtMap) bool {
+	if isWitnessProgram(script) {
+		return true
+	}
+	if isP2SH(script) {
+		return isWitnessProgram(input.get(psbtInputRedeemScript)) || input.get(psbtInputWitnessScript) != nil
+	}
+	return false
+}
+
+// packPsbtWitnessUtxo serializes the output in the format of the witness utxo record
+func packPsbtWitnessUtxo(value int64, script []byte) []byte {
+	var buf bytes.Buffer
+	var v [8]byte
+	binary.LittleEndian.PutUint64(v[:], uint64(value))
+	buf.Write(v[:])
+	wire.WriteVarBytes(&buf, 0, script)
+	return buf.Bytes()
+}
+
+// getPrevout returns the previous output spent by the input, from the index or from the backend if the transaction is not in the index
+func (w *Worker) getPrevout(txid string, vout uint32) (bchain.AddressDescriptor, *big.Int, error) {
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+	}
+	if ta != nil {
+		if int(vout) >= len(ta.Outputs) {
+			return nil, nil, NewAPIError("Output "+txid+":"+strconv.Itoa(int(vout))+" not found", true)
+		}
+		return ta.Outputs[vout].AddrDesc, &ta.Outputs[vout].ValueSat, nil
+	}
+	tx, err := w.chain.GetTransaction(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil, nil, NewAPIError("Transaction "+txid+" not found", true)
+		}
+		return nil, nil, errors.Annotatef(err, "GetTransaction %v", txid)
+	}
+	if int(vout) >= len(tx.Vout) {
+		return nil, nil, NewAPIError("Output "+txid+":"+strconv.Itoa(int(vout))+" not found", true)
+	}
+	addrDesc, err := w.chainParser.GetAddrDescFromVout(&tx.Vout[vout])
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "GetAddrDescFromVout %v:%v", txid, vout)
+	}
+	return addrDesc, &tx.Vout[vout].ValueSat, nil
+}
+
+// EnrichPsbt fills the previous outputs of the inputs of a base64 encoded PSBT
+// the witness utxo is added to the inputs spending segwit outputs, the non-witness utxo to all inputs if the backend provides the previous transaction
+func (w *Worker) EnrichPsbt(psbtBase64 string) (*EnrichedPsbt, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	data, err := base64.StdEncoding.DecodeString(psbtBase64)
+	if err != nil {
+		return nil, NewAPIError("Invalid PSBT, "+err.Error(), true)
+	}
+	p, err := parsePsbt(data)
+	if err != nil {
+		return nil, NewAPIError("Invalid PSBT, "+err.Error(), true)
+	}
+	r := &EnrichedPsbt{
+		Inputs: make([]PsbtInput, len(p.tx.TxIn)),
+	}
+	for i, in := range p.tx.TxIn {
+		txid := in.PreviousOutPoint.Hash.String()
+		vout := in.PreviousOutPoint.Index
+		addrDesc, value, err := w.getPrevout(txid, vout)
+		if err != nil {
+			return nil, err
+		}
+		input := &r.Inputs[i]
+		input.Txid = txid
+		input.Vout = vout
+		input.ValueSat = (*Amount)(value)
+		input.Script = hex.EncodeToString(addrDesc)
+		input.Addresses, _, _ = w.chainParser.GetAddressesFromAddrDesc(addrDesc)
+		if isPsbtSegwitInput(addrDesc, p.inputs[i]) && p.inputs[i].get(psbtInputWitnessUtxo) == nil {
+			p.inputs[i].set(psbtInputWitnessUtxo, packPsbtWitnessUtxo(value.Int64(), addrDesc))
+		}
+		if p.inputs[i].get(psbtInputNonWitnessUtxo) == nil {
+			txHex, err := w.chain.GetTransactionHex(txid)
+			if err != nil {
+				glog.V(1).Info("EnrichPsbt: GetTransactionHex ", txid, ": ", err)
+			} else if prevTx, err := hex.DecodeString(txHex); err == nil {
+				p.inputs[i].set(psbtInputNonWitnessUtxo, prevTx)
+			}
+		}
+		input.WitnessUtxo = p.inputs[i].get(psbtInputWitnessUtxo) != nil
+		input.NonWitnessUtxo = p.inputs[i].get(psbtInputNonWitnessUtxo) != nil
+	}
+	data, err = p.serialize()
+	if err != nil {
+		return nil, err
+	}
+	r.Psbt = base64.StdEncoding.EncodeToString(data)
+	glog.Info("EnrichPsbt ", len(r.Inputs), " inputs, ", time.Since(start))
+	return r, nil
+}
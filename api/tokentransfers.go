@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// defaultMaxTokenTransfers is the cap of the token transfers returned by one query used if the cap is not configured
+const defaultMaxTokenTransfers = 10000
+
+// maxTokenTransfers returns the maximum number of token transfers returned by one paged or exported query
+func (w *Worker) maxTokenTransfers() int {
+	if w.is.MaxTokenTransfers > 0 {
+		return w.is.MaxTokenTransfers
+	}
+	return defaultMaxTokenTransfers
+}
+
+// ForEachAddressTokenTransfer calls fn for the confirmed token transfers of the address, from the newest,
+// optionally only for the transfers of the contract; at most maxTokenTransfers transfers are passed to fn,
+// the iteration is stopped without an error if fn returns db.StopIteration
+// only the transactions with a contract index of the address are read, also at most maxTokenTransfers of them,
+// so that the transactions without the transfers of the address do not make the scan unbounded
+func (w *Worker) ForEachAddressTokenTransfer(address string, contract string, fn func(t *AddressTokenTransfer) error) error {
+	if w.chainType != bchain.ChainEthereumType {
+		return NewAPIError("Token transfers are supported only for Ethereum-type coins", true)
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	ca, err := w.db.GetAddrDescContracts(addrDesc)
+	if err != nil {
+		return NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
+	}
+	if ca == nil {
+		return nil
+	}
+	// the transactions of the contract are selected by the index of the contract in the address contracts
+	contractIndex := int32(AddressFilterVoutOff)
+	var cd bchain.AddressDescriptor
+	if contract != "" {
+		cd, err = w.chainParser.GetAddrDescFromAddress(contract)
+		if err != nil {
+			return NewAPIError(fmt.Sprintf("Invalid contract filter, %v", err), true)
+		}
+		for i := range ca.Contracts {
+			if bytes.Equal(cd, ca.Contracts[i].Contract) {
+				contractIndex = int32(i + db.ContractIndexOffset)
+				break
+			}
+		}
+		// the address has no transfers of the contract
+		if contractIndex == AddressFilterVoutOff {
+			return nil
+		}
+	}
+	maxTransfers := w.maxTokenTransfers()
+	count, scanned := 0, 0
+	var bi *db.BlockInfo
+	return w.db.GetAddrDescTransactions(addrDesc, 0, maxUint32, func(txid string, height uint32, indexes []int32) error {
+		found := false
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+			}
+			if (contractIndex == AddressFilterVoutOff && index >= db.ContractIndexOffset) || index == contractIndex {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+		if scanned >= maxTransfers {
+			return &db.StopIteration{}
+		}
+		scanned++
+		tx, _, err := w.txCache.GetTransaction(txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTransaction %v", txid)
+		}
+		transfers, err := w.chainParser.EthereumTypeGetTokenTransfersFromTx(tx)
+		if err != nil {
+			return errors.Annotatef(err, "EthereumTypeGetTokenTransfersFromTx %v", txid)
+		}
+		// only the transfers from or to the address, the transaction can contain transfers of other addresses
+		var own bchain.TokenTransfers
+		for _, t := range transfers {
+			if !strings.EqualFold(t.From, address) && !strings.EqualFold(t.To, address) {
+				continue
+			}
+			if len(cd) > 0 {
+				if tcd, err := w.chainParser.GetAddrDescFromAddress(t.Contract); err != nil || !bytes.Equal(cd, tcd) {
+					continue
+				}
+			}
+			own = append(own, t)
+		}
+		if len(own) == 0 {
+			return nil
+		}
+		if bi == nil || bi.Height != height {
+			if bi, err = w.db.GetBlockInfo(height); err != nil {
+				return errors.Annotatef(err, "GetBlockInfo %v", height)
+			}
+		}
+		var blockTime int64
+		if bi != nil {
+			blockTime = bi.Time
+		}
+		for _, t := range w.getEthereumTokensTransfers(own, nil) {
+			if err := fn(&AddressTokenTransfer{
+				Txid:        txid,
+				BlockHeight: int(height),
+				BlockTime:   blockTime,
+				Transfer:    t,
+			}); err != nil {
+				return err
+			}
+			count++
+			if count >= maxTransfers {
+				return &db.StopIteration{}
+			}
+		}
+		return nil
+	})
+}
+
+// GetAddressTokenTransfers returns paged list of the confirmed token transfers of the address, from the newest,
+// optionally only the transfers of the contract; the pages beyond maxTokenTransfers transfers are not available
+func (w *Worker) GetAddressTokenTransfers(address string, contract string, page int, itemsOnPage int) (*AddressTokenTransfers, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	_, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	maxTransfers := w.maxTokenTransfers()
+	if page*itemsOnPage >= maxTransfers {
+		return nil, NewAPIError(fmt.Sprintf("Page is beyond the limit of %d token transfers", maxTransfers), true)
+	}
+	// read the transfers up to the requested page, the number of all the transfers is not known
+	limit := (page + 1) * itemsOnPage
+	transfers := make([]AddressTokenTransfer, 0)
+	err = w.ForEachAddressTokenTransfer(address, contract, func(t *AddressTokenTransfer) error {
+		transfers = append(transfers, *t)
+		if len(transfers) >= limit {
+			return &db.StopIteration{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pg, from, to, page := computePaging(len(transfers), page, itemsOnPage)
+	if len(transfers) >= limit && limit < maxTransfers {
+		pg.TotalPages = -1
+	}
+	r := &AddressTokenTransfers{
+		Paging:    pg,
+		Address:   address,
+		Contract:  contract,
+		Transfers: transfers[from:to],
+	}
+	glog.Info("GetAddressTokenTransfers ", address, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
@@ -148,6 +148,10 @@ type Vout struct {
 	IsAddress   bool                     `json:"isAddress"`
 	IsOwn       bool                     `json:"isOwn,omitempty"`
 	Type        string                   `json:"type,omitempty"`
+	// LockTimeHeight is the block height and LockTimeTimestamp the unix time required by OP_CHECKLOCKTIMEVERIFY
+	// in the output script, the output cannot be spent before
+	LockTimeHeight    uint32 `json:"lockTimeHeight,omitempty"`
+	LockTimeTimestamp int64  `json:"lockTimeTimestamp,omitempty"`
 }
 
 // MultiTokenValue contains values for contract with id and value (like ERC1155)
@@ -215,6 +219,16 @@ type TokenTransfer struct {
 	MultiTokenValues []MultiTokenValue    `json:"multiTokenValues,omitempty"`
 }
 
+// NftTransfer contains the transfer of one ERC721 or ERC1155 token identified by its id
+type NftTransfer struct {
+	Type     bchain.TokenTypeName `json:"type" ts_type:"'ERC721' | 'ERC1155'"`
+	Contract string               `json:"contract"`
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	TokenId  *Amount              `json:"tokenId"`
+	Amount   *Amount              `json:"amount"`
+}
+
 type EthereumInternalTransfer struct {
 	Type  bchain.EthereumInternalTransactionType `json:"type"`
 	From  string                                 `json:"from"`
@@ -252,10 +266,12 @@ type Tx struct {
 	Vout                   []Vout            `json:"vout"`
 	Blockhash              string            `json:"blockHash,omitempty"`
 	Blockheight            int               `json:"blockHeight"`
+	BlockIndex             *int              `json:"blockIndex,omitempty"`
 	Confirmations          uint32            `json:"confirmations"`
 	ConfirmationETABlocks  uint32            `json:"confirmationETABlocks,omitempty"`
 	ConfirmationETASeconds int64             `json:"confirmationETASeconds,omitempty"`
 	Blocktime              int64             `json:"blockTime"`
+	FirstSeen              int64             `json:"firstSeen,omitempty"`
 	Size                   int               `json:"size,omitempty"`
 	VSize                  int               `json:"vsize,omitempty"`
 	ValueOutSat            *Amount           `json:"value"`
@@ -263,8 +279,22 @@ type Tx struct {
 	FeesSat                *Amount           `json:"fees,omitempty"`
 	Hex                    string            `json:"hex,omitempty"`
 	Rbf                    bool              `json:"rbf,omitempty"`
+	Standard               *bool             `json:"standard,omitempty"`
+	NonStandardReason      string            `json:"nonStandardReason,omitempty"`
+	DropReason             string            `json:"dropReason,omitempty"`
+	ReplacedBy             string            `json:"replacedBy,omitempty"`
+	ReplacementTxid        string            `json:"replacementTxid,omitempty"`
+	ReplacementConfs       uint32            `json:"replacementConfirmations,omitempty"`
+	Replaces               []string          `json:"replaces,omitempty"`
+	CoinStake              bool              `json:"coinStake,omitempty"`
+	CoinJoinLikely         bool              `json:"coinJoinLikely,omitempty"`
+	CoinJoinAnonymitySet   int               `json:"coinJoinAnonymitySet,omitempty"`
+	Memo                   string            `json:"memo,omitempty"`
+	ChangeIndex            *int              `json:"changeIndex,omitempty"`
+	ChangeConfidence       float64           `json:"changeConfidence,omitempty"`
 	CoinSpecificData       json.RawMessage   `json:"coinSpecificData,omitempty" ts_type:"any"`
 	TokenTransfers         []TokenTransfer   `json:"tokenTransfers,omitempty"`
+	NftTransfers           []NftTransfer     `json:"nftTransfers,omitempty"`
 	EthereumSpecific       *EthereumSpecific `json:"ethereumSpecific,omitempty"`
 	AddressAliases         AddressAliasesMap `json:"addressAliases,omitempty"`
 }
@@ -314,23 +344,26 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// MinValue filters out the transactions changing the balance of the address by less than MinValue (in absolute value), nil means no filter
+	MinValue *big.Int
 }
 
 // Address holds information about address and its transactions
 type Address struct {
 	Paging
 	AddrStr               string               `json:"address"`
-	BalanceSat            *Amount              `json:"balance"`
+	BalanceSat            *Amount              `json:"balance"` // confirmed balance, computed only from the block index
 	TotalReceivedSat      *Amount              `json:"totalReceived,omitempty"`
 	TotalSentSat          *Amount              `json:"totalSent,omitempty"`
-	UnconfirmedBalanceSat *Amount              `json:"unconfirmedBalance"`
-	UnconfirmedTxs        int                  `json:"unconfirmedTxs"`
-	Txs                   int                  `json:"txs"`
+	UnconfirmedBalanceSat *Amount              `json:"unconfirmedBalance"` // change of the balance by mempool transactions
+	UnconfirmedTxs        int                  `json:"unconfirmedTxs"`     // number of mempool transactions
+	Txs                   int                  `json:"txs"`                // number of confirmed transactions
 	NonTokenTxs           int                  `json:"nonTokenTxs,omitempty"`
 	InternalTxs           int                  `json:"internalTxs,omitempty"`
 	Transactions          []*Tx                `json:"transactions,omitempty"`
 	Txids                 []string             `json:"txids,omitempty"`
 	Nonce                 string               `json:"nonce,omitempty"`
+	IsContract            *bool                `json:"isContract,omitempty"`
 	UsedTokens            int                  `json:"usedTokens,omitempty"`
 	Tokens                Tokens               `json:"tokens,omitempty"`
 	SecondaryValue        float64              `json:"secondaryValue,omitempty"` // address value in secondary currency
@@ -346,6 +379,17 @@ type Address struct {
 	XPubAddresses map[string]struct{} `json:"-"`
 }
 
+// AddressBalance contains the balance and the number of transactions of an address returned by the batch balance query
+type AddressBalance struct {
+	AddrStr               string  `json:"address"`
+	BalanceSat            *Amount `json:"balance"`
+	TotalReceivedSat      *Amount `json:"totalReceived,omitempty"`
+	TotalSentSat          *Amount `json:"totalSent,omitempty"`
+	UnconfirmedBalanceSat *Amount `json:"unconfirmedBalance"`
+	UnconfirmedTxs        int     `json:"unconfirmedTxs"`
+	Txs                   int     `json:"txs"`
+}
+
 // Utxo is one unspent transaction output
 type Utxo struct {
 	Txid          string  `json:"txid"`
@@ -355,6 +399,7 @@ type Utxo struct {
 	Confirmations int     `json:"confirmations"`
 	Address       string  `json:"address,omitempty"`
 	Path          string  `json:"path,omitempty"`
+	Script        string  `json:"script,omitempty"`
 	Locktime      uint32  `json:"lockTime,omitempty"`
 	Coinbase      bool    `json:"coinbase,omitempty"`
 }
@@ -377,6 +422,22 @@ func (a Utxos) Less(i, j int) bool {
 	return hi >= hj
 }
 
+// SortByAge sorts the utxos from the oldest, i.e. by confirmations descending, unconfirmed utxos are at the end
+// the order of utxos from the same block is kept
+func (a Utxos) SortByAge() {
+	sort.SliceStable(a, func(i, j int) bool {
+		hi := a[i].Height
+		hj := a[j].Height
+		if hi == 0 {
+			hi = maxInt
+		}
+		if hj == 0 {
+			hj = maxInt
+		}
+		return hi < hj
+	})
+}
+
 // BalanceHistory contains info about one point in time of balance history
 type BalanceHistory struct {
 	Time          uint32             `json:"time"`
@@ -388,6 +449,23 @@ type BalanceHistory struct {
 	Txid          string             `json:"txid,omitempty"`
 }
 
+// BalanceDelta is the net change of the confirmed balance of an address in a block
+type BalanceDelta struct {
+	Height   uint32  `json:"height"`
+	Time     int64   `json:"time"`
+	Txs      int     `json:"txs"`
+	DeltaSat *Amount `json:"delta"`
+}
+
+// BalanceDeltas contains the changes of the balance of an address in the blocks of a range
+type BalanceDeltas struct {
+	AddrStr       string         `json:"address"`
+	FromHeight    uint32         `json:"fromHeight"`
+	ToHeight      uint32         `json:"toHeight"`
+	TotalDeltaSat *Amount        `json:"totalDelta"`
+	Deltas        []BalanceDelta `json:"deltas"`
+}
+
 // BalanceHistories is array of BalanceHistory
 type BalanceHistories []BalanceHistory
 
@@ -450,21 +528,223 @@ type Blocks struct {
 	Blocks []db.BlockInfo `json:"blocks"`
 }
 
+// OpReturnTx contains transaction with OP_RETURN outputs carrying an indexed prefix
+type OpReturnTx struct {
+	Txid        string  `json:"txid"`
+	BlockHeight int     `json:"blockHeight"`
+	Vouts       []int32 `json:"vouts"`
+}
+
+// TxRecipient is an output of a transaction with its addresses and value
+type TxRecipient struct {
+	N         int      `json:"n"`
+	Addresses []string `json:"addresses"`
+	IsAddress bool     `json:"isAddress"`
+	ValueSat  *Amount  `json:"value"`
+}
+
+// TxRecipients contains the outputs of a transaction without the rest of the transaction data
+type TxRecipients struct {
+	Txid          string        `json:"txid"`
+	Blockheight   int           `json:"blockHeight"`
+	Confirmations uint32        `json:"confirmations"`
+	Recipients    []TxRecipient `json:"recipients"`
+}
+
+// TxInputPrevout is the output spent by an input of a transaction
+type TxInputPrevout struct {
+	Txid            string   `json:"txid"`
+	N               int      `json:"n"`
+	PrevTxid        string   `json:"prevTxid"`
+	PrevVout        int      `json:"prevVout"`
+	PrevBlockHeight int      `json:"prevBlockHeight"`
+	Addresses       []string `json:"addresses"`
+	IsAddress       bool     `json:"isAddress"`
+	ValueSat        *Amount  `json:"value"`
+	Hex             string   `json:"hex,omitempty"`
+}
+
+// AddressSummary contains aggregated data of an address without the list of its transactions
+type AddressSummary struct {
+	AddrStr          string  `json:"address"`
+	BalanceSat       *Amount `json:"balance"`
+	TotalReceivedSat *Amount `json:"totalReceived"`
+	TotalSentSat     *Amount `json:"totalSent"`
+	Txs              int     `json:"txs"`
+	FirstSeenHeight  uint32  `json:"firstSeenHeight,omitempty"`
+	FirstSeenTime    int64   `json:"firstSeenTime,omitempty"`
+	FirstSeenTxid    string  `json:"firstSeenTxid,omitempty"` // only with the address first seen index
+	LastSeenHeight   uint32  `json:"lastSeenHeight,omitempty"`
+	LastSeenTime     int64   `json:"lastSeenTime,omitempty"`
+	// number of distinct transactions in which the address received funds, more than one means the address was reused,
+	// only if requested, it reads the whole history of the address
+	ReuseCount          *int   `json:"reuseCount,omitempty"`
+	FirstReceivedHeight uint32 `json:"firstReceivedHeight,omitempty"`
+}
+
+// AddressTokenTransfer is a token transfer of an address together with the transaction containing it
+type AddressTokenTransfer struct {
+	Txid        string        `json:"txid"`
+	BlockHeight int           `json:"blockHeight"`
+	BlockTime   int64         `json:"blockTime"`
+	Transfer    TokenTransfer `json:"transfer"`
+}
+
+// AddressTokenTransfers contains paged list of token transfers of an address, from the newest
+type AddressTokenTransfers struct {
+	Paging
+	Address   string                 `json:"address"`
+	Contract  string                 `json:"contract,omitempty"`
+	Transfers []AddressTokenTransfer `json:"transfers"`
+}
+
+// OpReturnTxs contains paged list of transactions with OP_RETURN outputs carrying the prefix
+type OpReturnTxs struct {
+	Paging
+	Prefix string       `json:"prefix"`
+	Txs    []OpReturnTx `json:"txs"`
+}
+
+// ScriptOutputTx contains transaction which created outputs with the requested output script
+type ScriptOutputTx struct {
+	Txid        string  `json:"txid"`
+	BlockHeight int     `json:"blockHeight"`
+	Vouts       []int32 `json:"vouts"`
+}
+
+// ScriptOutputTxs contains paged list of transactions which created outputs with the output script
+type ScriptOutputTxs struct {
+	Paging
+	Script string           `json:"script"`
+	Txs    []ScriptOutputTx `json:"txs"`
+}
+
+// SpendingEdge is an edge of the graph of spending transactions, the output Txid:Vout spent by the transaction SpentTxid
+type SpendingEdge struct {
+	Txid      string `json:"txid"`
+	Vout      int    `json:"vout"`
+	SpentTxid string `json:"spentTxid"`
+	Depth     int    `json:"depth"`
+	// Unknown is set if the output is spent but its spending transaction is not known, because the spend data were pruned
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// SpendingGraph contains the edges of the graph of transactions spending the funds of the output Txid:Vout up to the Depth
+type SpendingGraph struct {
+	Txid      string         `json:"txid"`
+	Vout      int            `json:"vout"`
+	Depth     int            `json:"depth"`
+	Edges     []SpendingEdge `json:"edges"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// ValueOutput is an output found by the index of outputs by value
+type ValueOutput struct {
+	Txid        string  `json:"txid"`
+	Vout        int32   `json:"vout"`
+	ValueSat    *Amount `json:"value"`
+	BlockHeight int     `json:"blockHeight"`
+}
+
+// ValueOutputs contains paged list of outputs with value in the requested range
+type ValueOutputs struct {
+	Paging
+	MinValueSat *Amount       `json:"minValue"`
+	MaxValueSat *Amount       `json:"maxValue"`
+	Outputs     []ValueOutput `json:"outputs"`
+}
+
+// MinerBlocks contains paged list of blocks mined by the miner
+type MinerBlocks struct {
+	Paging
+	Miner  string         `json:"miner"`
+	Blocks []db.BlockInfo `json:"blocks"`
+}
+
+// NewAddress is an address which appeared for the first time in the block
+type NewAddress struct {
+	AddrStr string `json:"address"`
+	Height  uint32 `json:"height"`
+	Txid    string `json:"txid"`
+}
+
+// NewAddresses contains paged list of addresses which appeared for the first time in a range of blocks
+type NewAddresses struct {
+	Paging
+	FromHeight uint32       `json:"fromHeight"`
+	ToHeight   uint32       `json:"toHeight"`
+	Addresses  []NewAddress `json:"addresses"`
+}
+
+// DifficultyPoint contains the difficulty of the block and the hashrate estimated from the difficulty in hashes per second
+type DifficultyPoint struct {
+	Height     uint32  `json:"height"`
+	Time       int64   `json:"time"`
+	Difficulty float64 `json:"difficulty"`
+	Hashrate   float64 `json:"hashrate"`
+}
+
+// DifficultyHistory contains the difficulty of the blocks sampled every Interval blocks
+type DifficultyHistory struct {
+	TargetBlockTime int               `json:"targetBlockTime"`
+	Interval        int               `json:"interval"`
+	Points          []DifficultyPoint `json:"points"`
+}
+
+// AddressValidation is the result of the validation of an address
+type AddressValidation struct {
+	Address      string `json:"address"`
+	IsValid      bool   `json:"isValid"`
+	ScriptType   string `json:"scriptType,omitempty"`
+	IsForNetwork bool   `json:"isForNetwork"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// GenesisInfo contains the information about the genesis block of the chain,
+// the coinbase fields are set only for Bitcoin-type coins
+type GenesisInfo struct {
+	Hash            string `json:"hash"`
+	Time            int64  `json:"time"`
+	CoinbaseTxid    string `json:"coinbaseTxid,omitempty"`
+	CoinbaseScript  string `json:"coinbaseScript,omitempty"`
+	CoinbaseMessage string `json:"coinbaseMessage,omitempty"`
+}
+
+// PsbtInput contains the previous output spent by an input of a PSBT
+type PsbtInput struct {
+	Txid           string   `json:"txid"`
+	Vout           uint32   `json:"vout"`
+	ValueSat       *Amount  `json:"value"`
+	Script         string   `json:"script"`
+	Addresses      []string `json:"addresses,omitempty"`
+	WitnessUtxo    bool     `json:"witnessUtxo"`
+	NonWitnessUtxo bool     `json:"nonWitnessUtxo"`
+}
+
+// EnrichedPsbt contains the base64 encoded PSBT with the previous outputs filled in the inputs
+type EnrichedPsbt struct {
+	Psbt   string      `json:"psbt"`
+	Inputs []PsbtInput `json:"inputs"`
+}
+
 // BlockInfo contains extended block header data and a list of block txids
 type BlockInfo struct {
-	Hash          string            `json:"hash"`
-	Prev          string            `json:"previousBlockHash,omitempty"`
-	Next          string            `json:"nextBlockHash,omitempty"`
-	Height        uint32            `json:"height"`
-	Confirmations int               `json:"confirmations"`
-	Size          int               `json:"size"`
-	Time          int64             `json:"time,omitempty"`
-	Version       common.JSONNumber `json:"version"`
-	MerkleRoot    string            `json:"merkleRoot"`
-	Nonce         string            `json:"nonce"`
-	Bits          string            `json:"bits"`
-	Difficulty    string            `json:"difficulty"`
-	Txids         []string          `json:"tx,omitempty"`
+	Hash           string            `json:"hash"`
+	Prev           string            `json:"previousBlockHash,omitempty"`
+	Next           string            `json:"nextBlockHash,omitempty"`
+	Height         uint32            `json:"height"`
+	Confirmations  int               `json:"confirmations"`
+	Size           int               `json:"size"`
+	StrippedSize   int               `json:"strippedSize,omitempty"`
+	Weight         int               `json:"weight,omitempty"`
+	Time           int64             `json:"time,omitempty"`
+	MedianTimePast int64             `json:"medianTimePast,omitempty"`
+	Version        common.JSONNumber `json:"version"`
+	MerkleRoot     string            `json:"merkleRoot"`
+	Nonce          string            `json:"nonce"`
+	Bits           string            `json:"bits"`
+	Difficulty     string            `json:"difficulty"`
+	Txids          []string          `json:"tx,omitempty"`
 }
 
 // Block contains information about block
@@ -472,15 +752,47 @@ type Block struct {
 	Paging
 	BlockInfo
 	TxCount        int               `json:"txCount"`
+	TotalInputs    int               `json:"totalInputs,omitempty"`
+	TotalOutputs   int               `json:"totalOutputs,omitempty"`
+	Reward         *BlockReward      `json:"reward,omitempty"`
 	Transactions   []*Tx             `json:"txs,omitempty"`
 	AddressAliases AddressAliasesMap `json:"addressAliases,omitempty"`
 }
 
+// BlockReward contains the split of the reward of the miner of the block to the subsidy and the fees of the transactions
+type BlockReward struct {
+	SubsidySat *Amount `json:"subsidy,omitempty"`
+	FeesSat    *Amount `json:"fees"`
+}
+
+// BlockTopTx is a transaction of a block ranked by its value or size
+type BlockTopTx struct {
+	Txid        string  `json:"txid"`
+	ValueOutSat *Amount `json:"value"`
+	VSize       int     `json:"vsize,omitempty"`
+	Size        int     `json:"size,omitempty"`
+}
+
+// BlockTopTxs contains the largest transactions of a block
+type BlockTopTxs struct {
+	Hash   string       `json:"hash"`
+	Height uint32       `json:"height"`
+	By     string       `json:"by"`
+	Txs    []BlockTopTx `json:"txs"`
+}
+
 // BlockRaw contains raw block in hex
 type BlockRaw struct {
 	Hex string `json:"hex"`
 }
 
+// BlockFilter contains the BIP158 basic compact filter and the BIP157 filter header of a block
+type BlockFilter struct {
+	BlockHash string `json:"blockHash"`
+	Filter    string `json:"filter"`
+	Header    string `json:"header"`
+}
+
 // BlockbookInfo contains information about the running blockbook instance
 type BlockbookInfo struct {
 	Coin                         string                       `json:"coin"`
@@ -527,6 +839,28 @@ type MempoolTxids struct {
 	MempoolSize int           `json:"mempoolSize"`
 }
 
+// MempoolInfo contains the summary of the mempool
+type MempoolInfo struct {
+	Count      int     `json:"count"`
+	TotalVSize int64   `json:"totalVSize"`
+	TotalFees  *Amount `json:"totalFees"`
+	MinFee     *Amount `json:"minFee"`
+}
+
+// SyncStatus contains the height of the index compared to the backend
+type SyncStatus struct {
+	Coin              string `json:"coin"`
+	Shortcut          string `json:"shortcut"`
+	Network           string `json:"network"`
+	BestHeight        uint32 `json:"bestHeight"`
+	BackendHeight     int    `json:"backendHeight"`
+	Lag               int    `json:"lag"`
+	InSync            bool   `json:"inSync"`
+	BackendVersion    string `json:"backendVersion,omitempty"`
+	BackendSubversion string `json:"backendSubversion,omitempty"`
+	BackendError      string `json:"backendError,omitempty"`
+}
+
 // FiatTicker contains formatted CurrencyRatesTicker data
 type FiatTicker struct {
 	Timestamp int64              `json:"ts,omitempty"`
@@ -539,6 +873,42 @@ type FiatTickers struct {
 	Tickers []FiatTicker `json:"tickers"`
 }
 
+// ConfirmationThreshold is a rule of a confirmation policy, transactions with fiat value at least MinValue require Confirmations
+type ConfirmationThreshold struct {
+	MinValue      float64
+	Confirmations int
+}
+
+// TxConfirmationPolicy contains the number of confirmations required for a transaction by its fiat value
+type TxConfirmationPolicy struct {
+	Txid                  string  `json:"txid"`
+	Currency              string  `json:"currency"`
+	Rate                  float32 `json:"rate"`
+	FiatValue             float64 `json:"fiatValue"`
+	Confirmations         int     `json:"confirmations"`
+	RequiredConfirmations int     `json:"requiredConfirmations"`
+	Met                   bool    `json:"met"`
+}
+
+// ConfirmationProbability contains the estimated probability that the transaction is confirmed within the number of blocks
+type ConfirmationProbability struct {
+	Txid               string  `json:"txid"`
+	Blocks             int     `json:"blocks"`
+	Probability        float64 `json:"probability"`
+	Confirmations      int     `json:"confirmations,omitempty"`
+	FeeRate            float64 `json:"feeRate,omitempty"`
+	MempoolVSizeAhead  int64   `json:"mempoolVSizeAhead,omitempty"`
+	BlockInclusionRate float64 `json:"blockInclusionRate,omitempty"`
+}
+
+// NextBlockFee contains the fee rates in satoshis per vbyte for the confirmation of a transaction in the next block
+type NextBlockFee struct {
+	Conservative     bool    `json:"conservative"`
+	EstimatedFeeRate float64 `json:"estimatedFeeRate"`
+	MempoolFeeRate   float64 `json:"mempoolFeeRate"`
+	MempoolVSize     int64   `json:"mempoolVSize"`
+}
+
 // AvailableVsCurrencies contains formatted data about available versus currencies for exchange rates
 type AvailableVsCurrencies struct {
 	Timestamp int64    `json:"ts,omitempty"`
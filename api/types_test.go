@@ -321,3 +321,59 @@ func TestTokens_Sort(t *testing.T) {
 		})
 	}
 }
+
+func TestUtxos_SortByAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		unsorted Utxos
+		sorted   Utxos
+	}{
+		{
+			name:     "empty",
+			unsorted: Utxos{},
+			sorted:   Utxos{},
+		},
+		{
+			name: "different heights",
+			unsorted: Utxos{
+				{Txid: "a", Vout: 0, Height: 0, Confirmations: 0},
+				{Txid: "b", Vout: 1, Height: 300, Confirmations: 1},
+				{Txid: "c", Vout: 0, Height: 200, Confirmations: 101},
+				{Txid: "d", Vout: 2, Height: 100, Confirmations: 201},
+			},
+			sorted: Utxos{
+				{Txid: "d", Vout: 2, Height: 100, Confirmations: 201},
+				{Txid: "c", Vout: 0, Height: 200, Confirmations: 101},
+				{Txid: "b", Vout: 1, Height: 300, Confirmations: 1},
+				{Txid: "a", Vout: 0, Height: 0, Confirmations: 0},
+			},
+		},
+		{
+			name: "same height keeps order",
+			unsorted: Utxos{
+				{Txid: "a", Vout: 0, Height: 0},
+				{Txid: "b", Vout: 1, Height: 200},
+				{Txid: "c", Vout: 0, Height: 100},
+				{Txid: "d", Vout: 0, Height: 0},
+				{Txid: "b", Vout: 0, Height: 200},
+				{Txid: "e", Vout: 3, Height: 100},
+			},
+			sorted: Utxos{
+				{Txid: "c", Vout: 0, Height: 100},
+				{Txid: "e", Vout: 3, Height: 100},
+				{Txid: "b", Vout: 1, Height: 200},
+				{Txid: "b", Vout: 0, Height: 200},
+				{Txid: "a", Vout: 0, Height: 0},
+				{Txid: "d", Vout: 0, Height: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.unsorted.SortByAge()
+			if !reflect.DeepEqual(tt.unsorted, tt.sorted) {
+				t.Errorf("Utxos SortByAge got %v, want %v", tt.unsorted, tt.sorted)
+			}
+		})
+	}
+}
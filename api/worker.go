@@ -2,6 +2,8 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -32,6 +34,17 @@ type Worker struct {
 	mempool           bchain.Mempool
 	is                *common.InternalState
 	metrics           *common.Metrics
+	// cached fee rate percentiles of the recent blocks
+	blockFeeRates    map[uint32][]float64
+	blockFeeRatesMux sync.Mutex
+	// cached information about the genesis block
+	genesisInfo    *GenesisInfo
+	genesisInfoMux sync.Mutex
+	// cached totals of the blocks by the block hash
+	blockTotals    map[string]*blockTotals
+	blockTotalsMux sync.Mutex
+	// heuristics detecting the change output of transactions, empty disables the detection
+	changeHeuristics []changeHeuristic
 }
 
 // NewWorker creates new api worker
@@ -50,6 +63,10 @@ func NewWorker(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool,
 	if w.chainType == bchain.ChainBitcoinType {
 		w.initXpubCache()
 	}
+	var err error
+	if w.changeHeuristics, err = selectChangeHeuristics(is.ChangeHeuristics); err != nil {
+		return nil, err
+	}
 	return w, nil
 }
 
@@ -130,6 +147,129 @@ func (w *Worker) GetSpendingTxid(txid string, n int) (string, error) {
 	return tx.Vout[n].SpentTxID, nil
 }
 
+// GetSpendingTxs returns transaction ids of transactions that spent the outputs of given transaction,
+// ordered by the output index, unspent outputs have empty txid
+func (w *Worker) GetSpendingTxs(txid string) ([]string, error) {
+	start := time.Now()
+	spendingTxs, _, err := w.getSpendingTxs(txid)
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("GetSpendingTxs ", txid, ", ", time.Since(start))
+	return spendingTxs, nil
+}
+
+// getSpendingTxs returns the spending transactions of the outputs of the transaction and the flags of the outputs
+// which are spent by an unknown transaction, because the spend data were pruned from the extended index
+func (w *Worker) getSpendingTxs(txid string) ([]string, []bool, error) {
+	var spendingTxs []string
+	var spentUnknown []bool
+	if w.db.HasExtendedIndex() {
+		// the spending transactions are stored in txAddresses, no need to get the transaction from backend
+		tsp, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, nil, err
+		} else if tsp == nil {
+			return nil, nil, NewAPIError(fmt.Sprintf("Txid %v not found", txid), false)
+		}
+		spendingTxs = make([]string, len(tsp.Outputs))
+		spentUnknown = make([]bool, len(tsp.Outputs))
+		for i := range tsp.Outputs {
+			spendingTxs[i] = tsp.Outputs[i].SpentTxid
+			spentUnknown[i] = tsp.Outputs[i].Spent && tsp.Outputs[i].SpentTxid == ""
+		}
+	} else {
+		tx, err := w.getTransaction(txid, true, false, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		spendingTxs = make([]string, len(tx.Vout))
+		spentUnknown = make([]bool, len(tx.Vout))
+		for i := range tx.Vout {
+			spendingTxs[i] = tx.Vout[i].SpentTxID
+		}
+	}
+	return spendingTxs, spentUnknown, nil
+}
+
+// limits of the traversal of the graph of spending transactions
+const (
+	defaultSpendingGraphDepth = 3
+	maxSpendingGraphDepth     = 10
+	maxSpendingGraphEdges     = 1000
+)
+
+// GetSpendingGraph follows the spends of the funds of the output txid:vout up to the depth (breadth first)
+// and returns the edges of the graph of spending transactions; each transaction is expanded only once,
+// the traversal stops at unspent outputs, at outputs spent by an unknown transaction (the spend data were pruned)
+// and after maxSpendingGraphEdges edges, in which case the graph is marked as truncated
+func (w *Worker) GetSpendingGraph(txid string, vout int, depth int) (*SpendingGraph, error) {
+	start := time.Now()
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if depth == 0 {
+		depth = defaultSpendingGraphDepth
+	}
+	if depth < 0 || depth > maxSpendingGraphDepth {
+		return nil, NewAPIError(fmt.Sprintf("Depth must be between 1 and %d", maxSpendingGraphDepth), true)
+	}
+	spendingTxs, spentUnknown, err := w.getSpendingTxs(txid)
+	if err != nil {
+		return nil, err
+	}
+	if vout < 0 || vout >= len(spendingTxs) {
+		return nil, NewAPIError(fmt.Sprintf("Passed incorrect vout index %v for tx %v, len vout %v", vout, txid, len(spendingTxs)), true)
+	}
+	r := &SpendingGraph{
+		Txid:  txid,
+		Vout:  vout,
+		Depth: depth,
+		Edges: make([]SpendingEdge, 0),
+	}
+	visited := make(map[string]struct{})
+	var next []string
+	if spendingTxs[vout] != "" {
+		r.Edges = append(r.Edges, SpendingEdge{Txid: txid, Vout: vout, SpentTxid: spendingTxs[vout], Depth: 1})
+		visited[spendingTxs[vout]] = struct{}{}
+		next = append(next, spendingTxs[vout])
+	} else if spentUnknown[vout] {
+		r.Edges = append(r.Edges, SpendingEdge{Txid: txid, Vout: vout, Depth: 1, Unknown: true})
+	}
+	for d := 2; d <= depth && len(next) > 0 && !r.Truncated; d++ {
+		level := next
+		next = nil
+		for _, t := range level {
+			if spendingTxs, spentUnknown, err = w.getSpendingTxs(t); err != nil {
+				return nil, err
+			}
+			for n, spentTxid := range spendingTxs {
+				if spentTxid == "" && !spentUnknown[n] {
+					continue
+				}
+				if len(r.Edges) >= maxSpendingGraphEdges {
+					r.Truncated = true
+					break
+				}
+				if spentTxid == "" {
+					r.Edges = append(r.Edges, SpendingEdge{Txid: t, Vout: n, Depth: d, Unknown: true})
+					continue
+				}
+				r.Edges = append(r.Edges, SpendingEdge{Txid: t, Vout: n, SpentTxid: spentTxid, Depth: d})
+				if _, found := visited[spentTxid]; !found {
+					visited[spentTxid] = struct{}{}
+					next = append(next, spentTxid)
+				}
+			}
+			if r.Truncated {
+				break
+			}
+		}
+	}
+	glog.Info("GetSpendingGraph ", txid, ":", vout, ", depth ", depth, ", ", len(r.Edges), " edges, ", time.Since(start))
+	return r, nil
+}
+
 func aggregateAddress(m map[string]struct{}, a string) {
 	if m != nil && len(a) > 0 {
 		m[a] = struct{}{}
@@ -188,6 +328,7 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 	if err != nil {
 		return nil, err
 	}
+	w.setStoredTxData(tx)
 	tx.AddressAliases = w.getAddressAliases(addresses)
 	return tx, nil
 }
@@ -197,6 +338,9 @@ func (w *Worker) getTransaction(txid string, spendingTxs bool, specificJSON bool
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
+			if tx, err := w.getDroppedTransaction(txid); tx != nil || err != nil {
+				return tx, err
+			}
 			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 		}
 		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
@@ -204,6 +348,58 @@ func (w *Worker) getTransaction(txid string, spendingTxs bool, specificJSON bool
 	return w.getTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON, addresses)
 }
 
+// getDroppedTransaction returns the last known state of a transaction dropped from the mempool, nil if it is not retained
+func (w *Worker) getDroppedTransaction(txid string) (*Tx, error) {
+	if w.mempool == nil {
+		return nil, nil
+	}
+	d := w.mempool.GetDroppedTransaction(txid)
+	if d == nil || d.Tx == nil {
+		return nil, nil
+	}
+	tx, err := w.GetTransactionFromMempoolTx(d.Tx)
+	if err != nil {
+		return nil, err
+	}
+	// the transaction is not going to be confirmed
+	tx.Blockheight = -1
+	tx.ConfirmationETASeconds, tx.ConfirmationETABlocks = 0, 0
+	tx.DropReason = string(d.Reason)
+	tx.ReplacedBy = d.ReplacedBy
+	if d.Reason == bchain.MempoolDropReplaced && d.ReplacedBy != "" {
+		w.setReplacementStatus(tx)
+	}
+	return tx, nil
+}
+
+// maxReplacementDepth limits the number of followed replacements of a transaction
+const maxReplacementDepth = 16
+
+// setReplacementStatus sets the last replacement of a replaced transaction and its confirmations,
+// the clients tracking the replaced transaction can see that the payment was confirmed by the replacement
+func (w *Worker) setReplacementStatus(tx *Tx) {
+	replacement := tx.ReplacedBy
+	// the replacement can be replaced again, follow the replacements to the last one
+	for i := 0; i < maxReplacementDepth; i++ {
+		d := w.mempool.GetDroppedTransaction(replacement)
+		if d == nil || d.Reason != bchain.MempoolDropReplaced || d.ReplacedBy == "" {
+			break
+		}
+		replacement = d.ReplacedBy
+	}
+	tx.ReplacementTxid = replacement
+	rtx, height, err := w.txCache.GetTransaction(replacement)
+	if err != nil {
+		if err != bchain.ErrTxNotFound {
+			glog.Errorf("GetTransaction(%v) error %v", replacement, err)
+		}
+		return
+	}
+	if height > 0 {
+		tx.ReplacementConfs = rtx.Confirmations
+	}
+}
+
 func (w *Worker) getParsedEthereumInputData(data string) *bchain.EthereumParsedInputData {
 	var err error
 	var signatures *[]bchain.FourByteSignature
@@ -214,6 +410,10 @@ func (w *Worker) getParsedEthereumInputData(data string) *bchain.EthereumParsedI
 			glog.Errorf("GetFourByteSignatures(%v) error %v", fourBytes, err)
 			return nil
 		}
+		if signatures == nil {
+			// fall back to the bundled signatures of the common token methods
+			signatures = eth.GetCommonFourByteSignatures(fourBytes)
+		}
 		if signatures == nil {
 			return nil
 		}
@@ -269,6 +469,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	var err error
 	var ta *db.TxAddresses
 	var tokens []TokenTransfer
+	var nfts []NftTransfer
 	var ethSpecific *EthereumSpecific
 	var blockhash string
 	if bchainTx.Confirmations > 0 {
@@ -285,6 +486,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	}
 	var valInSat, valOutSat, feesSat big.Int
 	var pValInSat *big.Int
+	var prevouts []*bchain.Vout
 	vins := make([]Vin, len(bchainTx.Vin))
 	rbf := false
 	for i := range bchainTx.Vin {
@@ -309,28 +511,40 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 					return nil, errors.Annotatef(err, "GetTxAddresses %v", bchainVin.Txid)
 				}
 				if tas == nil {
-					// try to load from backend
-					otx, _, err := w.txCache.GetTransaction(bchainVin.Txid)
-					if err != nil {
-						if err == bchain.ErrTxNotFound {
-							// try to get AddrDesc using coin specific handling and continue processing the tx
-							vin.AddrDesc = w.chainParser.GetAddrDescForUnknownInput(bchainTx, i)
-							vin.Addresses, vin.IsAddress, err = w.chainParser.GetAddressesFromAddrDesc(vin.AddrDesc)
-							if err != nil {
-								glog.Warning("GetAddressesFromAddrDesc tx ", bchainVin.Txid, ", addrDesc ", vin.AddrDesc, ": ", err)
+					// try to load from backend, the parents of all inputs are fetched at once in one batched call
+					if prevouts == nil {
+						prevouts, err = bchain.GetInputsPrevouts(w.chain, bchainTx)
+						if err != nil {
+							glog.V(2).Info("GetInputsPrevouts ", bchainTx.Txid, ": ", err)
+							prevouts = make([]*bchain.Vout, len(bchainTx.Vin))
+						}
+					}
+					vout := prevouts[i]
+					if vout == nil {
+						otx, _, err := w.txCache.GetTransaction(bchainVin.Txid)
+						if err != nil {
+							if err == bchain.ErrTxNotFound {
+								// try to get AddrDesc using coin specific handling and continue processing the tx
+								vin.AddrDesc = w.chainParser.GetAddrDescForUnknownInput(bchainTx, i)
+								vin.Addresses, vin.IsAddress, err = w.chainParser.GetAddressesFromAddrDesc(vin.AddrDesc)
+								if err != nil {
+									glog.Warning("GetAddressesFromAddrDesc tx ", bchainVin.Txid, ", addrDesc ", vin.AddrDesc, ": ", err)
+								}
+								aggregateAddresses(addresses, vin.Addresses, vin.IsAddress)
+								continue
 							}
-							aggregateAddresses(addresses, vin.Addresses, vin.IsAddress)
-							continue
+							return nil, errors.Annotatef(err, "txCache.GetTransaction %v", bchainVin.Txid)
+						}
+						if len(otx.Vout) > int(vin.Vout) {
+							vout = &otx.Vout[vin.Vout]
 						}
-						return nil, errors.Annotatef(err, "txCache.GetTransaction %v", bchainVin.Txid)
 					}
 					// mempool transactions are not in TxAddresses but confirmed should be there, log a problem
 					// ignore when Confirmations==1, it may be just a timing problem
 					if bchainTx.Confirmations > 1 {
 						glog.Warning("DB inconsistency:  tx ", bchainVin.Txid, ": not found in txAddresses, confirmations ", bchainTx.Confirmations)
 					}
-					if len(otx.Vout) > int(vin.Vout) {
-						vout := &otx.Vout[vin.Vout]
+					if vout != nil {
 						vin.ValueSat = (*Amount)(&vout.ValueSat)
 						vin.AddrDesc, vin.Addresses, vin.IsAddress, err = w.getAddressesFromVout(vout)
 						if err != nil {
@@ -378,6 +592,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		if err != nil {
 			glog.V(2).Infof("getAddressesFromVout error %v, %v, output %v", err, bchainTx.Txid, bchainVout.N)
 		}
+		w.setVoutLockTime(vout, vout.AddrDesc)
 		aggregateAddresses(addresses, vout.Addresses, vout.IsAddress)
 		if ta != nil {
 			vout.Spent = ta.Outputs[i].Spent
@@ -408,6 +623,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 			glog.Errorf("GetTokenTransfersFromTx error %v, %v", err, bchainTx)
 		}
 		tokens = w.getEthereumTokensTransfers(tokenTransfers, addresses)
+		nfts = getEthereumNftTransfers(tokenTransfers)
 		ethTxData := eth.GetEthereumTxData(bchainTx)
 
 		var internalData *bchain.EthereumInternalData
@@ -465,6 +681,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	r := &Tx{
 		Blockhash:        blockhash,
 		Blockheight:      height,
+		BlockIndex:       getBlockIndex(ta),
 		Blocktime:        bchainTx.Blocktime,
 		Confirmations:    bchainTx.Confirmations,
 		FeesSat:          (*Amount)(&feesSat),
@@ -477,19 +694,47 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		VSize:            int(bchainTx.VSize),
 		Hex:              bchainTx.Hex,
 		Rbf:              rbf,
+		CoinStake:        w.chainParser.IsCoinStake(bchainTx),
 		Vin:              vins,
 		Vout:             vouts,
 		CoinSpecificData: sj,
 		TokenTransfers:   tokens,
+		NftTransfers:     nfts,
 		EthereumSpecific: ethSpecific,
 	}
+	if w.chainType == bchain.ChainBitcoinType {
+		setCoinJoin(r)
+		w.setMemo(r)
+		w.setChange(r)
+	}
 	if bchainTx.Confirmations == 0 {
 		r.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
+		r.FirstSeen = r.Blocktime
 		r.ConfirmationETASeconds, r.ConfirmationETABlocks = w.getConfirmationETA(r)
+		w.setStandard(r, bchainTx)
 	}
 	return r, nil
 }
 
+// setStoredTxData sets the first seen time of a confirmed transaction and the replaced transactions from the db,
+// the lookups are done only for the transaction detail, not for the transactions in lists
+func (w *Worker) setStoredTxData(tx *Tx) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return
+	}
+	var err error
+	if tx.Confirmations > 0 {
+		tx.FirstSeen, err = w.db.GetTxFirstSeen(tx.Txid)
+		if err != nil {
+			glog.Error("GetTxFirstSeen ", tx.Txid, ": ", err)
+		}
+	}
+	tx.Replaces, err = w.db.GetTxReplaces(tx.Txid)
+	if err != nil {
+		glog.Error("GetTxReplaces ", tx.Txid, ": ", err)
+	}
+}
+
 // GetTransactionFromMempoolTx converts bchain.MempoolTx to Tx, with limited amount of data
 // it is not doing any request to backend or to db
 func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx, error) {
@@ -497,6 +742,7 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 	var valInSat, valOutSat, feesSat big.Int
 	var pValInSat *big.Int
 	var tokens []TokenTransfer
+	var nfts []NftTransfer
 	var ethSpecific *EthereumSpecific
 	addresses := w.newAddressesMapForAliases()
 	vins := make([]Vin, len(mempoolTx.Vin))
@@ -549,6 +795,7 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 		if err != nil {
 			glog.V(2).Infof("getAddressesFromVout error %v, %v, output %v", err, mempoolTx.Txid, bchainVout.N)
 		}
+		w.setVoutLockTime(vout, vout.AddrDesc)
 		aggregateAddresses(addresses, vout.Addresses, vout.IsAddress)
 	}
 	if w.chainType == bchain.ChainBitcoinType {
@@ -563,6 +810,7 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 			valOutSat = mempoolTx.Vout[0].ValueSat
 		}
 		tokens = w.getEthereumTokensTransfers(mempoolTx.TokenTransfers, addresses)
+		nfts = getEthereumNftTransfers(mempoolTx.TokenTransfers)
 		ethTxData := eth.GetEthereumTxDataFromSpecificData(mempoolTx.CoinSpecificData)
 		ethSpecific = &EthereumSpecific{
 			GasLimit: ethTxData.GasLimit,
@@ -575,6 +823,7 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 	}
 	r := &Tx{
 		Blocktime:        mempoolTx.Blocktime,
+		FirstSeen:        mempoolTx.Blocktime,
 		FeesSat:          (*Amount)(&feesSat),
 		Locktime:         mempoolTx.LockTime,
 		Txid:             mempoolTx.Txid,
@@ -588,13 +837,47 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 		Vin:              vins,
 		Vout:             vouts,
 		TokenTransfers:   tokens,
+		NftTransfers:     nfts,
 		EthereumSpecific: ethSpecific,
 		AddressAliases:   w.getAddressAliases(addresses),
 	}
 	r.ConfirmationETASeconds, r.ConfirmationETABlocks = w.getConfirmationETA(r)
+	if w.chainType == bchain.ChainBitcoinType {
+		setCoinJoin(r)
+		w.setMemo(r)
+		w.setChange(r)
+		tx := bchain.Tx{
+			Hex:      mempoolTx.Hex,
+			Txid:     mempoolTx.Txid,
+			Version:  mempoolTx.Version,
+			LockTime: mempoolTx.LockTime,
+			VSize:    mempoolTx.VSize,
+			Vin:      make([]bchain.Vin, len(mempoolTx.Vin)),
+			Vout:     mempoolTx.Vout,
+		}
+		for i := range mempoolTx.Vin {
+			tx.Vin[i] = mempoolTx.Vin[i].Vin
+		}
+		w.setStandard(r, &tx)
+	}
 	return r, nil
 }
 
+// setStandard sets the flag if the unconfirmed transaction is standard by the relay policy of the backend,
+// the flag is not set if the check is not supported by the coin
+func (w *Worker) setStandard(r *Tx, tx *bchain.Tx) {
+	prevOutputs := make([]bchain.AddressDescriptor, len(r.Vin))
+	for i := range r.Vin {
+		prevOutputs[i] = r.Vin[i].AddrDesc
+	}
+	standard, reason, err := w.chainParser.IsStandardTx(tx, prevOutputs)
+	if err != nil {
+		return
+	}
+	r.Standard = &standard
+	r.NonStandardReason = reason
+}
+
 func (w *Worker) getContractInfo(contract string, typeFromContext bchain.TokenTypeName) (*bchain.ContractInfo, bool, error) {
 	cd, err := w.chainParser.GetAddrDescFromAddress(contract)
 	if err != nil {
@@ -781,6 +1064,30 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 	return txids, nil
 }
 
+// txAddrValueAtLeast returns true if the confirmed transaction changes the balance of the address by at least minValue in absolute value
+func (w *Worker) txAddrValueAtLeast(txid string, addrDesc bchain.AddressDescriptor, minValue *big.Int) (bool, error) {
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return false, errors.Annotatef(err, "GetTxAddresses %v", txid)
+	}
+	if ta == nil {
+		glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+		return false, nil
+	}
+	var val big.Int
+	for i := range ta.Outputs {
+		if bytes.Equal(ta.Outputs[i].AddrDesc, addrDesc) {
+			val.Add(&val, &ta.Outputs[i].ValueSat)
+		}
+	}
+	for i := range ta.Inputs {
+		if bytes.Equal(ta.Inputs[i].AddrDesc, addrDesc) {
+			val.Sub(&val, &ta.Inputs[i].ValueSat)
+		}
+	}
+	return val.CmpAbs(minValue) >= 0, nil
+}
+
 func (t *Tx) getAddrVoutValue(addrDesc bchain.AddressDescriptor) *big.Int {
 	var val big.Int
 	for _, vout := range t.Vout {
@@ -840,6 +1147,7 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		vin.N = i
 		vin.ValueSat = (*Amount)(&tai.ValueSat)
 		valInSat.Add(&valInSat, &tai.ValueSat)
+		vin.AddrDesc = tai.AddrDesc
 		vin.Addresses, vin.IsAddress, err = tai.Addresses(w.chainParser)
 		if err != nil {
 			glog.Errorf("tai.Addresses error %v, tx %v, input %v, tai %+v", err, txid, i, tai)
@@ -847,6 +1155,9 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		if w.db.HasExtendedIndex() {
 			vin.Txid = tai.Txid
 			vin.Vout = tai.Vout
+			if ta.HasLockTime {
+				vin.Sequence = int64(tai.Sequence)
+			}
 		}
 		aggregateAddresses(addresses, vin.Addresses, vin.IsAddress)
 	}
@@ -861,6 +1172,8 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		if err != nil {
 			glog.Errorf("tai.Addresses error %v, tx %v, output %v, tao %+v", err, txid, i, tao)
 		}
+		vout.AddrDesc = tao.AddrDesc
+		w.setVoutLockTime(vout, tao.AddrDesc)
 		vout.Spent = tao.Spent
 		if vout.Spent && w.db.HasExtendedIndex() {
 			vout.SpentTxID = tao.SpentTxid
@@ -877,6 +1190,7 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 	r := &Tx{
 		Blockhash:     bi.Hash,
 		Blockheight:   int(ta.Height),
+		BlockIndex:    getBlockIndex(ta),
 		Blocktime:     bi.Time,
 		Confirmations: bestheight - ta.Height + 1,
 		FeesSat:       (*Amount)(&feesSat),
@@ -886,14 +1200,29 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		Vin:           vins,
 		Vout:          vouts,
 	}
+	if w.db.HasExtendedIndex() && ta.HasLockTime {
+		r.Locktime = ta.LockTime
+	}
 	if w.chainParser.SupportsVSize() {
 		r.VSize = int(ta.VSize)
 	} else {
 		r.Size = int(ta.VSize)
 	}
+	setCoinJoin(r)
+	w.setMemo(r)
+	w.setChange(r)
 	return r
 }
 
+// getBlockIndex returns the position of the transaction in its block, nil if it is not stored in the index
+func getBlockIndex(ta *db.TxAddresses) *int {
+	if ta == nil || ta.BlockIndex < 0 {
+		return nil
+	}
+	i := int(ta.BlockIndex)
+	return &i
+}
+
 func computePaging(count, page, itemsOnPage int) (Paging, int, int, int) {
 	from := page * itemsOnPage
 	totalPages := (count - 1) / itemsOnPage
@@ -1034,6 +1363,7 @@ type ethereumTypeAddressData struct {
 	tokens               Tokens
 	contractInfo         *bchain.ContractInfo
 	nonce                string
+	isContract           bool
 	nonContractTxs       int
 	internalTxs          int
 	totalResults         int
@@ -1068,10 +1398,6 @@ func (w *Worker) getEthereumTypeAddressBalances(addrDesc bchain.AddressDescripto
 		if b != nil {
 			ba.BalanceSat = *b
 		}
-		n, err = w.chain.EthereumTypeGetNonce(addrDesc)
-		if err != nil {
-			return nil, nil, errors.Annotatef(err, "EthereumTypeGetNonce %v", addrDesc)
-		}
 		ticker := w.is.GetCurrentTicker("", "")
 		if details > AccountDetailsBasic {
 			d.tokens = make([]Token, len(ca.Contracts))
@@ -1126,7 +1452,17 @@ func (w *Worker) getEthereumTypeAddressBalances(addrDesc bchain.AddressDescripto
 		}
 	}
 	// returns 0 for unknown address
+	n, err = w.chain.EthereumTypeGetNonce(addrDesc)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "EthereumTypeGetNonce %v", addrDesc)
+	}
 	d.nonce = strconv.Itoa(int(n))
+	// the address is a contract if it has a code, the externally owned accounts have no code
+	code, err := w.chain.EthereumTypeGetCode(addrDesc)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "EthereumTypeGetCode %v", addrDesc)
+	}
+	d.isContract = len(code) > 0
 	// special handling if filtering for a contract, return the contract details even though the address had no transactions with it
 	if len(d.tokens) == 0 && len(filterDesc) > 0 && details >= AccountDetailsTokens {
 		t, err := w.getEthereumContractBalanceFromBlockchain(addrDesc, filterDesc, details)
@@ -1180,7 +1516,61 @@ func (w *Worker) txFromTxid(txid string, bestHeight uint32, option AccountDetail
 	return tx, nil
 }
 
+// txsFromTxids returns the transactions of the txids in the order of the txids, the transactions are read
+// in parallel by at most is.AddressTxsConcurrency goroutines, sequentially if the concurrency is not set
+func (w *Worker) txsFromTxids(txids []string, bestHeight uint32, option AccountDetails, addresses map[string]struct{}) ([]*Tx, error) {
+	txs := make([]*Tx, len(txids))
+	concurrency := w.is.AddressTxsConcurrency
+	if concurrency > len(txids) {
+		concurrency = len(txids)
+	}
+	if concurrency <= 1 {
+		for i, txid := range txids {
+			tx, err := w.txFromTxid(txid, bestHeight, option, nil, addresses)
+			if err != nil {
+				return nil, err
+			}
+			txs[i] = tx
+		}
+		return txs, nil
+	}
+	errs := make([]error, len(txids))
+	// each goroutine collects the addresses for aliases to its own map, the maps are merged at the end
+	workerAddresses := make([]map[string]struct{}, concurrency)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for g := 0; g < concurrency; g++ {
+		if addresses != nil {
+			workerAddresses[g] = make(map[string]struct{})
+		}
+		wg.Add(1)
+		go func(addresses map[string]struct{}) {
+			defer wg.Done()
+			for i := range next {
+				txs[i], errs[i] = w.txFromTxid(txids[i], bestHeight, option, nil, addresses)
+			}
+		}(workerAddresses[g])
+	}
+	for i := range txids {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range workerAddresses {
+		for a := range m {
+			addresses[a] = struct{}{}
+		}
+	}
+	return txs, nil
+}
+
 func (w *Worker) getAddrDescAndNormalizeAddress(address string) (bchain.AddressDescriptor, string, error) {
+	address = w.chainParser.NormalizeAddress(address)
 	addrDesc, err := w.chainParser.GetAddrDescFromAddress(address)
 	if err != nil {
 		var errAd error
@@ -1201,6 +1591,51 @@ func (w *Worker) getAddrDescAndNormalizeAddress(address string) (bchain.AddressD
 	return addrDesc, address, nil
 }
 
+// ValidateAddress checks if the address can be decoded by the parser of the coin, returns the type of its output script
+// and if the address is for the network of the coin; an invalid address is not an error, the reason is returned in the result
+func (w *Worker) ValidateAddress(address string) (*AddressValidation, error) {
+	address = w.chainParser.NormalizeAddress(address)
+	r := &AddressValidation{Address: address}
+	if _, err := w.chainParser.GetAddrDescFromAddress(address); err != nil {
+		r.Reason = err.Error()
+		return r, nil
+	}
+	r.IsValid = true
+	scriptType, forNetwork, err := w.chainParser.GetAddressType(address)
+	if err != nil {
+		// the coin does not distinguish the networks or uses its own address format, the address was accepted by its parser
+		glog.V(1).Infof("GetAddressType %v: %v", address, err)
+		r.IsForNetwork = true
+		return r, nil
+	}
+	r.ScriptType = scriptType
+	r.IsForNetwork = forNetwork
+	if !forNetwork {
+		r.Reason = "Address is for a different network"
+	}
+	return r, nil
+}
+
+// setVoutLockTime sets the lock time required by OP_CHECKLOCKTIMEVERIFY in the output script to the vout
+func (w *Worker) setVoutLockTime(vout *Vout, addrDesc bchain.AddressDescriptor) {
+	if w.chainType != bchain.ChainBitcoinType || len(addrDesc) == 0 {
+		return
+	}
+	lockTime, err := w.chainParser.GetLockTimeFromAddrDesc(addrDesc)
+	if err != nil {
+		glog.V(2).Infof("GetLockTimeFromAddrDesc error %v, %v", err, addrDesc)
+		return
+	}
+	if lockTime == 0 {
+		return
+	}
+	if lockTime < bchain.LockTimeThreshold {
+		vout.LockTimeHeight = lockTime
+	} else {
+		vout.LockTimeTimestamp = int64(lockTime)
+	}
+}
+
 func isOwnAddress(address string, addresses []string) bool {
 	if len(addresses) == 1 {
 		return address == addresses[0]
@@ -1246,6 +1681,9 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	if err != nil {
 		return nil, err
 	}
+	if filter.MinValue != nil && w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Filter minValue is supported only for Bitcoin-type coins", true)
+	}
 	if w.chainType == bchain.ChainEthereumType {
 		ba, ed, err = w.getEthereumTypeAddressBalances(addrDesc, option, filter, secondaryCoin)
 		if err != nil {
@@ -1259,7 +1697,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 		}
 		if ba != nil {
-			// totalResults is known only if there is no filter
+			// totalResults is known only if there is no filter, the minValue filter is applied only to the transactions of the page
 			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 {
 				totalResults = int(ba.Txs)
 			} else {
@@ -1295,6 +1733,12 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 					} else {
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
 					}
+					if filter.MinValue != nil {
+						var val big.Int
+						if val.Sub(tx.getAddrVoutValue(addrDesc), tx.getAddrVinValue(addrDesc)).CmpAbs(filter.MinValue) < 0 {
+							continue
+						}
+					}
 					if page == 0 {
 						if option == AccountDetailsTxidHistory {
 							txids = append(txids, tx.Txid)
@@ -1315,7 +1759,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		}
 		bestheight, _, err := w.db.GetBestBlock()
 		if err != nil {
-			return nil, errors.Annotatef(err, "GetBestBlock")
+			return nil, errors.Annotate(err, "GetBestBlock")
 		}
 		var from, to int
 		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
@@ -1326,15 +1770,30 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
 			}
 		}
-		for i := from; i < to; i++ {
-			txid := txc[i]
-			if option == AccountDetailsTxidHistory {
-				txids = append(txids, txid)
-			} else {
-				tx, err := w.txFromTxid(txid, bestheight, option, nil, addresses)
+		pageTxids := txc[from:to]
+		// the value of the transactions is not in the address index, the filter reads only the transactions of the page,
+		// the page can so contain fewer transactions than txsOnPage
+		if filter.MinValue != nil {
+			filtered := make([]string, 0, len(pageTxids))
+			for _, txid := range pageTxids {
+				ok, err := w.txAddrValueAtLeast(txid, addrDesc, filter.MinValue)
 				if err != nil {
 					return nil, err
 				}
+				if ok {
+					filtered = append(filtered, txid)
+				}
+			}
+			pageTxids = filtered
+		}
+		if option == AccountDetailsTxidHistory {
+			txids = append(txids, pageTxids...)
+		} else {
+			pageTxs, err := w.txsFromTxids(pageTxids, bestheight, option, addresses)
+			if err != nil {
+				return nil, err
+			}
+			for _, tx := range pageTxs {
 				setIsOwnAddress(tx, address)
 				txs = append(txs, tx)
 			}
@@ -1383,6 +1842,9 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		Nonce:                 ed.nonce,
 		AddressAliases:        w.getAddressAliases(addresses),
 	}
+	if w.chainType == bchain.ChainEthereumType {
+		r.IsContract = &ed.isContract
+	}
 	// keep address backward compatible, set deprecated Erc20Contract value if ERC20 token
 	if ed.contractInfo != nil && ed.contractInfo.Type == bchain.ERC20TokenType {
 		r.Erc20Contract = ed.contractInfo
@@ -1753,57 +2215,596 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 	return utxos, nil
 }
 
-// GetAddressUtxo returns unspent outputs for given address
-func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, error) {
+// GetTxRecipients returns the addresses and values of the outputs of the transaction,
+// confirmed Bitcoin-type transactions are read from the index without a request to the backend
+func (w *Worker) GetTxRecipients(txid string) (*TxRecipients, error) {
+	if w.chainType == bchain.ChainBitcoinType {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta != nil {
+			bestheight, _, err := w.db.GetBestBlock()
+			if err != nil {
+				return nil, errors.Annotate(err, "GetBestBlock")
+			}
+			r := &TxRecipients{
+				Txid:          txid,
+				Blockheight:   int(ta.Height),
+				Confirmations: bestheight - ta.Height + 1,
+				Recipients:    make([]TxRecipient, len(ta.Outputs)),
+			}
+			for i := range ta.Outputs {
+				tao := &ta.Outputs[i]
+				rc := &r.Recipients[i]
+				rc.N = i
+				rc.ValueSat = (*Amount)(&tao.ValueSat)
+				rc.Addresses, rc.IsAddress, err = tao.Addresses(w.chainParser)
+				if err != nil {
+					glog.Errorf("tao.Addresses error %v, tx %v, output %v", err, txid, i)
+				}
+			}
+			return r, nil
+		}
+	}
+	// mempool transactions and other coin types
+	tx, err := w.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	r := &TxRecipients{
+		Txid:          tx.Txid,
+		Blockheight:   tx.Blockheight,
+		Confirmations: tx.Confirmations,
+		Recipients:    make([]TxRecipient, len(tx.Vout)),
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		r.Recipients[i] = TxRecipient{
+			N:         vout.N,
+			Addresses: vout.Addresses,
+			IsAddress: vout.IsAddress,
+			ValueSat:  vout.ValueSat,
+		}
+	}
+	return r, nil
+}
+
+// GetTxInputPrevout returns the output spent by the input n of the transaction,
+// the spent output is read from the index if the spent transaction is confirmed
+func (w *Worker) GetTxInputPrevout(txid string, n int) (*TxInputPrevout, error) {
 	if w.chainType != bchain.ChainBitcoinType {
 		return nil, NewAPIError("Not supported", true)
 	}
-	start := time.Now()
-	addrDesc, err := w.chainParser.GetAddrDescFromAddress(address)
+	tx, err := w.GetTransaction(txid, false, false)
 	if err != nil {
-		return nil, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", address, err), true)
+		return nil, err
 	}
-	r, err := w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false)
+	if n < 0 || n >= len(tx.Vin) {
+		return nil, NewAPIError(fmt.Sprintf("Input %d of transaction '%v' not found", n, txid), true)
+	}
+	vin := &tx.Vin[n]
+	if vin.Txid == "" {
+		return nil, NewAPIError(fmt.Sprintf("Input %d of transaction '%v' is coinbase", n, txid), true)
+	}
+	r := &TxInputPrevout{
+		Txid:     tx.Txid,
+		N:        n,
+		PrevTxid: vin.Txid,
+		PrevVout: int(vin.Vout),
+	}
+	ta, err := w.db.GetTxAddresses(vin.Txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTxAddresses %v", vin.Txid)
+	}
+	if ta != nil {
+		if r.PrevVout >= len(ta.Outputs) {
+			return nil, NewAPIError(fmt.Sprintf("Output %d of transaction '%v' not found", r.PrevVout, vin.Txid), true)
+		}
+		tao := &ta.Outputs[r.PrevVout]
+		r.PrevBlockHeight = int(ta.Height)
+		r.ValueSat = (*Amount)(&tao.ValueSat)
+		r.Hex = hex.EncodeToString(tao.AddrDesc)
+		r.Addresses, r.IsAddress, err = tao.Addresses(w.chainParser)
+		if err != nil {
+			glog.Errorf("tao.Addresses error %v, tx %v, output %v", err, vin.Txid, r.PrevVout)
+		}
+		return r, nil
+	}
+	// the spent transaction is in mempool
+	prevTx, err := w.GetTransaction(vin.Txid, false, false)
 	if err != nil {
 		return nil, err
 	}
-	glog.Info("GetAddressUtxo ", address, ", ", len(r), " utxos, ", time.Since(start))
+	if r.PrevVout >= len(prevTx.Vout) {
+		return nil, NewAPIError(fmt.Sprintf("Output %d of transaction '%v' not found", r.PrevVout, vin.Txid), true)
+	}
+	vout := &prevTx.Vout[r.PrevVout]
+	r.PrevBlockHeight = prevTx.Blockheight
+	r.ValueSat = vout.ValueSat
+	r.Hex = vout.Hex
+	r.Addresses = vout.Addresses
+	r.IsAddress = vout.IsAddress
 	return r, nil
 }
 
-// GetBlocks returns BlockInfo for blocks on given page
-func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
+// GetAddressSummary returns the totals of the address and the heights of the first and the last block
+// with a transaction of the address, computed from the index without loading the transactions
+func (w *Worker) GetAddressSummary(address string, reuse bool) (*AddressSummary, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
 	start := time.Now()
-	page--
-	if page < 0 {
-		page = 0
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
 	}
-	b, _, err := w.db.GetBestBlock()
-	bestheight := int(b)
+	ba, err := w.db.GetAddrDescBalance(addrDesc, db.AddressBalanceDetailNoUTXO)
 	if err != nil {
-		return nil, errors.Annotatef(err, "GetBestBlock")
+		return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 	}
-	pg, from, to, page := computePaging(bestheight+1, page, blocksOnPage)
-	r := &Blocks{Paging: pg}
-	r.Blocks = make([]db.BlockInfo, to-from)
-	for i := from; i < to; i++ {
-		bi, err := w.db.GetBlockInfo(uint32(bestheight - i))
+	if ba == nil {
+		ba = &db.AddrBalance{}
+	}
+	r := &AddressSummary{
+		AddrStr:          address,
+		BalanceSat:       (*Amount)(&ba.BalanceSat),
+		TotalReceivedSat: (*Amount)(ba.ReceivedSat()),
+		TotalSentSat:     (*Amount)(&ba.SentSat),
+		Txs:              int(ba.Txs),
+	}
+	first, last, found, err := w.db.GetAddrDescHeightRange(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescHeightRange %v", addrDesc)
+	}
+	if found {
+		r.FirstSeenHeight, r.LastSeenHeight = first, last
+		if bi, err := w.db.GetBlockInfo(first); err == nil && bi != nil {
+			r.FirstSeenTime = bi.Time
+		}
+		if bi, err := w.db.GetBlockInfo(last); err == nil && bi != nil {
+			r.LastSeenTime = bi.Time
+		}
+		if reuse {
+			var count int
+			if count, r.FirstReceivedHeight, err = w.db.GetAddrDescReceivingTxs(addrDesc); err != nil {
+				return nil, errors.Annotatef(err, "GetAddrDescReceivingTxs %v", addrDesc)
+			}
+			r.ReuseCount = &count
+		}
+		if w.db.HasAddressFirstSeenIndex() {
+			height, txid, found, err := w.db.GetAddressFirstSeen(addrDesc)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetAddressFirstSeen %v", addrDesc)
+			}
+			// the index is not backfilled, if it was enabled after the address appeared, the index contains a later appearance,
+			// the txid is returned only if the index covers the whole history of the address
+			if found && height == r.FirstSeenHeight {
+				r.FirstSeenTxid = txid
+			}
+		}
+	}
+	glog.Info("GetAddressSummary ", address, ", ", time.Since(start))
+	return r, nil
+}
+
+// defaultMaxBatchAddresses is the limit of the batch balance query used if the limit is not configured
+const defaultMaxBatchAddresses = 100
+
+// GetAddressesBalances returns the balances and the numbers of transactions of the addresses in the order of the request
+// the number of addresses is limited by the -maxbatchaddresses option
+func (w *Worker) GetAddressesBalances(addresses []string) ([]AddressBalance, error) {
+	if len(addresses) == 0 {
+		return nil, NewAPIError("Missing addresses", true)
+	}
+	maxAddresses := w.is.MaxBatchAddresses
+	if maxAddresses <= 0 {
+		maxAddresses = defaultMaxBatchAddresses
+	}
+	if len(addresses) > maxAddresses {
+		return nil, NewAPIError(fmt.Sprintf("Too many addresses, the limit is %d", maxAddresses), true)
+	}
+	start := time.Now()
+	r := make([]AddressBalance, len(addresses))
+	for i, address := range addresses {
+		a, err := w.GetAddress(address, 0, 0, AccountDetailsBasic, &AddressFilter{Vout: AddressFilterVoutOff}, "")
 		if err != nil {
 			return nil, err
 		}
-		if bi == nil {
-			r.Blocks = r.Blocks[:i]
-			break
+		r[i] = AddressBalance{
+			AddrStr:               a.AddrStr,
+			BalanceSat:            a.BalanceSat,
+			TotalReceivedSat:      a.TotalReceivedSat,
+			TotalSentSat:          a.TotalSentSat,
+			UnconfirmedBalanceSat: a.UnconfirmedBalanceSat,
+			UnconfirmedTxs:        a.UnconfirmedTxs,
+			Txs:                   a.Txs,
 		}
-		r.Blocks[i-from] = *bi
 	}
-	glog.Info("GetBlocks page ", page, ", ", time.Since(start))
+	glog.Info("GetAddressesBalances ", len(addresses), " addresses, ", time.Since(start))
 	return r, nil
 }
 
-// removeEmpty removes empty strings from a slice
-func removeEmpty(stringSlice []string) []string {
-	var ret []string
+// GetAddressUtxo returns unspent outputs for given address
+func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, err := w.chainParser.GetAddrDescFromAddress(w.chainParser.NormalizeAddress(address))
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", address, err), true)
+	}
+	r, err := w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false)
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("GetAddressUtxo ", address, ", ", len(r), " utxos, ", time.Since(start))
+	return r, nil
+}
+
+// GetScriptHashUtxo returns unspent outputs of the output script with the hash, the hash is the hex encoded sha256 of the script
+// in the reversed byte order, as used by the Electrum protocol; only the scripts already seen in a block are found
+// the index of output scripts by hash must be enabled by the -scripthashindex option
+func (w *Worker) GetScriptHashUtxo(scriptHash string, onlyConfirmed bool) (Utxos, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if !w.db.HasScriptHashIndex() {
+		return nil, NewAPIError("Index of output scripts by hash is not enabled", true)
+	}
+	start := time.Now()
+	hash, err := hex.DecodeString(scriptHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, NewAPIError(fmt.Sprintf("Invalid script hash '%v'", scriptHash), true)
+	}
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	addrDesc, err := w.db.GetAddrDescByScriptHash(hash)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescByScriptHash %v", scriptHash)
+	}
+	r := Utxos{}
+	if addrDesc != nil {
+		if r, err = w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false); err != nil {
+			return nil, err
+		}
+	}
+	glog.Info("GetScriptHashUtxo ", scriptHash, ", ", len(r), " utxos, ", time.Since(start))
+	return r, nil
+}
+
+// GetBlocks returns BlockInfo for blocks on given page
+func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	b, _, err := w.db.GetBestBlock()
+	bestheight := int(b)
+	if err != nil {
+		return nil, errors.Annotate(err, "GetBestBlock")
+	}
+	pg, from, to, page := computePaging(bestheight+1, page, blocksOnPage)
+	r := &Blocks{Paging: pg}
+	r.Blocks = make([]db.BlockInfo, to-from)
+	for i := from; i < to; i++ {
+		bi, err := w.db.GetBlockInfo(uint32(bestheight - i))
+		if err != nil {
+			return nil, err
+		}
+		if bi == nil {
+			r.Blocks = r.Blocks[:i]
+			break
+		}
+		r.Blocks[i-from] = *bi
+	}
+	glog.Info("GetBlocks page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// GetOpReturnTxs returns transactions with OP_RETURN outputs with payload starting with the given hex prefix
+// the prefix must be one of the prefixes indexed by the -opreturnprefixes option
+func (w *Worker) GetOpReturnTxs(prefix string, page int, txsOnPage int, filter *AddressFilter) (*OpReturnTxs, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	p, err := hex.DecodeString(prefix)
+	if err != nil || len(p) == 0 {
+		return nil, NewAPIError("Invalid prefix", true)
+	}
+	if !w.db.IsOpReturnPrefixIndexed(p) {
+		return nil, NewAPIError("Prefix "+prefix+" is not indexed", true)
+	}
+	higher := filter.ToHeight
+	if higher == 0 {
+		higher = maxUint32
+	}
+	pager := newIndexPager(page, txsOnPage)
+	txs := make([]OpReturnTx, 0)
+	err = w.db.GetOpReturnTransactions(p, filter.FromHeight, higher, func(txid string, height uint32, indexes []int32) error {
+		inPage, stop := pager.next()
+		if stop {
+			return &db.StopIteration{}
+		}
+		if inPage {
+			txs = append(txs, OpReturnTx{
+				Txid:        txid,
+				BlockHeight: int(height),
+				Vouts:       append([]int32(nil), indexes...),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetOpReturnTransactions %v", prefix)
+	}
+	r := &OpReturnTxs{
+		Paging: pager.paging(),
+		Prefix: prefix,
+		Txs:    txs,
+	}
+	glog.Info("GetOpReturnTxs ", prefix, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// GetScriptOutputTxs returns transactions which created outputs with the hex encoded output script, from the newest block to the oldest
+// the transactions spending the outputs are not returned
+func (w *Worker) GetScriptOutputTxs(script string, page int, txsOnPage int, filter *AddressFilter) (*ScriptOutputTxs, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	s, err := hex.DecodeString(script)
+	if err != nil || len(s) == 0 {
+		return nil, NewAPIError("Invalid script", true)
+	}
+	if !w.chainParser.IsAddrDescIndexable(s) {
+		return nil, NewAPIError("Script "+script+" is not indexed", true)
+	}
+	higher := filter.ToHeight
+	if higher == 0 {
+		higher = maxUint32
+	}
+	pager := newIndexPager(page, txsOnPage)
+	txs := make([]ScriptOutputTx, 0)
+	err = w.db.GetScriptOutputTransactions(s, filter.FromHeight, higher, func(txid string, height uint32, indexes []int32) error {
+		inPage, stop := pager.next()
+		if stop {
+			return &db.StopIteration{}
+		}
+		if inPage {
+			txs = append(txs, ScriptOutputTx{
+				Txid:        txid,
+				BlockHeight: int(height),
+				Vouts:       append([]int32(nil), indexes...),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetScriptOutputTransactions %v", script)
+	}
+	r := &ScriptOutputTxs{
+		Paging: pager.paging(),
+		Script: script,
+		Txs:    txs,
+	}
+	glog.Info("GetScriptOutputTxs ", script, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// maxOutputsByValueBlocks is the maximal number of blocks in the query of outputs by value
+const maxOutputsByValueBlocks = 1000
+
+// maxOutputsByValueTxs is the maximal number of transactions read by one query of outputs by value
+const maxOutputsByValueTxs = 10000
+
+// GetOutputsByValue returns outputs with value in satoshis between minValue and maxValue (inclusive), from the newest block to the oldest
+// at most maxOutputsByValueBlocks blocks can be searched, by default the last maxOutputsByValueBlocks blocks;
+// the index of outputs by value must be enabled by the -outputvalueindex option
+func (w *Worker) GetOutputsByValue(minValue string, maxValue string, page int, outputsOnPage int, filter *AddressFilter) (*ValueOutputs, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	if !w.db.HasOutputValueIndex() {
+		return nil, NewAPIError("Index of outputs by value is not enabled", true)
+	}
+	var minSat, maxSat big.Int
+	if _, ok := minSat.SetString(minValue, 10); !ok || minSat.Sign() < 0 {
+		return nil, NewAPIError("Invalid minimum value", true)
+	}
+	if _, ok := maxSat.SetString(maxValue, 10); !ok || maxSat.Cmp(&minSat) < 0 {
+		return nil, NewAPIError("Invalid maximum value", true)
+	}
+	higher := filter.ToHeight
+	if higher == 0 {
+		bestHeight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		higher = bestHeight
+	}
+	lower := filter.FromHeight
+	if lower == 0 && higher >= maxOutputsByValueBlocks {
+		lower = higher - maxOutputsByValueBlocks + 1
+	}
+	if lower > higher {
+		return nil, NewAPIError("Parameter 'from' is greater than 'to'", true)
+	}
+	if higher-lower >= maxOutputsByValueBlocks {
+		return nil, NewAPIError(fmt.Sprintf("At most %d blocks can be requested", maxOutputsByValueBlocks), true)
+	}
+	pager := newIndexPager(page, outputsOnPage)
+	outputs := make([]ValueOutput, 0)
+	err := w.db.GetOutputsByValue(&minSat, &maxSat, lower, higher, maxOutputsByValueTxs, func(txid string, height uint32, vout int32, value *big.Int) error {
+		inPage, stop := pager.next()
+		if stop {
+			return &db.StopIteration{}
+		}
+		if inPage {
+			outputs = append(outputs, ValueOutput{
+				Txid:        txid,
+				Vout:        vout,
+				ValueSat:    (*Amount)(new(big.Int).Set(value)),
+				BlockHeight: int(height),
+			})
+		}
+		return nil
+	})
+	if err == db.ErrOutputsScanLimit {
+		return nil, NewAPIError("Too many transactions in the range, narrow the value or block range", true)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetOutputsByValue %v-%v", minValue, maxValue)
+	}
+	r := &ValueOutputs{
+		Paging:      pager.paging(),
+		MinValueSat: (*Amount)(&minSat),
+		MaxValueSat: (*Amount)(&maxSat),
+		Outputs:     outputs,
+	}
+	glog.Info("GetOutputsByValue ", minValue, "-", maxValue, ", blocks ", lower, "-", higher, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// GetBlocksByMiner returns blocks mined by the miner, from the newest block to the oldest
+// the index of blocks by miner must be enabled by the -minerindex option
+func (w *Worker) GetBlocksByMiner(miner string, page int, blocksOnPage int, filter *AddressFilter) (*MinerBlocks, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	if !w.db.HasBlockMinerIndex() {
+		return nil, NewAPIError("Index of blocks by miner is not enabled", true)
+	}
+	if miner == "" {
+		return nil, NewAPIError("Missing miner", true)
+	}
+	higher := filter.ToHeight
+	if higher == 0 {
+		higher = maxUint32
+	}
+	pager := newIndexPager(page, blocksOnPage)
+	heights := make([]uint32, 0)
+	err := w.db.GetBlocksByMiner(miner, filter.FromHeight, higher, func(height uint32) error {
+		inPage, stop := pager.next()
+		if stop {
+			return &db.StopIteration{}
+		}
+		if inPage {
+			heights = append(heights, height)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlocksByMiner %v", miner)
+	}
+	r := &MinerBlocks{
+		Paging: pager.paging(),
+		Miner:  miner,
+		Blocks: make([]db.BlockInfo, 0, len(heights)),
+	}
+	for _, height := range heights {
+		bi, err := w.db.GetBlockInfo(height)
+		if err != nil {
+			return nil, err
+		}
+		if bi != nil {
+			r.Blocks = append(r.Blocks, *bi)
+		}
+	}
+	glog.Info("GetBlocksByMiner ", miner, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// maxNewAddressesBlocks is the maximal number of blocks in the query of new addresses
+const maxNewAddressesBlocks = 100
+
+// GetNewAddresses returns the addresses which appeared for the first time in blocks between filter.FromHeight and filter.ToHeight,
+// from the oldest block to the newest; by default the best block is returned, at most maxNewAddressesBlocks blocks can be requested
+// the address first seen index must be enabled by the -addressfirstseenindex option
+func (w *Worker) GetNewAddresses(page int, addressesOnPage int, filter *AddressFilter) (*NewAddresses, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	if !w.db.HasAddressFirstSeenIndex() {
+		return nil, NewAPIError("Index of the first appearance of addresses is not enabled", true)
+	}
+	higher := filter.ToHeight
+	if higher == 0 {
+		bestHeight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		higher = bestHeight
+	}
+	lower := filter.FromHeight
+	if lower == 0 {
+		lower = higher
+	}
+	if lower > higher {
+		return nil, NewAPIError("Parameter 'from' is greater than 'to'", true)
+	}
+	if higher-lower >= maxNewAddressesBlocks {
+		return nil, NewAPIError(fmt.Sprintf("At most %d blocks can be requested", maxNewAddressesBlocks), true)
+	}
+	type newAddrDesc struct {
+		addrDesc bchain.AddressDescriptor
+		height   uint32
+		txid     string
+	}
+	found := make([]newAddrDesc, 0)
+	err := w.db.GetAddressesFirstSeen(lower, higher, func(addrDesc bchain.AddressDescriptor, height uint32, txid string) error {
+		found = append(found, newAddrDesc{addrDesc: addrDesc, height: height, txid: txid})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddressesFirstSeen %d-%d", lower, higher)
+	}
+	pg, from, to, page := computePaging(len(found), page, addressesOnPage)
+	r := &NewAddresses{
+		Paging:     pg,
+		FromHeight: lower,
+		ToHeight:   higher,
+		Addresses:  make([]NewAddress, 0, to-from),
+	}
+	for _, a := range found[from:to] {
+		addresses, _, err := w.chainParser.GetAddressesFromAddrDesc(a.addrDesc)
+		if err != nil {
+			glog.V(2).Infof("GetAddressesFromAddrDesc error %v, %v", err, a.addrDesc)
+		}
+		na := NewAddress{
+			Height: a.height,
+			Txid:   a.txid,
+		}
+		if len(addresses) == 1 {
+			na.AddrStr = addresses[0]
+		} else {
+			na.AddrStr = a.addrDesc.String()
+		}
+		r.Addresses = append(r.Addresses, na)
+	}
+	glog.Info("GetNewAddresses ", lower, "-", higher, ", page ", page, ", ", time.Since(start))
+	return r, nil
+}
+
+// removeEmpty removes empty strings from a slice
+func removeEmpty(stringSlice []string) []string {
+	var ret []string
 	for _, str := range stringSlice {
 		if str != "" {
 			ret = append(ret, str)
@@ -1904,6 +2905,60 @@ func (w *Worker) GetCurrentFiatRates(currencies []string, token string) (*FiatTi
 	return result, nil
 }
 
+// GetTxConfirmationPolicy returns the number of confirmations required for the transaction by the policy given by thresholds
+// the fiat value of the transaction is the value of its outputs in the currency, using the fiat rate at the time of the transaction
+// the required confirmations are given by the threshold with the highest MinValue not exceeding the fiat value, 0 if there is no such threshold
+func (w *Worker) GetTxConfirmationPolicy(txid string, currency string, thresholds []ConfirmationThreshold) (*TxConfirmationPolicy, error) {
+	currency = strings.ToLower(currency)
+	if currency == "" {
+		return nil, NewAPIError("Missing currency", true)
+	}
+	tx, err := w.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	// the rate at the time of the transaction, for recent transactions the last rate
+	t := time.Unix(tx.Blocktime, 0)
+	ticker, err := w.db.FiatRatesFindTicker(&t, currency, "")
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Error finding ticker: %v", err), false)
+	}
+	if ticker == nil {
+		if ticker = w.is.GetCurrentTicker(currency, ""); ticker == nil {
+			if ticker, err = w.db.FiatRatesFindLastTicker(currency, ""); err != nil {
+				return nil, NewAPIError(fmt.Sprintf("Error finding ticker: %v", err), false)
+			}
+		}
+	}
+	var rate float32
+	if ticker != nil {
+		rate = ticker.Rates[currency]
+	}
+	if rate <= 0 {
+		return nil, NewAPIError(fmt.Sprintf("No fiat rate available for currency %v", currency), true)
+	}
+	value, err := strconv.ParseFloat(tx.ValueOutSat.DecimalString(w.chainParser.AmountDecimals()), 64)
+	if err != nil {
+		return nil, err
+	}
+	r := &TxConfirmationPolicy{
+		Txid:          tx.Txid,
+		Currency:      currency,
+		Rate:          rate,
+		FiatValue:     value * float64(rate),
+		Confirmations: int(tx.Confirmations),
+	}
+	minValue := -1.0
+	for _, th := range thresholds {
+		if r.FiatValue >= th.MinValue && th.MinValue > minValue {
+			minValue = th.MinValue
+			r.RequiredConfirmations = th.Confirmations
+		}
+	}
+	r.Met = r.Confirmations >= r.RequiredConfirmations
+	return r, nil
+}
+
 // makeErrorRates returns a map of currencies, with each value equal to -1
 // used when there was an error finding ticker
 func makeErrorRates(currencies []string) map[string]float32 {
@@ -2004,6 +3059,326 @@ func (w *Worker) getBlockInfoFromBlockID(bid string) (*bchain.BlockInfo, error)
 	return bi, err
 }
 
+// maxFeeRatePackageTxs limits the number of unconfirmed ancestors and descendants considered in the effective fee rate,
+// it corresponds to the default ancestor/descendant limit of the bitcoind mempool
+const maxFeeRatePackageTxs = 25
+
+func feeRateTxSize(tx *Tx) int64 {
+	if tx.VSize > 0 {
+		return int64(tx.VSize)
+	}
+	return int64(tx.Size)
+}
+
+func feeRate(fees, size int64) float64 {
+	if size <= 0 {
+		return 0
+	}
+	return float64(fees) / float64(size)
+}
+
+// feeRateGetTx returns transaction, the transactions are cached in the txs map during the computation of the fee rate
+func (w *Worker) feeRateGetTx(txs map[string]*Tx, txid string) (*Tx, error) {
+	if tx, found := txs[txid]; found {
+		return tx, nil
+	}
+	tx, err := w.getTransaction(txid, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	txs[txid] = tx
+	return tx, nil
+}
+
+// ancestorFeeRate returns the fee rate of the transaction together with its unconfirmed ancestors
+func (w *Worker) ancestorFeeRate(txs map[string]*Tx, tx *Tx) (float64, error) {
+	fees, size := tx.FeesSat.AsInt64(), feeRateTxSize(tx)
+	visited := map[string]struct{}{tx.Txid: {}}
+	queue := []*Tx{tx}
+	for len(queue) > 0 && len(visited) <= maxFeeRatePackageTxs {
+		t := queue[0]
+		queue = queue[1:]
+		for i := range t.Vin {
+			txid := t.Vin[i].Txid
+			if txid == "" {
+				continue
+			}
+			if _, found := visited[txid]; found {
+				continue
+			}
+			visited[txid] = struct{}{}
+			// confirmed transactions are in txAddresses
+			ta, err := w.db.GetTxAddresses(txid)
+			if err != nil {
+				return 0, err
+			}
+			if ta != nil {
+				continue
+			}
+			p, err := w.feeRateGetTx(txs, txid)
+			if err != nil {
+				return 0, err
+			}
+			if p.Confirmations == 0 {
+				fees += p.FeesSat.AsInt64()
+				size += feeRateTxSize(p)
+				queue = append(queue, p)
+			}
+		}
+	}
+	return feeRate(fees, size), nil
+}
+
+// unconfirmedDescendants returns mempool transactions spending outputs of the transaction, directly or indirectly
+func (w *Worker) unconfirmedDescendants(txs map[string]*Tx, tx *Tx) ([]*Tx, error) {
+	var descendants []*Tx
+	visited := map[string]struct{}{tx.Txid: {}}
+	queue := []*Tx{tx}
+	for len(queue) > 0 && len(descendants) < maxFeeRatePackageTxs {
+		t := queue[0]
+		queue = queue[1:]
+		for i := range t.Vout {
+			vout := &t.Vout[i]
+			if len(vout.AddrDesc) == 0 {
+				continue
+			}
+			outpoints, err := w.mempool.GetAddrDescTransactions(vout.AddrDesc)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range outpoints {
+				// only inputs can spend the output
+				if o.Vout >= 0 {
+					continue
+				}
+				if _, found := visited[o.Txid]; found {
+					continue
+				}
+				c, err := w.feeRateGetTx(txs, o.Txid)
+				if err != nil {
+					return nil, err
+				}
+				n := int(^o.Vout)
+				if n < len(c.Vin) && c.Vin[n].Txid == t.Txid && int(c.Vin[n].Vout) == vout.N {
+					visited[o.Txid] = struct{}{}
+					descendants = append(descendants, c)
+					queue = append(queue, c)
+				}
+			}
+		}
+	}
+	return descendants, nil
+}
+
+// GetEffectiveFeeRate returns the fee rate of the transaction in satoshis per vbyte
+// for mempool transactions the rate accounts for unconfirmed ancestors, which must be mined together with the transaction,
+// and for descendants paying for the transaction (CPFP), for confirmed transactions the standalone rate is returned
+func (w *Worker) GetEffectiveFeeRate(txid string) (float64, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return 0, NewAPIError("Effective fee rate is supported only for Bitcoin type coins", true)
+	}
+	start := time.Now()
+	txs := make(map[string]*Tx)
+	tx, err := w.feeRateGetTx(txs, txid)
+	if err != nil {
+		return 0, err
+	}
+	if tx.Confirmations > 0 {
+		return feeRate(tx.FeesSat.AsInt64(), feeRateTxSize(tx)), nil
+	}
+	rate, err := w.ancestorFeeRate(txs, tx)
+	if err != nil {
+		return 0, err
+	}
+	descendants, err := w.unconfirmedDescendants(txs, tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range descendants {
+		r, err := w.ancestorFeeRate(txs, d)
+		if err != nil {
+			return 0, err
+		}
+		if r > rate {
+			rate = r
+		}
+	}
+	glog.Info("GetEffectiveFeeRate ", txid, ", ", len(txs), " txs, ", time.Since(start))
+	return rate, nil
+}
+
+// parameters of the estimation of the confirmation probability
+const (
+	defaultConfirmationBlocks = 1
+	maxConfirmationBlocks     = 144
+	confirmationRecentBlocks  = 6
+	confirmationBlockVSize    = 1000000
+)
+
+// getRecentBlockFeeRates returns the fee rate percentiles of the recent blocks, the blocks without the percentiles are skipped
+func (w *Worker) getRecentBlockFeeRates() ([][]float64, error) {
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	w.blockFeeRatesMux.Lock()
+	defer w.blockFeeRatesMux.Unlock()
+	if w.blockFeeRates == nil {
+		w.blockFeeRates = make(map[uint32][]float64)
+	}
+	var r [][]float64
+	for i := uint32(0); i < confirmationRecentBlocks && i <= bestHeight; i++ {
+		height := bestHeight - i
+		rates, found := w.blockFeeRates[height]
+		if !found {
+			rates, err = w.chain.GetBlockFeeRatePercentiles(height)
+			if err != nil {
+				glog.V(1).Info("GetBlockFeeRatePercentiles ", height, ": ", err)
+				continue
+			}
+			w.blockFeeRates[height] = rates
+		}
+		if len(rates) > 0 {
+			r = append(r, rates)
+		}
+	}
+	// forget the blocks which are no longer recent or were disconnected
+	for height := range w.blockFeeRates {
+		if height+confirmationRecentBlocks <= bestHeight || height > bestHeight {
+			delete(w.blockFeeRates, height)
+		}
+	}
+	return r, nil
+}
+
+// blockInclusionRate returns the share of the recent blocks which would include a transaction paying the fee rate,
+// a block includes the transaction if the fee rate is at least the lowest percentile of the fee rates of the block,
+// below the percentile the likelihood decreases linearly to zero; without the block statistics the rate is 1
+func blockInclusionRate(feeRate float64, blockFeeRates [][]float64) float64 {
+	if len(blockFeeRates) == 0 {
+		return 1
+	}
+	var sum float64
+	for _, rates := range blockFeeRates {
+		if feeRate >= rates[0] {
+			sum++
+		} else {
+			sum += feeRate / rates[0]
+		}
+	}
+	return sum / float64(len(blockFeeRates))
+}
+
+// confirmationProbability estimates the probability that a transaction paying the fee rate is confirmed within the blocks;
+// the mempool transactions paying higher fee rates are mined first and together with the transaction fill some blocks,
+// in each of the remaining blocks the transaction is included with the inclusion rate of the recent blocks
+func confirmationProbability(feeRate float64, vsize int64, blocks int, histogram []bchain.MempoolFeeRateBucket, inclusionRate float64) (float64, int64) {
+	var ahead int64
+	for i := range histogram {
+		if histogram[i].FeeRate > feeRate {
+			ahead += histogram[i].VSize
+		}
+	}
+	needed := int((ahead + vsize + confirmationBlockVSize - 1) / confirmationBlockVSize)
+	if needed < 1 {
+		needed = 1
+	}
+	if needed > blocks {
+		return 0, ahead
+	}
+	return 1 - math.Pow(1-inclusionRate, float64(blocks-needed+1)), ahead
+}
+
+// GetConfirmationProbability estimates the probability that the mempool transaction is confirmed within the number of blocks,
+// the effective fee rate of the transaction is compared to the fee histogram of the mempool and to the fee rates of the recent blocks
+func (w *Worker) GetConfirmationProbability(txid string, blocks int) (*ConfirmationProbability, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if blocks == 0 {
+		blocks = defaultConfirmationBlocks
+	}
+	if blocks < 0 || blocks > maxConfirmationBlocks {
+		return nil, NewAPIError(fmt.Sprintf("Blocks must be between 1 and %d", maxConfirmationBlocks), true)
+	}
+	start := time.Now()
+	tx, err := w.getTransaction(txid, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &ConfirmationProbability{
+		Txid:   txid,
+		Blocks: blocks,
+	}
+	if tx.Confirmations > 0 {
+		r.Probability = 1
+		r.Confirmations = tx.Confirmations
+		return r, nil
+	}
+	if r.FeeRate, err = w.GetEffectiveFeeRate(txid); err != nil {
+		return nil, err
+	}
+	histogram, err := w.chain.GetMempoolFeeHistogram()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetMempoolFeeHistogram")
+	}
+	blockFeeRates, err := w.getRecentBlockFeeRates()
+	if err != nil {
+		return nil, err
+	}
+	r.BlockInclusionRate = blockInclusionRate(r.FeeRate, blockFeeRates)
+	r.Probability, r.MempoolVSizeAhead = confirmationProbability(r.FeeRate, feeRateTxSize(tx), blocks, histogram, r.BlockInclusionRate)
+	glog.Info("GetConfirmationProbability ", txid, ", ", blocks, " blocks, ", time.Since(start))
+	return r, nil
+}
+
+// nextBlockFeeRate returns the fee rate which places a transaction among the mempool transactions fitting to the next block
+// and the total virtual size of the mempool; the rate is the upper bound of the bucket of the histogram in which the block fills up,
+// i.e. the transactions paying a lower fee rate may not fit; if the whole mempool fits to the block, the rate is 0
+func nextBlockFeeRate(histogram []bchain.MempoolFeeRateBucket) (float64, int64) {
+	var size int64
+	for i := range histogram {
+		size += histogram[i].VSize
+	}
+	if size <= confirmationBlockVSize {
+		return 0, size
+	}
+	var ahead int64
+	for i := range histogram {
+		if ahead+histogram[i].VSize > confirmationBlockVSize {
+			if i == 0 {
+				return histogram[0].FeeRate, size
+			}
+			return histogram[i-1].FeeRate, size
+		}
+		ahead += histogram[i].VSize
+	}
+	return 0, size
+}
+
+// GetNextBlockFee returns the fee rate estimated by the backend for the confirmation in the next block
+// together with the fee rate derived from the fee histogram of the mempool
+func (w *Worker) GetNextBlockFee(conservative bool) (*NextBlockFee, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	fee, err := w.EstimateFee(1, conservative)
+	if err != nil {
+		return nil, errors.Annotatef(err, "EstimateFee")
+	}
+	histogram, err := w.chain.GetMempoolFeeHistogram()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetMempoolFeeHistogram")
+	}
+	r := &NextBlockFee{
+		Conservative: conservative,
+		// the estimate is in satoshis per kilobyte, 1 kilobyte is 1000 virtual bytes
+		EstimatedFeeRate: float64(fee.Int64()) / 1000,
+	}
+	r.MempoolFeeRate, r.MempoolVSize = nextBlockFeeRate(histogram)
+	return r, nil
+}
+
 // GetFeeStats returns statistics about block fees
 func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 	// txSpecific extends Tx with an additional Size and Vsize info
@@ -2040,6 +3415,11 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 			return nil, errors.Annotatef(err, "Unmarshal")
 		}
 
+		// coinstake transaction creates the staking reward, it does not pay any fee
+		if w.chainParser.IsCoinStake(txSpec.Tx) {
+			continue
+		}
+
 		// Calculate the TX size in bytes
 		txSize := 0
 		if txSpec.Vsize > 0 {
@@ -2132,7 +3512,7 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 	txCount := len(bi.Txids)
 	bestheight, _, err := w.db.GetBestBlock()
 	if err != nil {
-		return nil, errors.Annotatef(err, "GetBestBlock")
+		return nil, errors.Annotate(err, "GetBestBlock")
 	}
 	pg, from, to, page := computePaging(txCount, page, txsOnPage)
 	txs := make([]*Tx, to-from)
@@ -2152,38 +3532,211 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 		bi.Next, _ = w.db.GetBlockHash(bi.Height + 1)
 	}
 	txs = txs[:txi]
+	var totalInputs, totalOutputs int
+	var reward *BlockReward
+	var medianTime int64
+	if w.chainType == bchain.ChainBitcoinType {
+		if medianTime, err = w.getMedianTimePast(bi.Height); err != nil {
+			glog.Error("getMedianTimePast ", bi.Height, ": ", err)
+			medianTime = 0
+		}
+		var fees *big.Int
+		if totalInputs, totalOutputs, fees, err = w.getBlockTotals(bi.Hash, bi.Txids); err != nil {
+			return nil, err
+		}
+		if fees != nil {
+			reward = &BlockReward{FeesSat: (*Amount)(fees)}
+			if subsidy, err := w.chainParser.GetBlockSubsidy(bi.Height); err == nil {
+				reward.SubsidySat = (*Amount)(subsidy)
+			}
+		}
+	}
 	bi.Txids = nil
 	glog.Info("GetBlock ", bid, ", page ", page, ", ", time.Since(start))
 	return &Block{
 		Paging: pg,
 		BlockInfo: BlockInfo{
-			Hash:          bi.Hash,
-			Prev:          bi.Prev,
-			Next:          bi.Next,
-			Height:        bi.Height,
-			Confirmations: bi.Confirmations,
-			Size:          bi.Size,
-			Time:          bi.Time,
-			Bits:          bi.Bits,
-			Difficulty:    string(bi.Difficulty),
-			MerkleRoot:    bi.MerkleRoot,
-			Nonce:         string(bi.Nonce),
-			Txids:         bi.Txids,
-			Version:       bi.Version,
+			Hash:           bi.Hash,
+			Prev:           bi.Prev,
+			Next:           bi.Next,
+			Height:         bi.Height,
+			Confirmations:  bi.Confirmations,
+			Size:           bi.Size,
+			StrippedSize:   bi.StrippedSize,
+			Weight:         bi.Weight,
+			Time:           bi.Time,
+			MedianTimePast: medianTime,
+			Bits:           bi.Bits,
+			Difficulty:     string(bi.Difficulty),
+			MerkleRoot:     bi.MerkleRoot,
+			Nonce:          string(bi.Nonce),
+			Txids:          bi.Txids,
+			Version:        bi.Version,
 		},
 		TxCount:        txCount,
+		TotalInputs:    totalInputs,
+		TotalOutputs:   totalOutputs,
+		Reward:         reward,
 		Transactions:   txs,
 		AddressAliases: w.getAddressAliases(addresses),
 	}, nil
 }
 
+// blockTotalsCacheSize is the maximum number of the blocks with the cached totals
+const blockTotalsCacheSize = 1000
+
+type blockTotals struct {
+	inputs  int
+	outputs int
+	fees    *big.Int
+}
+
+// getBlockTotals returns the total number of inputs and outputs of the transactions of the block and the fees collected by the block,
+// read from the index; the fees are the sum of the inputs minus the outputs of the transactions spending non-zero value,
+// which leaves out the coinbase transaction; if some transaction is not indexed (yet), the totals are not known and zeros are returned
+// the totals read all the transactions of the block, they are cached by the block hash so that the pages of the block do not repeat the reads
+func (w *Worker) getBlockTotals(hash string, txids []string) (int, int, *big.Int, error) {
+	w.blockTotalsMux.Lock()
+	bt, found := w.blockTotals[hash]
+	w.blockTotalsMux.Unlock()
+	if found {
+		return bt.inputs, bt.outputs, bt.fees, nil
+	}
+	var inputs, outputs int
+	var fees big.Int
+	for _, txid := range txids {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return 0, 0, nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta == nil {
+			glog.V(1).Info("getBlockTotals: tx ", txid, " not found in index")
+			return 0, 0, nil, nil
+		}
+		inputs += len(ta.Inputs)
+		outputs += len(ta.Outputs)
+		var valIn big.Int
+		for i := range ta.Inputs {
+			valIn.Add(&valIn, &ta.Inputs[i].ValueSat)
+		}
+		if valIn.Sign() > 0 {
+			fees.Add(&fees, &valIn)
+			for i := range ta.Outputs {
+				fees.Sub(&fees, &ta.Outputs[i].ValueSat)
+			}
+		}
+	}
+	w.blockTotalsMux.Lock()
+	if w.blockTotals == nil {
+		w.blockTotals = make(map[string]*blockTotals)
+	}
+	if len(w.blockTotals) >= blockTotalsCacheSize {
+		// forget an arbitrary cached block, its totals are computed again when requested
+		for h := range w.blockTotals {
+			delete(w.blockTotals, h)
+			break
+		}
+	}
+	w.blockTotals[hash] = &blockTotals{inputs: inputs, outputs: outputs, fees: &fees}
+	w.blockTotalsMux.Unlock()
+	return inputs, outputs, &fees, nil
+}
+
+// limits of the number of the largest transactions of a block
+const (
+	defaultBlockTopTxs = 10
+	maxBlockTopTxs     = 100
+)
+
+// GetBlockTopTxs returns limit largest transactions of the block sorted by the total output value (by "value")
+// or by the (virtual) size (by "vsize"), the transactions of the same value or size keep the order of the block;
+// the data are read from the index, only Bitcoin-type coins are supported
+func (w *Worker) GetBlockTopTxs(bid string, by string, limit int) (*BlockTopTxs, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if by == "" {
+		by = "value"
+	}
+	if by != "value" && by != "vsize" {
+		return nil, NewAPIError(fmt.Sprintf("Unknown sort order '%v', use value or vsize", by), true)
+	}
+	if limit <= 0 {
+		limit = defaultBlockTopTxs
+	}
+	if limit > maxBlockTopTxs {
+		limit = maxBlockTopTxs
+	}
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return nil, NewAPIError("Block not found", true)
+		}
+		return nil, NewAPIError(fmt.Sprintf("Block not found, %v", err), true)
+	}
+	type rankedTx struct {
+		txid  string
+		value big.Int
+		size  int
+	}
+	txs := make([]rankedTx, 0, len(bi.Txids))
+	for _, txid := range bi.Txids {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta == nil {
+			return nil, NewAPIError(fmt.Sprintf("Transaction %v of the block is not indexed", txid), true)
+		}
+		t := rankedTx{txid: txid, size: int(ta.VSize)}
+		for i := range ta.Outputs {
+			t.value.Add(&t.value, &ta.Outputs[i].ValueSat)
+		}
+		txs = append(txs, t)
+	}
+	if by == "value" {
+		sort.SliceStable(txs, func(i, j int) bool { return txs[i].value.Cmp(&txs[j].value) > 0 })
+	} else {
+		sort.SliceStable(txs, func(i, j int) bool { return txs[i].size > txs[j].size })
+	}
+	if len(txs) > limit {
+		txs = txs[:limit]
+	}
+	r := &BlockTopTxs{
+		Hash:   bi.Hash,
+		Height: bi.Height,
+		By:     by,
+		Txs:    make([]BlockTopTx, len(txs)),
+	}
+	for i := range txs {
+		r.Txs[i] = BlockTopTx{Txid: txs[i].txid, ValueOutSat: (*Amount)(&txs[i].value)}
+		if w.chainParser.SupportsVSize() {
+			r.Txs[i].VSize = txs[i].size
+		} else {
+			r.Txs[i].Size = txs[i].size
+		}
+	}
+	return r, nil
+}
+
 // GetBlock returns paged data about block
 func (w *Worker) GetBlockRaw(bid string) (*BlockRaw, error) {
+	return w.getBlockRaw(bid, w.chain.GetBlockRaw)
+}
+
+// GetBlockRawFromBackend returns the raw block as returned by the named back-end
+func (w *Worker) GetBlockRawFromBackend(backend string, bid string) (*BlockRaw, error) {
+	return w.getBlockRaw(bid, func(hash string) (string, error) {
+		return w.chain.GetBlockRawFromBackend(backend, hash)
+	})
+}
+
+func (w *Worker) getBlockRaw(bid string, getBlockRaw func(hash string) (string, error)) (*BlockRaw, error) {
 	hash := w.getBlockHashBlockID(bid)
 	if hash == "" {
 		return nil, NewAPIError("Block not found", true)
 	}
-	hex, err := w.chain.GetBlockRaw(hash)
+	hex, err := getBlockRaw(hash)
 	if err != nil {
 		if err == bchain.ErrBlockNotFound {
 			return nil, NewAPIError("Block not found", true)
@@ -2193,11 +3746,47 @@ func (w *Worker) GetBlockRaw(bid string) (*BlockRaw, error) {
 	return &BlockRaw{Hex: hex}, err
 }
 
+// GetBlockSpecificFromBackend returns json of the block as returned by the named back-end,
+// the block height is resolved to the hash by the index
+func (w *Worker) GetBlockSpecificFromBackend(backend string, bid string) (json.RawMessage, error) {
+	hash := w.getBlockHashBlockID(bid)
+	if hash == "" {
+		return nil, NewAPIError("Block not found", true)
+	}
+	block, err := w.chain.GetBlockSpecificFromBackend(backend, hash)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return nil, NewAPIError("Block not found", true)
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+// GetBlockFilter returns the compact block filter and the filter header of the block
+func (w *Worker) GetBlockFilter(bid string) (*BlockFilter, error) {
+	hash := w.getBlockHashBlockID(bid)
+	if hash == "" {
+		return nil, NewAPIError("Block not found", true)
+	}
+	bf, err := w.chain.GetBlockFilter(hash)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return nil, NewAPIError("Block not found", true)
+		}
+		if err == bchain.ErrNotSupported {
+			return nil, NewAPIError("Block filters are not supported by the backend", true)
+		}
+		return nil, err
+	}
+	return &BlockFilter{BlockHash: hash, Filter: bf.Filter, Header: bf.Header}, nil
+}
+
 // ComputeFeeStats computes fee distribution in defined blocks and logs them to log
 func (w *Worker) ComputeFeeStats(blockFrom, blockTo int, stopCompute chan os.Signal) error {
 	bestheight, _, err := w.db.GetBestBlock()
 	if err != nil {
-		return errors.Annotatef(err, "GetBestBlock")
+		return errors.Annotate(err, "GetBestBlock")
 	}
 	for block := blockFrom; block <= blockTo; block++ {
 		hash, err := w.db.GetBlockHash(uint32(block))
@@ -2282,6 +3871,11 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 		inSync = false
 		inSyncMempool = false
 	}
+	// the mempool min fee is updated during the mempool synchronization
+	var mempoolMinFee string
+	if backendError == "" {
+		mempoolMinFee = w.is.GetMempoolMinFee()
+	}
 	var columnStats []common.InternalStateColumn
 	var internalDBSize int64
 	if internal {
@@ -2330,6 +3924,8 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 		Timeoffset:       ci.Timeoffset,
 		Version:          ci.Version,
 		Warnings:         ci.Warnings,
+		MempoolMinFee:    mempoolMinFee,
+		MinRelayTxFee:    ci.MinRelayTxFee,
 		ConsensusVersion: ci.ConsensusVersion,
 		Consensus:        ci.Consensus,
 	}
@@ -2361,6 +3957,52 @@ func (w *Worker) GetMempool(page int, itemsOnPage int) (*MempoolTxids, error) {
 	return r, nil
 }
 
+// GetMempoolInfo returns the summary of the mempool, the number of transactions is taken from the synchronized mempool index,
+// the total virtual size, fees and the minimal fee per kilobyte from the backend
+func (w *Worker) GetMempoolInfo() (*MempoolInfo, error) {
+	mi, err := w.chain.GetMempoolInfo()
+	if err != nil {
+		return nil, err
+	}
+	return &MempoolInfo{
+		Count:      len(w.mempool.GetAllEntries()),
+		TotalVSize: mi.Bytes,
+		TotalFees:  (*Amount)(&mi.TotalFeeSat),
+		MinFee:     (*Amount)(&mi.MinFeeSat),
+	}, nil
+}
+
+// GetSyncStatus returns the height of the index, the height of the backend and the number of blocks by which the index lags behind the backend
+func (w *Worker) GetSyncStatus() (*SyncStatus, error) {
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotate(err, "GetBestBlock")
+	}
+	inSync, _, _, _ := w.is.GetSyncState()
+	r := &SyncStatus{
+		Coin:       w.is.Coin,
+		Shortcut:   w.is.CoinShortcut,
+		Network:    w.chain.GetNetworkName(),
+		BestHeight: bestHeight,
+		InSync:     inSync,
+	}
+	ci, err := w.chain.GetChainInfo()
+	if err != nil {
+		glog.Error("GetChainInfo error ", err)
+		r.BackendError = errors.Annotatef(err, "GetChainInfo").Error()
+		r.InSync = false
+		return r, nil
+	}
+	r.BackendHeight = ci.Blocks
+	r.BackendVersion = ci.Version
+	r.BackendSubversion = ci.Subversion
+	// the backend can be behind the index for a short time, e.g. after a restart of the backend
+	if lag := ci.Blocks - int(bestHeight); lag > 0 {
+		r.Lag = lag
+	}
+	return r, nil
+}
+
 type bitcoinTypeEstimatedFee struct {
 	timestamp int64
 	fee       big.Int
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"sort"
@@ -11,6 +12,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/juju/errors"
 	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
 	"github.com/trezor/blockbook/db"
 )
 
@@ -25,6 +27,18 @@ const xpubCacheExpirationSeconds = 3600
 var cachedXpubs map[string]xpubData
 var cachedXpubsMux sync.Mutex
 
+// xpubMaxIncrementalBlocks is the maximum number of new blocks for which the cached xpub data are updated incrementally,
+// if there are more new blocks, balances of all derived addresses are reloaded
+const xpubMaxIncrementalBlocks = 10
+
+type xpubBlockAddresses struct {
+	hash      string
+	addresses map[string]struct{}
+}
+
+var cachedBlockAddresses map[uint32]xpubBlockAddresses
+var cachedBlockAddressesMux sync.Mutex
+
 const xpubLogPrefix = 30
 
 type xpubTxid struct {
@@ -38,11 +52,16 @@ type xpubTxids []xpubTxid
 func (a xpubTxids) Len() int      { return len(a) }
 func (a xpubTxids) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a xpubTxids) Less(i, j int) bool {
-	// if the heights are equal, make inputs less than outputs
+	// if the heights are equal, make inputs less than outputs and then order by txid to make the order deterministic
 	hi := a[i].height
 	hj := a[j].height
 	if hi == hj {
-		return (a[i].inputOutput & txInput) >= (a[j].inputOutput & txInput)
+		ii := a[i].inputOutput & txInput
+		ij := a[j].inputOutput & txInput
+		if ii != ij {
+			return ii > ij
+		}
+		return a[i].txid > a[j].txid
 	}
 	return hi > hj
 }
@@ -59,6 +78,7 @@ type xpubAddress struct {
 type xpubData struct {
 	descriptor      *bchain.XpubDescriptor
 	gap             int
+	changeGap       int
 	accessed        int64
 	basePath        string
 	dataHeight      uint32
@@ -73,6 +93,7 @@ func (w *Worker) initXpubCache() {
 	cachedXpubsMux.Lock()
 	if cachedXpubs == nil {
 		cachedXpubs = make(map[string]xpubData)
+		cachedBlockAddresses = make(map[uint32]xpubBlockAddresses)
 		go func() {
 			for {
 				time.Sleep(20 * time.Second)
@@ -193,16 +214,58 @@ func (w *Worker) xpubDerivedAddressBalance(data *xpubData, ad *xpubAddress) (boo
 	if ad.balance, err = w.db.GetAddrDescBalance(ad.addrDesc, db.AddressBalanceDetailUTXO); err != nil {
 		return false, err
 	}
+	w.metrics.XPubCacheEfficiency.With(common.Labels{"status": "miss"}).Inc()
+	return xpubAddBalance(data, ad), nil
+}
+
+// xpubAddBalance adds the balance of the derived address to the xpub totals, returns true if the address is used
+func xpubAddBalance(data *xpubData, ad *xpubAddress) bool {
 	if ad.balance != nil {
 		data.txCountEstimate += ad.balance.Txs
 		data.sentSat.Add(&data.sentSat, &ad.balance.SentSat)
 		data.balanceSat.Add(&data.balanceSat, &ad.balance.BalanceSat)
-		return true, nil
+		return true
+	}
+	return false
+}
+
+// xpubGetBlocksAddresses returns addresses touched by the blocks in the range lower-higher
+// the sets of addresses are cached as they are shared by all xpubs, nil is returned if the data are not available
+func (w *Worker) xpubGetBlocksAddresses(lower, higher uint32) ([]map[string]struct{}, error) {
+	if higher-lower >= xpubMaxIncrementalBlocks {
+		return nil, nil
+	}
+	r := make([]map[string]struct{}, 0, higher-lower+1)
+	for height := lower; height <= higher; height++ {
+		hash, err := w.db.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		cachedBlockAddressesMux.Lock()
+		ba, found := cachedBlockAddresses[height]
+		cachedBlockAddressesMux.Unlock()
+		if !found || ba.hash != hash {
+			addresses, ok, err := w.db.GetBlockAddresses(height)
+			if err != nil || !ok {
+				return nil, err
+			}
+			ba = xpubBlockAddresses{hash: hash, addresses: addresses}
+			cachedBlockAddressesMux.Lock()
+			cachedBlockAddresses[height] = ba
+			// keep only the recent blocks
+			for h := range cachedBlockAddresses {
+				if h+xpubMaxIncrementalBlocks < higher {
+					delete(cachedBlockAddresses, h)
+				}
+			}
+			cachedBlockAddressesMux.Unlock()
+		}
+		r = append(r, ba.addresses)
 	}
-	return false, nil
+	return r, nil
 }
 
-func (w *Worker) xpubScanAddresses(xd *bchain.XpubDescriptor, data *xpubData, addresses []xpubAddress, gap int, change uint32, minDerivedIndex int, fork bool) (int, []xpubAddress, error) {
+func (w *Worker) xpubScanAddresses(xd *bchain.XpubDescriptor, data *xpubData, addresses []xpubAddress, gap int, change uint32, minDerivedIndex int, fork bool, touched []map[string]struct{}) (int, []xpubAddress, error) {
 	// rescan known addresses
 	lastUsed := 0
 	for i := range addresses {
@@ -214,7 +277,15 @@ func (w *Worker) xpubScanAddresses(xd *bchain.XpubDescriptor, data *xpubData, ad
 			ad.complete = false
 			ad.txids = nil
 		}
-		used, err := w.xpubDerivedAddressBalance(data, ad)
+		var used bool
+		var err error
+		if touched != nil && !isTouchedAddress(touched, ad.addrDesc) {
+			// the balance did not change, reuse the cached one
+			w.metrics.XPubCacheEfficiency.With(common.Labels{"status": "hit"}).Inc()
+			used = xpubAddBalance(data, ad)
+		} else {
+			used, err = w.xpubDerivedAddressBalance(data, ad)
+		}
 		if err != nil {
 			return 0, nil, err
 		}
@@ -250,6 +321,15 @@ func (w *Worker) xpubScanAddresses(xd *bchain.XpubDescriptor, data *xpubData, ad
 	return lastUsed, addresses, nil
 }
 
+func isTouchedAddress(touched []map[string]struct{}, addrDesc bchain.AddressDescriptor) bool {
+	for _, t := range touched {
+		if _, found := t[string(addrDesc)]; found {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *Worker) tokenFromXpubAddress(data *xpubData, ad *xpubAddress, changeIndex int, index int, option AccountDetails) Token {
 	a, _, _ := w.chainParser.GetAddressesFromAddrDesc(ad.addrDesc)
 	var address string
@@ -305,7 +385,21 @@ func setIsOwnAddresses(txs []*Tx, xpubAddresses map[string]struct{}) {
 	}
 }
 
-func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*xpubData, uint32, bool, error) {
+// xpubGap returns the gap limit used for derivation of addresses
+func xpubGap(gap int) int {
+	if gap <= 0 {
+		gap = defaultAddressesGap
+	} else if gap > maxAddressesGap {
+		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
+		gap = maxAddressesGap
+	}
+	// gap is increased one as there must be gap of empty addresses before the derivation is stopped
+	return gap + 1
+}
+
+// getXpubData returns data of the xpub, gap is used for the first (receive) derivation chain, changeGap for the other (change) chains
+// if changeGap is not specified, gap is used for all chains
+func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int, changeGap int) (*xpubData, uint32, bool, error) {
 	if w.chainType != bchain.ChainBitcoinType {
 		return nil, 0, false, ErrUnsupportedXpub
 	}
@@ -314,14 +408,11 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 		bestheight uint32
 		besthash   string
 	)
-	if gap <= 0 {
-		gap = defaultAddressesGap
-	} else if gap > maxAddressesGap {
-		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
-		gap = maxAddressesGap
+	if changeGap <= 0 {
+		changeGap = gap
 	}
-	// gap is increased one as there must be gap of empty addresses before the derivation is stopped
-	gap++
+	gap = xpubGap(gap)
+	changeGap = xpubGap(changeGap)
 	var processedHash string
 	cachedXpubsMux.Lock()
 	data, inCache := cachedXpubs[xd.XpubDescriptor]
@@ -336,9 +427,11 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 			break
 		}
 		fork := false
-		if !inCache || data.gap != gap {
+		var touched []map[string]struct{}
+		if !inCache || data.gap != gap || data.changeGap != changeGap {
 			data = xpubData{
 				gap:       gap,
+				changeGap: changeGap,
 				addresses: make([][]xpubAddress, len(xd.ChangeIndexes)),
 			}
 			data.basePath, err = w.chainParser.DerivationBasePath(xd)
@@ -353,6 +446,11 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 			if hash != data.dataHash {
 				// in case of for reset all cached data
 				fork = true
+			} else if data.dataHeight < bestheight {
+				// reload only balances of the addresses touched by the new blocks
+				if touched, err = w.xpubGetBlocksAddresses(data.dataHeight+1, bestheight); err != nil {
+					return nil, 0, inCache, err
+				}
 			}
 		}
 		processedHash = besthash
@@ -364,7 +462,11 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 			data.txCountEstimate = 0
 			var minDerivedIndex int
 			for i, change := range xd.ChangeIndexes {
-				minDerivedIndex, data.addresses[i], err = w.xpubScanAddresses(xd, &data, data.addresses[i], gap, change, minDerivedIndex, fork)
+				g := gap
+				if i > 0 {
+					g = changeGap
+				}
+				minDerivedIndex, data.addresses[i], err = w.xpubScanAddresses(xd, &data, data.addresses[i], g, change, minDerivedIndex, fork, touched)
 				if err != nil {
 					return nil, 0, inCache, err
 				}
@@ -388,7 +490,7 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 }
 
 // GetXpubAddress computes address value and gets transactions for given address
-func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int, secondaryCoin string) (*Address, error) {
+func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int, changeGap int, secondaryCoin string) (*Address, error) {
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -413,7 +515,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	if err != nil {
 		return nil, err
 	}
-	data, bestheight, inCache, err := w.getXpubData(xd, page, txsOnPage, option, filter, gap)
+	data, bestheight, inCache, err := w.getXpubData(xd, page, txsOnPage, option, filter, gap, changeGap)
 	if err != nil {
 		return nil, err
 	}
@@ -526,17 +628,18 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			}
 		}
 		// get confirmed transactions
+		pageTxids := make([]string, 0, to-from)
 		for i := from; i < to; i++ {
-			xpubTxid := &txc[i]
-			if option == AccountDetailsTxidHistory {
-				txids = append(txids, xpubTxid.txid)
-			} else {
-				tx, err := w.txFromTxid(xpubTxid.txid, bestheight, option, nil, addresses)
-				if err != nil {
-					return nil, err
-				}
-				txs = append(txs, tx)
+			pageTxids = append(pageTxids, txc[i].txid)
+		}
+		if option == AccountDetailsTxidHistory {
+			txids = append(txids, pageTxids...)
+		} else {
+			pageTxs, err := w.txsFromTxids(pageTxids, bestheight, option, addresses)
+			if err != nil {
+				return nil, err
 			}
+			txs = append(txs, pageTxs...)
 		}
 	} else {
 		txCount = int(data.txCountEstimate)
@@ -604,16 +707,32 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 }
 
 // GetXpubUtxo returns unspent outputs for given xpub
-func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, error) {
-	start := time.Now()
+func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int, changeGap int) (Utxos, error) {
 	xd, err := w.chainParser.ParseXpub(xpub)
 	if err != nil {
 		return nil, err
 	}
+	return w.getXpubUtxo(xpub, xd, onlyConfirmed, gap, changeGap, false)
+}
+
+// GetXpubUtxoWithScripts returns unspent outputs for given xpub with the derivation path and the output script of each utxo
+func (w *Worker) GetXpubUtxoWithScripts(xpub string, onlyConfirmed bool, gap int, changeGap int) (Utxos, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid xpub '%v', %v", xpub, err), true)
+	}
+	return w.getXpubUtxo(xpub, xd, onlyConfirmed, gap, changeGap, true)
+}
+
+func (w *Worker) getXpubUtxo(xpub string, xd *bchain.XpubDescriptor, onlyConfirmed bool, gap int, changeGap int, withScripts bool) (Utxos, error) {
+	start := time.Now()
 	data, _, inCache, err := w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
 		Vout:          AddressFilterVoutOff,
 		OnlyConfirmed: onlyConfirmed,
-	}, gap)
+	}, gap, changeGap)
 	if err != nil {
 		return nil, err
 	}
@@ -634,10 +753,19 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 			}
 			if len(utxos) > 0 {
 				t := w.tokenFromXpubAddress(data, ad, ci, i, AccountDetailsTokens)
+				var script string
+				if withScripts {
+					s, err := w.chainParser.GetScriptFromAddrDesc(ad.addrDesc)
+					if err != nil {
+						return nil, err
+					}
+					script = hex.EncodeToString(s)
+				}
 				for j := range utxos {
 					a := &utxos[j]
 					a.Address = t.Name
 					a.Path = t.Path
+					a.Script = script
 				}
 				r = append(r, utxos...)
 			}
@@ -648,8 +776,41 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 	return r, nil
 }
 
+// GetMempoolTransactionsForXpub returns txids of mempool transactions touching any address derived from the xpub within the gap
+func (w *Worker) GetMempoolTransactionsForXpub(xpub string, gap int, changeGap int) ([]string, error) {
+	start := time.Now()
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	data, _, inCache, err := w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout: AddressFilterVoutOff,
+	}, gap, changeGap)
+	if err != nil {
+		return nil, err
+	}
+	txids := make([]string, 0)
+	uniqueTxs := make(map[string]struct{})
+	for _, da := range data.addresses {
+		for i := range da {
+			o, err := w.mempool.GetAddrDescTransactions(da[i].addrDesc)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range o {
+				if _, found := uniqueTxs[m.Txid]; !found {
+					uniqueTxs[m.Txid] = struct{}{}
+					txids = append(txids, m.Txid)
+				}
+			}
+		}
+	}
+	glog.Info("GetMempoolTransactionsForXpub ", xpub[:xpubLogPrefix], ", cache ", inCache, ", ", len(txids), " txs, ", time.Since(start))
+	return txids, nil
+}
+
 // GetXpubBalanceHistory returns history of balance for given xpub
-func (w *Worker) GetXpubBalanceHistory(xpub string, fromTimestamp, toTimestamp int64, currencies []string, gap int, groupBy uint32) (BalanceHistories, error) {
+func (w *Worker) GetXpubBalanceHistory(xpub string, fromTimestamp, toTimestamp int64, currencies []string, gap int, changeGap int, groupBy uint32) (BalanceHistories, error) {
 	bhs := make(BalanceHistories, 0)
 	start := time.Now()
 	fromUnix, fromHeight, toUnix, toHeight := w.balanceHistoryHeightsFromTo(fromTimestamp, toTimestamp)
@@ -665,7 +826,7 @@ func (w *Worker) GetXpubBalanceHistory(xpub string, fromTimestamp, toTimestamp i
 		OnlyConfirmed: true,
 		FromHeight:    fromHeight,
 		ToHeight:      toHeight,
-	}, gap)
+	}, gap, changeGap)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package bchain
 
 import (
+	"encoding/json"
 	"errors"
 	"math/big"
 )
@@ -34,11 +35,96 @@ func (b *BaseChain) GetBlockRaw(hash string) (string, error) {
 	return "", errors.New("GetBlockRaw: not supported")
 }
 
+// GetBlockHeaderHex is not supported by default
+func (b *BaseChain) GetBlockHeaderHex(hash string) (string, error) {
+	return "", errors.New("GetBlockHeaderHex: not supported")
+}
+
+// GetBlockFilter is not supported by default
+func (b *BaseChain) GetBlockFilter(hash string) (*BlockFilter, error) {
+	return nil, ErrNotSupported
+}
+
+// GetBlockMiner is not supported by default
+func (b *BaseChain) GetBlockMiner(hash string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// MatchBlockMiner does not recognize any miner by default
+func (b *BaseChain) MatchBlockMiner(block *Block) string {
+	return ""
+}
+
+// GetTransactionHex is not supported by default
+func (b *BaseChain) GetTransactionHex(txid string) (string, error) {
+	return "", errors.New("GetTransactionHex: not supported")
+}
+
+// GetBackendNames returns no names by default, the chain is connected to a single back-end
+func (b *BaseChain) GetBackendNames() []string {
+	return nil
+}
+
+// GetTransactionSpecificFromBackend is not supported by default
+func (b *BaseChain) GetTransactionSpecificFromBackend(backend string, tx *Tx) (json.RawMessage, error) {
+	return nil, ErrNotSupported
+}
+
+// GetBlockSpecificFromBackend is not supported by default
+func (b *BaseChain) GetBlockSpecificFromBackend(backend string, hash string) (json.RawMessage, error) {
+	return nil, ErrNotSupported
+}
+
+// GetBlockRawFromBackend is not supported by default
+func (b *BaseChain) GetBlockRawFromBackend(backend string, hash string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// EstimateSmartFeeFromBackend is not supported by default
+func (b *BaseChain) EstimateSmartFeeFromBackend(backend string, blocks int, conservative bool) (big.Int, error) {
+	return big.Int{}, ErrNotSupported
+}
+
+// GetTransactions is not supported by default, the transactions must be fetched one by one using GetTransaction
+func (b *BaseChain) GetTransactions(txids []string) ([]*Tx, error) {
+	return nil, errors.New("GetTransactions: not supported")
+}
+
 // GetMempoolEntry is not supported by default
 func (b *BaseChain) GetMempoolEntry(txid string) (*MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not supported")
 }
 
+// GetMempoolEntries is not supported by default
+func (b *BaseChain) GetMempoolEntries(txids []string) ([]*MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not supported")
+}
+
+// GetMempoolInfo is not supported by default
+func (b *BaseChain) GetMempoolInfo() (*MempoolInfo, error) {
+	return nil, errors.New("GetMempoolInfo: not supported")
+}
+
+// GetBackendChainInfoRaw is not supported by default
+func (b *BaseChain) GetBackendChainInfoRaw() (json.RawMessage, error) {
+	return nil, ErrNotSupported
+}
+
+// GetMempoolFeeHistogram is not supported by default
+func (b *BaseChain) GetMempoolFeeHistogram() ([]MempoolFeeRateBucket, error) {
+	return nil, errors.New("GetMempoolFeeHistogram: not supported")
+}
+
+// GetBlockFeeRatePercentiles is not supported by default
+func (b *BaseChain) GetBlockFeeRatePercentiles(height uint32) ([]float64, error) {
+	return nil, errors.New("GetBlockFeeRatePercentiles: not supported")
+}
+
+// GetMempoolMinFee is not supported by default
+func (b *BaseChain) GetMempoolMinFee() (*big.Int, error) {
+	return nil, errors.New("GetMempoolMinFee: not supported")
+}
+
 // EthereumTypeGetBalance is not supported
 func (b *BaseChain) EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error) {
 	return nil, errors.New("Not supported")
@@ -49,6 +135,11 @@ func (b *BaseChain) EthereumTypeGetNonce(addrDesc AddressDescriptor) (uint64, er
 	return 0, errors.New("Not supported")
 }
 
+// EthereumTypeGetCode is not supported
+func (b *BaseChain) EthereumTypeGetCode(addrDesc AddressDescriptor) ([]byte, error) {
+	return nil, errors.New("Not supported")
+}
+
 // EthereumTypeEstimateGas is not supported
 func (b *BaseChain) EthereumTypeEstimateGas(params map[string]interface{}) (uint64, error) {
 	return 0, errors.New("Not supported")
@@ -14,11 +14,18 @@ type addrIndex struct {
 type txEntry struct {
 	addrIndexes []addrIndex
 	time        uint32
+	nodeTime    uint32
+	// feeRate in satoshis per vbyte is known only if the number of transactions is limited
+	feeRate float64
+	// tx is kept only if the dropped transactions are retained
+	tx *MempoolTx
 }
 
 type txidio struct {
-	txid string
-	io   []addrIndex
+	txid    string
+	io      []addrIndex
+	feeRate float64
+	tx      *MempoolTx
 }
 
 // BaseMempool is mempool base handle
@@ -27,8 +34,10 @@ type BaseMempool struct {
 	mux          sync.Mutex
 	txEntries    map[string]txEntry
 	addrDescToTx map[string][]Outpoint
+	droppedTxs   map[string]*MempoolDroppedTx
 	OnNewTxAddr  OnNewTxAddrFunc
 	OnNewTx      OnNewTxFunc
+	OnTxReplaced OnTxReplacedFunc
 }
 
 // GetTransactions returns slice of mempool transactions for given address
@@ -116,6 +125,13 @@ func (m *BaseMempool) GetTransactionTime(txid string) uint32 {
 	return e.time
 }
 
+// GetDroppedTransaction returns the last known state of a transaction dropped from the mempool or nil if not retained
+func (m *BaseMempool) GetDroppedTransaction(txid string) *MempoolDroppedTx {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.droppedTxs[txid]
+}
+
 func (m *BaseMempool) txToMempoolTx(tx *Tx) *MempoolTx {
 	mtx := MempoolTx{
 		Hex:              tx.Hex,
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
@@ -43,6 +44,7 @@ const zeros = "0000000000000000000000000000000000000000"
 
 // AmountToBigInt converts amount in common.JSONNumber (string) to big.Int
 // it uses string operations to avoid problems with rounding
+// amount with more decimal places than AmountDecimalPoint of the coin is rejected, unless the extra places are zeros
 func (p *BaseParser) AmountToBigInt(n common.JSONNumber) (big.Int, error) {
 	var r big.Int
 	s := string(n)
@@ -58,6 +60,9 @@ func (p *BaseParser) AmountToBigInt(n common.JSONNumber) (big.Int, error) {
 		if z > 0 {
 			s = s[:i] + s[i+1:] + zeros[:z]
 		} else {
+			if strings.TrimRight(s[len(s)+z:], "0") != "" {
+				return r, errors.Errorf("AmountToBigInt: amount %v exceeds %v decimal places", n, d)
+			}
 			s = s[:i] + s[i+1:len(s)+z]
 		}
 	}
@@ -69,6 +74,13 @@ func (p *BaseParser) AmountToBigInt(n common.JSONNumber) (big.Int, error) {
 
 // AmountToDecimalString converts amount in big.Int to string with decimal point in the place defined by the parameter d
 func AmountToDecimalString(a *big.Int, d int) string {
+	return AmountToDecimalStringOptions(a, d, true, 0)
+}
+
+// AmountToDecimalStringOptions converts amount in big.Int to string with decimal point in the place defined by the parameter d
+// if trimZeros is set, the trailing zeros of the decimal places are removed, but at least minDecimals decimal places are kept,
+// otherwise all d decimal places are returned
+func AmountToDecimalStringOptions(a *big.Int, d int, trimZeros bool, minDecimals int) string {
 	if a == nil {
 		return ""
 	}
@@ -81,11 +93,20 @@ func AmountToDecimalString(a *big.Int, d int) string {
 	if d > len(zeros) {
 		d = len(zeros)
 	}
+	if minDecimals > d {
+		minDecimals = d
+	}
 	if len(n) <= d {
 		n = zeros[:d-len(n)+1] + n
 	}
 	i := len(n) - d
-	ad := strings.TrimRight(n[i:], "0")
+	ad := n[i:]
+	if trimZeros {
+		ad = strings.TrimRight(ad, "0")
+		if len(ad) < minDecimals {
+			ad = n[i : i+minDecimals]
+		}
+	}
 	if len(ad) > 0 {
 		n = n[:i] + "." + ad
 	} else {
@@ -99,6 +120,12 @@ func (p *BaseParser) AmountToDecimalString(a *big.Int) string {
 	return AmountToDecimalString(a, p.AmountDecimalPoint)
 }
 
+// AmountToDecimalStringOptions converts amount in big.Int to string with decimal point in the correct place
+// with the trailing zeros formatted according to trimZeros and minDecimals
+func (p *BaseParser) AmountToDecimalStringOptions(a *big.Int, trimZeros bool, minDecimals int) string {
+	return AmountToDecimalStringOptions(a, p.AmountDecimalPoint, trimZeros, minDecimals)
+}
+
 // AmountDecimals returns number of decimal places in amounts
 func (p *BaseParser) AmountDecimals() int {
 	return p.AmountDecimalPoint
@@ -178,6 +205,28 @@ func (p *BaseParser) SupportsVSize() bool {
 	return false
 }
 
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction, default is false
+func (p *BaseParser) IsCoinStake(tx *Tx) bool {
+	return false
+}
+
+// IsStandardTx is unsupported
+func (p *BaseParser) IsStandardTx(tx *Tx, prevOutputs []AddressDescriptor) (bool, string, error) {
+	return false, "", ErrNotSupported
+}
+
+// IsCoinStakeTx detects proof-of-stake coinstake transaction
+// it spends at least one input and its first output is an empty marker (zero value and empty script)
+func IsCoinStakeTx(tx *Tx) bool {
+	if len(tx.Vin) == 0 || len(tx.Vout) < 2 {
+		return false
+	}
+	if tx.Vin[0].Coinbase != "" || tx.Vin[0].Txid == "" {
+		return false
+	}
+	return tx.Vout[0].ValueSat.Sign() == 0 && tx.Vout[0].ScriptPubKey.Hex == ""
+}
+
 // PackTx packs transaction to byte array using protobuf
 func (p *BaseParser) PackTx(tx *Tx, height uint32, blockTime int64) ([]byte, error) {
 	var err error
@@ -293,6 +342,36 @@ func (p *BaseParser) IsAddrDescIndexable(addrDesc AddressDescriptor) bool {
 	return true
 }
 
+// NormalizeAddress removes the surrounding whitespace from the address
+func (p *BaseParser) NormalizeAddress(address string) string {
+	return strings.TrimSpace(address)
+}
+
+// GetAddressType is unsupported
+func (p *BaseParser) GetAddressType(address string) (string, bool, error) {
+	return "", false, errors.New("Not supported")
+}
+
+// GetLockTimeFromAddrDesc is unsupported
+func (p *BaseParser) GetLockTimeFromAddrDesc(addrDesc AddressDescriptor) (uint32, error) {
+	return 0, errors.New("Not supported")
+}
+
+// GetTargetBlockTime is unsupported
+func (p *BaseParser) GetTargetBlockTime() (time.Duration, error) {
+	return 0, errors.New("Not supported")
+}
+
+// GetBlockSubsidy is unsupported
+func (p *BaseParser) GetBlockSubsidy(height uint32) (*big.Int, error) {
+	return nil, errors.New("Not supported")
+}
+
+// GetBlocksToNextHalving is unsupported
+func (p *BaseParser) GetBlocksToNextHalving(height uint32) (uint32, error) {
+	return 0, errors.New("Not supported")
+}
+
 // ParseXpub is unsupported
 func (p *BaseParser) ParseXpub(xpub string) (*XpubDescriptor, error) {
 	return nil, errors.New("Not supported")
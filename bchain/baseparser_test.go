@@ -30,7 +30,7 @@ var amounts = []struct {
 	{big.NewInt(-8), "-0.00000008", 8, "!"},
 	{big.NewInt(-89012345678), "-890.12345678", 8, "!"},
 	{big.NewInt(-12345), "-0.00012345", 8, "!"},
-	{big.NewInt(12345678), "0.123456789012", 8, "0.12345678"},                       // test of truncation of too many decimal places
+	{big.NewInt(12345678), "0.12345678", 8, "!"},
 	{big.NewInt(12345678), "0.0000000000000000000000000000000012345678", 1234, "!"}, // test of too big number decimal places
 }
 
@@ -44,6 +44,39 @@ func TestBaseParser_AmountToDecimalString(t *testing.T) {
 	}
 }
 
+func TestBaseParser_AmountToDecimalStringOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		a           *big.Int
+		adp         int
+		trimZeros   bool
+		minDecimals int
+		want        string
+	}{
+		{name: "trimmed", a: big.NewInt(498700000), adp: 8, trimZeros: true, want: "4.987"},
+		{name: "padded", a: big.NewInt(498700000), adp: 8, want: "4.98700000"},
+		{name: "trimmed to min decimals", a: big.NewInt(498700000), adp: 8, trimZeros: true, minDecimals: 5, want: "4.98700"},
+		{name: "min decimals shorter than value", a: big.NewInt(123456789), adp: 8, trimZeros: true, minDecimals: 2, want: "1.23456789"},
+		{name: "whole number trimmed", a: big.NewInt(300000000), adp: 8, trimZeros: true, want: "3"},
+		{name: "whole number min decimals", a: big.NewInt(300000000), adp: 8, trimZeros: true, minDecimals: 2, want: "3.00"},
+		{name: "zero trimmed", a: big.NewInt(0), adp: 8, trimZeros: true, want: "0"},
+		{name: "zero padded", a: big.NewInt(0), adp: 8, want: "0.00000000"},
+		{name: "zero min decimals", a: big.NewInt(0), adp: 18, trimZeros: true, minDecimals: 2, want: "0.00"},
+		{name: "negative sentinel trimmed", a: big.NewInt(-100000000), adp: 8, trimZeros: true, want: "-1"},
+		{name: "negative sentinel padded", a: big.NewInt(-100000000), adp: 8, want: "-1.00000000"},
+		{name: "negative small padded", a: big.NewInt(-8), adp: 8, want: "-0.00000008"},
+		{name: "min decimals over precision", a: big.NewInt(5), adp: 2, trimZeros: true, minDecimals: 4, want: "0.05"},
+		{name: "no decimals", a: big.NewInt(1234), adp: 0, want: "1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewBaseParser(tt.adp).AmountToDecimalStringOptions(tt.a, tt.trimZeros, tt.minDecimals); got != tt.want {
+				t.Errorf("BaseParser.AmountToDecimalStringOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBaseParser_AmountToBigInt(t *testing.T) {
 	for _, tt := range amounts {
 		t.Run(tt.s, func(t *testing.T) {
@@ -58,3 +91,31 @@ func TestBaseParser_AmountToBigInt(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseParser_AmountToBigInt_DecimalPlaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		adp     int
+		want    string
+		wantErr bool
+	}{
+		{name: "8 decimals valid", s: "21000000.12345678", adp: 8, want: "2100000012345678"},
+		{name: "8 decimals trailing zeros", s: "0.1234567800", adp: 8, want: "12345678"},
+		{name: "8 decimals over-precise", s: "0.123456789", adp: 8, wantErr: true},
+		{name: "8 decimals over-precise negative", s: "-1.000000001", adp: 8, wantErr: true},
+		{name: "18 decimals valid", s: "1.123456789012345678", adp: 18, want: "1123456789012345678"},
+		{name: "18 decimals over-precise", s: "0.0000000000000000001", adp: 18, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBaseParser(tt.adp).AmountToBigInt(common.JSONNumber(tt.s))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseParser.AmountToBigInt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("BaseParser.AmountToBigInt() = %v, want %v", got.String(), tt.want)
+			}
+		})
+	}
+}
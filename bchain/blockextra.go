@@ -0,0 +1,26 @@
+package bchain
+
+import "github.com/juju/errors"
+
+// ParseBlockExtraFunc parses coin specific data from the raw block and attaches them to the already parsed block,
+// typically to Block.CoinSpecificData
+type ParseBlockExtraFunc func(raw []byte, block *Block) error
+
+// blockExtraParsers is a map of coin names to the registered block parsing hooks
+var blockExtraParsers = make(map[string][]ParseBlockExtraFunc)
+
+// RegisterParseBlockExtra registers a hook called after a raw block of the coin is parsed
+// the hooks must be registered before the sync starts, usually in init function of the coin package
+func RegisterParseBlockExtra(coin string, fn ParseBlockExtraFunc) {
+	blockExtraParsers[coin] = append(blockExtraParsers[coin], fn)
+}
+
+// ParseBlockExtra calls the hooks registered for the coin in the order of registration
+func ParseBlockExtra(coin string, raw []byte, block *Block) error {
+	for _, fn := range blockExtraParsers[coin] {
+		if err := fn(raw, block); err != nil {
+			return errors.Annotatef(err, "ParseBlockExtra %v", coin)
+		}
+	}
+	return nil
+}
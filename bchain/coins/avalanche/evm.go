@@ -43,6 +43,11 @@ func (c *AvalancheClient) NonceAt(ctx context.Context, addrDesc bchain.AddressDe
 	return c.Client.NonceAt(ctx, common.BytesToAddress(addrDesc), blockNumber)
 }
 
+// CodeAt returns the contract code of the given account at a specific block, or latest known block if no block number is provided
+func (c *AvalancheClient) CodeAt(ctx context.Context, addrDesc bchain.AddressDescriptor, blockNumber *big.Int) ([]byte, error) {
+	return c.Client.CodeAt(ctx, common.BytesToAddress(addrDesc), blockNumber)
+}
+
 // AvalancheRPCClient wraps an rpc client to implement the EVMRPCClient interface
 type AvalancheRPCClient struct {
 	*rpc.Client
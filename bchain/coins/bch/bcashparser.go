@@ -2,6 +2,7 @@ package bch
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/martinboehm/bchutil"
 	"github.com/martinboehm/btcutil"
@@ -109,6 +110,38 @@ func (p *BCashParser) GetAddrDescFromAddress(address string) (bchain.AddressDesc
 	return p.addressToOutputScript(address)
 }
 
+// NormalizeAddress removes the surrounding whitespace, converts uppercase CashAddr address to lowercase
+// and adds the network prefix to CashAddr address entered without it
+func (p *BCashParser) NormalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	if address == strings.ToUpper(address) {
+		if lower := strings.ToLower(address); isCashAddr(lower) || isCashAddrPayload(lower) {
+			address = lower
+		}
+	}
+	if isCashAddrPayload(address) {
+		address = p.cashAddrPrefix() + address
+	}
+	return address
+}
+
+// isCashAddrPayload returns true if the address looks like P2PKH or P2SH CashAddr address without the prefix
+func isCashAddrPayload(addr string) bool {
+	return len(addr) == 42 && (addr[0] == 'q' || addr[0] == 'p') && !strings.Contains(addr, ":")
+}
+
+// cashAddrPrefix returns CashAddr prefix of the network of the parser
+func (p *BCashParser) cashAddrPrefix() string {
+	switch p.Params.Net {
+	case bchutil.TestnetMagic:
+		return TestNetPrefix
+	case bchutil.Regtestmagic:
+		return RegTestPrefix
+	default:
+		return MainNetPrefix
+	}
+}
+
 // addressToOutputScript converts bitcoin address to ScriptPubKey
 func (p *BCashParser) addressToOutputScript(address string) ([]byte, error) {
 	if isCashAddr(address) {
@@ -88,6 +88,37 @@ func Test_GetAddrDescFromAddress(t *testing.T) {
 	}
 }
 
+func Test_NormalizeAddress(t *testing.T) {
+	mainParserCashAddr, mainParserLegacy, testParserCashAddr, _ := setupParsers(t)
+	tests := []struct {
+		name    string
+		parser  *BCashParser
+		address string
+		hex     string
+	}{
+		{"main legacy", mainParserCashAddr, "129HiRqekqPVucKy2M8zsqvafGgKypciPp", "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac"},
+		{"main cashaddr", mainParserCashAddr, "bitcoincash:qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5", "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac"},
+		{"main cashaddr without prefix", mainParserCashAddr, "qqxgjelx8qk85t9xfk8g2zlunxmhxms6p55xarv2r5", "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac"},
+		{"main uppercase cashaddr", mainParserCashAddr, "BITCOINCASH:QQXGJELX8QK85T9XFK8G2ZLUNXMHXMS6P55XARV2R5", "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac"},
+		{"main uppercase cashaddr without prefix", mainParserLegacy, "QQXGJELX8QK85T9XFK8G2ZLUNXMHXMS6P55XARV2R5", "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac"},
+		{"main P2SH cashaddr with whitespace", mainParserLegacy, " pzy0wuj9pjps5v8dmlwq32fatu4wrgcwzuayq5nfhh ", "a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787"},
+		{"main P2SH legacy", mainParserLegacy, "3EBEFWPtDYWCNszQ7etoqtWmmygccayLiH", "a91488f772450c830a30eddfdc08a93d5f2ae1a30e1787"},
+		{"test cashaddr without prefix", testParserCashAddr, "qp86jfla8084048rckpv85ht90falr050s03ejaesm", "76a9144fa927fd3bcf57d4e3c582c3d2eb2bd3df8df47c88ac"},
+		{"test legacy", testParserCashAddr, "mnnAKPTSrWjgoi3uEYaQkHA1QEC5btFeBr", "76a9144fa927fd3bcf57d4e3c582c3d2eb2bd3df8df47c88ac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.GetAddrDescFromAddress(tt.parser.NormalizeAddress(tt.address))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(got); h != tt.hex {
+				t.Errorf("GetAddrDescFromAddress(NormalizeAddress()) = %v, want %v", h, tt.hex)
+			}
+		})
+	}
+}
+
 func Test_GetAddressesFromAddrDesc(t *testing.T) {
 	mainParserCashAddr, mainParserLegacy, testParserCashAddr, testParserLegacy := setupParsers(t)
 	tests := []struct {
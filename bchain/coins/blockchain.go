@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/golang/glog"
 	"github.com/juju/errors"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/bchain/coins/avalanche"
@@ -65,6 +66,7 @@ var BlockChainFactories = make(map[string]blockChainFactory)
 func init() {
 	BlockChainFactories["Bitcoin"] = btc.NewBitcoinRPC
 	BlockChainFactories["Testnet"] = btc.NewBitcoinRPC
+	BlockChainFactories["Testnet4"] = btc.NewBitcoinRPC
 	BlockChainFactories["Signet"] = btc.NewBitcoinRPC
 	BlockChainFactories["Regtest"] = btc.NewBitcoinRPC
 	BlockChainFactories["Zcash"] = zec.NewZCashRPC
@@ -184,12 +186,30 @@ func NewBlockChain(coin string, configfile string, pushHandler func(bchain.Notif
 	if err != nil {
 		return nil, nil, err
 	}
-	return &blockChainWithMetrics{b: bc, m: metrics}, &mempoolWithMetrics{mempool: mempool, m: metrics}, nil
+	var sc struct {
+		RPCSlowCallThreshold int `json:"rpc_slow_call_threshold"`
+	}
+	if err = json.Unmarshal(config, &sc); err != nil {
+		return nil, nil, errors.Annotatef(err, "Error parsing file %v", configfile)
+	}
+	slowCallThreshold := time.Duration(sc.RPCSlowCallThreshold) * time.Millisecond
+	return &blockChainWithMetrics{b: bc, m: metrics, slowCallThreshold: slowCallThreshold},
+		&mempoolWithMetrics{mempool: mempool, m: metrics, slowCallThreshold: slowCallThreshold}, nil
+}
+
+// observeSlowRPCCall logs and counts the call which took at least the threshold, zero threshold disables the check
+func observeSlowRPCCall(m *common.Metrics, threshold time.Duration, method string, duration time.Duration) {
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+	glog.Warning("rpc: slow call ", method, " took ", duration)
+	m.RPCSlowCalls.With(common.Labels{"method": method}).Inc()
 }
 
 type blockChainWithMetrics struct {
-	b bchain.BlockChain
-	m *common.Metrics
+	b                 bchain.BlockChain
+	m                 *common.Metrics
+	slowCallThreshold time.Duration
 }
 
 func (c *blockChainWithMetrics) observeRPCLatency(method string, start time.Time, err error) {
@@ -197,7 +217,9 @@ func (c *blockChainWithMetrics) observeRPCLatency(method string, start time.Time
 	if err != nil {
 		e = "failure"
 	}
-	c.m.RPCLatency.With(common.Labels{"method": method, "error": e}).Observe(float64(time.Since(start)) / 1e6) // in milliseconds
+	d := time.Since(start)
+	c.m.RPCLatency.With(common.Labels{"method": method, "error": e}).Observe(float64(d) / 1e6) // in milliseconds
+	observeSlowRPCCall(c.m, c.slowCallThreshold, method, d)
 }
 
 func (c *blockChainWithMetrics) Initialize() error {
@@ -208,8 +230,8 @@ func (c *blockChainWithMetrics) CreateMempool(chain bchain.BlockChain) (bchain.M
 	return c.b.CreateMempool(chain)
 }
 
-func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) error {
-	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onNewTx)
+func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, spendingTxidForOutpoint bchain.SpendingTxidForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
+	return c.b.InitializeMempool(addrDescForOutpoint, spendingTxidForOutpoint, onNewTxAddr, onNewTx, onTxReplaced)
 }
 
 func (c *blockChainWithMetrics) Shutdown(ctx context.Context) error {
@@ -237,6 +259,11 @@ func (c *blockChainWithMetrics) GetChainInfo() (v *bchain.ChainInfo, err error)
 	return c.b.GetChainInfo()
 }
 
+func (c *blockChainWithMetrics) GetBackendChainInfoRaw() (v json.RawMessage, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBackendChainInfoRaw", s, err) }(time.Now())
+	return c.b.GetBackendChainInfoRaw()
+}
+
 func (c *blockChainWithMetrics) GetBestBlockHash() (v string, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetBestBlockHash", s, err) }(time.Now())
 	return c.b.GetBestBlockHash()
@@ -257,6 +284,25 @@ func (c *blockChainWithMetrics) GetBlockHeader(hash string) (v *bchain.BlockHead
 	return c.b.GetBlockHeader(hash)
 }
 
+func (c *blockChainWithMetrics) GetBlockHeaderHex(hash string) (v string, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockHeaderHex", s, err) }(time.Now())
+	return c.b.GetBlockHeaderHex(hash)
+}
+
+func (c *blockChainWithMetrics) GetBlockFilter(hash string) (v *bchain.BlockFilter, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockFilter", s, err) }(time.Now())
+	return c.b.GetBlockFilter(hash)
+}
+
+func (c *blockChainWithMetrics) GetBlockMiner(hash string) (v string, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockMiner", s, err) }(time.Now())
+	return c.b.GetBlockMiner(hash)
+}
+
+func (c *blockChainWithMetrics) MatchBlockMiner(block *bchain.Block) string {
+	return c.b.MatchBlockMiner(block)
+}
+
 func (c *blockChainWithMetrics) GetBlock(hash string, height uint32) (v *bchain.Block, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetBlock", s, err) }(time.Now())
 	return c.b.GetBlock(hash, height)
@@ -282,16 +328,50 @@ func (c *blockChainWithMetrics) GetTransaction(txid string) (v *bchain.Tx, err e
 	return c.b.GetTransaction(txid)
 }
 
+func (c *blockChainWithMetrics) GetTransactions(txids []string) (v []*bchain.Tx, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetTransactions", s, err) }(time.Now())
+	return c.b.GetTransactions(txids)
+}
+
 func (c *blockChainWithMetrics) GetTransactionSpecific(tx *bchain.Tx) (v json.RawMessage, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetTransactionSpecific", s, err) }(time.Now())
 	return c.b.GetTransactionSpecific(tx)
 }
 
+func (c *blockChainWithMetrics) GetBlockSpecificFromBackend(backend string, hash string) (v json.RawMessage, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockSpecificFromBackend", s, err) }(time.Now())
+	return c.b.GetBlockSpecificFromBackend(backend, hash)
+}
+
+func (c *blockChainWithMetrics) GetBlockRawFromBackend(backend string, hash string) (v string, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockRawFromBackend", s, err) }(time.Now())
+	return c.b.GetBlockRawFromBackend(backend, hash)
+}
+
+func (c *blockChainWithMetrics) EstimateSmartFeeFromBackend(backend string, blocks int, conservative bool) (v big.Int, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EstimateSmartFeeFromBackend", s, err) }(time.Now())
+	return c.b.EstimateSmartFeeFromBackend(backend, blocks, conservative)
+}
+
+func (c *blockChainWithMetrics) GetBackendNames() []string {
+	return c.b.GetBackendNames()
+}
+
+func (c *blockChainWithMetrics) GetTransactionSpecificFromBackend(backend string, tx *bchain.Tx) (v json.RawMessage, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetTransactionSpecificFromBackend", s, err) }(time.Now())
+	return c.b.GetTransactionSpecificFromBackend(backend, tx)
+}
+
 func (c *blockChainWithMetrics) GetTransactionForMempool(txid string) (v *bchain.Tx, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetTransactionForMempool", s, err) }(time.Now())
 	return c.b.GetTransactionForMempool(txid)
 }
 
+func (c *blockChainWithMetrics) GetTransactionHex(txid string) (v string, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetTransactionHex", s, err) }(time.Now())
+	return c.b.GetTransactionHex(txid)
+}
+
 func (c *blockChainWithMetrics) EstimateSmartFee(blocks int, conservative bool) (v big.Int, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("EstimateSmartFee", s, err) }(time.Now())
 	return c.b.EstimateSmartFee(blocks, conservative)
@@ -312,6 +392,31 @@ func (c *blockChainWithMetrics) GetMempoolEntry(txid string) (v *bchain.MempoolE
 	return c.b.GetMempoolEntry(txid)
 }
 
+func (c *blockChainWithMetrics) GetMempoolEntries(txids []string) (v []*bchain.MempoolEntry, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMempoolEntries", s, err) }(time.Now())
+	return c.b.GetMempoolEntries(txids)
+}
+
+func (c *blockChainWithMetrics) GetMempoolInfo() (v *bchain.MempoolInfo, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMempoolInfo", s, err) }(time.Now())
+	return c.b.GetMempoolInfo()
+}
+
+func (c *blockChainWithMetrics) GetMempoolFeeHistogram() (v []bchain.MempoolFeeRateBucket, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMempoolFeeHistogram", s, err) }(time.Now())
+	return c.b.GetMempoolFeeHistogram()
+}
+
+func (c *blockChainWithMetrics) GetBlockFeeRatePercentiles(height uint32) (v []float64, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockFeeRatePercentiles", s, err) }(time.Now())
+	return c.b.GetBlockFeeRatePercentiles(height)
+}
+
+func (c *blockChainWithMetrics) GetMempoolMinFee() (v *big.Int, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetMempoolMinFee", s, err) }(time.Now())
+	return c.b.GetMempoolMinFee()
+}
+
 func (c *blockChainWithMetrics) GetChainParser() bchain.BlockChainParser {
 	return c.b.GetChainParser()
 }
@@ -326,6 +431,11 @@ func (c *blockChainWithMetrics) EthereumTypeGetNonce(addrDesc bchain.AddressDesc
 	return c.b.EthereumTypeGetNonce(addrDesc)
 }
 
+func (c *blockChainWithMetrics) EthereumTypeGetCode(addrDesc bchain.AddressDescriptor) (v []byte, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetCode", s, err) }(time.Now())
+	return c.b.EthereumTypeGetCode(addrDesc)
+}
+
 func (c *blockChainWithMetrics) EthereumTypeEstimateGas(params map[string]interface{}) (v uint64, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeEstimateGas", s, err) }(time.Now())
 	return c.b.EthereumTypeEstimateGas(params)
@@ -348,8 +458,9 @@ func (c *blockChainWithMetrics) GetTokenURI(contractDesc bchain.AddressDescripto
 }
 
 type mempoolWithMetrics struct {
-	mempool bchain.Mempool
-	m       *common.Metrics
+	mempool           bchain.Mempool
+	m                 *common.Metrics
+	slowCallThreshold time.Duration
 }
 
 func (c *mempoolWithMetrics) observeRPCLatency(method string, start time.Time, err error) {
@@ -357,7 +468,9 @@ func (c *mempoolWithMetrics) observeRPCLatency(method string, start time.Time, e
 	if err != nil {
 		e = "failure"
 	}
-	c.m.RPCLatency.With(common.Labels{"method": method, "error": e}).Observe(float64(time.Since(start)) / 1e6) // in milliseconds
+	d := time.Since(start)
+	c.m.RPCLatency.With(common.Labels{"method": method, "error": e}).Observe(float64(d) / 1e6) // in milliseconds
+	observeSlowRPCCall(c.m, c.slowCallThreshold, method, d)
 }
 
 func (c *mempoolWithMetrics) Resync() (count int, err error) {
@@ -387,3 +500,7 @@ func (c *mempoolWithMetrics) GetAllEntries() (v bchain.MempoolTxidEntries) {
 func (c *mempoolWithMetrics) GetTransactionTime(txid string) uint32 {
 	return c.mempool.GetTransactionTime(txid)
 }
+
+func (c *mempoolWithMetrics) GetDroppedTransaction(txid string) *bchain.MempoolDroppedTx {
+	return c.mempool.GetDroppedTransaction(txid)
+}
@@ -0,0 +1,78 @@
+//go:build unittest
+
+package coins
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// slowBlockChain delays the calls of GetBestBlockHash
+type slowBlockChain struct {
+	bchain.BlockChain
+	delay time.Duration
+}
+
+func (c *slowBlockChain) GetBestBlockHash() (string, error) {
+	time.Sleep(c.delay)
+	return "0000000000000000000000000000000000000000000000000000000000000000", nil
+}
+
+func TestBlockChainWithMetrics_SlowCalls(t *testing.T) {
+	metrics, err := common.GetMetrics("Fakecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slowCalls := func() float64 {
+		var m dto.Metric
+		if err := metrics.RPCSlowCalls.With(common.Labels{"method": "GetBestBlockHash"}).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		threshold time.Duration
+		want      float64
+	}{
+		{
+			name:      "below threshold",
+			delay:     0,
+			threshold: time.Second,
+			want:      0,
+		},
+		{
+			name:      "above threshold",
+			delay:     50 * time.Millisecond,
+			threshold: 10 * time.Millisecond,
+			want:      1,
+		},
+		{
+			name:      "disabled",
+			delay:     50 * time.Millisecond,
+			threshold: 0,
+			want:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &blockChainWithMetrics{
+				b:                 &slowBlockChain{delay: tt.delay},
+				m:                 metrics,
+				slowCallThreshold: tt.threshold,
+			}
+			before := slowCalls()
+			if _, err := c.GetBestBlockHash(); err != nil {
+				t.Fatal(err)
+			}
+			if got := slowCalls() - before; got != tt.want {
+				t.Errorf("slow calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"math"
 	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	vlq "github.com/bsm/go-vlq"
@@ -36,6 +38,9 @@ type BitcoinLikeParser struct {
 	XPubMagicSegwitNative        uint32
 	Slip44                       uint32
 	VSizeSupport                 bool
+	InitialBlockSubsidy          *big.Int
+	SubsidyHalvingInterval       uint32
+	TargetBlockTime              time.Duration
 	minimumCoinbaseConfirmations int
 }
 
@@ -55,9 +60,52 @@ func NewBitcoinLikeParser(params *chaincfg.Params, c *Configuration) *BitcoinLik
 		minimumCoinbaseConfirmations: c.MinimumCoinbaseConfirmations,
 	}
 	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
+	// target block time is taken from the chain parameters unless configured
+	if c.TargetBlockTime > 0 {
+		p.TargetBlockTime = time.Duration(c.TargetBlockTime) * time.Second
+	} else {
+		p.TargetBlockTime = params.TargetTimePerBlock
+	}
+	if c.InitialBlockSubsidy > 0 {
+		p.InitialBlockSubsidy = big.NewInt(c.InitialBlockSubsidy)
+		// halving interval is taken from the chain parameters unless configured
+		p.SubsidyHalvingInterval = c.SubsidyHalvingInterval
+		if p.SubsidyHalvingInterval == 0 && params.SubsidyReductionInterval > 0 {
+			p.SubsidyHalvingInterval = uint32(params.SubsidyReductionInterval)
+		}
+	}
 	return p
 }
 
+// GetTargetBlockTime returns the target time between blocks of the coin
+func (p *BitcoinLikeParser) GetTargetBlockTime() (time.Duration, error) {
+	if p.TargetBlockTime <= 0 {
+		return 0, errors.New("Target block time not configured")
+	}
+	return p.TargetBlockTime, nil
+}
+
+// GetBlockSubsidy returns the subsidy of the block at given height, the initial subsidy is halved every SubsidyHalvingInterval blocks
+// coins with a different emission schedule must override this method
+func (p *BitcoinLikeParser) GetBlockSubsidy(height uint32) (*big.Int, error) {
+	if p.InitialBlockSubsidy == nil {
+		return nil, errors.New("Block subsidy not configured")
+	}
+	subsidy := new(big.Int).Set(p.InitialBlockSubsidy)
+	if p.SubsidyHalvingInterval > 0 {
+		subsidy.Rsh(subsidy, uint(height/p.SubsidyHalvingInterval))
+	}
+	return subsidy, nil
+}
+
+// GetBlocksToNextHalving returns the number of blocks from the block at given height to the next subsidy halving
+func (p *BitcoinLikeParser) GetBlocksToNextHalving(height uint32) (uint32, error) {
+	if p.InitialBlockSubsidy == nil || p.SubsidyHalvingInterval == 0 {
+		return 0, errors.New("Subsidy halving not configured")
+	}
+	return p.SubsidyHalvingInterval - height%p.SubsidyHalvingInterval, nil
+}
+
 // GetAddrDescFromVout returns internal address representation (descriptor) of given transaction output
 func (p *BitcoinLikeParser) GetAddrDescFromVout(output *bchain.Vout) (bchain.AddressDescriptor, error) {
 	ad, err := hex.DecodeString(output.ScriptPubKey.Hex)
@@ -69,11 +117,82 @@ func (p *BitcoinLikeParser) GetAddrDescFromVout(output *bchain.Vout) (bchain.Add
 	return txscript.ConvertP2PKtoP2PKH(p.Params.Base58CksumHasher, ad)
 }
 
+// GetAddrDescForUnknownInput returns the address descriptor of an input, which was not found in txAddresses
+// the address can be derived from the scriptSig of the P2SH wrapped segwit inputs (P2SH-P2WPKH and P2SH-P2WSH),
+// their scriptSig is only the push of the witness program, which is the redeem script of the spent P2SH output
+func (p *BitcoinLikeParser) GetAddrDescForUnknownInput(tx *bchain.Tx, input int) bchain.AddressDescriptor {
+	if len(tx.Vin) > input {
+		if addrDesc := p2shWrappedWitnessAddrDesc(tx.Vin[input].ScriptSig.Hex); addrDesc != nil {
+			return addrDesc
+		}
+	}
+	return p.BaseParser.GetAddrDescForUnknownInput(tx, input)
+}
+
+// p2shWrappedWitnessAddrDesc returns the P2SH output script if the scriptSig is a single push of a version 0 witness program
+func p2shWrappedWitnessAddrDesc(scriptSigHex string) bchain.AddressDescriptor {
+	scriptSig, err := hex.DecodeString(scriptSigHex)
+	if err != nil || len(scriptSig) < 2 || int(scriptSig[0]) != len(scriptSig)-1 {
+		return nil
+	}
+	redeemScript := scriptSig[1:]
+	if redeemScript[0] != txscript.OP_0 ||
+		!(len(redeemScript) == 22 && redeemScript[1] == txscript.OP_DATA_20 || len(redeemScript) == 34 && redeemScript[1] == txscript.OP_DATA_32) {
+		return nil
+	}
+	addrDesc := make(bchain.AddressDescriptor, 0, 23)
+	addrDesc = append(addrDesc, txscript.OP_HASH160, txscript.OP_DATA_20)
+	addrDesc = append(addrDesc, btcutil.Hash160(redeemScript)...)
+	return append(addrDesc, txscript.OP_EQUAL)
+}
+
 // GetAddrDescFromAddress returns internal address representation (descriptor) of given address
 func (p *BitcoinLikeParser) GetAddrDescFromAddress(address string) (bchain.AddressDescriptor, error) {
 	return p.addressToOutputScript(address)
 }
 
+// NormalizeAddress removes the surrounding whitespace and converts uppercase bech32 address to lowercase
+// mixed case bech32 addresses are invalid and are left unchanged
+func (p *BitcoinLikeParser) NormalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	hrp := p.Params.Bech32HRPSegwit
+	if hrp != "" && address == strings.ToUpper(address) && strings.HasPrefix(address, strings.ToUpper(hrp)+"1") {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// addressTypes are the names of the types of the output scripts of addresses, the other types are named by the script class
+var addressTypes = map[txscript.ScriptClass]string{
+	txscript.PubKeyHashTy:          "p2pkh",
+	txscript.ScriptHashTy:          "p2sh",
+	txscript.WitnessV0PubKeyHashTy: "p2wpkh",
+	txscript.WitnessV0ScriptHashTy: "p2wsh",
+	txscript.WitnessV1TaprootTy:    "p2tr",
+}
+
+// GetAddressType returns the type of the output script of the address (p2pkh, p2sh, p2wpkh, p2wsh, p2tr or the class of the script)
+// and false if the address is valid but encoded for a different network, e.g. testnet bech32 address on mainnet
+func (p *BitcoinLikeParser) GetAddressType(address string) (string, bool, error) {
+	da, err := btcutil.DecodeAddress(address, p.Params)
+	if err != nil {
+		return "", false, err
+	}
+	script, err := txscript.PayToAddrScript(da)
+	if err != nil {
+		return "", false, err
+	}
+	sc, _, _, err := txscript.ExtractPkScriptAddrs(script, p.Params)
+	if err != nil {
+		return "", false, err
+	}
+	t, found := addressTypes[sc]
+	if !found {
+		t = sc.String()
+	}
+	return t, da.IsForNet(p.Params), nil
+}
+
 // GetAddressesFromAddrDesc returns addresses for given address descriptor with flag if the addresses are searchable
 func (p *BitcoinLikeParser) GetAddressesFromAddrDesc(addrDesc bchain.AddressDescriptor) ([]string, bool, error) {
 	return p.OutputScriptToAddressesFunc(addrDesc)
@@ -185,6 +304,10 @@ func (p *BitcoinLikeParser) tryParseOmni(data []byte) string {
 func (p *BitcoinLikeParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
 	sc, addresses, _, err := txscript.ExtractPkScriptAddrs(script, p.Params)
 	if err != nil {
+		// scripts with new opcodes are labeled even if they cannot be parsed by the script engine
+		if es := tryParseEmergingScript(script); es != "" {
+			return []string{es}, false, nil
+		}
 		return nil, false, err
 	}
 	rv := make([]string, len(addresses))
@@ -196,6 +319,9 @@ func (p *BitcoinLikeParser) outputScriptToAddresses(script []byte) ([]string, bo
 		s = true
 	} else if len(rv) == 0 {
 		or := p.TryParseOPReturn(script)
+		if or == "" {
+			or = tryParseEmergingScript(script)
+		}
 		if or != "" {
 			rv = []string{or}
 		}
@@ -203,6 +329,152 @@ func (p *BitcoinLikeParser) outputScriptToAddresses(script []byte) ([]string, bo
 	return rv, s, nil
 }
 
+// opcodes of soft fork proposals, which are not known to the script engine
+const (
+	opCat                  = 0x7e
+	opCheckTemplateVerify  = 0xb3
+	opInternalKey          = 0xcb
+	opCheckSigFromStack    = 0xcc
+	anyPrevOutKeyPrefix    = 0x01
+	maxWitnessProgramBytes = 40
+)
+
+var emergingOpcodeNames = map[byte]string{
+	opCat:                 "OP_CAT",
+	opCheckTemplateVerify: "OP_CHECKTEMPLATEVERIFY",
+	opInternalKey:         "OP_INTERNALKEY",
+	opCheckSigFromStack:   "OP_CHECKSIGFROMSTACK",
+}
+
+// tryParseEmergingScript tries to classify scripts using new opcodes or future witness versions
+// and returns their string representation, these scripts are otherwise reported as nonstandard without any label
+func tryParseEmergingScript(script []byte) string {
+	l := len(script)
+	// <32 byte template hash> OP_CHECKTEMPLATEVERIFY
+	if l == 34 && script[0] == txscript.OP_DATA_32 && script[33] == opCheckTemplateVerify {
+		return "OP_CHECKTEMPLATEVERIFY " + hex.EncodeToString(script[1:33])
+	}
+	// <33 byte ANYPREVOUT key> OP_CHECKSIG
+	if l == 35 && script[0] == txscript.OP_DATA_33 && script[1] == anyPrevOutKeyPrefix && script[34] == txscript.OP_CHECKSIG {
+		return "ANYPREVOUT " + hex.EncodeToString(script[2:34])
+	}
+	// OP_1 to OP_16 <2 to 40 bytes of witness program>, known witness programs are parsed as addresses
+	if l >= 4 && l <= maxWitnessProgramBytes+2 && script[0] >= txscript.OP_1 && script[0] <= txscript.OP_16 && int(script[1]) == l-2 {
+		return "WITNESS_V" + strconv.Itoa(int(script[0]-txscript.OP_1+1)) + " " + hex.EncodeToString(script[2:])
+	}
+	// any other script containing the new opcodes, the data pushes are skipped
+	var names []string
+	found := make(map[byte]struct{})
+	for i := 0; i < l; {
+		op := script[i]
+		i++
+		switch {
+		case op >= txscript.OP_DATA_1 && op <= txscript.OP_DATA_75:
+			i += int(op)
+		case op == txscript.OP_PUSHDATA1 && i < l:
+			i += 1 + int(script[i])
+		case op == txscript.OP_PUSHDATA2 && i+1 < l:
+			i += 2 + int(binary.LittleEndian.Uint16(script[i:]))
+		case op == txscript.OP_PUSHDATA4 && i+3 < l:
+			if n := binary.LittleEndian.Uint32(script[i:]); n < uint32(l) {
+				i += 4 + int(n)
+			} else {
+				i = l
+			}
+		default:
+			if name, ok := emergingOpcodeNames[op]; ok {
+				if _, ok = found[op]; !ok {
+					found[op] = struct{}{}
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	if len(names) > 0 {
+		return "NONSTANDARD (" + strings.Join(names, ", ") + ")"
+	}
+	return ""
+}
+
+// maximal length of the lock time operand of OP_CHECKLOCKTIMEVERIFY
+const maxLockTimeBytes = 5
+
+// scriptNumToLockTime decodes the minimally encoded script number used as the operand of OP_CHECKLOCKTIMEVERIFY,
+// returns false if the number is negative or does not fit to the lock time of the transaction
+func scriptNumToLockTime(b []byte) (uint32, bool) {
+	if len(b) > maxLockTimeBytes {
+		return 0, false
+	}
+	var v int64
+	for i := range b {
+		v |= int64(b[i]) << uint(8*i)
+	}
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		// sign bit is set, the number is negative
+		return 0, false
+	}
+	if v > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(v), true
+}
+
+// GetLockTimeFromAddrDesc returns the absolute lock time (block height or unix timestamp, see bchain.LockTimeThreshold)
+// required by OP_CHECKLOCKTIMEVERIFY in the output script, 0 if the script does not contain the opcode
+// the lock time is reported only if it applies to all spending paths, i.e. the script does not have conditional branches
+// the scripts hidden behind a hash (P2SH, P2WSH, P2TR) are not known until the output is spent
+func (p *BitcoinLikeParser) GetLockTimeFromAddrDesc(addrDesc bchain.AddressDescriptor) (uint32, error) {
+	script := addrDesc
+	l := len(script)
+	var lockTime uint32
+	// operand of the last opcode, nil if the last opcode did not push a number
+	var operand []byte
+	for i := 0; i < l; {
+		op := script[i]
+		i++
+		n := -1
+		switch {
+		case op == txscript.OP_0:
+			operand = []byte{}
+			continue
+		case op >= txscript.OP_DATA_1 && op <= txscript.OP_DATA_75:
+			n = int(op)
+		case op == txscript.OP_PUSHDATA1 && i < l:
+			n = int(script[i])
+			i++
+		case op == txscript.OP_PUSHDATA2 && i+1 < l:
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == txscript.OP_PUSHDATA4 && i+3 < l:
+			n = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		case op == txscript.OP_PUSHDATA1 || op == txscript.OP_PUSHDATA2 || op == txscript.OP_PUSHDATA4:
+			return 0, errors.New("Invalid script")
+		case op >= txscript.OP_1 && op <= txscript.OP_16:
+			operand = []byte{op - txscript.OP_1 + 1}
+			continue
+		case op == txscript.OP_IF || op == txscript.OP_NOTIF:
+			return 0, nil
+		case op == txscript.OP_CHECKLOCKTIMEVERIFY:
+			if operand != nil {
+				if lt, ok := scriptNumToLockTime(operand); ok && lt > lockTime {
+					lockTime = lt
+				}
+			}
+		}
+		if n >= 0 {
+			if n > l-i {
+				return 0, errors.New("Invalid script")
+			}
+			operand = script[i : i+n]
+			i += n
+		} else {
+			operand = nil
+		}
+	}
+	return lockTime, nil
+}
+
 // TxFromMsgTx converts bitcoin wire Tx to bchain.Tx
 func (p *BitcoinLikeParser) TxFromMsgTx(t *wire.MsgTx, parseAddresses bool) bchain.Tx {
 	var vSize int64
@@ -290,14 +562,19 @@ func (p *BitcoinLikeParser) ParseBlock(b []byte) (*bchain.Block, error) {
 	}
 
 	txs := make([]bchain.Tx, len(w.Transactions))
+	// the stripped size of the block is the header, the number of transactions and the transactions without witness data
+	strippedSize := wire.MaxBlockHeaderPayload + wire.VarIntSerializeSize(uint64(len(w.Transactions)))
 	for ti, t := range w.Transactions {
 		txs[ti] = p.TxFromMsgTx(t, false)
+		strippedSize += t.SerializeSizeStripped()
 	}
 
 	return &bchain.Block{
 		BlockHeader: bchain.BlockHeader{
-			Size: len(b),
-			Time: w.Header.Timestamp.Unix(),
+			Size:         len(b),
+			StrippedSize: strippedSize,
+			Weight:       strippedSize*(blockchain.WitnessScaleFactor-1) + len(b),
+			Time:         w.Header.Timestamp.Unix(),
 		},
 		Txs: txs,
 	}, nil
@@ -4,27 +4,57 @@ import (
 	"encoding/json"
 	"math/big"
 
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/common"
 )
 
+// magic numbers
+const (
+	TestNet4Magic wire.BitcoinNet = 0x283f161c
+)
+
+// chain parameters
+var (
+	TestNet4Params chaincfg.Params
+)
+
+func init() {
+	// testnet4 uses the same address formats as testnet3
+	TestNet4Params = chaincfg.TestNet3Params
+	TestNet4Params.Name = "testnet4"
+	TestNet4Params.Net = TestNet4Magic
+	TestNet4Params.DefaultPort = "48333"
+	TestNet4Params.DNSSeeds = nil
+	TestNet4Params.GenesisBlock = nil
+	genesisHash, err := chainhash.NewHashFromStr("00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043")
+	if err != nil {
+		panic(err)
+	}
+	TestNet4Params.GenesisHash = genesisHash
+	TestNet4Params.Checkpoints = nil
+}
+
 // BitcoinParser handle
 type BitcoinParser struct {
 	*BitcoinLikeParser
+	standardness standardnessPolicy
 }
 
 // NewBitcoinParser returns new BitcoinParser instance
 func NewBitcoinParser(params *chaincfg.Params, c *Configuration) *BitcoinParser {
 	p := &BitcoinParser{
 		BitcoinLikeParser: NewBitcoinLikeParser(params, c),
+		standardness:      newStandardnessPolicy(c),
 	}
 	p.VSizeSupport = true
 	return p
 }
 
 // GetChainParams contains network parameters for the main Bitcoin network,
-// the regression test Bitcoin network, the test Bitcoin network and
+// the regression test Bitcoin network, the test Bitcoin networks and
 // the simulation test Bitcoin network, in this order
 func GetChainParams(chain string) *chaincfg.Params {
 	if !chaincfg.IsRegistered(&chaincfg.MainNetParams) {
@@ -33,6 +63,14 @@ func GetChainParams(chain string) *chaincfg.Params {
 	switch chain {
 	case "test":
 		return &chaincfg.TestNet3Params
+	case "testnet4":
+		if !chaincfg.IsRegistered(&TestNet4Params) {
+			err := chaincfg.Register(&TestNet4Params)
+			if err != nil {
+				panic(err)
+			}
+		}
+		return &TestNet4Params
 	case "regtest":
 		return &chaincfg.RegressionNetParams
 	case "signet":
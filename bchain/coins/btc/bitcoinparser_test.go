@@ -3,12 +3,18 @@
 package btc
 
 import (
+	"bytes"
 	"encoding/hex"
+	"io/ioutil"
 	"math/big"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/trezor/blockbook/bchain"
 )
@@ -147,6 +153,272 @@ func TestGetAddrDescFromAddressTestnet(t *testing.T) {
 	}
 }
 
+func TestGetAddressType(t *testing.T) {
+	tests := []struct {
+		name           string
+		network        string
+		address        string
+		want           string
+		wantForNetwork bool
+		wantErr        bool
+	}{
+		{
+			name:           "P2PKH",
+			network:        "main",
+			address:        "1JKgN43B9SyLuZH19H5ECvr4KcfrbVHzZ6",
+			want:           "p2pkh",
+			wantForNetwork: true,
+		},
+		{
+			name:           "P2SH",
+			network:        "main",
+			address:        "321x69Cb9HZLWwAWGiUBT1U81r1zPLnEjL",
+			want:           "p2sh",
+			wantForNetwork: true,
+		},
+		{
+			name:           "P2WPKH",
+			network:        "main",
+			address:        "bc1qrsf2l34jvqnq0lduyz0j5pfu2nkd93nnq0qggn",
+			want:           "p2wpkh",
+			wantForNetwork: true,
+		},
+		{
+			name:           "P2WSH",
+			network:        "main",
+			address:        "bc1qqwtn5s8vjnqdzrm0du885c46ypzt05vakmljhasx28shlv5a355sw5exgr",
+			want:           "p2wsh",
+			wantForNetwork: true,
+		},
+		{
+			name:           "P2TR",
+			network:        "main",
+			address:        "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+			want:           "p2tr",
+			wantForNetwork: true,
+		},
+		{
+			name:           "testnet P2WPKH on mainnet",
+			network:        "main",
+			address:        "tb1qupjdck20as3y4l95cd5wepkv0grcz0p7d8rd5s",
+			want:           "p2wpkh",
+			wantForNetwork: false,
+		},
+		{
+			name:           "testnet P2TR",
+			network:        "test",
+			address:        "tb1pqsv2qyp8hsma46422ecfd3ek02jayumkkzjx7vkf3cqpmfd4ucpsx0cc9h",
+			want:           "p2tr",
+			wantForNetwork: true,
+		},
+		{
+			name:    "P2PKH invalid checksum",
+			network: "main",
+			address: "1JKgN43B9SyLuZH19H5ECvr4KcfrbVHzZ7",
+			wantErr: true,
+		},
+		{
+			name:    "P2WPKH invalid checksum",
+			network: "main",
+			address: "bc1qrsf2l34jvqnq0lduyz0j5pfu2nkd93nnq0qggm",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewBitcoinParser(GetChainParams(tt.network), &Configuration{})
+			got, forNetwork, err := parser.GetAddressType(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAddressType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want || forNetwork != tt.wantForNetwork {
+				t.Errorf("GetAddressType() = %v, %v, want %v, %v", got, forNetwork, tt.want, tt.wantForNetwork)
+			}
+		})
+	}
+}
+
+func TestGetLockTimeFromAddrDesc(t *testing.T) {
+	const pubKey = "210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac"
+	tests := []struct {
+		name    string
+		script  string
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name:   "height 600000",
+			script: "03c02709b175" + pubKey,
+			want:   600000,
+		},
+		{
+			name:   "height 128 with sign byte",
+			script: "028000b175" + pubKey,
+			want:   128,
+		},
+		{
+			name:   "small integer height",
+			script: "60b175" + pubKey,
+			want:   16,
+		},
+		{
+			name:   "timestamp 1700000000",
+			script: "0400f15365b175" + pubKey,
+			want:   1700000000,
+		},
+		{
+			name:   "negative lock time",
+			script: "0181b175" + pubKey,
+			want:   0,
+		},
+		{
+			name:   "conditional branch",
+			script: "63" + pubKey + "6703c02709b17568" + pubKey,
+			want:   0,
+		},
+		{
+			name:   "P2PKH",
+			script: "76a914be027bf3eac907bd4ac8cb9c5293b6f37662722088ac",
+			want:   0,
+		},
+		{
+			name:    "truncated push",
+			script:  "05c02709b1",
+			wantErr: true,
+		},
+	}
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.script)
+			got, err := parser.GetLockTimeFromAddrDesc(script)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetLockTimeFromAddrDesc() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetLockTimeFromAddrDesc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestnet4Parser(t *testing.T) {
+	params := GetChainParams("testnet4")
+	if params.Name != "testnet4" || params.Net != TestNet4Magic {
+		t.Fatalf("GetChainParams(testnet4) returned %v, net %x", params.Name, uint32(params.Net))
+	}
+	if got := params.GenesisHash.String(); got != "00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043" {
+		t.Errorf("GenesisHash = %v", got)
+	}
+	parser := NewBitcoinParser(params, &Configuration{})
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{
+			name:    "P2WPKH",
+			address: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+			want:    "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			name:    "pubkeyhash",
+			address: "mtkbaiLiUH3fvGJeSzuN3kUgmJzqinLejJ",
+			want:    "76a914912e2b234f941f30b18afbb4fa46171214bf66c888ac",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.GetAddrDescFromAddress(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(got); h != tt.want {
+				t.Errorf("GetAddrDescFromAddress() = %v, want %v", h, tt.want)
+			}
+			addresses, _, err := parser.GetAddressesFromAddrDesc(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(addresses, []string{tt.address}) {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", addresses, tt.address)
+			}
+		})
+	}
+}
+
+func TestGetBlockSubsidy(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{InitialBlockSubsidy: 5000000000})
+	tests := []struct {
+		name          string
+		height        uint32
+		want          string
+		wantToHalving uint32
+	}{
+		{name: "genesis", height: 0, want: "5000000000", wantToHalving: 210000},
+		{name: "before first halving", height: 209999, want: "5000000000", wantToHalving: 1},
+		{name: "first halving", height: 210000, want: "2500000000", wantToHalving: 210000},
+		{name: "before fourth halving", height: 839999, want: "625000000", wantToHalving: 1},
+		{name: "fourth halving", height: 840000, want: "312500000", wantToHalving: 210000},
+		{name: "last subsidy", height: 6929999, want: "1", wantToHalving: 1},
+		{name: "no subsidy", height: 6930000, want: "0", wantToHalving: 210000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.GetBlockSubsidy(tt.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("GetBlockSubsidy() = %v, want %v", got, tt.want)
+			}
+			toHalving, err := parser.GetBlocksToNextHalving(tt.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if toHalving != tt.wantToHalving {
+				t.Errorf("GetBlocksToNextHalving() = %v, want %v", toHalving, tt.wantToHalving)
+			}
+		})
+	}
+	// without configured subsidy the emission is unknown
+	parser = NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	if _, err := parser.GetBlockSubsidy(0); err == nil {
+		t.Error("GetBlockSubsidy() expected error without configured subsidy")
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{name: "lowercase bech32", address: "bc1qrsf2l34jvqnq0lduyz0j5pfu2nkd93nnq0qggn", want: "00141c12afc6b2602607fdbc209f2a053c54ecd2c673"},
+		{name: "uppercase bech32", address: "BC1QRSF2L34JVQNQ0LDUYZ0J5PFU2NKD93NNQ0QGGN", want: "00141c12afc6b2602607fdbc209f2a053c54ecd2c673"},
+		{name: "bech32 with whitespace", address: " bc1qrsf2l34jvqnq0lduyz0j5pfu2nkd93nnq0qggn\n", want: "00141c12afc6b2602607fdbc209f2a053c54ecd2c673"},
+		{name: "uppercase bech32m", address: "BC1PW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KT5ND6Y", want: "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{name: "P2PKH", address: "1JKgN43B9SyLuZH19H5ECvr4KcfrbVHzZ6", want: "76a914be027bf3eac907bd4ac8cb9c5293b6f37662722088ac"},
+		{name: "mixed case bech32", address: "bc1QRSF2L34JVQNQ0LDUYZ0J5PFU2NKD93NNQ0QGGN", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.GetAddrDescFromAddress(parser.NormalizeAddress(tt.address))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAddrDescFromAddress(NormalizeAddress()) error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if h := hex.EncodeToString(got); h != tt.want {
+				t.Errorf("GetAddrDescFromAddress(NormalizeAddress()) = %v, want %v", h, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetAddrDescFromVout(t *testing.T) {
 	type args struct {
 		vout bchain.Vout
@@ -355,6 +627,48 @@ We know the game and we're gonna play it (TO FRONT)
 			want2:   false,
 			wantErr: false,
 		},
+		{
+			name:    "OP_CHECKTEMPLATEVERIFY bare",
+			args:    args{script: "209d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b3"},
+			want:    []string{"OP_CHECKTEMPLATEVERIFY 9d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9"},
+			want2:   false,
+			wantErr: false,
+		},
+		{
+			name:    "ANYPREVOUT key",
+			args:    args{script: "21019d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9ac"},
+			want:    []string{"ANYPREVOUT 9d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9"},
+			want2:   false,
+			wantErr: false,
+		},
+		{
+			name:    "witness v2 program",
+			args:    args{script: "52209d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9"},
+			want:    []string{"WITNESS_V2 9d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9"},
+			want2:   false,
+			wantErr: false,
+		},
+		{
+			name:    "OP_CAT OP_CHECKSIGFROMSTACK",
+			args:    args{script: "7e7e209d3a1ad3c4f6d8b3e2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9cc"},
+			want:    []string{"NONSTANDARD (OP_CAT, OP_CHECKSIGFROMSTACK)"},
+			want2:   false,
+			wantErr: false,
+		},
+		{
+			name:    "OP_CHECKTEMPLATEVERIFY with truncated push",
+			args:    args{script: "b34c10cb"},
+			want:    []string{"NONSTANDARD (OP_CHECKTEMPLATEVERIFY)"},
+			want2:   false,
+			wantErr: false,
+		},
+		{
+			name:    "nonstandard without new opcodes",
+			args:    args{script: "5152ac"},
+			want:    []string{},
+			want2:   false,
+			wantErr: false,
+		},
 	}
 
 	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
@@ -720,6 +1034,321 @@ func TestUnpackTx(t *testing.T) {
 	}
 }
 
+func TestGetAddrDescForUnknownInput_P2SHWrappedSegwit(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	tests := []struct {
+		name      string
+		scriptSig string
+		output    string
+		want      string
+	}{
+		{
+			// redeem script of the BIP49 test vector
+			name:      "P2SH-P2WPKH",
+			scriptSig: "16001438971f73930f6c141d977ac4fd4a727c854935b3",
+			output:    "a914336caa13e08b96080a32b5d818d59b4ab3b3674287",
+			want:      "36NvZTcMsMowbt78wPzJaHHWaNiyR73Y4g",
+		},
+		{
+			name:      "P2SH-P2WSH",
+			scriptSig: "2200204ae81572f06e1b88fd5ced7a1a000945432e83e1551e6f721ee9c00b8cc33260",
+			output:    "a91472c44f957fc011d97e3406667dca5b1c930c402687",
+			want:      "3C9r8LAC7PAURpXmC31h15yHbrCBccB12N",
+		},
+		{
+			name:      "P2PKH spend",
+			scriptSig: "473044022047ac8e878352d3ebbde1c94ce3a10d057c24175747116f8288e5d794d12d482f0220217f36a485cae903c713331d877c1f64677e3622ad4010726870540656fe9dcb012102e5f2ef92e6a0dcb7e8e9ae0ad7d8d1df1e34a4a2b1c5d0e9b4b2c9a5f4c7e0d1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the funding output pays to the P2SH address
+			if tt.output != "" {
+				addrDesc, err := parser.GetAddrDescFromVout(&bchain.Vout{ScriptPubKey: bchain.ScriptPubKey{Hex: tt.output}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				addresses, searchable, err := parser.GetAddressesFromAddrDesc(addrDesc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(addresses, []string{tt.want}) || !searchable {
+					t.Errorf("GetAddressesFromAddrDesc() output = %v, %v, want [%v]", addresses, searchable, tt.want)
+				}
+			}
+			// the spending input keeps the scriptSig through parse, pack and unpack
+			scriptSig, err := hex.DecodeString(tt.scriptSig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prevHash, err := chainhash.NewHashFromStr("1d4cb2bd8c8b1c7f4bdc8a4b45a39f5c95b8b3c61aab94e4a3e3f8d3cd6b5a4c")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkScript, _ := hex.DecodeString("76a914be027bf3eac907bd4ac8cb9c5293b6f37662722088ac")
+			msgTx := wire.NewMsgTx(2)
+			msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, 1), scriptSig, nil))
+			msgTx.AddTxOut(wire.NewTxOut(1000, pkScript))
+			var buf bytes.Buffer
+			if err := msgTx.Serialize(&buf); err != nil {
+				t.Fatal(err)
+			}
+			tx, err := parser.ParseTx(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			packed, err := parser.PackTx(tx, 700000, 1632000000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unpacked, _, err := parser.UnpackTx(packed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrDesc := parser.GetAddrDescForUnknownInput(unpacked, 0)
+			if tt.want == "" {
+				if addrDesc != nil {
+					t.Errorf("GetAddrDescForUnknownInput() = %v, want nil", addrDesc)
+				}
+				return
+			}
+			if hex.EncodeToString(addrDesc) != tt.output {
+				t.Errorf("GetAddrDescForUnknownInput() = %v, want %v", hex.EncodeToString(addrDesc), tt.output)
+			}
+			addresses, _, err := parser.GetAddressesFromAddrDesc(addrDesc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(addresses, []string{tt.want}) {
+				t.Errorf("GetAddressesFromAddrDesc() input = %v, want [%v]", addresses, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlock_Segwit(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("regtest"), &Configuration{})
+	// block with the coinbase and one transaction spending a P2WPKH output
+	d, err := ioutil.ReadFile(filepath.Join("testdata", "block_dump.101"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hex.DecodeString(string(bytes.TrimSpace(d)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bchain.BlockHeader{
+		Size:         358,
+		StrippedSize: 249,
+		Weight:       1105,
+		Time:         1700000000,
+	}
+	if !reflect.DeepEqual(block.BlockHeader, want) {
+		t.Errorf("ParseBlock() header = %+v, want %+v", block.BlockHeader, want)
+	}
+	wantTxids := []string{
+		"609838af30cae926132a330452c023e5c7847c4b4104a1d9e46fed4ef49cf90b",
+		"5fd18b62b63d1c67a3721657d67163d1a7e5c04192135d0372b40e212847d519",
+	}
+	if len(block.Txs) != len(wantTxids) {
+		t.Fatalf("ParseBlock() number of transactions: got %d, want %d", len(block.Txs), len(wantTxids))
+	}
+	for i, txid := range wantTxids {
+		if block.Txs[i].Txid != txid {
+			t.Errorf("ParseBlock() transaction %d: got %s, want %s", i, block.Txs[i].Txid, txid)
+		}
+	}
+}
+
+func TestIsStandardTx(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	// the data carrier policy of Bitcoin Core before v30
+	preV30Parser := NewBitcoinParser(GetChainParams("main"), &Configuration{DataCarrierSize: 83, SingleDataCarrier: true})
+	customParser := NewBitcoinParser(GetChainParams("main"), &Configuration{DataCarrierSize: 100, DustRelayFee: 6000})
+	const (
+		p2pkh     = "76a914be027bf3eac907bd4ac8cb9c5293b6f37662722088ac"
+		p2wpkh    = "0014381be30ca46ddf378ef69ebc4a601bd6ff30b754"
+		scriptSig = "473044022047ac8e878352d3ebbde1c94ce3a10d057c24175747116f8288e5d794d12d482f0220217f36a485cae903c713331d877c1f64677e3622ad4010726870540656fe9dcb012102e5f2ef92e6a0dcb7e8e9ae0ad7d8d1df1e34a4a2b1c5d0e9b4b2c9a5f4c7e0d1"
+	)
+	pubKey := "21" + "02" + strings.Repeat("11", 32)
+	multisig1of3 := "51" + strings.Repeat(pubKey, 3) + "53ae"
+	multisig1of4 := "51" + strings.Repeat(pubKey, 4) + "54ae"
+	output := func(value int64, script string) bchain.Vout {
+		return bchain.Vout{ValueSat: *big.NewInt(value), ScriptPubKey: bchain.ScriptPubKey{Hex: script}}
+	}
+	tx := func(version int32, outputs ...bchain.Vout) *bchain.Tx {
+		return &bchain.Tx{
+			Version: version,
+			Vin: []bchain.Vin{{
+				Txid:      "1d4cb2bd8c8b1c7f4bdc8a4b45a39f5c95b8b3c61aab94e4a3e3f8d3cd6b5a4c",
+				ScriptSig: bchain.ScriptSig{Hex: scriptSig},
+			}},
+			Vout: outputs,
+		}
+	}
+	manyMultisigs := make([]bchain.Vout, 201)
+	for i := range manyMultisigs {
+		manyMultisigs[i] = output(1000, multisig1of3)
+	}
+	// the P2SH output spent by the redeem script pushed by the scriptSig
+	p2sh, _ := hex.DecodeString("a914" + strings.Repeat("22", 20) + "87")
+	p2shTx := func(redeemScript string, outputs ...bchain.Vout) *bchain.Tx {
+		return &bchain.Tx{
+			Version: 2,
+			Vin: []bchain.Vin{{
+				Txid:      "1d4cb2bd8c8b1c7f4bdc8a4b45a39f5c95b8b3c61aab94e4a3e3f8d3cd6b5a4c",
+				ScriptSig: bchain.ScriptSig{Hex: "004c" + hex.EncodeToString([]byte{byte(len(redeemScript) / 2)}) + redeemScript},
+			}},
+			Vout: outputs,
+		}
+	}
+	tests := []struct {
+		name        string
+		parser      *BitcoinParser
+		tx          *bchain.Tx
+		prevOutputs []bchain.AddressDescriptor
+		want        bool
+		wantReason  string
+	}{
+		{
+			name: "standard transaction",
+			tx:   tx(2, output(10000, p2pkh), output(5000, p2wpkh), output(0, "6a0b68656c6c6f20776f726c64")),
+			want: true,
+		},
+		{
+			name: "outputs at the dust threshold",
+			tx:   tx(1, output(546, p2pkh), output(294, p2wpkh)),
+			want: true,
+		},
+		{
+			name:   "OP_RETURN of the maximal size before v30",
+			parser: preV30Parser,
+			tx:     tx(2, output(10000, p2pkh), output(0, "6a4c50"+strings.Repeat("00", 80))),
+			want:   true,
+		},
+		{
+			name: "large OP_RETURN",
+			tx:   tx(2, output(10000, p2pkh), output(0, "6a4dd007"+strings.Repeat("00", 2000))),
+			want: true,
+		},
+		{
+			name: "two OP_RETURN outputs",
+			tx:   tx(2, output(0, "6a0100"), output(0, "6a0101")),
+			want: true,
+		},
+		{
+			name: "bare multisig with 3 keys",
+			tx:   tx(2, output(1000, multisig1of3)),
+			want: true,
+		},
+		{
+			name:       "oversized OP_RETURN before v30",
+			parser:     preV30Parser,
+			tx:         tx(2, output(10000, p2pkh), output(0, "6a4c51"+strings.Repeat("00", 81))),
+			wantReason: "datacarrier",
+		},
+		{
+			name:       "two OP_RETURN outputs before v30",
+			parser:     preV30Parser,
+			tx:         tx(2, output(0, "6a0100"), output(0, "6a0101")),
+			wantReason: "multi-op-return",
+		},
+		{
+			name:       "OP_RETURN outputs over the configured total size",
+			parser:     customParser,
+			tx:         tx(2, output(0, "6a32"+strings.Repeat("00", 50)), output(0, "6a32"+strings.Repeat("00", 50))),
+			wantReason: "datacarrier",
+		},
+		{
+			name:       "dust by the configured dust relay fee",
+			parser:     customParser,
+			tx:         tx(2, output(1091, p2pkh)),
+			wantReason: "dust",
+		},
+		{
+			name:       "P2PKH dust",
+			tx:         tx(2, output(10000, p2wpkh), output(545, p2pkh)),
+			wantReason: "dust",
+		},
+		{
+			name:       "P2WPKH dust",
+			tx:         tx(2, output(293, p2wpkh)),
+			wantReason: "dust",
+		},
+		{
+			name:       "bare multisig with 4 keys",
+			tx:         tx(2, output(1000, multisig1of4)),
+			wantReason: "scriptpubkey",
+		},
+		{
+			name:       "nonstandard output script",
+			tx:         tx(2, output(10000, "52935387")),
+			wantReason: "scriptpubkey",
+		},
+		{
+			name:       "too many signature operations",
+			tx:         tx(2, manyMultisigs...),
+			wantReason: "bad-txns-too-many-sigops",
+		},
+		{
+			name: "spent P2SH output not known",
+			tx:   p2shTx(multisig1of3, manyMultisigs[:200]...),
+			want: true,
+		},
+		{
+			name:        "too many signature operations with P2SH redeem script",
+			tx:          p2shTx(multisig1of3, manyMultisigs[:200]...),
+			prevOutputs: []bchain.AddressDescriptor{p2sh},
+			wantReason:  "bad-txns-too-many-sigops",
+		},
+		{
+			name:        "P2SH redeem script with 3 signature operations",
+			tx:          p2shTx(multisig1of3, output(10000, p2pkh)),
+			prevOutputs: []bchain.AddressDescriptor{p2sh},
+			want:        true,
+		},
+		{
+			name:        "P2SH redeem script with too many signature operations",
+			tx:          p2shTx(strings.Repeat("ac", 16), output(10000, p2pkh)),
+			prevOutputs: []bchain.AddressDescriptor{p2sh},
+			wantReason:  "bad-txns-nonstandard-inputs",
+		},
+		{
+			name:       "unknown version",
+			tx:         tx(4, output(10000, p2pkh)),
+			wantReason: "version",
+		},
+		{
+			name: "scriptSig not push only",
+			tx: &bchain.Tx{
+				Version: 2,
+				Vin:     []bchain.Vin{{Txid: "1d4cb2bd8c8b1c7f4bdc8a4b45a39f5c95b8b3c61aab94e4a3e3f8d3cd6b5a4c", ScriptSig: bchain.ScriptSig{Hex: "0176a9"}}},
+				Vout:    []bchain.Vout{output(10000, p2pkh)},
+			},
+			wantReason: "scriptsig-not-pushonly",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.parser
+			if p == nil {
+				p = parser
+			}
+			got, reason, err := p.IsStandardTx(tt.tx, tt.prevOutputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("IsStandardTx() = %v, %q, want %v, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
 func TestParseXpubDescriptors(t *testing.T) {
 	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
 	btcTestnetParser := NewBitcoinParser(GetChainParams("test"), &Configuration{XPubMagic: 70617039, XPubMagicSegwitP2sh: 71979618, XPubMagicSegwitNative: 73342198})
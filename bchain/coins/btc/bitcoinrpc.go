@@ -10,7 +10,11 @@ import (
 	"math/big"
 	"net"
 	"net/http"
+	"net/url"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -24,7 +28,7 @@ import (
 type BitcoinRPC struct {
 	*bchain.BaseChain
 	client       http.Client
-	rpcURL       string
+	rpcPool      *rpcPool
 	user         string
 	password     string
 	Mempool      *bchain.MempoolBitcoinType
@@ -33,33 +37,70 @@ type BitcoinRPC struct {
 	mq           *bchain.MQ
 	ChainConfig  *Configuration
 	RPCMarshaler RPCMarshaler
+	// pool tags are prepared on the first use, the parser is set by Initialize of the coin
+	poolMatcher     *poolMatcher
+	poolMatcherOnce sync.Once
+	// cached fee histogram of the mempool
+	feeHistogram     []bchain.MempoolFeeRateBucket
+	feeHistogramTime time.Time
+	feeHistogramMux  sync.Mutex
+	// guards the fee estimation methods in ChainConfig, they are changed at runtime if the backend does not support a method
+	estimateFeeMux sync.RWMutex
 }
 
 // Configuration represents json config file
 type Configuration struct {
-	CoinName                     string `json:"coin_name"`
-	CoinShortcut                 string `json:"coin_shortcut"`
-	RPCURL                       string `json:"rpc_url"`
-	RPCUser                      string `json:"rpc_user"`
-	RPCPass                      string `json:"rpc_pass"`
-	RPCTimeout                   int    `json:"rpc_timeout"`
-	AddressAliases               bool   `json:"address_aliases,omitempty"`
-	Parse                        bool   `json:"parse"`
-	MessageQueueBinding          string `json:"message_queue_binding"`
-	Subversion                   string `json:"subversion"`
-	BlockAddressesToKeep         int    `json:"block_addresses_to_keep"`
-	MempoolWorkers               int    `json:"mempool_workers"`
-	MempoolSubWorkers            int    `json:"mempool_sub_workers"`
-	AddressFormat                string `json:"address_format"`
-	SupportsEstimateFee          bool   `json:"supports_estimate_fee"`
-	SupportsEstimateSmartFee     bool   `json:"supports_estimate_smart_fee"`
-	XPubMagic                    uint32 `json:"xpub_magic,omitempty"`
-	XPubMagicSegwitP2sh          uint32 `json:"xpub_magic_segwit_p2sh,omitempty"`
-	XPubMagicSegwitNative        uint32 `json:"xpub_magic_segwit_native,omitempty"`
-	Slip44                       uint32 `json:"slip44,omitempty"`
-	AlternativeEstimateFee       string `json:"alternative_estimate_fee,omitempty"`
-	AlternativeEstimateFeeParams string `json:"alternative_estimate_fee_params,omitempty"`
-	MinimumCoinbaseConfirmations int    `json:"minimumCoinbaseConfirmations,omitempty"`
+	CoinName                     string   `json:"coin_name"`
+	CoinShortcut                 string   `json:"coin_shortcut"`
+	RPCURL                       string   `json:"rpc_url"`
+	RPCUser                      string   `json:"rpc_user"`
+	RPCPass                      string   `json:"rpc_pass"`
+	RPCTimeout                   int      `json:"rpc_timeout"`
+	AddressAliases               bool     `json:"address_aliases,omitempty"`
+	Parse                        bool     `json:"parse"`
+	MaxBlockSize                 int      `json:"max_block_size,omitempty"`
+	MessageQueueBinding          string   `json:"message_queue_binding"`
+	MessageQueueTopics           []string `json:"message_queue_topics,omitempty"`
+	Subversion                   string   `json:"subversion"`
+	BlockAddressesToKeep         int      `json:"block_addresses_to_keep"`
+	MempoolWorkers               int      `json:"mempool_workers"`
+	MempoolSubWorkers            int      `json:"mempool_sub_workers"`
+	MempoolMaxAgeHours           int      `json:"mempool_max_age_hours,omitempty"`
+	MempoolAddressWhitelist      []string `json:"mempool_address_whitelist,omitempty"`
+	MempoolAddressBlacklist      []string `json:"mempool_address_blacklist,omitempty"`
+	MempoolDroppedTxTTLSeconds   int      `json:"mempool_dropped_tx_ttl_seconds,omitempty"`
+	MempoolMaxTransactions       int      `json:"mempool_max_transactions,omitempty"`
+	AddressFormat                string   `json:"address_format"`
+	SupportsEstimateFee          bool     `json:"supports_estimate_fee"`
+	SupportsEstimateSmartFee     bool     `json:"supports_estimate_smart_fee"`
+	XPubMagic                    uint32   `json:"xpub_magic,omitempty"`
+	XPubMagicSegwitP2sh          uint32   `json:"xpub_magic_segwit_p2sh,omitempty"`
+	XPubMagicSegwitNative        uint32   `json:"xpub_magic_segwit_native,omitempty"`
+	Slip44                       uint32   `json:"slip44,omitempty"`
+	AlternativeEstimateFee       string   `json:"alternative_estimate_fee,omitempty"`
+	AlternativeEstimateFeeParams string   `json:"alternative_estimate_fee_params,omitempty"`
+	MinimumCoinbaseConfirmations int      `json:"minimumCoinbaseConfirmations,omitempty"`
+	InitialBlockSubsidy          int64    `json:"initial_block_subsidy,omitempty"`
+	SubsidyHalvingInterval       uint32   `json:"subsidy_halving_interval,omitempty"`
+	TargetBlockTime              int      `json:"target_block_time,omitempty"`
+
+	// table of mining pools used by GetBlockMiner, the pools from the file are appended to PoolTags
+	PoolTags     []PoolTag `json:"pool_tags,omitempty"`
+	PoolTagsFile string    `json:"pool_tags_file,omitempty"`
+
+	// additional back-end endpoints, the calls are distributed among the healthy endpoints
+	RPCURLs                []string `json:"rpc_urls,omitempty"`
+	RPCPoolSize            int      `json:"rpc_pool_size,omitempty"`
+	RPCHealthCheckInterval int      `json:"rpc_health_check_interval,omitempty"`
+
+	// reconnection to the back-end after the connection is lost, negative RPCReconnectRetries disables it
+	RPCReconnectRetries int `json:"rpc_reconnect_retries,omitempty"`
+	RPCReconnectBackoff int `json:"rpc_reconnect_backoff,omitempty"`
+
+	// standardness policy of the back-end used to flag the nonstandard unconfirmed transactions, see standardnessPolicy
+	DataCarrierSize   int   `json:"datacarrier_size,omitempty"`
+	SingleDataCarrier bool  `json:"single_datacarrier,omitempty"`
+	DustRelayFee      int64 `json:"dust_relay_fee,omitempty"`
 }
 
 // NewBitcoinRPC returns new BitcoinRPC instance.
@@ -85,6 +126,12 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 	if c.MempoolSubWorkers < 1 {
 		c.MempoolSubWorkers = 1
 	}
+	if c.RPCReconnectRetries == 0 {
+		c.RPCReconnectRetries = defaultRPCReconnectRetries
+	}
+	if c.RPCReconnectBackoff <= 0 {
+		c.RPCReconnectBackoff = defaultRPCReconnectBackoff
+	}
 	// btc supports both calls, other coins overriding BitcoinRPC can change this
 	c.SupportsEstimateFee = true
 	c.SupportsEstimateSmartFee = true
@@ -94,11 +141,16 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 100, // necessary to not to deplete ports
 	}
+	// limit the number of connections to each back-end endpoint
+	if c.RPCPoolSize > 0 {
+		transport.MaxConnsPerHost = c.RPCPoolSize
+		transport.MaxIdleConnsPerHost = c.RPCPoolSize
+	}
 
 	s := &BitcoinRPC{
 		BaseChain:    &bchain.BaseChain{},
 		client:       http.Client{Timeout: time.Duration(c.RPCTimeout) * time.Second, Transport: transport},
-		rpcURL:       c.RPCURL,
+		rpcPool:      newRPCPool(append([]string{c.RPCURL}, c.RPCURLs...)),
 		user:         c.RPCUser,
 		password:     c.RPCPass,
 		ParseBlocks:  c.Parse,
@@ -107,18 +159,36 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		RPCMarshaler: JSONMarshalerV2{},
 	}
 
+	if len(c.RPCURLs) > 0 {
+		interval := c.RPCHealthCheckInterval
+		if interval <= 0 {
+			interval = defaultRPCHealthCheckInterval
+		}
+		go s.rpcHealthCheckLoop(time.Duration(interval) * time.Second)
+	}
+
 	return s, nil
 }
 
+// versions of Bitcoin Core changing the supported rpc methods
+const (
+	// estimatesmartfee was added in 0.15
+	versionEstimateSmartFee = 150000
+	// estimatefee was removed in 0.17
+	versionNoEstimateFee = 170000
+)
+
+// rpcErrMethodNotFound is the JSON-RPC error code of a method not supported by the backend
+const rpcErrMethodNotFound = -32601
+
 // Initialize initializes BitcoinRPC instance.
 func (b *BitcoinRPC) Initialize() error {
-	b.ChainConfig.SupportsEstimateFee = false
-
 	ci, err := b.GetChainInfo()
 	if err != nil {
 		return err
 	}
 	chainName := ci.Chain
+	b.setFeaturesByVersion(ci.Version)
 
 	params := GetChainParams(chainName)
 
@@ -147,24 +217,67 @@ func (b *BitcoinRPC) Initialize() error {
 	return nil
 }
 
+// setFeaturesByVersion selects the rpc methods supported by the backend of the given version
+// if the version cannot be detected, the methods of the current Bitcoin Core are used
+func (b *BitcoinRPC) setFeaturesByVersion(version string) {
+	v, err := strconv.Atoi(version)
+	if err != nil || v <= 0 {
+		glog.Warning("rpc: cannot detect backend version from '", version, "', using methods of the current backend")
+		_, estimateSmartFee := b.estimateFeeMethods()
+		b.setEstimateFeeMethods(false, estimateSmartFee)
+		return
+	}
+	b.setEstimateFeeMethods(v < versionNoEstimateFee, v >= versionEstimateSmartFee)
+	glog.Info("rpc: backend version ", v, ", estimatesmartfee ", v >= versionEstimateSmartFee, ", estimatefee ", v < versionNoEstimateFee)
+}
+
+// estimateFeeMethods returns if the backend supports the estimatefee and estimatesmartfee methods
+func (b *BitcoinRPC) estimateFeeMethods() (estimateFee bool, estimateSmartFee bool) {
+	b.estimateFeeMux.RLock()
+	defer b.estimateFeeMux.RUnlock()
+	return b.ChainConfig.SupportsEstimateFee, b.ChainConfig.SupportsEstimateSmartFee
+}
+
+// setEstimateFeeMethods sets the fee estimation methods supported by the backend
+func (b *BitcoinRPC) setEstimateFeeMethods(estimateFee bool, estimateSmartFee bool) {
+	b.estimateFeeMux.Lock()
+	defer b.estimateFeeMux.Unlock()
+	b.ChainConfig.SupportsEstimateFee = estimateFee
+	b.ChainConfig.SupportsEstimateSmartFee = estimateSmartFee
+}
+
 // CreateMempool creates mempool if not already created, however does not initialize it
 func (b *BitcoinRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
 	if b.Mempool == nil {
+		filter, err := bchain.NewAddrDescFilter(chain.GetChainParser(), b.ChainConfig.MempoolAddressWhitelist, b.ChainConfig.MempoolAddressBlacklist)
+		if err != nil {
+			return nil, errors.Annotate(err, "mempool address filter")
+		}
 		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers)
+		b.Mempool.MaxAge = time.Duration(b.ChainConfig.MempoolMaxAgeHours) * time.Hour
+		b.Mempool.AddrDescFilter = filter
+		b.Mempool.DroppedTxTTL = time.Duration(b.ChainConfig.MempoolDroppedTxTTLSeconds) * time.Second
+		b.Mempool.MaxTransactions = b.ChainConfig.MempoolMaxTransactions
 	}
 	return b.Mempool, nil
 }
 
 // InitializeMempool creates ZeroMQ subscription and sets AddrDescForOutpointFunc to the Mempool
-func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) error {
+func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, spendingTxidForOutpoint bchain.SpendingTxidForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
 	if b.Mempool == nil {
 		return errors.New("Mempool not created")
 	}
 	b.Mempool.AddrDescForOutpoint = addrDescForOutpoint
+	b.Mempool.SpendingTxidForOutpoint = spendingTxidForOutpoint
 	b.Mempool.OnNewTxAddr = onNewTxAddr
 	b.Mempool.OnNewTx = onNewTx
+	b.Mempool.OnTxReplaced = onTxReplaced
+	if b.ChainConfig.MessageQueueBinding == "" {
+		glog.Info("mq: message_queue_binding not configured, using polling of the backend")
+		return nil
+	}
 	if b.mq == nil {
-		mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler)
+		mq, err := bchain.NewMQWithTopics(b.ChainConfig.MessageQueueBinding, b.ChainConfig.MessageQueueTopics, b.pushHandler)
 		if err != nil {
 			glog.Error("mq: ", err)
 			return err
@@ -176,6 +289,9 @@ func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOut
 
 // Shutdown ZeroMQ and other resources
 func (b *BitcoinRPC) Shutdown(ctx context.Context) error {
+	if len(b.rpcPool.endpoints) > 1 {
+		close(b.rpcPool.quit)
+	}
 	if b.mq != nil {
 		if err := b.mq.Shutdown(ctx); err != nil {
 			glog.Error("MQ.Shutdown error: ", err)
@@ -250,6 +366,11 @@ type ResGetBlockChainInfo struct {
 	} `json:"result"`
 }
 
+type ResGetBlockChainInfoRaw struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result json.RawMessage  `json:"result"`
+}
+
 // getnetworkinfo
 
 type CmdGetNetworkInfo struct {
@@ -263,6 +384,7 @@ type ResGetNetworkInfo struct {
 		Subversion      common.JSONNumber `json:"subversion"`
 		ProtocolVersion common.JSONNumber `json:"protocolversion"`
 		Timeoffset      float64           `json:"timeoffset"`
+		RelayFee        common.JSONNumber `json:"relayfee"`
 		Warnings        string            `json:"warnings"`
 	} `json:"result"`
 }
@@ -278,6 +400,18 @@ type ResGetMempool struct {
 	Result []string         `json:"result"`
 }
 
+type CmdGetMempoolVerbose struct {
+	Method string `json:"method"`
+	Params struct {
+		Verbose bool `json:"verbose"`
+	} `json:"params"`
+}
+
+type ResGetMempoolVerbose struct {
+	Error  *bchain.RPCError                `json:"error"`
+	Result map[string]*bchain.MempoolEntry `json:"result"`
+}
+
 // getblockheader
 
 type CmdGetBlockHeader struct {
@@ -293,6 +427,20 @@ type ResGetBlockHeader struct {
 	Result bchain.BlockHeader `json:"result"`
 }
 
+// getblockfilter
+
+type CmdGetBlockFilter struct {
+	Method string `json:"method"`
+	Params struct {
+		BlockHash string `json:"blockhash"`
+	} `json:"params"`
+}
+
+type ResGetBlockFilter struct {
+	Error  *bchain.RPCError   `json:"error"`
+	Result bchain.BlockFilter `json:"result"`
+}
+
 // getblock
 
 type CmdGetBlock struct {
@@ -308,6 +456,11 @@ type ResGetBlockRaw struct {
 	Result string           `json:"result"`
 }
 
+type ResGetBlockSpecific struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result json.RawMessage  `json:"result"`
+}
+
 type BlockThin struct {
 	bchain.BlockHeader
 	Txids []string `json:"tx"`
@@ -404,6 +557,23 @@ type ResGetMempoolEntry struct {
 	Result *bchain.MempoolEntry `json:"result"`
 }
 
+// getmempoolinfo
+
+type CmdGetMempoolInfo struct {
+	Method string `json:"method"`
+}
+
+type ResGetMempoolInfo struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		Size          int               `json:"size"`
+		Bytes         int64             `json:"bytes"`
+		TotalFee      common.JSONNumber `json:"total_fee"`
+		MempoolMinFee common.JSONNumber `json:"mempoolminfee"`
+		MinRelayTxFee common.JSONNumber `json:"minrelaytxfee"`
+	} `json:"result"`
+}
+
 // GetBestBlockHash returns hash of the tip of the best-block-chain.
 func (b *BitcoinRPC) GetBestBlockHash() (string, error) {
 
@@ -474,6 +644,13 @@ func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	}
 	rv.Version = string(resNi.Result.Version)
 	rv.ProtocolVersion = string(resNi.Result.ProtocolVersion)
+	// parser is not yet available during initialization
+	if len(resNi.Result.RelayFee) > 0 && b.Parser != nil {
+		relayFee, err := b.Parser.AmountToBigInt(resNi.Result.RelayFee)
+		if err == nil {
+			rv.MinRelayTxFee = relayFee.String()
+		}
+	}
 	if len(resCi.Result.Warnings) > 0 {
 		rv.Warnings = resCi.Result.Warnings + " "
 	}
@@ -483,6 +660,21 @@ func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	return rv, nil
 }
 
+// GetBackendChainInfoRaw returns the result of getblockchaininfo exactly as returned by the backend
+func (b *BitcoinRPC) GetBackendChainInfoRaw() (json.RawMessage, error) {
+	glog.V(1).Info("rpc: getblockchaininfo")
+
+	res := ResGetBlockChainInfoRaw{}
+	err := b.Call(&CmdGetBlockChainInfo{Method: "getblockchaininfo"}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res.Result, nil
+}
+
 // IsErrBlockNotFound returns true if error means block was not found
 func IsErrBlockNotFound(err *bchain.RPCError) bool {
 	return err.Message == "Block not found" ||
@@ -532,6 +724,58 @@ func (b *BitcoinRPC) GetBlockHeader(hash string) (*bchain.BlockHeader, error) {
 	return &res.Result, nil
 }
 
+// GetBlockHeaderHex returns the serialized header of block with given hash as hex string
+func (b *BitcoinRPC) GetBlockHeaderHex(hash string) (string, error) {
+	glog.V(1).Info("rpc: getblockheader (verbose=false) ", hash)
+
+	res := ResGetBlockRaw{}
+	req := CmdGetBlockHeader{Method: "getblockheader"}
+	req.Params.BlockHash = hash
+	req.Params.Verbose = false
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return "", errors.Annotatef(err, "hash %v", hash)
+	}
+	if res.Error != nil {
+		if IsErrBlockNotFound(res.Error) {
+			return "", bchain.ErrBlockNotFound
+		}
+		return "", errors.Annotatef(res.Error, "hash %v", hash)
+	}
+	return res.Result, nil
+}
+
+// GetBlockFilter returns the basic block filter and the filter header of block with given hash
+// bchain.ErrNotSupported is returned if the backend does not run with the block filter index
+func (b *BitcoinRPC) GetBlockFilter(hash string) (*bchain.BlockFilter, error) {
+	glog.V(1).Info("rpc: getblockfilter ", hash)
+
+	res := ResGetBlockFilter{}
+	req := CmdGetBlockFilter{Method: "getblockfilter"}
+	req.Params.BlockHash = hash
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "hash %v", hash)
+	}
+	if res.Error != nil {
+		if IsErrBlockNotFound(res.Error) {
+			return nil, bchain.ErrBlockNotFound
+		}
+		if isErrBlockFilterNotSupported(res.Error) {
+			return nil, bchain.ErrNotSupported
+		}
+		return nil, errors.Annotatef(res.Error, "hash %v", hash)
+	}
+	return &res.Result, nil
+}
+
+// isErrBlockFilterNotSupported returns true if the backend is too old or runs without the block filter index
+func isErrBlockFilterNotSupported(err *bchain.RPCError) bool {
+	return err.Code == rpcErrMethodNotFound || strings.HasPrefix(err.Message, "Index is not enabled")
+}
+
 // GetBlock returns block with given hash.
 func (b *BitcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
 	var err error
@@ -552,7 +796,10 @@ func (b *BitcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error)
 	if err != nil {
 		return nil, err
 	}
-	data, err := b.GetBlockBytes(hash)
+	data, err := b.getBlockBytesWithinLimit(hash)
+	if err == errBlockTooLarge {
+		return b.GetBlockFull(hash)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -560,6 +807,19 @@ func (b *BitcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error)
 	if err != nil {
 		return nil, errors.Annotatef(err, "hash %v", hash)
 	}
+	if err = bchain.ParseBlockExtra(b.ChainConfig.CoinName, data, block); err != nil {
+		return nil, errors.Annotatef(err, "hash %v", hash)
+	}
+	// the sizes are not returned by getblockheader, use the values computed from the block data
+	if header.Size == 0 {
+		header.Size = block.Size
+	}
+	if header.StrippedSize == 0 {
+		header.StrippedSize = block.StrippedSize
+	}
+	if header.Weight == 0 {
+		header.Weight = block.Weight
+	}
 	block.BlockHeader = *header
 	return block, nil
 }
@@ -589,7 +849,10 @@ func (b *BitcoinRPC) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
 // GetBlockWithoutHeader is an optimization - it does not call GetBlockHeader to get prev, next hashes
 // instead it sets to header only block hash and height passed in parameters
 func (b *BitcoinRPC) GetBlockWithoutHeader(hash string, height uint32) (*bchain.Block, error) {
-	data, err := b.GetBlockBytes(hash)
+	data, err := b.getBlockBytesWithinLimit(hash)
+	if err == errBlockTooLarge {
+		return b.GetBlockFull(hash)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -597,6 +860,9 @@ func (b *BitcoinRPC) GetBlockWithoutHeader(hash string, height uint32) (*bchain.
 	if err != nil {
 		return nil, errors.Annotatef(err, "%v %v", height, hash)
 	}
+	if err = bchain.ParseBlockExtra(b.ChainConfig.CoinName, data, block); err != nil {
+		return nil, errors.Annotatef(err, "%v %v", height, hash)
+	}
 	block.BlockHeader.Hash = hash
 	block.BlockHeader.Height = height
 	return block, nil
@@ -604,13 +870,27 @@ func (b *BitcoinRPC) GetBlockWithoutHeader(hash string, height uint32) (*bchain.
 
 // GetBlockRaw returns block with given hash as hex string
 func (b *BitcoinRPC) GetBlockRaw(hash string) (string, error) {
+	return b.getBlockRawFromEndpoint(nil, hash)
+}
+
+// GetBlockRawFromBackend returns block with given hash as hex string as returned by the named back-end
+func (b *BitcoinRPC) GetBlockRawFromBackend(backend string, hash string) (string, error) {
+	e := b.rpcPool.endpoint(backend)
+	if e == nil {
+		return "", errors.Errorf("Unknown backend %v", backend)
+	}
+	return b.getBlockRawFromEndpoint(e, hash)
+}
+
+// getBlockRawFromEndpoint calls getblock (verbosity=0) on the endpoint e or on the pool of back-ends if e is nil
+func (b *BitcoinRPC) getBlockRawFromEndpoint(e *rpcEndpoint, hash string) (string, error) {
 	glog.V(1).Info("rpc: getblock (verbosity=0) ", hash)
 
 	res := ResGetBlockRaw{}
 	req := CmdGetBlock{Method: "getblock"}
 	req.Params.BlockHash = hash
 	req.Params.Verbosity = 0
-	err := b.Call(&req, &res)
+	err := b.callOn(e, &req, &res)
 
 	if err != nil {
 		return "", errors.Annotatef(err, "hash %v", hash)
@@ -624,6 +904,32 @@ func (b *BitcoinRPC) GetBlockRaw(hash string) (string, error) {
 	return res.Result, nil
 }
 
+// GetBlockSpecificFromBackend returns json of the block with given hash (getblock with verbosity=1) as returned by the named back-end
+func (b *BitcoinRPC) GetBlockSpecificFromBackend(backend string, hash string) (json.RawMessage, error) {
+	e := b.rpcPool.endpoint(backend)
+	if e == nil {
+		return nil, errors.Errorf("Unknown backend %v", backend)
+	}
+	glog.V(1).Info("rpc: getblock (verbosity=1) ", hash, " from ", backend)
+
+	res := ResGetBlockSpecific{}
+	req := CmdGetBlock{Method: "getblock"}
+	req.Params.BlockHash = hash
+	req.Params.Verbosity = 1
+	err := b.callEndpoint(e, &req, &res)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "hash %v", hash)
+	}
+	if res.Error != nil {
+		if IsErrBlockNotFound(res.Error) {
+			return nil, bchain.ErrBlockNotFound
+		}
+		return nil, errors.Annotatef(res.Error, "hash %v", hash)
+	}
+	return res.Result, nil
+}
+
 // GetBlockBytes returns block with given hash as bytes
 func (b *BitcoinRPC) GetBlockBytes(hash string) ([]byte, error) {
 	block, err := b.GetBlockRaw(hash)
@@ -633,6 +939,27 @@ func (b *BitcoinRPC) GetBlockBytes(hash string) ([]byte, error) {
 	return hex.DecodeString(block)
 }
 
+// errBlockTooLarge is returned by getBlockBytesWithinLimit if the block is larger than the configured max_block_size
+var errBlockTooLarge = errors.New("block exceeds max_block_size")
+
+// getBlockBytesWithinLimit returns the serialized block with given hash
+// if max_block_size is set, the size of the block is checked by getblock (verbosity=1) before the block is downloaded,
+// larger blocks are not downloaded, errBlockTooLarge is returned instead and the block must be obtained already parsed
+// from the backend, to avoid holding the raw and the parsed block in memory at the same time
+func (b *BitcoinRPC) getBlockBytesWithinLimit(hash string) ([]byte, error) {
+	if b.ChainConfig.MaxBlockSize > 0 {
+		info, err := b.GetBlockInfo(hash)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size > b.ChainConfig.MaxBlockSize {
+			glog.Warning("rpc: block ", hash, " size ", info.Size, " exceeds max_block_size ", b.ChainConfig.MaxBlockSize, ", using getblock (verbosity=2)")
+			return nil, errBlockTooLarge
+		}
+	}
+	return b.GetBlockBytes(hash)
+}
+
 // GetBlockFull returns block with given hash
 func (b *BitcoinRPC) GetBlockFull(hash string) (*bchain.Block, error) {
 	glog.V(1).Info("rpc: getblock (verbosity=2) ", hash)
@@ -692,9 +1019,10 @@ func IsMissingTx(err *bchain.RPCError) bool {
 	return err.Code == -5
 }
 
-// GetTransactionForMempool returns a transaction by the transaction ID
-// It could be optimized for mempool, i.e. without block time and confirmations
-func (b *BitcoinRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
+// GetTransactionHex returns the serialized transaction as hex string
+// It works both for mempool and confirmed transactions, the backend resolves the confirmed ones using its transaction index,
+// therefore a transaction confirmed after it was listed in the mempool is still returned
+func (b *BitcoinRPC) GetTransactionHex(txid string) (string, error) {
 	glog.V(1).Info("rpc: getrawtransaction nonverbose ", txid)
 
 	res := ResGetRawTransactionNonverbose{}
@@ -703,15 +1031,25 @@ func (b *BitcoinRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
 	req.Params.Verbose = false
 	err := b.Call(&req, &res)
 	if err != nil {
-		return nil, errors.Annotatef(err, "txid %v", txid)
+		return "", errors.Annotatef(err, "txid %v", txid)
 	}
 	if res.Error != nil {
 		if IsMissingTx(res.Error) {
-			return nil, bchain.ErrTxNotFound
+			return "", bchain.ErrTxNotFound
 		}
-		return nil, errors.Annotatef(res.Error, "txid %v", txid)
+		return "", errors.Annotatef(res.Error, "txid %v", txid)
 	}
-	data, err := hex.DecodeString(res.Result)
+	return res.Result, nil
+}
+
+// GetTransactionForMempool returns a transaction by the transaction ID
+// It could be optimized for mempool, i.e. without block time and confirmations
+func (b *BitcoinRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
+	txHex, err := b.GetTransactionHex(txid)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(txHex)
 	if err != nil {
 		return nil, errors.Annotatef(err, "txid %v", txid)
 	}
@@ -736,6 +1074,47 @@ func (b *BitcoinRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	return tx, nil
 }
 
+// GetTransactions returns transactions by their IDs using one batched request to the backend
+// the returned slice corresponds to txids, transactions not found by the backend are nil
+func (b *BitcoinRPC) GetTransactions(txids []string) ([]*bchain.Tx, error) {
+	glog.V(1).Info("rpc: getrawtransaction batch of ", len(txids))
+
+	if len(txids) == 0 {
+		return nil, nil
+	}
+	reqs := make([]interface{}, len(txids))
+	for i, txid := range txids {
+		req := CmdGetRawTransaction{Method: "getrawtransaction"}
+		req.Params.Txid = txid
+		req.Params.Verbose = true
+		reqs[i] = &req
+	}
+	res := []ResGetRawTransaction{}
+	err := b.CallBatch(reqs, &res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != len(txids) {
+		return nil, errors.Errorf("getrawtransaction batch: got %v results for %v transactions", len(res), len(txids))
+	}
+	txs := make([]*bchain.Tx, len(txids))
+	for i := range res {
+		if res[i].Error != nil {
+			if IsMissingTx(res[i].Error) {
+				continue
+			}
+			return nil, errors.Annotatef(res[i].Error, "txid %v", txids[i])
+		}
+		tx, err := b.Parser.ParseTxFromJson(res[i].Result)
+		if err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txids[i])
+		}
+		tx.CoinSpecificData = res[i].Result
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
 // GetTransactionSpecific returns json as returned by backend, with all coin specific data
 func (b *BitcoinRPC) GetTransactionSpecific(tx *bchain.Tx) (json.RawMessage, error) {
 	if csd, ok := tx.CoinSpecificData.(json.RawMessage); ok {
@@ -746,13 +1125,27 @@ func (b *BitcoinRPC) GetTransactionSpecific(tx *bchain.Tx) (json.RawMessage, err
 
 // getRawTransaction returns json as returned by backend, with all coin specific data
 func (b *BitcoinRPC) getRawTransaction(txid string) (json.RawMessage, error) {
+	return b.getRawTransactionFromEndpoint(nil, txid)
+}
+
+// GetTransactionSpecificFromBackend returns json as returned by the named back-end, the names are returned by GetBackendNames
+func (b *BitcoinRPC) GetTransactionSpecificFromBackend(backend string, tx *bchain.Tx) (json.RawMessage, error) {
+	e := b.rpcPool.endpoint(backend)
+	if e == nil {
+		return nil, errors.Errorf("Unknown backend %v", backend)
+	}
+	return b.getRawTransactionFromEndpoint(e, tx.Txid)
+}
+
+// getRawTransactionFromEndpoint calls getrawtransaction on the endpoint e or on the pool of back-ends if e is nil
+func (b *BitcoinRPC) getRawTransactionFromEndpoint(e *rpcEndpoint, txid string) (json.RawMessage, error) {
 	glog.V(1).Info("rpc: getrawtransaction ", txid)
 
 	res := ResGetRawTransaction{}
 	req := CmdGetRawTransaction{Method: "getrawtransaction"}
 	req.Params.Txid = txid
 	req.Params.Verbose = true
-	err := b.Call(&req, &res)
+	err := b.callOn(e, &req, &res)
 
 	if err != nil {
 		return nil, errors.Annotatef(err, "txid %v", txid)
@@ -769,27 +1162,22 @@ func (b *BitcoinRPC) getRawTransaction(txid string) (json.RawMessage, error) {
 // EstimateSmartFee returns fee estimation
 func (b *BitcoinRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, error) {
 	// use EstimateFee if EstimateSmartFee is not supported
-	if !b.ChainConfig.SupportsEstimateSmartFee && b.ChainConfig.SupportsEstimateFee {
+	estimateFee, estimateSmartFee := b.estimateFeeMethods()
+	if !estimateSmartFee && estimateFee {
 		return b.EstimateFee(blocks)
 	}
 
-	glog.V(1).Info("rpc: estimatesmartfee ", blocks)
-
-	res := ResEstimateSmartFee{}
-	req := CmdEstimateSmartFee{Method: "estimatesmartfee"}
-	req.Params.ConfTarget = blocks
-	if conservative {
-		req.Params.EstimateMode = "CONSERVATIVE"
-	} else {
-		req.Params.EstimateMode = "ECONOMICAL"
-	}
-	err := b.Call(&req, &res)
-
+	res, err := b.estimateSmartFeeOn(nil, blocks, conservative)
 	var r big.Int
 	if err != nil {
 		return r, err
 	}
 	if res.Error != nil {
+		if res.Error.Code == rpcErrMethodNotFound && estimateSmartFee {
+			glog.Warning("rpc: estimatesmartfee not supported by the backend, using estimatefee")
+			b.setEstimateFeeMethods(true, false)
+			return b.EstimateFee(blocks)
+		}
 		return r, res.Error
 	}
 	r, err = b.Parser.AmountToBigInt(res.Result.Feerate)
@@ -799,10 +1187,46 @@ func (b *BitcoinRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, e
 	return r, nil
 }
 
+// EstimateSmartFeeFromBackend returns fee estimation of the named back-end, without the fallback to estimatefee
+func (b *BitcoinRPC) EstimateSmartFeeFromBackend(backend string, blocks int, conservative bool) (big.Int, error) {
+	var r big.Int
+	e := b.rpcPool.endpoint(backend)
+	if e == nil {
+		return r, errors.Errorf("Unknown backend %v", backend)
+	}
+	res, err := b.estimateSmartFeeOn(e, blocks, conservative)
+	if err != nil {
+		return r, err
+	}
+	if res.Error != nil {
+		return r, res.Error
+	}
+	return b.Parser.AmountToBigInt(res.Result.Feerate)
+}
+
+// estimateSmartFeeOn calls estimatesmartfee on the endpoint e or on the pool of back-ends if e is nil
+func (b *BitcoinRPC) estimateSmartFeeOn(e *rpcEndpoint, blocks int, conservative bool) (*ResEstimateSmartFee, error) {
+	glog.V(1).Info("rpc: estimatesmartfee ", blocks)
+
+	res := ResEstimateSmartFee{}
+	req := CmdEstimateSmartFee{Method: "estimatesmartfee"}
+	req.Params.ConfTarget = blocks
+	if conservative {
+		req.Params.EstimateMode = "CONSERVATIVE"
+	} else {
+		req.Params.EstimateMode = "ECONOMICAL"
+	}
+	if err := b.callOn(e, &req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 // EstimateFee returns fee estimation.
 func (b *BitcoinRPC) EstimateFee(blocks int) (big.Int, error) {
 	// use EstimateSmartFee if EstimateFee is not supported
-	if !b.ChainConfig.SupportsEstimateFee && b.ChainConfig.SupportsEstimateSmartFee {
+	estimateFee, estimateSmartFee := b.estimateFeeMethods()
+	if !estimateFee && estimateSmartFee {
 		return b.EstimateSmartFee(blocks, true)
 	}
 
@@ -818,6 +1242,13 @@ func (b *BitcoinRPC) EstimateFee(blocks int) (big.Int, error) {
 		return r, err
 	}
 	if res.Error != nil {
+		if res.Error.Code == rpcErrMethodNotFound && estimateFee {
+			b.setEstimateFeeMethods(false, estimateSmartFee)
+			if estimateSmartFee {
+				glog.Warning("rpc: estimatefee not supported by the backend, using estimatesmartfee")
+				return b.EstimateSmartFee(blocks, true)
+			}
+		}
 		return r, res.Error
 	}
 	r, err = b.Parser.AmountToBigInt(res.Result)
@@ -872,6 +1303,108 @@ func (b *BitcoinRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error)
 	return res.Result, nil
 }
 
+// GetMempoolEntries returns the mempool entries of the transactions taken from one verbose getrawmempool request,
+// the returned slice corresponds to txids, the entries of the transactions no longer in the mempool are nil
+func (b *BitcoinRPC) GetMempoolEntries(txids []string) ([]*bchain.MempoolEntry, error) {
+	glog.V(1).Info("rpc: getrawmempool (verbose=true) for ", len(txids), " txs")
+
+	if len(txids) == 0 {
+		return nil, nil
+	}
+	res := ResGetMempoolVerbose{}
+	req := CmdGetMempoolVerbose{Method: "getrawmempool"}
+	req.Params.Verbose = true
+	err := b.Call(&req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	entries := make([]*bchain.MempoolEntry, len(txids))
+	for i, txid := range txids {
+		e := res.Result[txid]
+		if e == nil {
+			continue
+		}
+		if e.FeeSat, err = b.Parser.AmountToBigInt(e.Fee); err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txid)
+		}
+		if e.ModifiedFeeSat, err = b.Parser.AmountToBigInt(e.ModifiedFee); err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txid)
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// GetMempoolMinFee returns the minimal fee per kilobyte a transaction must pay to be accepted to the mempool of the backend
+func (b *BitcoinRPC) GetMempoolMinFee() (*big.Int, error) {
+	res, err := b.getMempoolInfo()
+	if err != nil {
+		return nil, err
+	}
+	return b.mempoolMinFee(res)
+}
+
+// GetMempoolInfo returns the number of transactions, their total virtual size and fees and the minimal fee per kilobyte of the mempool of the backend
+// backends older than Bitcoin Core 22.0 do not return the total fee
+func (b *BitcoinRPC) GetMempoolInfo() (*bchain.MempoolInfo, error) {
+	res, err := b.getMempoolInfo()
+	if err != nil {
+		return nil, err
+	}
+	minFee, err := b.mempoolMinFee(res)
+	if err != nil {
+		return nil, err
+	}
+	mi := &bchain.MempoolInfo{
+		Size:      res.Result.Size,
+		Bytes:     res.Result.Bytes,
+		MinFeeSat: *minFee,
+	}
+	if len(res.Result.TotalFee) > 0 {
+		if mi.TotalFeeSat, err = b.Parser.AmountToBigInt(res.Result.TotalFee); err != nil {
+			return nil, err
+		}
+	}
+	return mi, nil
+}
+
+func (b *BitcoinRPC) getMempoolInfo() (*ResGetMempoolInfo, error) {
+	glog.V(1).Info("rpc: getmempoolinfo")
+
+	res := ResGetMempoolInfo{}
+	err := b.Call(&CmdGetMempoolInfo{Method: "getmempoolinfo"}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &res, nil
+}
+
+func (b *BitcoinRPC) mempoolMinFee(res *ResGetMempoolInfo) (*big.Int, error) {
+	var err error
+	var mempoolMinFee, minRelayTxFee big.Int
+	if len(res.Result.MempoolMinFee) > 0 {
+		if mempoolMinFee, err = b.Parser.AmountToBigInt(res.Result.MempoolMinFee); err != nil {
+			return nil, err
+		}
+	}
+	// older backends do not return minrelaytxfee, newer ones return mempoolminfee never lower than minrelaytxfee
+	if len(res.Result.MinRelayTxFee) > 0 {
+		if minRelayTxFee, err = b.Parser.AmountToBigInt(res.Result.MinRelayTxFee); err != nil {
+			return nil, err
+		}
+	}
+	if mempoolMinFee.Cmp(&minRelayTxFee) < 0 {
+		return &minRelayTxFee, nil
+	}
+	return &mempoolMinFee, nil
+}
+
 func safeDecodeResponse(body io.ReadCloser, res interface{}) (err error) {
 	var data []byte
 	defer func() {
@@ -898,7 +1431,89 @@ func (b *BitcoinRPC) Call(req interface{}, res interface{}) error {
 	if err != nil {
 		return err
 	}
-	httpReq, err := http.NewRequest("POST", b.rpcURL, bytes.NewBuffer(httpData))
+	_, primaryOnly := rpcPrimaryMethods[rpcMethod(req)]
+	return b.post(primaryOnly, httpData, res)
+}
+
+// CallBatch calls Backend RPC interface with a batch of requests, using RPCMarshaler interface to marshall each request
+// the backend returns the results in the order of the requests
+func (b *BitcoinRPC) CallBatch(reqs []interface{}, res interface{}) error {
+	httpData := []byte{'['}
+	for i, req := range reqs {
+		d, err := b.RPCMarshaler.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			httpData = append(httpData, ',')
+		}
+		httpData = append(httpData, d...)
+	}
+	httpData = append(httpData, ']')
+	// the batches read the mempool transactions, they are pinned to the primary back-end like the other mempool calls
+	return b.post(true, httpData, res)
+}
+
+// post sends the request to the primary back-end if primaryOnly is set or if there are no secondary back-ends,
+// otherwise to the next healthy back-end, failing over to the other healthy back-ends if the call fails
+func (b *BitcoinRPC) post(primaryOnly bool, httpData []byte, res interface{}) error {
+	if primaryOnly || len(b.rpcPool.endpoints) == 1 {
+		return b.postURL(b.rpcPool.endpoints[0].url, httpData, res)
+	}
+	e := b.rpcPool.get()
+	err := b.postEndpoint(e, httpData, res)
+	if err == nil {
+		return nil
+	}
+	for _, o := range b.rpcPool.endpoints {
+		if o == e || !o.isHealthy() {
+			continue
+		}
+		glog.V(1).Info("rpc: call to ", e.url, " failed, failing over to ", o.url, ": ", err)
+		if err = b.postEndpoint(o, httpData, res); err == nil {
+			return nil
+		}
+		e = o
+	}
+	return err
+}
+
+func (b *BitcoinRPC) postEndpoint(e *rpcEndpoint, httpData []byte, res interface{}) error {
+	err := b.postURL(e.url, httpData, res)
+	// the endpoint which cannot be connected is not used until it passes the health check
+	if _, ok := err.(*url.Error); ok {
+		e.setHealthy(false)
+	}
+	return err
+}
+
+// isConnectionError returns true if the request failed because the connection to the back-end could not be
+// established or was lost, e.g. after a restart of the back-end; timeouts are not considered connection errors
+func isConnectionError(err error) bool {
+	ue, ok := err.(*url.Error)
+	return ok && !ue.Timeout()
+}
+
+// postURL sends the request to the back-end; if the connection is lost, the idle connections are closed
+// and the request is retried on a new connection with exponential backoff
+func (b *BitcoinRPC) postURL(rpcURL string, httpData []byte, res interface{}) error {
+	backoff := time.Duration(b.ChainConfig.RPCReconnectBackoff) * time.Millisecond
+	for retry := 0; ; retry++ {
+		err := b.postURLOnce(rpcURL, httpData, res)
+		if err == nil || !isConnectionError(err) || retry >= b.ChainConfig.RPCReconnectRetries {
+			return err
+		}
+		glog.Warning("rpc: connection to ", rpcURL, " lost, reconnecting in ", backoff, ": ", err)
+		b.client.CloseIdleConnections()
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRPCReconnectBackoff {
+			backoff = maxRPCReconnectBackoff
+		}
+	}
+}
+
+func (b *BitcoinRPC) postURLOnce(rpcURL string, httpData []byte, res interface{}) error {
+	httpReq, err := http.NewRequest("POST", rpcURL, bytes.NewBuffer(httpData))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,564 @@
+//go:build unittest
+
+package btc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// genesis block of the Bitcoin main network
+const testGenesisBlockHex = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c0101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+
+// newTestBitcoinRPC returns BitcoinRPC connected to a mocked backend returning fixed results of rpc methods
+func newTestBitcoinRPC(t *testing.T, coin string, results map[string]string) (*BitcoinRPC, *httptest.Server) {
+	return newTestBitcoinRPCWithErrors(t, coin, results, nil)
+}
+
+// newTestBitcoinRPCWithErrors creates BitcoinRPC connected to a mock backend, which returns the results
+// of the methods in results and the rpc errors of the methods in rpcErrors
+func newTestBitcoinRPCWithErrors(t *testing.T, coin string, results map[string]string, rpcErrors map[string]string) (*BitcoinRPC, *httptest.Server) {
+	return newTestBitcoinRPCWithHandler(t, coin, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if rpcErr, found := rpcErrors[req.Method]; found {
+			fmt.Fprintf(w, `{"result":null,"error":%s,"id":"0"}`, rpcErr)
+			return
+		}
+		result, found := results[req.Method]
+		if !found {
+			http.Error(w, "unexpected method "+req.Method, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"result":%s,"error":null,"id":"0"}`, result)
+	})
+}
+
+// newTestBitcoinRPCWithHandler creates BitcoinRPC connected to a mock backend served by the handler
+func newTestBitcoinRPCWithHandler(t *testing.T, coin string, handler http.HandlerFunc) (*BitcoinRPC, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+	config := fmt.Sprintf(`{"coin_name":%q,"rpc_url":%q,"rpc_timeout":5,"parse":true}`, coin, ts.URL)
+	chain, err := NewBitcoinRPC(json.RawMessage(config), nil)
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	b := chain.(*BitcoinRPC)
+	b.Parser = NewBitcoinParser(GetChainParams("main"), b.ChainConfig)
+	return b, ts
+}
+
+func TestGetBlockHeaderHex(t *testing.T) {
+	const genesisHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	genesisHeaderHex := testGenesisBlockHex[:160]
+	tests := []struct {
+		name      string
+		headerHex string
+	}{
+		{
+			name:      "80 byte header",
+			headerHex: genesisHeaderHex,
+		},
+		{
+			// coins with auxpow or PoS data serialize additional bytes after the 80 byte header
+			name:      "extended header",
+			headerHex: genesisHeaderHex + "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{"getblockheader": `"` + tt.headerHex + `"`})
+			defer ts.Close()
+			got, err := b.GetBlockHeaderHex(genesisHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.headerHex {
+				t.Errorf("GetBlockHeaderHex() = %v, want %v", got, tt.headerHex)
+			}
+			raw, err := hex.DecodeString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var header wire.BlockHeader
+			if err := header.Deserialize(bytes.NewReader(raw)); err != nil {
+				t.Fatal(err)
+			}
+			if h := header.BlockHash().String(); h != genesisHash {
+				t.Errorf("GetBlockHeaderHex() header hash = %v, want %v", h, genesisHash)
+			}
+		})
+	}
+}
+
+func TestGetTransactionHex_Mempool(t *testing.T) {
+	// coinbase transaction of the genesis block, the block header and the transaction count precede it in the block
+	const txid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	txHex := testGenesisBlockHex[162:]
+	b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getrawmempool":     `["` + txid + `"]`,
+		"getrawtransaction": `"` + txHex + `"`,
+	})
+	defer ts.Close()
+
+	txids, err := b.GetMempoolTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txids) != 1 || txids[0] != txid {
+		t.Fatalf("GetMempoolTransactions() = %v, want [%v]", txids, txid)
+	}
+	parsed, err := b.GetTransactionForMempool(txids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.GetTransactionHex(txids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != txHex {
+		t.Errorf("GetTransactionHex() = %v, want %v", got, txHex)
+	}
+	raw, err := hex.DecodeString(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if h := tx.TxHash().String(); h != parsed.Txid || h != txid {
+		t.Errorf("GetTransactionHex() txid = %v, parsed txid %v, want %v", h, parsed.Txid, txid)
+	}
+}
+
+func TestGetTransactionHex_NotFound(t *testing.T) {
+	b, ts := newTestBitcoinRPCWithErrors(t, "Bitcoin", nil, map[string]string{
+		"getrawtransaction": `{"code":-5,"message":"No such mempool or blockchain transaction. Use gettransaction for wallet transactions."}`,
+	})
+	defer ts.Close()
+
+	if _, err := b.GetTransactionHex("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"); err != bchain.ErrTxNotFound {
+		t.Errorf("GetTransactionHex() error = %v, want %v", err, bchain.ErrTxNotFound)
+	}
+}
+
+func TestGetBlockFilter(t *testing.T) {
+	// BIP158 test vector, basic filter of the testnet genesis block
+	const (
+		genesisHash   = "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"
+		genesisFilter = "019dfca8"
+		genesisHeader = "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750"
+	)
+	tests := []struct {
+		name     string
+		result   string
+		rpcError string
+		want     *bchain.BlockFilter
+		wantErr  error
+	}{
+		{
+			name:   "genesis block",
+			result: `{"filter":"` + genesisFilter + `","header":"` + genesisHeader + `"}`,
+			want:   &bchain.BlockFilter{Filter: genesisFilter, Header: genesisHeader},
+		},
+		{
+			name:     "block filter index not enabled",
+			rpcError: `{"code":-1,"message":"Index is not enabled for filtertype basic"}`,
+			wantErr:  bchain.ErrNotSupported,
+		},
+		{
+			name:     "old backend",
+			rpcError: `{"code":-32601,"message":"Method not found"}`,
+			wantErr:  bchain.ErrNotSupported,
+		},
+		{
+			name:     "unknown block",
+			rpcError: `{"code":-5,"message":"Block not found"}`,
+			wantErr:  bchain.ErrBlockNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := map[string]string{}
+			rpcErrors := map[string]string{}
+			if tt.rpcError != "" {
+				rpcErrors["getblockfilter"] = tt.rpcError
+			} else {
+				results["getblockfilter"] = tt.result
+			}
+			b, ts := newTestBitcoinRPCWithErrors(t, "Bitcoin", results, rpcErrors)
+			defer ts.Close()
+			got, err := b.GetBlockFilter(genesisHash)
+			if err != tt.wantErr {
+				t.Fatalf("GetBlockFilter() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetBlockFilter() = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			// the filter header is double sha256 of the filter hash and the previous filter header (zero for genesis)
+			filter, err := hex.DecodeString(got.Filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filterHash := chainhash.DoubleHashH(filter)
+			var prevHeader chainhash.Hash
+			header := chainhash.DoubleHashH(append(filterHash[:], prevHeader[:]...))
+			if header.String() != got.Header {
+				t.Errorf("GetBlockFilter() header = %v, want linked header %v", got.Header, header.String())
+			}
+		})
+	}
+}
+
+type testBlockExtra struct {
+	Nonce uint32
+}
+
+func TestGetBlock_ParseBlockExtra(t *testing.T) {
+	const coin = "ParseBlockExtra Test"
+	bchain.RegisterParseBlockExtra(coin, func(raw []byte, block *bchain.Block) error {
+		if len(raw) < 80 {
+			return fmt.Errorf("short block header")
+		}
+		// nonce is the last field of the block header
+		block.CoinSpecificData = &testBlockExtra{Nonce: binary.LittleEndian.Uint32(raw[76:80])}
+		return nil
+	})
+
+	b, ts := newTestBitcoinRPC(t, coin, map[string]string{
+		"getblock": `"` + testGenesisBlockHex + `"`,
+	})
+	defer ts.Close()
+
+	block, err := b.GetBlock("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, ok := block.CoinSpecificData.(*testBlockExtra)
+	if !ok {
+		t.Fatalf("GetBlock() CoinSpecificData = %+v, want *testBlockExtra", block.CoinSpecificData)
+	}
+	if extra.Nonce != 2083236893 {
+		t.Errorf("GetBlock() Nonce = %v, want %v", extra.Nonce, 2083236893)
+	}
+	if len(block.Txs) != 1 || block.Txs[0].Txid != "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b" {
+		t.Errorf("GetBlock() unexpected transactions %+v", block.Txs)
+	}
+}
+
+func TestGetBlock_MaxBlockSize(t *testing.T) {
+	const genesisHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	const genesisTxid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	// the genesis block as returned by getblock with verbosity 2
+	const genesisBlockFull = `{"hash":"` + genesisHash + `","height":0,"confirmations":1,"time":1231006505,"size":285,` +
+		`"tx":[{"txid":"` + genesisTxid + `","version":1,"locktime":0,` +
+		`"vin":[{"coinbase":"04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73","sequence":4294967295}],` +
+		`"vout":[{"value":50.00000000,"n":0,"scriptPubKey":{"hex":"4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac"}}]}]}`
+	// the genesis block as returned by getblock with verbosity 1
+	const genesisBlockInfo = `{"hash":"` + genesisHash + `","height":0,"confirmations":1,"time":1231006505,"size":285,"tx":["` + genesisTxid + `"]}`
+	// the size of the block is checked before the block is downloaded, the block is downloaded only once
+	tests := []struct {
+		name            string
+		maxBlockSize    int
+		wantVerbosities []int
+	}{
+		{name: "no limit", maxBlockSize: 0, wantVerbosities: []int{0}},
+		{name: "within limit", maxBlockSize: 285, wantVerbosities: []int{1, 0}},
+		{name: "oversized block", maxBlockSize: 284, wantVerbosities: []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verbosities []int
+			b, ts := newTestBitcoinRPCWithHandler(t, "Bitcoin", func(w http.ResponseWriter, r *http.Request) {
+				var req CmdGetBlock
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getblock" {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				verbosities = append(verbosities, req.Params.Verbosity)
+				switch req.Params.Verbosity {
+				case 0:
+					fmt.Fprintf(w, `{"result":"%s","error":null,"id":"0"}`, testGenesisBlockHex)
+				case 1:
+					fmt.Fprintf(w, `{"result":%s,"error":null,"id":"0"}`, genesisBlockInfo)
+				case 2:
+					fmt.Fprintf(w, `{"result":%s,"error":null,"id":"0"}`, genesisBlockFull)
+				default:
+					http.Error(w, "unexpected verbosity", http.StatusBadRequest)
+				}
+			})
+			defer ts.Close()
+			b.ChainConfig.MaxBlockSize = tt.maxBlockSize
+
+			block, err := b.GetBlock(genesisHash, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(verbosities, tt.wantVerbosities) {
+				t.Errorf("GetBlock() getblock verbosities = %v, want %v", verbosities, tt.wantVerbosities)
+			}
+			if len(block.Txs) != 1 || block.Txs[0].Txid != genesisTxid {
+				t.Fatalf("GetBlock() unexpected transactions %+v", block.Txs)
+			}
+			if v := block.Txs[0].Vout[0].ValueSat.String(); v != "5000000000" {
+				t.Errorf("GetBlock() output value = %v, want 5000000000", v)
+			}
+		})
+	}
+}
+
+func TestGetMempoolMinFee(t *testing.T) {
+	tests := []struct {
+		name        string
+		mempoolInfo string
+		want        string
+	}{
+		{
+			name:        "mempoolminfee",
+			mempoolInfo: `{"size":10,"bytes":2500,"mempoolminfee":0.00002000,"minrelaytxfee":0.00001000}`,
+			want:        "2000",
+		},
+		{
+			name:        "minrelaytxfee",
+			mempoolInfo: `{"size":10,"bytes":2500,"mempoolminfee":0.00000000,"minrelaytxfee":0.00001000}`,
+			want:        "1000",
+		},
+		{
+			name:        "without minrelaytxfee",
+			mempoolInfo: `{"size":10,"bytes":2500,"mempoolminfee":0.00001500}`,
+			want:        "1500",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{"getmempoolinfo": tt.mempoolInfo})
+			defer ts.Close()
+			got, err := b.GetMempoolMinFee()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("GetMempoolMinFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMempoolInfo(t *testing.T) {
+	b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getmempoolinfo": `{"loaded":true,"size":2,"bytes":373,"usage":2784,"total_fee":0.00003546,"maxmempool":300000000,"mempoolminfee":0.00001000,"minrelaytxfee":0.00001000}`,
+	})
+	defer ts.Close()
+	got, err := b.GetMempoolInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Size != 2 || got.Bytes != 373 || got.TotalFeeSat.String() != "3546" || got.MinFeeSat.String() != "1000" {
+		t.Errorf("GetMempoolInfo() = %+v", got)
+	}
+}
+
+func TestGetChainInfo_MinRelayTxFee(t *testing.T) {
+	b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getblockchaininfo": `{"chain":"main","blocks":100,"headers":100,"bestblockhash":"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f","difficulty":1,"size_on_disk":1000,"warnings":""}`,
+		"getnetworkinfo":    `{"version":280000,"subversion":"/Satoshi:28.0.0/","protocolversion":70016,"timeoffset":0,"relayfee":0.00001000,"warnings":""}`,
+	})
+	defer ts.Close()
+	ci, err := b.GetChainInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.MinRelayTxFee != "1000" {
+		t.Errorf("GetChainInfo() MinRelayTxFee = %v, want %v", ci.MinRelayTxFee, "1000")
+	}
+}
+
+func TestGetBackendChainInfoRaw(t *testing.T) {
+	// the fields unknown to Blockbook and the formatting must be preserved
+	const info = `{"chain": "main", "blocks": 100, "headers": 100, "bestblockhash": "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", "difficulty": 1, "time": 1231006505, "verificationprogress": 1, "initialblockdownload": false, "chainwork": "0000000000000000000000000000000000000000000000000000006500650065", "size_on_disk": 1000, "pruned": false, "warnings": ""}`
+	b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getblockchaininfo": info,
+	})
+	defer ts.Close()
+	got, err := b.GetBackendChainInfoRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != info {
+		t.Errorf("GetBackendChainInfoRaw() = %s, want %s", got, info)
+	}
+}
+
+func TestCall_Reconnect(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{name: "reconnect", retries: 3, wantCalls: 3},
+		{name: "reconnection disabled", retries: -1, wantErr: true, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			b, ts := newTestBitcoinRPCWithHandler(t, "Bitcoin", func(w http.ResponseWriter, r *http.Request) {
+				// the second request is dropped without a response as if the back-end restarted
+				if atomic.AddInt32(&calls, 1) == 2 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					conn.Close()
+					return
+				}
+				fmt.Fprint(w, `{"result":100,"error":null,"id":"0"}`)
+			})
+			defer ts.Close()
+			b.ChainConfig.RPCReconnectRetries = tt.retries
+			b.ChainConfig.RPCReconnectBackoff = 1
+
+			if _, err := b.GetBestBlockHeight(); err != nil {
+				t.Fatal(err)
+			}
+			height, err := b.GetBestBlockHeight()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBestBlockHeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && height != 100 {
+				t.Errorf("GetBestBlockHeight() = %v, want 100", height)
+			}
+			if c := atomic.LoadInt32(&calls); c != tt.wantCalls {
+				t.Errorf("backend calls = %v, want %v", c, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestInitialize_EstimateFeeByVersion(t *testing.T) {
+	const (
+		blockChainInfo = `{"chain":"main","blocks":100,"headers":100,"bestblockhash":"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f","difficulty":1,"size_on_disk":1000,"warnings":""}`
+		methodNotFound = `{"code":-32601,"message":"Method not found"}`
+	)
+	tests := []struct {
+		name              string
+		version           string
+		results           map[string]string
+		rpcErrors         map[string]string
+		wantSmartFee      bool
+		wantFee           bool
+		want              string
+		wantAfterSmartFee bool
+	}{
+		{
+			name:    "old backend without estimatesmartfee",
+			version: "140000",
+			results: map[string]string{
+				"estimatefee": "0.0001",
+			},
+			wantSmartFee:      false,
+			wantFee:           true,
+			want:              "10000",
+			wantAfterSmartFee: false,
+		},
+		{
+			name:    "backend with both methods",
+			version: "160000",
+			results: map[string]string{
+				"estimatesmartfee": `{"feerate":0.0002,"blocks":2}`,
+			},
+			wantSmartFee:      true,
+			wantFee:           true,
+			want:              "20000",
+			wantAfterSmartFee: true,
+		},
+		{
+			name:    "current backend",
+			version: "280000",
+			results: map[string]string{
+				"estimatesmartfee": `{"feerate":0.0002,"blocks":2}`,
+			},
+			wantSmartFee:      true,
+			wantFee:           false,
+			want:              "20000",
+			wantAfterSmartFee: true,
+		},
+		{
+			name:    "estimatesmartfee not found falls back to estimatefee",
+			version: "280000",
+			results: map[string]string{
+				"estimatefee": "0.0001",
+			},
+			rpcErrors: map[string]string{
+				"estimatesmartfee": methodNotFound,
+			},
+			wantSmartFee:      true,
+			wantFee:           false,
+			want:              "10000",
+			wantAfterSmartFee: false,
+		},
+		{
+			name:    "unknown version",
+			version: `"unknown"`,
+			results: map[string]string{
+				"estimatesmartfee": `{"feerate":0.0002,"blocks":2}`,
+			},
+			wantSmartFee:      true,
+			wantFee:           false,
+			want:              "20000",
+			wantAfterSmartFee: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := map[string]string{
+				"getblockchaininfo": blockChainInfo,
+				"getnetworkinfo":    `{"version":` + tt.version + `,"subversion":"/Satoshi/","protocolversion":70016,"timeoffset":0,"warnings":""}`,
+			}
+			for k, v := range tt.results {
+				results[k] = v
+			}
+			b, ts := newTestBitcoinRPCWithErrors(t, "Bitcoin", results, tt.rpcErrors)
+			defer ts.Close()
+			if err := b.Initialize(); err != nil {
+				t.Fatal(err)
+			}
+			if b.ChainConfig.SupportsEstimateSmartFee != tt.wantSmartFee || b.ChainConfig.SupportsEstimateFee != tt.wantFee {
+				t.Errorf("Initialize() SupportsEstimateSmartFee = %v, SupportsEstimateFee = %v, want %v, %v",
+					b.ChainConfig.SupportsEstimateSmartFee, b.ChainConfig.SupportsEstimateFee, tt.wantSmartFee, tt.wantFee)
+			}
+			got, err := b.EstimateSmartFee(2, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("EstimateSmartFee() = %v, want %v", got.String(), tt.want)
+			}
+			if b.ChainConfig.SupportsEstimateSmartFee != tt.wantAfterSmartFee {
+				t.Errorf("EstimateSmartFee() SupportsEstimateSmartFee = %v, want %v", b.ChainConfig.SupportsEstimateSmartFee, tt.wantAfterSmartFee)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package btc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// getrawmempool verbose
+
+type CmdGetMempoolVerbose struct {
+	Method string `json:"method"`
+	Params struct {
+		Verbose bool `json:"verbose"`
+	} `json:"params"`
+}
+
+type ResGetMempoolVerbose struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result map[string]struct {
+		VSize int64 `json:"vsize"`
+		Fees  struct {
+			Base common.JSONNumber `json:"base"`
+		} `json:"fees"`
+	} `json:"result"`
+}
+
+// getblockstats
+
+type CmdGetBlockStats struct {
+	Method string `json:"method"`
+	Params struct {
+		HashOrHeight uint32   `json:"hash_or_height"`
+		Stats        []string `json:"stats"`
+	} `json:"params"`
+}
+
+type ResGetBlockStats struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		FeeratePercentiles []float64 `json:"feerate_percentiles"`
+	} `json:"result"`
+}
+
+// the lower bounds of the buckets of the mempool fee histogram in satoshis per vbyte
+var feeHistogramRates = []float64{1000, 700, 500, 400, 300, 250, 200, 170, 140, 120, 100, 80, 70, 60, 50, 40, 30, 25, 20, 17, 14, 12, 10, 8, 7, 6, 5, 4, 3, 2, 1, 0}
+
+// the histogram requires the whole mempool from the backend, it is cached for feeHistogramCacheTime
+const feeHistogramCacheTime = 30 * time.Second
+
+// GetMempoolFeeHistogram returns the virtual size of the mempool transactions grouped by the fee rate, sorted from the highest fee rate
+func (b *BitcoinRPC) GetMempoolFeeHistogram() ([]bchain.MempoolFeeRateBucket, error) {
+	b.feeHistogramMux.Lock()
+	defer b.feeHistogramMux.Unlock()
+	if b.feeHistogram != nil && time.Since(b.feeHistogramTime) < feeHistogramCacheTime {
+		return b.feeHistogram, nil
+	}
+
+	glog.V(1).Info("rpc: getrawmempool verbose")
+
+	res := ResGetMempoolVerbose{}
+	req := CmdGetMempoolVerbose{Method: "getrawmempool"}
+	req.Params.Verbose = true
+	err := b.Call(&req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	histogram := make([]bchain.MempoolFeeRateBucket, len(feeHistogramRates))
+	for i := range histogram {
+		histogram[i].FeeRate = feeHistogramRates[i]
+	}
+	for _, e := range res.Result {
+		if e.VSize <= 0 {
+			continue
+		}
+		fee, err := b.Parser.AmountToBigInt(e.Fees.Base)
+		if err != nil {
+			return nil, err
+		}
+		rate := float64(fee.Int64()) / float64(e.VSize)
+		// the rates are sorted descending, find the first bucket with the lower bound not greater than the rate
+		i := sort.Search(len(feeHistogramRates), func(i int) bool { return feeHistogramRates[i] <= rate })
+		if i == len(feeHistogramRates) {
+			i--
+		}
+		histogram[i].VSize += e.VSize
+	}
+	b.feeHistogram = histogram
+	b.feeHistogramTime = time.Now()
+	return histogram, nil
+}
+
+// GetBlockFeeRatePercentiles returns the 10th, 25th, 50th, 75th and 90th percentiles of the fee rates of the transactions of the block
+// in satoshis per vbyte, weighted by the virtual size of the transactions
+func (b *BitcoinRPC) GetBlockFeeRatePercentiles(height uint32) ([]float64, error) {
+	glog.V(1).Info("rpc: getblockstats ", height)
+
+	res := ResGetBlockStats{}
+	req := CmdGetBlockStats{Method: "getblockstats"}
+	req.Params.HashOrHeight = height
+	req.Params.Stats = []string{"feerate_percentiles"}
+	err := b.Call(&req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res.Result.FeeratePercentiles, nil
+}
@@ -0,0 +1,142 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// PoolTag describes a mining pool, the pool is recognized by a tag in the coinbase scriptSig or by a payout address
+type PoolTag struct {
+	Name      string   `json:"name"`
+	Tags      []string `json:"tags,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+type poolMatcher struct {
+	pools     []PoolTag
+	tags      [][][]byte
+	addrDescs map[string]int
+}
+
+// loadPoolTags reads the table of pool tags from a json file containing an array of PoolTag
+func loadPoolTags(file string) ([]PoolTag, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var pools []PoolTag
+	if err = json.Unmarshal(data, &pools); err != nil {
+		return nil, errors.Annotatef(err, "pool tags file %v", file)
+	}
+	return pools, nil
+}
+
+// newPoolMatcher prepares the pool tags for matching, the pools are matched in the order of the table
+func newPoolMatcher(parser bchain.BlockChainParser, pools []PoolTag) (*poolMatcher, error) {
+	m := &poolMatcher{
+		pools:     pools,
+		tags:      make([][][]byte, len(pools)),
+		addrDescs: make(map[string]int),
+	}
+	for i := range pools {
+		for _, tag := range pools[i].Tags {
+			if tag != "" {
+				m.tags[i] = append(m.tags[i], []byte(tag))
+			}
+		}
+		for _, a := range pools[i].Addresses {
+			addrDesc, err := parser.GetAddrDescFromAddress(a)
+			if err != nil {
+				return nil, errors.Annotatef(err, "pool %v address %v", pools[i].Name, a)
+			}
+			if _, found := m.addrDescs[string(addrDesc)]; !found {
+				m.addrDescs[string(addrDesc)] = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// match returns the name of the pool which created the coinbase transaction or empty string if the pool is not recognized
+// payout addresses are checked first, as the tags in scriptSig can be set by anybody
+func (m *poolMatcher) match(parser bchain.BlockChainParser, coinbase *bchain.Tx) string {
+	for i := range coinbase.Vout {
+		addrDesc, err := parser.GetAddrDescFromVout(&coinbase.Vout[i])
+		if err != nil || len(addrDesc) == 0 {
+			continue
+		}
+		if p, found := m.addrDescs[string(addrDesc)]; found {
+			return m.pools[p].Name
+		}
+	}
+	if len(coinbase.Vin) == 0 || coinbase.Vin[0].Coinbase == "" {
+		return ""
+	}
+	scriptSig, err := hex.DecodeString(coinbase.Vin[0].Coinbase)
+	if err != nil {
+		return ""
+	}
+	for i := range m.tags {
+		for _, tag := range m.tags[i] {
+			if bytes.Contains(scriptSig, tag) {
+				return m.pools[i].Name
+			}
+		}
+	}
+	return ""
+}
+
+// getPoolMatcher creates the pool matcher from the pool tags in the configuration on the first use
+func (b *BitcoinRPC) getPoolMatcher() *poolMatcher {
+	b.poolMatcherOnce.Do(func() {
+		pools := b.ChainConfig.PoolTags
+		if b.ChainConfig.PoolTagsFile != "" {
+			p, err := loadPoolTags(b.ChainConfig.PoolTagsFile)
+			if err != nil {
+				glog.Error("pool tags: ", err)
+				return
+			}
+			pools = append(pools, p...)
+		}
+		if len(pools) == 0 {
+			return
+		}
+		m, err := newPoolMatcher(b.Parser, pools)
+		if err != nil {
+			glog.Error("pool tags: ", err)
+			return
+		}
+		b.poolMatcher = m
+	})
+	return b.poolMatcher
+}
+
+// GetBlockMiner returns the name of the mining pool which mined the block with given hash
+// the pool is recognized by the coinbase transaction using the pool tag table of the coin, empty string is returned if the pool is not recognized
+func (b *BitcoinRPC) GetBlockMiner(hash string) (string, error) {
+	m := b.getPoolMatcher()
+	if m == nil {
+		return "", nil
+	}
+	block, err := b.GetBlock(hash, 0)
+	if err != nil {
+		return "", err
+	}
+	return b.MatchBlockMiner(block), nil
+}
+
+// MatchBlockMiner returns the name of the mining pool which mined the block, recognized by its coinbase transaction
+// empty string is returned if the pool is not recognized or there are no pool tags for the coin
+func (b *BitcoinRPC) MatchBlockMiner(block *bchain.Block) string {
+	m := b.getPoolMatcher()
+	if m == nil || len(block.Txs) == 0 {
+		return ""
+	}
+	return m.match(b.Parser, &block.Txs[0])
+}
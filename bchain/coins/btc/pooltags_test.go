@@ -0,0 +1,109 @@
+//go:build unittest
+
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func Test_poolMatcher_match(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	payout, err := parser.GetAddrDescFromAddress("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := parser.GetAddrDescFromAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := newPoolMatcher(parser, []PoolTag{
+		{Name: "ViaBTC", Tags: []string{"/ViaBTC/"}},
+		{Name: "Payout Pool", Tags: []string{"/payout/"}, Addresses: []string{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := func(scriptSig string, outputs ...bchain.AddressDescriptor) *bchain.Tx {
+		tx := &bchain.Tx{Vin: []bchain.Vin{{Coinbase: scriptSig}}}
+		for i, o := range outputs {
+			tx.Vout = append(tx.Vout, bchain.Vout{N: uint32(i), ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(o)}})
+		}
+		return tx
+	}
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want string
+	}{
+		{
+			name: "tag in scriptSig",
+			// height 900000 followed by the push of "/ViaBTC/Mined by abc/" and the merged mining header
+			tx:   coinbase("03a0bb0d152f5669614254432f4d696e6564206279206162632f2cfabe6d6d", other),
+			want: "ViaBTC",
+		},
+		{
+			name: "payout address",
+			tx:   coinbase("03a0bb0d", other, payout),
+			want: "Payout Pool",
+		},
+		{
+			name: "payout address takes precedence over tag",
+			tx:   coinbase("03a0bb0d152f5669614254432f4d696e6564206279206162632f", payout),
+			want: "Payout Pool",
+		},
+		{
+			name: "unknown pool",
+			tx:   coinbase("03a0bb0d0d2f756e6b6e6f776e706f6f6c2f", other),
+			want: "",
+		},
+		{
+			name: "not a coinbase",
+			tx:   &bchain.Tx{Vin: []bchain.Vin{{Txid: "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"}}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.match(parser, tt.tx); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBlockMiner(t *testing.T) {
+	b, ts := newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getblockheader": `{"hash":"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f","height":0,"confirmations":1,"time":1231006505}`,
+		"getblock":       `"` + testGenesisBlockHex + `"`,
+	})
+	defer ts.Close()
+
+	// without the pool tags the miner is not resolved
+	got, err := b.GetBlockMiner("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("GetBlockMiner() = %v, want empty", got)
+	}
+
+	b, ts = newTestBitcoinRPC(t, "Bitcoin", map[string]string{
+		"getblockheader": `{"hash":"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f","height":0,"confirmations":1,"time":1231006505}`,
+		"getblock":       `"` + testGenesisBlockHex + `"`,
+	})
+	defer ts.Close()
+	b.ChainConfig.PoolTags = []PoolTag{
+		{Name: "ViaBTC", Tags: []string{"/ViaBTC/"}},
+		{Name: "Satoshi", Tags: []string{"The Times 03/Jan/2009"}},
+	}
+	got, err = b.GetBlockMiner("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Satoshi" {
+		t.Errorf("GetBlockMiner() = %v, want %v", got, "Satoshi")
+	}
+}
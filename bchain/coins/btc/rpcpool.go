@@ -0,0 +1,185 @@
+package btc
+
+import (
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const defaultRPCHealthCheckInterval = 30
+
+// the reconnection to a back-end which lost the connection, the backoff in milliseconds is doubled after each retry
+const (
+	defaultRPCReconnectRetries = 3
+	defaultRPCReconnectBackoff = 100
+	maxRPCReconnectBackoff     = 10 * time.Second
+)
+
+// rpcPrimaryMethods are always called on the primary back-end without failover,
+// the synchronization of the index must see a single consistent view of the blockchain and the transactions are sent by one node,
+// the mempool differs between the nodes and must be read from the same node, otherwise the transactions would flap in and out of the mempool
+var rpcPrimaryMethods = map[string]struct{}{
+	"getbestblockhash":   {},
+	"getblockcount":      {},
+	"getblockhash":       {},
+	"getblockheader":     {},
+	"getblock":           {},
+	"sendrawtransaction": {},
+	"getrawmempool":      {},
+	"getmempoolentry":    {},
+	"getmempoolinfo":     {},
+	"getrawtransaction":  {},
+}
+
+// rpcMethod returns the method of the RPC request, the requests are structs with the Method field
+func rpcMethod(req interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() == reflect.Struct {
+		if m := v.FieldByName("Method"); m.IsValid() && m.Kind() == reflect.String {
+			return m.String()
+		}
+	}
+	return ""
+}
+
+type rpcEndpoint struct {
+	url  string
+	name string
+	// 1 if the endpoint is healthy, accessed atomically
+	healthy int32
+}
+
+func (e *rpcEndpoint) isHealthy() bool {
+	return atomic.LoadInt32(&e.healthy) == 1
+}
+
+func (e *rpcEndpoint) setHealthy(healthy bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	if atomic.SwapInt32(&e.healthy, v) != v {
+		if healthy {
+			glog.Info("rpc: backend ", e.url, " is healthy")
+		} else {
+			glog.Warning("rpc: backend ", e.url, " is unhealthy")
+		}
+	}
+}
+
+// rpcPool distributes the RPC calls among the backend endpoints in round robin order, skipping the unhealthy endpoints
+// the first endpoint is the primary back-end
+type rpcPool struct {
+	endpoints []*rpcEndpoint
+	next      uint32
+	quit      chan struct{}
+}
+
+func newRPCPool(urls []string) *rpcPool {
+	p := &rpcPool{
+		endpoints: make([]*rpcEndpoint, len(urls)),
+		quit:      make(chan struct{}),
+	}
+	for i, u := range urls {
+		p.endpoints[i] = &rpcEndpoint{url: u, name: backendName(u), healthy: 1}
+	}
+	return p
+}
+
+// backendName returns the name of the back-end used in the X-Backend header, it is the host of the url without the credentials
+func backendName(u string) string {
+	if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return u
+}
+
+// endpoint returns the endpoint with the name or nil if there is no such endpoint
+func (p *rpcPool) endpoint(name string) *rpcEndpoint {
+	for _, e := range p.endpoints {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// get returns the endpoint for the next call, if no endpoint is healthy, all endpoints are used
+func (p *rpcPool) get() *rpcEndpoint {
+	if len(p.endpoints) == 1 {
+		return p.endpoints[0]
+	}
+	healthy := make([]*rpcEndpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.isHealthy() {
+			healthy = append(healthy, e)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = p.endpoints
+	}
+	n := atomic.AddUint32(&p.next, 1) - 1
+	return healthy[n%uint32(len(healthy))]
+}
+
+// GetBackendNames returns the names of the back-ends, the primary back-end is the first
+func (b *BitcoinRPC) GetBackendNames() []string {
+	names := make([]string, len(b.rpcPool.endpoints))
+	for i, e := range b.rpcPool.endpoints {
+		names[i] = e.name
+	}
+	return names
+}
+
+// callOn calls the RPC method on the given back-end, or on the pool of back-ends by Call if e is nil
+func (b *BitcoinRPC) callOn(e *rpcEndpoint, req interface{}, res interface{}) error {
+	if e == nil {
+		return b.Call(req, res)
+	}
+	return b.callEndpoint(e, req, res)
+}
+
+// callEndpoint calls the RPC method on the given back-end without failover
+func (b *BitcoinRPC) callEndpoint(e *rpcEndpoint, req interface{}, res interface{}) error {
+	httpData, err := b.RPCMarshaler.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return b.postURL(e.url, httpData, res)
+}
+
+// checkRPCHealth calls getblockcount on all backend endpoints and marks them healthy or unhealthy by the result
+func (b *BitcoinRPC) checkRPCHealth() {
+	for _, e := range b.rpcPool.endpoints {
+		res := ResGetBlockCount{}
+		httpData, err := b.RPCMarshaler.Marshal(&CmdGetBlockCount{Method: "getblockcount"})
+		// the check is repeated periodically, it does not retry the lost connection
+		if err == nil {
+			err = b.postURLOnce(e.url, httpData, &res)
+		}
+		if err == nil && res.Error != nil {
+			err = res.Error
+		}
+		if err != nil {
+			glog.V(1).Info("rpc: health check of ", e.url, " failed: ", err)
+		}
+		e.setHealthy(err == nil)
+	}
+}
+
+// rpcHealthCheckLoop periodically checks the health of the backend endpoints until the pool is closed
+func (b *BitcoinRPC) rpcHealthCheckLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.rpcPool.quit:
+			return
+		case <-ticker.C:
+			b.checkRPCHealth()
+		}
+	}
+}
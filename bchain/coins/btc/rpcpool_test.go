@@ -0,0 +1,253 @@
+//go:build unittest
+
+package btc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+// newTestRPCBackend creates a mock backend counting the calls of the methods, unhealthy backend returns http error to all calls
+func newTestRPCBackend(calls map[string]*int32, healthy bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if c, found := calls[req.Method]; found {
+			atomic.AddInt32(c, 1)
+		}
+		if !healthy {
+			http.Error(w, "backend is loading", http.StatusServiceUnavailable)
+			return
+		}
+		switch req.Method {
+		case "getblockcount":
+			fmt.Fprint(w, `{"result":100,"error":null,"id":"0"}`)
+		case "getbestblockhash":
+			fmt.Fprint(w, `{"result":"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f","error":null,"id":"0"}`)
+		case "getrawmempool":
+			fmt.Fprint(w, `{"result":[],"error":null,"id":"0"}`)
+		case "estimatesmartfee":
+			fmt.Fprint(w, `{"result":{"feerate":0.0001,"blocks":2},"error":null,"id":"0"}`)
+		case "getrawtransaction":
+			fmt.Fprint(w, `{"result":{"txid":"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},"error":null,"id":"0"}`)
+		case "getblock":
+			fmt.Fprint(w, `{"result":"00","error":null,"id":"0"}`)
+		default:
+			http.Error(w, "unexpected method "+req.Method, http.StatusBadRequest)
+		}
+	}))
+}
+
+func newTestBitcoinRPCWithBackends(t *testing.T, primary string, secondary ...string) *BitcoinRPC {
+	urls, _ := json.Marshal(secondary)
+	// the health check loop is not started by the long interval, the check is called explicitly
+	config := fmt.Sprintf(`{"coin_name":"Bitcoin","rpc_url":%q,"rpc_urls":%s,"rpc_timeout":5,"rpc_health_check_interval":3600}`, primary, urls)
+	chain, err := NewBitcoinRPC(json.RawMessage(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain.(*BitcoinRPC)
+}
+
+func TestRPCPool(t *testing.T) {
+	var calls [3]int32
+	ts0 := newTestRPCBackend(map[string]*int32{"estimatesmartfee": &calls[0]}, true)
+	ts1 := newTestRPCBackend(map[string]*int32{"estimatesmartfee": &calls[1]}, false)
+	ts2 := newTestRPCBackend(map[string]*int32{"estimatesmartfee": &calls[2]}, true)
+	defer ts0.Close()
+	defer ts1.Close()
+	defer ts2.Close()
+	b := newTestBitcoinRPCWithBackends(t, ts0.URL, ts1.URL, ts2.URL)
+	defer b.Shutdown(context.Background())
+
+	b.checkRPCHealth()
+	for i, want := range []bool{true, false, true} {
+		if got := b.rpcPool.endpoints[i].isHealthy(); got != want {
+			t.Errorf("endpoint %d healthy = %v, want %v", i, got, want)
+		}
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, err := b.EstimateSmartFee(2, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, want := range []int32{3, 0, 3} {
+		if got := atomic.LoadInt32(&calls[i]); got != want {
+			t.Errorf("endpoint %d calls = %v, want %v", i, got, want)
+		}
+	}
+
+	// the endpoint which cannot be connected is ejected from the pool
+	ts2.Close()
+	for i := 0; i < 4; i++ {
+		if _, err := b.EstimateSmartFee(2, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.rpcPool.endpoints[2].isHealthy() {
+		t.Error("closed endpoint is still healthy")
+	}
+	atomic.StoreInt32(&calls[0], 0)
+	for i := 0; i < 3; i++ {
+		if _, err := b.EstimateSmartFee(2, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls[0]); got != 3 {
+		t.Errorf("endpoint 0 calls = %v, want 3", got)
+	}
+}
+
+func TestRPCPool_Failover(t *testing.T) {
+	var primaryFee, primaryBest, primaryMempool, secondaryFee, secondaryBest, secondaryMempool int32
+	primary := newTestRPCBackend(map[string]*int32{"estimatesmartfee": &primaryFee, "getbestblockhash": &primaryBest, "getrawmempool": &primaryMempool}, false)
+	secondary := newTestRPCBackend(map[string]*int32{"estimatesmartfee": &secondaryFee, "getbestblockhash": &secondaryBest, "getrawmempool": &secondaryMempool}, true)
+	defer primary.Close()
+	defer secondary.Close()
+	b := newTestBitcoinRPCWithBackends(t, primary.URL, secondary.URL)
+	defer b.Shutdown(context.Background())
+
+	// the failing primary is not yet detected by the health check, the reads fail over to the secondary
+	for i := 0; i < 4; i++ {
+		if _, err := b.EstimateSmartFee(2, true); err != nil {
+			t.Fatalf("EstimateSmartFee() error = %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&secondaryFee); got != 4 {
+		t.Errorf("secondary estimatesmartfee calls = %v, want 4", got)
+	}
+	if got := atomic.LoadInt32(&primaryFee); got == 0 {
+		t.Error("primary estimatesmartfee was not called")
+	}
+
+	// the sync and mempool calls stay on the primary
+	if _, err := b.GetBestBlockHash(); err == nil {
+		t.Error("GetBestBlockHash() expected error from the primary")
+	}
+	if _, err := b.GetMempoolTransactions(); err == nil {
+		t.Error("GetMempoolTransactions() expected error from the primary")
+	}
+	for _, c := range []struct {
+		method             string
+		primary, secondary *int32
+	}{
+		{"getbestblockhash", &primaryBest, &secondaryBest},
+		{"getrawmempool", &primaryMempool, &secondaryMempool},
+	} {
+		if got := atomic.LoadInt32(c.primary); got != 1 {
+			t.Errorf("primary %s calls = %v, want 1", c.method, got)
+		}
+		if got := atomic.LoadInt32(c.secondary); got != 0 {
+			t.Errorf("secondary %s calls = %v, want 0", c.method, got)
+		}
+	}
+}
+
+func TestGetTransactionSpecificFromBackend(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	primary := newTestRPCBackend(map[string]*int32{"getrawtransaction": &primaryCalls}, true)
+	secondary := newTestRPCBackend(map[string]*int32{"getrawtransaction": &secondaryCalls}, true)
+	defer primary.Close()
+	defer secondary.Close()
+	b := newTestBitcoinRPCWithBackends(t, primary.URL, secondary.URL)
+	defer b.Shutdown(context.Background())
+
+	names := b.GetBackendNames()
+	want := []string{strings.TrimPrefix(primary.URL, "http://"), strings.TrimPrefix(secondary.URL, "http://")}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("GetBackendNames() = %v, want %v", names, want)
+	}
+
+	tx := &bchain.Tx{Txid: "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"}
+	for i := 0; i < 3; i++ {
+		if _, err := b.GetTransactionSpecificFromBackend(names[1], tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&secondaryCalls); got != 3 {
+		t.Errorf("secondary getrawtransaction calls = %v, want 3", got)
+	}
+	if got := atomic.LoadInt32(&primaryCalls); got != 0 {
+		t.Errorf("primary getrawtransaction calls = %v, want 0", got)
+	}
+
+	if _, err := b.GetTransactionSpecificFromBackend("unknown:8030", tx); err == nil {
+		t.Error("GetTransactionSpecificFromBackend() expected error for unknown backend")
+	}
+}
+
+func TestFromBackend(t *testing.T) {
+	const hash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	tests := []struct {
+		name   string
+		method string
+		call   func(b *BitcoinRPC, backend string) error
+	}{
+		{
+			name:   "GetBlockSpecificFromBackend",
+			method: "getblock",
+			call: func(b *BitcoinRPC, backend string) error {
+				_, err := b.GetBlockSpecificFromBackend(backend, hash)
+				return err
+			},
+		},
+		{
+			name:   "GetBlockRawFromBackend",
+			method: "getblock",
+			call: func(b *BitcoinRPC, backend string) error {
+				_, err := b.GetBlockRawFromBackend(backend, hash)
+				return err
+			},
+		},
+		{
+			name:   "EstimateSmartFeeFromBackend",
+			method: "estimatesmartfee",
+			call: func(b *BitcoinRPC, backend string) error {
+				_, err := b.EstimateSmartFeeFromBackend(backend, 2, true)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryCalls, secondaryCalls int32
+			primary := newTestRPCBackend(map[string]*int32{tt.method: &primaryCalls}, true)
+			secondary := newTestRPCBackend(map[string]*int32{tt.method: &secondaryCalls}, true)
+			defer primary.Close()
+			defer secondary.Close()
+			b := newTestBitcoinRPCWithBackends(t, primary.URL, secondary.URL)
+			defer b.Shutdown(context.Background())
+
+			names := b.GetBackendNames()
+			for i := 0; i < 3; i++ {
+				if err := tt.call(b, names[1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := atomic.LoadInt32(&secondaryCalls); got != 3 {
+				t.Errorf("secondary %s calls = %v, want 3", tt.method, got)
+			}
+			if got := atomic.LoadInt32(&primaryCalls); got != 0 {
+				t.Errorf("primary %s calls = %v, want 0", tt.method, got)
+			}
+			if err := tt.call(b, "unknown:8030"); err == nil {
+				t.Errorf("%s expected error for unknown backend", tt.name)
+			}
+		})
+	}
+}
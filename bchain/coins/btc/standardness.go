@@ -0,0 +1,333 @@
+package btc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/martinboehm/btcutil/txscript"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// standardness of transactions by the default relay policy of Bitcoin Core
+// the transactions which are not standard are valid in a block but are not relayed and mined by the default nodes
+// the size of the data carrier (OP_RETURN) outputs and the dust relay fee can be configured to match the policy of the backend,
+// the defaults are the defaults of Bitcoin Core v30
+
+const (
+	maxStandardTxVersion      = 3
+	maxStandardTxWeight       = 400000
+	maxStandardScriptSigSize  = 1650
+	maxStandardTxSigOpsCost   = 16000
+	maxStandardMultisigKeys   = 3
+	maxP2SHSigOps             = 15
+	defaultMaxDataCarrierSize = 100000
+	defaultDustRelayFeePerKb  = 3000
+	witnessScaleFactor        = 4
+	sigOpsPerMultisig         = 20
+)
+
+// standardnessPolicy is the part of the relay policy of the backend which can be configured
+type standardnessPolicy struct {
+	// maxDataCarrierSize is the maximal total size of the OP_RETURN output scripts, 0 means that OP_RETURN outputs are not standard
+	maxDataCarrierSize int
+	// singleDataCarrier allows only one OP_RETURN output, as Bitcoin Core before v30
+	singleDataCarrier bool
+	// dustRelayFeePerKb is the fee rate in satoshis per kvB used to compute the dust threshold
+	dustRelayFeePerKb int64
+}
+
+// newStandardnessPolicy returns the policy set by the configuration, a zero datacarrier_size means the default size,
+// a negative one disables the OP_RETURN outputs
+func newStandardnessPolicy(c *Configuration) standardnessPolicy {
+	sp := standardnessPolicy{
+		maxDataCarrierSize: defaultMaxDataCarrierSize,
+		dustRelayFeePerKb:  defaultDustRelayFeePerKb,
+	}
+	if c == nil {
+		return sp
+	}
+	if c.DataCarrierSize > 0 {
+		sp.maxDataCarrierSize = c.DataCarrierSize
+	} else if c.DataCarrierSize < 0 {
+		sp.maxDataCarrierSize = 0
+	}
+	sp.singleDataCarrier = c.SingleDataCarrier
+	if c.DustRelayFee > 0 {
+		sp.dustRelayFeePerKb = c.DustRelayFee
+	}
+	return sp
+}
+
+// the reasons of nonstandard transactions are the reject reasons of Bitcoin Core
+const (
+	NonStandardVersion       = "version"
+	NonStandardTxSize        = "tx-size"
+	NonStandardCoinbase      = "coinbase"
+	NonStandardScriptSigSize = "scriptsig-size"
+	NonStandardScriptSigPush = "scriptsig-not-pushonly"
+	NonStandardScriptPubKey  = "scriptpubkey"
+	NonStandardDust          = "dust"
+	NonStandardDataCarrier   = "datacarrier"
+	NonStandardMultiOpReturn = "multi-op-return"
+	NonStandardInputs        = "bad-txns-nonstandard-inputs"
+	NonStandardSigOps        = "bad-txns-too-many-sigops"
+)
+
+type scriptOp struct {
+	opcode byte
+	data   []byte
+}
+
+// parseScriptOps splits the script to opcodes with their pushed data, returns false if a push exceeds the script
+func parseScriptOps(script []byte) ([]scriptOp, bool) {
+	var ops []scriptOp
+	l := len(script)
+	for i := 0; i < l; {
+		op := script[i]
+		i++
+		n := 0
+		switch {
+		case op < txscript.OP_PUSHDATA1:
+			n = int(op)
+		case op == txscript.OP_PUSHDATA1:
+			if i+1 > l {
+				return nil, false
+			}
+			n = int(script[i])
+			i++
+		case op == txscript.OP_PUSHDATA2:
+			if i+2 > l {
+				return nil, false
+			}
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == txscript.OP_PUSHDATA4:
+			if i+4 > l {
+				return nil, false
+			}
+			v := binary.LittleEndian.Uint32(script[i:])
+			if v > uint32(l) {
+				return nil, false
+			}
+			n = int(v)
+			i += 4
+		}
+		if i+n > l {
+			return nil, false
+		}
+		ops = append(ops, scriptOp{opcode: op, data: script[i : i+n]})
+		i += n
+	}
+	return ops, true
+}
+
+func isPushOnly(ops []scriptOp) bool {
+	for _, op := range ops {
+		if op.opcode > txscript.OP_16 {
+			return false
+		}
+	}
+	return true
+}
+
+// countSigOps counts the signature operations of the script as GetSigOpCount of Bitcoin Core, by the legacy counting
+// each multisig counts as the maximum number of keys, by the accurate counting (used for P2SH redeem scripts)
+// a multisig preceded by the number of keys counts as the number of keys
+func countSigOps(ops []scriptOp, accurate bool) int {
+	n := 0
+	var lastOpcode byte = txscript.OP_INVALIDOPCODE
+	for _, op := range ops {
+		switch op.opcode {
+		case txscript.OP_CHECKSIG, txscript.OP_CHECKSIGVERIFY:
+			n++
+		case txscript.OP_CHECKMULTISIG, txscript.OP_CHECKMULTISIGVERIFY:
+			if accurate && lastOpcode >= txscript.OP_1 && lastOpcode <= txscript.OP_16 {
+				n += smallIntValue(lastOpcode)
+			} else {
+				n += sigOpsPerMultisig
+			}
+		}
+		lastOpcode = op.opcode
+	}
+	return n
+}
+
+// isP2SH returns true if the output script is pay to script hash
+func isP2SH(script []byte) bool {
+	return len(script) == 23 && script[0] == txscript.OP_HASH160 && script[1] == txscript.OP_DATA_20 && script[22] == txscript.OP_EQUAL
+}
+
+// p2shSigOps counts the signature operations of the redeem script, which is the last push of the scriptSig spending a P2SH output
+func p2shSigOps(scriptSigOps []scriptOp) (int, bool) {
+	if len(scriptSigOps) == 0 {
+		return 0, true
+	}
+	ops, ok := parseScriptOps(scriptSigOps[len(scriptSigOps)-1].data)
+	if !ok {
+		return 0, false
+	}
+	return countSigOps(ops, true), true
+}
+
+func isSmallInt(opcode byte) bool {
+	return opcode == txscript.OP_0 || (opcode >= txscript.OP_1 && opcode <= txscript.OP_16)
+}
+
+func smallIntValue(opcode byte) int {
+	if opcode == txscript.OP_0 {
+		return 0
+	}
+	return int(opcode-txscript.OP_1) + 1
+}
+
+// outputWitnessProgram returns true if the output script is a witness program,
+// version 0 programs must be 20 or 32 bytes long, the programs of other versions are standard with any length
+func outputWitnessProgram(script []byte) (isProgram bool, valid bool) {
+	l := len(script)
+	if l < 4 || l > maxWitnessProgramBytes+2 || !isSmallInt(script[0]) || int(script[1]) != l-2 {
+		return false, false
+	}
+	if script[0] == txscript.OP_0 {
+		return true, l == 22 || l == 34
+	}
+	return true, true
+}
+
+// bareMultisigKeys returns the number of keys of the bare multisig output script m <keys> n OP_CHECKMULTISIG
+func bareMultisigKeys(ops []scriptOp) (int, bool) {
+	l := len(ops)
+	if l < 4 || ops[l-1].opcode != txscript.OP_CHECKMULTISIG || !isSmallInt(ops[0].opcode) || !isSmallInt(ops[l-2].opcode) {
+		return 0, false
+	}
+	m := smallIntValue(ops[0].opcode)
+	n := smallIntValue(ops[l-2].opcode)
+	if n != l-3 || m < 1 || m > n {
+		return 0, false
+	}
+	for _, op := range ops[1 : l-2] {
+		if len(op.data) != 33 && len(op.data) != 65 {
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+// isStandardOutputScript returns true if the output script is of a standard type (excluding OP_RETURN outputs),
+// bare multisig outputs are standard with at most 3 keys
+func isStandardOutputScript(script []byte, ops []scriptOp) bool {
+	l := len(script)
+	// P2PKH
+	if l == 25 && script[0] == txscript.OP_DUP && script[1] == txscript.OP_HASH160 && script[2] == txscript.OP_DATA_20 &&
+		script[23] == txscript.OP_EQUALVERIFY && script[24] == txscript.OP_CHECKSIG {
+		return true
+	}
+	if isP2SH(script) {
+		return true
+	}
+	if isProgram, valid := outputWitnessProgram(script); isProgram {
+		return valid
+	}
+	// P2PK
+	if (l == 35 && script[0] == txscript.OP_DATA_33 || l == 67 && script[0] == txscript.OP_DATA_65) && script[l-1] == txscript.OP_CHECKSIG {
+		return true
+	}
+	if keys, ok := bareMultisigKeys(ops); ok {
+		return keys <= maxStandardMultisigKeys
+	}
+	return false
+}
+
+// dustThreshold returns the minimal value of the output not considered dust,
+// which is the fee at the dust relay fee rate to create and spend the output
+func dustThreshold(script []byte, dustRelayFeePerKb int64) int64 {
+	size := 8 + len(script) + 1
+	if len(script) >= 0xfd {
+		size += 2
+	}
+	if isProgram, _ := outputWitnessProgram(script); isProgram {
+		// outpoint, scriptSig length, sequence and the witness discounted by the witness scale factor
+		size += 32 + 4 + 1 + 107/witnessScaleFactor + 4
+	} else {
+		size += 32 + 4 + 1 + 107 + 4
+	}
+	return int64(size) * dustRelayFeePerKb / 1000
+}
+
+// IsStandardTx checks the transaction against the standardness policy of Bitcoin Core, i.e. the version, the size,
+// the input scripts, the types of output scripts including bare multisig with at most 3 keys, the size and the count of OP_RETURN outputs,
+// dust outputs and signature operations; prevOutputs are the output scripts spent by the inputs (nil items if not known),
+// the signature operations of the P2SH redeem scripts are counted only for the known P2SH outputs and the witness signature
+// operations are not counted; returns false and the reject reason of Bitcoin Core if the transaction is not standard
+func (p *BitcoinParser) IsStandardTx(tx *bchain.Tx, prevOutputs []bchain.AddressDescriptor) (bool, string, error) {
+	if tx.Version < 1 || tx.Version > maxStandardTxVersion {
+		return false, NonStandardVersion, nil
+	}
+	weight := tx.VSize * witnessScaleFactor
+	if weight == 0 {
+		weight = int64(len(tx.Hex)>>1) * witnessScaleFactor
+	}
+	if weight > maxStandardTxWeight {
+		return false, NonStandardTxSize, nil
+	}
+	sigOps := 0
+	for i := range tx.Vin {
+		if tx.Vin[i].Coinbase != "" {
+			return false, NonStandardCoinbase, nil
+		}
+		scriptSig, err := hex.DecodeString(tx.Vin[i].ScriptSig.Hex)
+		if err != nil {
+			return false, NonStandardScriptSigPush, nil
+		}
+		if len(scriptSig) > maxStandardScriptSigSize {
+			return false, NonStandardScriptSigSize, nil
+		}
+		ops, ok := parseScriptOps(scriptSig)
+		if !ok || !isPushOnly(ops) {
+			return false, NonStandardScriptSigPush, nil
+		}
+		sigOps += countSigOps(ops, false)
+		if i < len(prevOutputs) && isP2SH(prevOutputs[i]) {
+			n, ok := p2shSigOps(ops)
+			if !ok || n > maxP2SHSigOps {
+				return false, NonStandardInputs, nil
+			}
+			sigOps += n
+		}
+	}
+	dataOutputs, dataSize := 0, 0
+	for i := range tx.Vout {
+		script, err := hex.DecodeString(tx.Vout[i].ScriptPubKey.Hex)
+		if err != nil {
+			return false, NonStandardScriptPubKey, nil
+		}
+		ops, ok := parseScriptOps(script)
+		if !ok {
+			return false, NonStandardScriptPubKey, nil
+		}
+		sigOps += countSigOps(ops, false)
+		if len(script) > 0 && script[0] == txscript.OP_RETURN {
+			if !isPushOnly(ops[1:]) {
+				return false, NonStandardScriptPubKey, nil
+			}
+			dataOutputs++
+			dataSize += len(script)
+			continue
+		}
+		if !isStandardOutputScript(script, ops) {
+			return false, NonStandardScriptPubKey, nil
+		}
+		if tx.Vout[i].ValueSat.Int64() < dustThreshold(script, p.standardness.dustRelayFeePerKb) {
+			return false, NonStandardDust, nil
+		}
+	}
+	if dataOutputs > 1 && p.standardness.singleDataCarrier {
+		return false, NonStandardMultiOpReturn, nil
+	}
+	if dataSize > p.standardness.maxDataCarrierSize {
+		return false, NonStandardDataCarrier, nil
+	}
+	if sigOps*witnessScaleFactor > maxStandardTxSigOpsCost {
+		return false, NonStandardSigOps, nil
+	}
+	return true, "", nil
+}
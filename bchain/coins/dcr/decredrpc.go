@@ -199,6 +199,11 @@ type GetBlockResult struct {
 	} `json:"result"`
 }
 
+type GetBlockHeaderHexResult struct {
+	Error  Error  `json:"error"`
+	Result string `json:"result"`
+}
+
 type GetBlockHeaderResult struct {
 	Error  Error `json:"error"`
 	Result struct {
@@ -463,6 +468,26 @@ func (d *DecredRPC) getBlockHashByHeight(height uint32) (*GetBlockHashResult, er
 	return &blockHashResult, nil
 }
 
+// GetBlockHeaderHex returns the serialized block header of the block with the provided block hash as hex string.
+func (d *DecredRPC) GetBlockHeaderHex(hash string) (string, error) {
+	blockHeaderRequest := GenericCmd{
+		ID:     1,
+		Method: "getblockheader",
+		Params: []interface{}{hash, false},
+	}
+
+	var blockHeader GetBlockHeaderHexResult
+	if err := d.Call(blockHeaderRequest, &blockHeader); err != nil {
+		return "", err
+	}
+
+	if blockHeader.Error.Message != "" {
+		return "", mapToStandardErr("Error fetching block header: %s", blockHeader.Error)
+	}
+
+	return blockHeader.Result, nil
+}
+
 // GetBlockHeader returns the block header of the block the provided block hash.
 func (d *DecredRPC) GetBlockHeader(hash string) (*bchain.BlockHeader, error) {
 	blockHeaderRequest := GenericCmd{
@@ -653,6 +678,11 @@ func (d *DecredRPC) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
 	return bInfo, nil
 }
 
+// GetTransactions is not supported, the transactions must be fetched one by one using GetTransaction
+func (d *DecredRPC) GetTransactions(txids []string) ([]*bchain.Tx, error) {
+	return nil, errors.New("GetTransactions: not supported")
+}
+
 // GetTransaction returns a transaction by the transaction ID
 func (d *DecredRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	r, err := d.getRawTransaction(txid)
@@ -198,3 +198,23 @@ func Test_UnpackTx(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnpackTx_TxTime(t *testing.T) {
+	parser := NewDeepOnionParser(GetChainParams("main"), &btc.Configuration{})
+	// pack the transaction with a block time different from the timestamp of the transaction
+	const blockTime = 1564554300
+	packed, err := parser.PackTx(&testTx1, 1377592, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := parser.UnpackTx(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Time != testTx1.Time {
+		t.Errorf("UnpackTx() Time = %v, want %v", got.Time, testTx1.Time)
+	}
+	if got.Blocktime != blockTime {
+		t.Errorf("UnpackTx() Blocktime = %v, want %v", got.Blocktime, blockTime)
+	}
+}
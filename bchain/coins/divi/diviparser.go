@@ -116,6 +116,11 @@ func (p *DivicoinParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 	return p.baseparser.UnpackTx(buf)
 }
 
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+func (p *DivicoinParser) IsCoinStake(tx *bchain.Tx) bool {
+	return bchain.IsCoinStakeTx(tx)
+}
+
 // ParseTx parses byte array containing transaction and returns Tx struct
 func (p *DivicoinParser) ParseTx(b []byte) (*bchain.Tx, error) {
 	t := wire.MsgTx{}
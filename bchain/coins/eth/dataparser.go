@@ -97,6 +97,35 @@ func GetSignatureFromData(data string) uint32 {
 	return uint32(sig)
 }
 
+// commonFourByteSignatures is a bundled set of signatures of the common token methods,
+// used to decode the input data if the signature is not found in the 4byte signature database
+var commonFourByteSignatures = map[uint32][]bchain.FourByteSignature{
+	0xa9059cbb: {{Name: "transfer", Parameters: []string{"address", "uint256"}}},
+	0x095ea7b3: {{Name: "approve", Parameters: []string{"address", "uint256"}}},
+	0x23b872dd: {{Name: "transferFrom", Parameters: []string{"address", "address", "uint256"}}},
+	0x42842e0e: {{Name: "safeTransferFrom", Parameters: []string{"address", "address", "uint256"}}},
+	0xb88d4fde: {{Name: "safeTransferFrom", Parameters: []string{"address", "address", "uint256", "bytes"}}},
+	0xa22cb465: {{Name: "setApprovalForAll", Parameters: []string{"address", "bool"}}},
+	0xf242432a: {{Name: "safeTransferFrom", Parameters: []string{"address", "address", "uint256", "uint256", "bytes"}}},
+	0x2eb2c2d6: {{Name: "safeBatchTransferFrom", Parameters: []string{"address", "address", "uint256[]", "uint256[]", "bytes"}}},
+	0xd0e30db0: {{Name: "deposit", Parameters: []string{}}},
+	0x2e1a7d4d: {{Name: "withdraw", Parameters: []string{"uint256"}}},
+}
+
+// GetCommonFourByteSignatures returns the bundled signatures of the fourBytes or nil if the fourBytes are not in the bundled set
+// a copy is returned, ParseInputData stores the processed parameters in the signatures
+func GetCommonFourByteSignatures(fourBytes uint32) *[]bchain.FourByteSignature {
+	s, found := commonFourByteSignatures[fourBytes]
+	if !found {
+		return nil
+	}
+	r := make([]bchain.FourByteSignature, len(s))
+	for i := range s {
+		r[i] = bchain.FourByteSignature{Name: s[i].Name, Parameters: s[i].Parameters}
+	}
+	return &r
+}
+
 const ErrorTy byte = 255
 
 func processParam(data string, index int, dataOffset int, t *abi.Type, processed []bool) ([]string, int, bool) {
@@ -428,6 +428,75 @@ func TestParseInputData(t *testing.T) {
 	}
 }
 
+func TestParseInputData_commonSignatures(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *bchain.EthereumParsedInputData
+	}{
+		{
+			name: "transfer",
+			data: "0xa9059cbb000000000000000000000000b80e5aaa2131c07568128f68b8538ed3c89512340000000000000000000000000000000000000000000000000de0b6b3a7640000",
+			want: &bchain.EthereumParsedInputData{
+				MethodId: "0xa9059cbb",
+				Name:     "Transfer",
+				Function: "transfer(address, uint256)",
+				Params: []bchain.EthereumParsedInputParam{
+					{
+						Type:   "address",
+						Values: []string{"0xB80e5AaA2131c07568128f68b8538eD3C8951234"},
+					},
+					{
+						Type:   "uint256",
+						Values: []string{"1000000000000000000"},
+					},
+				},
+			},
+		},
+		{
+			name: "setApprovalForAll",
+			data: "0xa22cb4650000000000000000000000009f64b014ca26f2def573246543dd1115b229e4f40000000000000000000000000000000000000000000000000000000000000001",
+			want: &bchain.EthereumParsedInputData{
+				MethodId: "0xa22cb465",
+				Name:     "Set Approval For All",
+				Function: "setApprovalForAll(address, bool)",
+				Params: []bchain.EthereumParsedInputParam{
+					{
+						Type:   "address",
+						Values: []string{"0x9f64B014CA26F2DeF573246543DD1115b229e4F4"},
+					},
+					{
+						Type:   "bool",
+						Values: []string{"true"},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown method",
+			data: "0xf305d719000000000000000000000000b80e5aaa2131c07568128f68b8538ed3c8951234",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signatures := GetCommonFourByteSignatures(GetSignatureFromData(tt.data))
+			if signatures == nil {
+				if tt.want != nil {
+					t.Fatal("GetCommonFourByteSignatures() = nil")
+				}
+				return
+			}
+			if got := ParseInputData(signatures, tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInputData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// the bundled signatures are not modified by the parsing
+	if s := commonFourByteSignatures[0xa9059cbb][0]; s.DecamelName != "" || s.ParsedParameters != nil {
+		t.Errorf("commonFourByteSignatures modified %+v", s)
+	}
+}
+
 func Test_getEnsRecord(t *testing.T) {
 	tests := []struct {
 		name string
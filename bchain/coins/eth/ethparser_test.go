@@ -401,6 +401,43 @@ func TestEthereumParser_GetEthereumTxData(t *testing.T) {
 	}
 }
 
+func TestEthereumParser_GetEthereumTxData_Status(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want TxStatus
+	}{
+		{
+			name: "successful tx",
+			tx:   &testTx1,
+			want: TxStatusOK,
+		},
+		{
+			name: "reverted tx",
+			tx:   &testTx1Failed,
+			want: TxStatusFailure,
+		},
+		{
+			name: "tx without status in receipt",
+			tx:   &testTx1NoStatus,
+			want: TxStatusUnknown,
+		},
+		{
+			name: "pending tx",
+			tx:   &bchain.Tx{CoinSpecificData: bchain.EthereumSpecificData{Tx: testTx1.CoinSpecificData.(bchain.EthereumSpecificData).Tx}},
+			want: TxStatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetEthereumTxData(tt.tx)
+			if got.Status != tt.want {
+				t.Errorf("EthereumParser.GetEthereumTxData() status = %v, want %v", got.Status, tt.want)
+			}
+		})
+	}
+}
+
 func TestEthereumParser_ParseErrorFromOutput(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -170,7 +170,7 @@ func (b *EthereumRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, er
 }
 
 // InitializeMempool creates subscriptions to newHeads and newPendingTransactions
-func (b *EthereumRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) error {
+func (b *EthereumRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, spendingTxidForOutpoint bchain.SpendingTxidForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
 	if b.Mempool == nil {
 		return errors.New("Mempool not created")
 	}
@@ -410,6 +410,25 @@ func (b *EthereumRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 	return rv, nil
 }
 
+// backendChainInfoMethods are the methods of the backend describing the chain and the state of the node
+var backendChainInfoMethods = []string{"eth_chainId", "net_version", "eth_blockNumber", "eth_syncing", "web3_clientVersion"}
+
+// GetBackendChainInfoRaw returns the results of the backend methods describing the chain, as returned by the backend,
+// in an object with the method names as keys
+func (b *EthereumRPC) GetBackendChainInfoRaw() (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	r := make(map[string]json.RawMessage, len(backendChainInfoMethods))
+	for _, method := range backendChainInfoMethods {
+		var v json.RawMessage
+		if err := b.RPC.CallContext(ctx, &v, method); err != nil {
+			return nil, errors.Annotatef(err, "%v", method)
+		}
+		r[method] = v
+	}
+	return json.Marshal(r)
+}
+
 func (b *EthereumRPC) getBestHeader() (bchain.EVMHeader, error) {
 	b.bestHeaderLock.Lock()
 	defer b.bestHeaderLock.Unlock()
@@ -976,6 +995,13 @@ func (b *EthereumRPC) EthereumTypeGetNonce(addrDesc bchain.AddressDescriptor) (u
 	return b.Client.NonceAt(ctx, addrDesc, nil)
 }
 
+// EthereumTypeGetCode returns the current contract code of an address, empty for externally owned accounts
+func (b *EthereumRPC) EthereumTypeGetCode(addrDesc bchain.AddressDescriptor) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	return b.Client.CodeAt(ctx, addrDesc, nil)
+}
+
 // GetChainParser returns ethereum BlockChainParser
 func (b *EthereumRPC) GetChainParser() bchain.BlockChainParser {
 	return b.Parser
@@ -42,6 +42,11 @@ func (c *EthereumClient) NonceAt(ctx context.Context, addrDesc bchain.AddressDes
 	return c.Client.NonceAt(ctx, common.BytesToAddress(addrDesc), blockNumber)
 }
 
+// CodeAt returns the contract code of the given account at a specific block, or latest known block if no block number is provided
+func (c *EthereumClient) CodeAt(ctx context.Context, addrDesc bchain.AddressDescriptor, blockNumber *big.Int) ([]byte, error) {
+	return c.Client.CodeAt(ctx, common.BytesToAddress(addrDesc), blockNumber)
+}
+
 // EthereumRPCClient wraps an rpc client to implement the EVMRPCClient interface
 type EthereumRPCClient struct {
 	*rpc.Client
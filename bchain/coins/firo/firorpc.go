@@ -193,6 +193,11 @@ func (zc *FiroRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
 	return tx, nil
 }
 
+// GetTransactions is not supported, the transactions must be fetched one by one using GetTransaction
+func (zc *FiroRPC) GetTransactions(txids []string) ([]*bchain.Tx, error) {
+	return nil, errors.New("GetTransactions: not supported")
+}
+
 func (zc *FiroRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	r, err := zc.getRawTransaction(txid)
 	if err != nil {
@@ -109,6 +109,11 @@ func (z *KotoRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not implemented")
 }
 
+// GetMempoolEntries returns mempool data for given transactions
+func (z *KotoRPC) GetMempoolEntries(txids []string) ([]*bchain.MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not implemented")
+}
+
 func isErrBlockNotFound(err *bchain.RPCError) bool {
 	return err.Message == "Block not found" ||
 		err.Message == "Block height out of range"
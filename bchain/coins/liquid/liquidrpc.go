@@ -107,3 +107,8 @@ func (b *LiquidRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
 func (b *LiquidRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not implemented")
 }
+
+// GetMempoolEntries returns mempool data for given transactions
+func (b *LiquidRPC) GetMempoolEntries(txids []string) ([]*bchain.MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not implemented")
+}
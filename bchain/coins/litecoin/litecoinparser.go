@@ -1,6 +1,8 @@
 package litecoin
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 
 	"github.com/golang/glog"
@@ -119,6 +121,13 @@ func (p *LitecoinParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error
 		tx.Blocktime = fTx.Blocktime
 	}
 
+	if bytes.Contains(msg, []byte(`"ismweb"`)) {
+		if err = removeMwebComponents(msg, &tx); err != nil {
+			return nil, err
+		}
+	}
+	tx.Mweb |= getMwebScriptFlags(&tx)
+
 	for i := range tx.Vout {
 		vout := &tx.Vout[i]
 		// convert vout.JsonValue to big.Int and clear it, it is only temporary value used for unmarshal
@@ -132,6 +141,84 @@ func (p *LitecoinParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error
 	return &tx, nil
 }
 
+// MWEB (MimbleWimble Extension Block, LIP-0002 and LIP-0003)
+// the extension block is connected to the canonical chain by the integrating (HogEx) transaction, the last transaction of the block;
+// its first output pays to the HogAddr (witness version 8 program) holding all the coins of the extension block,
+// the other outputs are pegouts; pegin outputs are witness version 9 programs with the kernel id
+// the backend returns the confidential MWEB inputs and outputs with the flag ismweb and without txid, value and script,
+// they are placed after the canonical inputs and outputs, so removing them does not change the indexes of the canonical ones
+const (
+	mwebHogAddrVersion = 0x58 // OP_8
+	mwebPeginVersion   = 0x59 // OP_9
+	mwebProgramLen     = 32
+)
+
+type mwebComponent struct {
+	IsMweb bool `json:"ismweb"`
+}
+
+type mwebComponents struct {
+	Vin  []mwebComponent `json:"vin"`
+	Vout []mwebComponent `json:"vout"`
+}
+
+// removeMwebComponents removes the confidential MWEB inputs and outputs from the transaction, which cannot be indexed,
+// and sets the flags of the transaction accordingly
+func removeMwebComponents(msg json.RawMessage, tx *bchain.Tx) error {
+	var c mwebComponents
+	if err := json.Unmarshal(msg, &c); err != nil {
+		return err
+	}
+	if len(c.Vin) == len(tx.Vin) {
+		vin := tx.Vin[:0]
+		for i := range c.Vin {
+			if c.Vin[i].IsMweb {
+				tx.Mweb |= bchain.MwebInputs
+			} else {
+				vin = append(vin, tx.Vin[i])
+			}
+		}
+		tx.Vin = vin
+	}
+	if len(c.Vout) == len(tx.Vout) {
+		vout := tx.Vout[:0]
+		for i := range c.Vout {
+			if c.Vout[i].IsMweb {
+				tx.Mweb |= bchain.MwebOutputs
+			} else {
+				vout = append(vout, tx.Vout[i])
+			}
+		}
+		tx.Vout = vout
+	}
+	return nil
+}
+
+// isMwebScript returns true if the script is a witness program of the given version with the MWEB program length
+func isMwebScript(script []byte, version byte) bool {
+	return len(script) == mwebProgramLen+2 && script[0] == version && script[1] == mwebProgramLen
+}
+
+// getMwebScriptFlags returns the MWEB flags of the transaction recognized from the output scripts
+func getMwebScriptFlags(tx *bchain.Tx) bchain.MwebFlags {
+	var flags bchain.MwebFlags
+	for i := range tx.Vout {
+		script, err := hex.DecodeString(tx.Vout[i].ScriptPubKey.Hex)
+		if err != nil {
+			continue
+		}
+		if i == 0 && isMwebScript(script, mwebHogAddrVersion) {
+			flags |= bchain.MwebHogEx
+			if len(tx.Vout) > 1 {
+				flags |= bchain.MwebPegout
+			}
+		} else if isMwebScript(script, mwebPeginVersion) {
+			flags |= bchain.MwebPegin
+		}
+	}
+	return flags
+}
+
 // PackTx packs transaction to byte array using protobuf
 func (p *LitecoinParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64) ([]byte, error) {
 	return p.baseparser.PackTx(tx, height, blockTime)
@@ -367,3 +367,69 @@ func Test_UnpackTx(t *testing.T) {
 		})
 	}
 }
+
+func Test_ParseTxFromJson_Mweb(t *testing.T) {
+	parser := NewLitecoinParser(GetChainParams("main"), &btc.Configuration{})
+	tests := []struct {
+		name     string
+		json     string
+		wantMweb bchain.MwebFlags
+		wantVin  int
+		wantVout []int64
+	}{
+		{
+			name:     "HogEx with pegout",
+			json:     `{"txid":"f6d2d1b0f1a1e3b5c2e0d8a8e6a3c9b4d5e1f2a3b4c5d6e7f8091a2b3c4d5e6f","version":2,"locktime":0,"vin":[{"txid":"0b5f1c4d7e2a9b3c6d8e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e","vout":0,"scriptSig":{"asm":"","hex":""},"sequence":4294967295},{"txid":"4e3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a","vout":0,"scriptSig":{"asm":"","hex":""},"sequence":4294967295}],"vout":[{"value":1523.42187500,"n":0,"scriptPubKey":{"asm":"8 8d7bdc3a7fa5c1cb3b2a9a8d2a0c10ac6de5b2e0fa1f4c2c4c9f6a1e3e4f2b10","hex":"58208d7bdc3a7fa5c1cb3b2a9a8d2a0c10ac6de5b2e0fa1f4c2c4c9f6a1e3e4f2b10","type":"witness_mweb_hogaddr"}},{"value":0.50000000,"n":1,"scriptPubKey":{"asm":"0 9b3a94d4b3d53a08e9a1a1e6b1f6c1cf0fbc2f9e","hex":"00149b3a94d4b3d53a08e9a1a1e6b1f6c1cf0fbc2f9e","type":"witness_v0_keyhash"}}]}`,
+			wantMweb: bchain.MwebHogEx | bchain.MwebPegout,
+			wantVin:  2,
+			wantVout: []int64{152342187500, 50000000},
+		},
+		{
+			name:     "pegin",
+			json:     `{"txid":"4e3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a","version":2,"locktime":0,"vin":[{"txid":"c5d1f6a0b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2","vout":1,"scriptSig":{"asm":"","hex":""},"txinwitness":["3044022047ac8e878352d3ebbde1c94ce3a10d057c24175747116f8288e5d794d12d482f0220217f36a485cae903c713331d877c1f64677e3622ad4010726870540656fe9dcb01","02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"],"sequence":4294967294}],"vout":[{"value":1.00000000,"n":0,"scriptPubKey":{"asm":"9 2a1f3b7c9d0e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c","hex":"59202a1f3b7c9d0e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c","type":"witness_mweb_pegin"}},{"value":0.24990000,"n":1,"scriptPubKey":{"asm":"0 3f2e5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5","hex":"00143f2e5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5","type":"witness_v0_keyhash"}},{"ismweb":true,"output_id":"6b9c3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b","n":2}]}`,
+			wantMweb: bchain.MwebPegin | bchain.MwebOutputs,
+			wantVin:  1,
+			wantVout: []int64{100000000, 24990000},
+		},
+		{
+			name:     "MWEB only",
+			json:     `{"txid":"9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b","version":2,"locktime":0,"vin":[{"ismweb":true,"output_id":"1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c5b6a7988"}],"vout":[{"ismweb":true,"output_id":"8a7b6c5d4e3f20118a7b6c5d4e3f20118a7b6c5d4e3f20118a7b6c5d4e3f2011","n":0},{"ismweb":true,"output_id":"0f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a6978","n":1}]}`,
+			wantMweb: bchain.MwebInputs | bchain.MwebOutputs,
+			wantVin:  0,
+			wantVout: []int64{},
+		},
+		{
+			name:     "canonical",
+			json:     `{"txid":"c5d1f6a0b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2","version":1,"locktime":0,"vin":[{"txid":"0b5f1c4d7e2a9b3c6d8e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e","vout":1,"scriptSig":{"asm":"","hex":""},"sequence":4294967295}],"vout":[{"value":0.12345678,"n":0,"scriptPubKey":{"asm":"OP_DUP OP_HASH160 a4a3a1cfde1f5d6e0c3f1b3a8a5d2d8e2f6a3c9b OP_EQUALVERIFY OP_CHECKSIG","hex":"76a914a4a3a1cfde1f5d6e0c3f1b3a8a5d2d8e2f6a3c9b88ac","type":"pubkeyhash"}}]}`,
+			wantMweb: 0,
+			wantVin:  1,
+			wantVout: []int64{12345678},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := parser.ParseTxFromJson([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("ParseTxFromJson() error = %v", err)
+			}
+			if tx.Mweb != tt.wantMweb {
+				t.Errorf("ParseTxFromJson() Mweb = %b, want %b", tx.Mweb, tt.wantMweb)
+			}
+			if len(tx.Vin) != tt.wantVin {
+				t.Errorf("ParseTxFromJson() len(Vin) = %d, want %d", len(tx.Vin), tt.wantVin)
+			}
+			if len(tx.Vout) != len(tt.wantVout) {
+				t.Fatalf("ParseTxFromJson() len(Vout) = %d, want %d", len(tx.Vout), len(tt.wantVout))
+			}
+			for i := range tx.Vout {
+				if tx.Vout[i].ValueSat.Int64() != tt.wantVout[i] {
+					t.Errorf("ParseTxFromJson() Vout[%d] = %v, want %d", i, tx.Vout[i].ValueSat.String(), tt.wantVout[i])
+				}
+				// the outputs must be indexable
+				if _, err := parser.GetAddrDescFromVout(&tx.Vout[i]); err != nil {
+					t.Errorf("GetAddrDescFromVout() Vout[%d] error = %v", i, err)
+				}
+			}
+		})
+	}
+}
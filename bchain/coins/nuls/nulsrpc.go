@@ -268,6 +268,56 @@ func (n *NulsRPC) GetBlockHeader(hash string) (*bchain.BlockHeader, error) {
 	return n.getBlobkHeader(uri)
 }
 
+// GetBlockHeaderHex is not supported, the backend does not provide the serialized block header
+func (n *NulsRPC) GetBlockHeaderHex(hash string) (string, error) {
+	return "", errors.New("GetBlockHeaderHex: not supported")
+}
+
+// GetBlockFilter is not supported, the backend does not provide block filters
+func (n *NulsRPC) GetBlockFilter(hash string) (*bchain.BlockFilter, error) {
+	return nil, bchain.ErrNotSupported
+}
+
+// GetTransactionHex is not supported, the backend does not provide the serialized transaction
+func (n *NulsRPC) GetTransactionHex(txid string) (string, error) {
+	return "", errors.New("GetTransactionHex: not supported")
+}
+
+// GetBackendNames returns no names, the chain is connected to a single back-end
+func (n *NulsRPC) GetBackendNames() []string {
+	return nil
+}
+
+// GetTransactionSpecificFromBackend is not supported, the chain is connected to a single back-end
+func (n *NulsRPC) GetTransactionSpecificFromBackend(backend string, tx *bchain.Tx) (json.RawMessage, error) {
+	return nil, bchain.ErrNotSupported
+}
+
+// GetBlockSpecificFromBackend is not supported, the chain is connected to a single back-end
+func (n *NulsRPC) GetBlockSpecificFromBackend(backend string, hash string) (json.RawMessage, error) {
+	return nil, bchain.ErrNotSupported
+}
+
+// GetBlockRawFromBackend is not supported, the chain is connected to a single back-end
+func (n *NulsRPC) GetBlockRawFromBackend(backend string, hash string) (string, error) {
+	return "", bchain.ErrNotSupported
+}
+
+// EstimateSmartFeeFromBackend is not supported, the chain is connected to a single back-end
+func (n *NulsRPC) EstimateSmartFeeFromBackend(backend string, blocks int, conservative bool) (big.Int, error) {
+	return big.Int{}, bchain.ErrNotSupported
+}
+
+// GetBlockMiner is not supported, the pool tags are defined only for Bitcoin-like coins
+func (n *NulsRPC) GetBlockMiner(hash string) (string, error) {
+	return "", bchain.ErrNotSupported
+}
+
+// MatchBlockMiner does not recognize any miner, the pool tags are defined only for Bitcoin-like coins
+func (n *NulsRPC) MatchBlockMiner(block *bchain.Block) string {
+	return ""
+}
+
 func (n *NulsRPC) GetBlockHeaderByHeight(height uint32) (*bchain.BlockHeader, error) {
 	uri := "/api/block/header/height/" + strconv.Itoa(int(height))
 	return n.getBlobkHeader(uri)
@@ -392,6 +442,11 @@ func (n *NulsRPC) GetMempoolTransactions() ([]string, error) {
 	return nil, nil
 }
 
+// GetTransactions is not supported, the transactions must be fetched one by one using GetTransaction
+func (n *NulsRPC) GetTransactions(txids []string) ([]*bchain.Tx, error) {
+	return nil, errors.New("GetTransactions: not supported")
+}
+
 func (n *NulsRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	if txid == "" {
 		return nil, bchain.ErrTxidMissing
@@ -129,6 +129,11 @@ func (p *PivXParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 	return p.baseparser.UnpackTx(buf)
 }
 
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+func (p *PivXParser) IsCoinStake(tx *bchain.Tx) bool {
+	return bchain.IsCoinStakeTx(tx)
+}
+
 // ParseTx parses byte array containing transaction and returns Tx struct
 func (p *PivXParser) ParseTx(b []byte) (*bchain.Tx, error) {
 	t := wire.MsgTx{}
@@ -120,7 +120,7 @@ func Test_GetAddressesFromAddrDesc(t *testing.T) {
 }
 
 var (
-	// regular transaction
+	// coinstake transaction
 	testTx1       bchain.Tx
 	testTxPacked1 = "0a2052b116d26f7c8b633c284f8998a431e106d837c0c5888f9ea5273d36c4556bec12f501010000000188557c816acd0a61579b701278c7dde85ea25d57877f9dbc65d3b2df2feacc42320000006b483045022100f5d0e98d064d5256852e420a4a3779527fb182c5edbfecf6143fc70eeba8eeef02202f0b2445185fbf846cca07c56c317733a9a4e46f960615f541da7aa27c33cfa201210251c5555ff3c684aebfca92f5329e2f660da54856299da067060a1bcf5e8fae73ffffffff03000000000000000000f06832fa0100000023210251c5555ff3c684aebfca92f5329e2f660da54856299da067060a1bcf5e8fae73aca038370e000000001976a914b4aa56c103b398f875bb8d15c3bb4136aa62725f88ac000000001883a8aacd052880ea30329701122042ccea2fdfb2d365bc9d7f87575da25ee8ddc77812709b57610acd6a817c55881832226b483045022100f5d0e98d064d5256852e420a4a3779527fb182c5edbfecf6143fc70eeba8eeef02202f0b2445185fbf846cca07c56c317733a9a4e46f960615f541da7aa27c33cfa201210251c5555ff3c684aebfca92f5329e2f660da54856299da067060a1bcf5e8fae7328ffffffff0f3a003a520a0501fa3268f010011a23210251c5555ff3c684aebfca92f5329e2f660da54856299da067060a1bcf5e8fae73ac2222444b4c33517a43624a71724870524b4148764571736f6d7344686b515076567a5a673a470a040e3738a010021a1976a914b4aa56c103b398f875bb8d15c3bb4136aa62725f88ac2222444d634e45393855667571454b32674746664b423459705741577162774852415448"
 
@@ -474,3 +474,40 @@ func TestParseBlock(t *testing.T) {
 		}
 	}
 }
+
+func TestIsCoinStake(t *testing.T) {
+	p := NewPivXParser(GetChainParams("main"), &btc.Configuration{})
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want bool
+	}{
+		{name: "coinstake", tx: &testTx1, want: true},
+		{name: "zerocoin mint", tx: &testTx2, want: false},
+		{name: "zerocoin spend", tx: &testTx3, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.IsCoinStake(tt.tx); got != tt.want {
+				t.Errorf("IsCoinStake() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// in the PoS block, the first transaction is coinbase and the second one is coinstake
+	blk, err := p.ParseBlock(helperLoadBlock(t, 800000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsCoinStake(&blk.Txs[0]) {
+		t.Errorf("IsCoinStake() coinbase tx %v detected as coinstake", blk.Txs[0].Txid)
+	}
+	if !p.IsCoinStake(&blk.Txs[1]) {
+		t.Errorf("IsCoinStake() tx %v not detected as coinstake", blk.Txs[1].Txid)
+	}
+	// the empty marker output must not produce any address descriptor to be indexed
+	ad, err := p.GetAddrDescFromVout(&blk.Txs[1].Vout[0])
+	if err != nil || len(ad) != 0 {
+		t.Errorf("GetAddrDescFromVout() coinstake marker = %v, %v, want empty", ad, err)
+	}
+}
@@ -131,6 +131,11 @@ func (p *QtumParser) ParseBlock(b []byte) (*bchain.Block, error) {
 	}, nil
 }
 
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+func (p *QtumParser) IsCoinStake(tx *bchain.Tx) bool {
+	return bchain.IsCoinStakeTx(tx)
+}
+
 // ParseTxFromJson parses JSON message containing transaction and returns Tx struct
 func (p *QtumParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
 	var tx bchain.Tx
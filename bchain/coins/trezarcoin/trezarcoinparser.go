@@ -5,6 +5,7 @@ import (
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/bchain/coins/utils"
 )
 
 // magic numbers
@@ -58,6 +59,19 @@ func (p *TrezarcoinParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64)
 }
 
 // UnpackTx unpacks transaction from protobuf byte array
+// Trezarcoin transactions carry their own timestamp, which can differ from the block time
 func (p *TrezarcoinParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
-	return p.baseparser.UnpackTx(buf)
+	tx, height, err := p.baseparser.UnpackTx(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	if t, ok := utils.TxTimeFromHex(tx.Hex); ok {
+		tx.Time = t
+	}
+	return tx, height, nil
+}
+
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+func (p *TrezarcoinParser) IsCoinStake(tx *bchain.Tx) bool {
+	return bchain.IsCoinStakeTx(tx)
 }
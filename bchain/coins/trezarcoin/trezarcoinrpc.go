@@ -108,6 +108,11 @@ func (f *TrezarcoinRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, erro
 	return nil, errors.New("GetMempoolEntry: not implemented")
 }
 
+// GetMempoolEntries returns mempool data for given transactions
+func (f *TrezarcoinRPC) GetMempoolEntries(txids []string) ([]*bchain.MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not implemented")
+}
+
 func isErrBlockNotFound(err *bchain.RPCError) bool {
 	return err.Message == "Block not found" ||
 		err.Message == "Block height out of range"
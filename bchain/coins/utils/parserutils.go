@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"io"
 
@@ -103,3 +105,16 @@ func SkipAuxpow(r io.ReadSeeker) error {
 	}
 	return nil
 }
+
+// TxTimeFromHex returns the timestamp nTime of a transaction in the format used by Peercoin and derived PoS coins,
+// where the timestamp follows the version of the transaction
+func TxTimeFromHex(txHex string) (int64, bool) {
+	if len(txHex) < 16 {
+		return 0, false
+	}
+	b, err := hex.DecodeString(txHex[:16])
+	if err != nil {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint32(b[4:8])), true
+}
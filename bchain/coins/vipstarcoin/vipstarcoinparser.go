@@ -127,6 +127,11 @@ func (p *VIPSTARCOINParser) ParseBlock(b []byte) (*bchain.Block, error) {
 	}, nil
 }
 
+// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+func (p *VIPSTARCOINParser) IsCoinStake(tx *bchain.Tx) bool {
+	return bchain.IsCoinStakeTx(tx)
+}
+
 // ParseTxFromJson parses JSON message containing transaction and returns Tx struct
 func (p *VIPSTARCOINParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
 	var tx bchain.Tx
@@ -1,6 +1,11 @@
 package zec
 
 import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
 	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/trezor/blockbook/bchain"
@@ -92,6 +97,41 @@ func (p *ZCashParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64) ([]b
 }
 
 // UnpackTx unpacks transaction from protobuf byte array
+// the version group id is not stored in protobuf, it is restored from the transaction header
 func (p *ZCashParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
-	return p.baseparser.UnpackTx(buf)
+	tx, height, err := p.baseparser.UnpackTx(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	tx.VersionGroupID = txVersionGroupID(tx.Hex)
+	return tx, height, nil
+}
+
+// ParseTxFromJson parses JSON message containing transaction and returns Tx struct
+func (p *ZCashParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
+	tx, err := p.BitcoinLikeParser.ParseTxFromJson(msg)
+	if err != nil {
+		return nil, err
+	}
+	if tx.VersionGroupID == "" {
+		tx.VersionGroupID = txVersionGroupID(tx.Hex)
+	}
+	return tx, nil
+}
+
+// txVersionGroupID returns the version group id from the header of the transaction in the format of zcashd (big endian hex)
+// empty string is returned if the transaction is not overwintered (version 3 and higher)
+func txVersionGroupID(txHex string) string {
+	if len(txHex) < 16 {
+		return ""
+	}
+	header, err := hex.DecodeString(txHex[:16])
+	if err != nil {
+		return ""
+	}
+	// the highest bit of the version is the overwintered flag
+	if binary.LittleEndian.Uint32(header[:4])&0x80000000 == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%08x", binary.LittleEndian.Uint32(header[4:8]))
 }
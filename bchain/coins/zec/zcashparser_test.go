@@ -246,3 +246,57 @@ func TestUnpackTx(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTxFromJson_Version(t *testing.T) {
+	parser := NewZCashParser(GetChainParams("main"), &btc.Configuration{})
+	tests := []struct {
+		name               string
+		json               string
+		wantVersion        int32
+		wantVersionGroupID string
+	}{
+		{
+			name:        "v2",
+			json:        `{"hex":"02000000019c012650","txid":"e64aac0c211ad210c90934f06b1cc932327329e41a9f70c6eb76f79ef798b7b8","version":2}`,
+			wantVersion: 2,
+		},
+		{
+			name:               "v3 overwinter",
+			json:               `{"hex":"030000807082c403010cb33c","txid":"80d496a614f65e0933a5eb1107ff322d6c03fca8f1d3bfab2f6522291c8de612","version":3}`,
+			wantVersion:        3,
+			wantVersionGroupID: "03c48270",
+		},
+		{
+			name:               "v4 sapling, group id from backend",
+			json:               `{"hex":"0400008085202f8901","txid":"a9f7cc34d7e272d2d9fb68cfa1c1941e338f377e6e426ae2fea1c12616d89c63","version":4,"overwintered":true,"versiongroupid":"892f2085"}`,
+			wantVersion:        4,
+			wantVersionGroupID: "892f2085",
+		},
+		{
+			name:               "v4 sapling, group id from hex",
+			json:               `{"hex":"0400008085202f8901","txid":"a9f7cc34d7e272d2d9fb68cfa1c1941e338f377e6e426ae2fea1c12616d89c63","version":4}`,
+			wantVersion:        4,
+			wantVersionGroupID: "892f2085",
+		},
+		{
+			name:               "v5 nu5",
+			json:               `{"hex":"050000800a27a726b4d0d6c2","txid":"83f3db1d129a77bee9c6cf32cbc12d959cd0af8c8d734c2611db4bfddfe99202","version":5}`,
+			wantVersion:        5,
+			wantVersionGroupID: "26a7270a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseTxFromJson([]byte(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != tt.wantVersion {
+				t.Errorf("ParseTxFromJson() Version = %v, want %v", got.Version, tt.wantVersion)
+			}
+			if got.VersionGroupID != tt.wantVersionGroupID {
+				t.Errorf("ParseTxFromJson() VersionGroupID = %v, want %v", got.VersionGroupID, tt.wantVersionGroupID)
+			}
+		})
+	}
+}
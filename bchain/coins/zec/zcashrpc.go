@@ -164,6 +164,11 @@ func (z *ZCashRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not implemented")
 }
 
+// GetMempoolEntries returns mempool data for given transactions
+func (z *ZCashRPC) GetMempoolEntries(txids []string) ([]*bchain.MempoolEntry, error) {
+	return nil, errors.New("GetMempoolEntries: not implemented")
+}
+
 // GetBlockRaw is not supported
 func (z *ZCashRPC) GetBlockRaw(hash string) (string, error) {
 	return "", errors.New("GetBlockRaw: not supported")
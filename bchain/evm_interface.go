@@ -13,6 +13,7 @@ type EVMClient interface {
 	EstimateGas(ctx context.Context, msg interface{}) (uint64, error)
 	BalanceAt(ctx context.Context, addrDesc AddressDescriptor, blockNumber *big.Int) (*big.Int, error)
 	NonceAt(ctx context.Context, addrDesc AddressDescriptor, blockNumber *big.Int) (uint64, error)
+	CodeAt(ctx context.Context, addrDesc AddressDescriptor, blockNumber *big.Int) ([]byte, error)
 }
 
 // EVMRPCClient provides the necessary rpc functionality for evm chain sync
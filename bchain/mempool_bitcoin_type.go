@@ -2,9 +2,11 @@ package bchain
 
 import (
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/juju/errors"
 )
 
 type chanInputPayload struct {
@@ -18,6 +20,66 @@ type MempoolBitcoinType struct {
 	chanTxid            chan string
 	chanAddrIndex       chan txidio
 	AddrDescForOutpoint AddrDescForOutpointFunc
+	// SpendingTxidForOutpoint, if set, finds the confirmed transactions which replaced the dropped transactions
+	SpendingTxidForOutpoint SpendingTxidForOutpointFunc
+	// MaxAge is the age of a transaction (by the time reported by the backend) after which the transaction is not tracked, 0 means no limit
+	MaxAge time.Duration
+	// AddrDescFilter, if set, limits the index to the address descriptors accepted by the filter,
+	// the transactions not touching any accepted address descriptor are not tracked
+	AddrDescFilter AddrDescFilterFunc
+	// DroppedTxTTL is the time for which the replaced, conflicted and expired transactions are retained, 0 means no retention
+	DroppedTxTTL time.Duration
+	// MaxTransactions is the maximal number of transactions in the index, 0 means no limit
+	// the transactions with the lowest fee rate are evicted from the index first, similarly to the mempool of the backend
+	MaxTransactions int
+	expired         map[string]struct{}
+	filtered        map[string]struct{}
+	// evicted maps the transactions evicted because of MaxTransactions to their fee rate
+	evicted map[string]float64
+	// spentBy maps the outpoints spent by the mempool transactions to the spending txid, kept only with the retention
+	spentBy map[Outpoint]string
+}
+
+// AddrDescFilterFunc returns true if the address descriptor should be indexed in the mempool
+type AddrDescFilterFunc func(addrDesc AddressDescriptor) bool
+
+// NewAddrDescFilter returns filter accepting only the whitelisted addresses (if any are set) which are not blacklisted
+// nil is returned if both lists are empty
+func NewAddrDescFilter(parser BlockChainParser, whitelist []string, blacklist []string) (AddrDescFilterFunc, error) {
+	if len(whitelist) == 0 && len(blacklist) == 0 {
+		return nil, nil
+	}
+	toMap := func(addresses []string) (map[string]struct{}, error) {
+		if len(addresses) == 0 {
+			return nil, nil
+		}
+		m := make(map[string]struct{}, len(addresses))
+		for _, a := range addresses {
+			addrDesc, err := parser.GetAddrDescFromAddress(a)
+			if err != nil {
+				return nil, errors.Annotatef(err, "address %v", a)
+			}
+			m[string(addrDesc)] = struct{}{}
+		}
+		return m, nil
+	}
+	white, err := toMap(whitelist)
+	if err != nil {
+		return nil, err
+	}
+	black, err := toMap(blacklist)
+	if err != nil {
+		return nil, err
+	}
+	return func(addrDesc AddressDescriptor) bool {
+		if white != nil {
+			if _, found := white[string(addrDesc)]; !found {
+				return false
+			}
+		}
+		_, found := black[string(addrDesc)]
+		return !found
+	}, nil
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
@@ -31,7 +93,12 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *Mempo
 		},
 		chanTxid:      make(chan string, 1),
 		chanAddrIndex: make(chan txidio, 1),
+		expired:       make(map[string]struct{}),
+		filtered:      make(map[string]struct{}),
+		evicted:       make(map[string]float64),
+		spentBy:       make(map[Outpoint]string),
 	}
+	m.droppedTxs = make(map[string]*MempoolDroppedTx)
 	for i := 0; i < workers; i++ {
 		go func(i int) {
 			chanInput := make(chan chanInputPayload, 1)
@@ -45,11 +112,19 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *Mempo
 				}(j)
 			}
 			for txid := range m.chanTxid {
-				io, ok := m.getTxAddrs(txid, chanInput, chanResult)
+				io, mtx, ok := m.getTxAddrs(txid, chanInput, chanResult)
 				if !ok {
 					io = []addrIndex{}
 				}
-				m.chanAddrIndex <- txidio{txid, io}
+				// the fee rate computed from the transaction is used if the backend does not report the fee
+				var feeRate float64
+				if m.MaxTransactions > 0 {
+					feeRate = txFeeRate(mtx)
+				}
+				if !m.tracksDroppedTxs() {
+					mtx = nil
+				}
+				m.chanAddrIndex <- txidio{txid, io, feeRate, mtx}
 			}
 		}(i)
 	}
@@ -91,15 +166,77 @@ func (m *MempoolBitcoinType) getInputAddress(payload *chanInputPayload) *addrInd
 
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPayload, chanResult chan *addrIndex) ([]addrIndex, bool) {
+// getEntries returns the mempool entries of the new transactions, the backend returns the entries of its whole mempool by one request;
+// the entries are needed only if the maximum age or the maximum number of transactions are limited;
+// if the backend does not provide the entries, the returned map is incomplete and the missing data are estimated
+func (m *MempoolBitcoinType) getEntries(txids []string) map[string]*MempoolEntry {
+	if (m.MaxAge == 0 && m.MaxTransactions == 0) || len(txids) == 0 {
+		return nil
+	}
+	entries := make(map[string]*MempoolEntry, len(txids))
+	list, err := m.chain.GetMempoolEntries(txids)
+	if err != nil {
+		glog.V(1).Info("mempool: cannot get mempool entries: ", err)
+		return entries
+	}
+	for i, entry := range list {
+		if entry != nil {
+			entries[txids[i]] = entry
+		}
+	}
+	return entries
+}
+
+// entryData returns the time the transaction entered the mempool of the backend (0 if not known)
+// and the fee rate of the transaction in satoshis per vbyte, feeRate computed from the transaction is used if the backend does not report the fee
+func (m *MempoolBitcoinType) entryData(entry *MempoolEntry, feeRate float64) (uint32, float64) {
+	if entry == nil {
+		return 0, feeRate
+	}
+	if m.MaxTransactions == 0 {
+		return uint32(entry.Time), 0
+	}
+	// prefer the fee reported by the backend, it is not known for some backends
+	if entry.Size > 0 && entry.FeeSat.Sign() > 0 {
+		fee, _ := new(big.Float).SetInt(&entry.FeeSat).Float64()
+		return uint32(entry.Time), fee / float64(entry.Size)
+	}
+	return uint32(entry.Time), feeRate
+}
+
+// txFeeRate computes the fee rate of the transaction in satoshis per vbyte from the values of its inputs and outputs
+func txFeeRate(mtx *MempoolTx) float64 {
+	if mtx == nil {
+		return 0
+	}
+	var fee big.Int
+	for i := range mtx.Vin {
+		fee.Add(&fee, &mtx.Vin[i].ValueSat)
+	}
+	for i := range mtx.Vout {
+		fee.Sub(&fee, &mtx.Vout[i].ValueSat)
+	}
+	size := mtx.VSize
+	if size == 0 {
+		size = int64(len(mtx.Hex) / 2)
+	}
+	if fee.Sign() <= 0 || size == 0 {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(&fee).Float64()
+	return f / float64(size)
+}
+
+func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPayload, chanResult chan *addrIndex) ([]addrIndex, *MempoolTx, bool) {
 	tx, err := m.chain.GetTransactionForMempool(txid)
 	if err != nil {
 		glog.Error("cannot get transaction ", txid, ": ", err)
-		return nil, false
+		return nil, nil, false
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
 	mtx := m.txToMempoolTx(tx)
 	io := make([]addrIndex, 0, len(tx.Vout)+len(tx.Vin))
+	outputAddrDescs := make([]AddressDescriptor, 0, len(tx.Vout))
 	for _, output := range tx.Vout {
 		addrDesc, err := m.chain.GetChainParser().GetAddrDescFromVout(&output)
 		if err != nil {
@@ -109,9 +246,7 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPay
 		if len(addrDesc) > 0 {
 			io = append(io, addrIndex{string(addrDesc), int32(output.N)})
 		}
-		if m.OnNewTxAddr != nil {
-			m.OnNewTxAddr(tx, addrDesc)
-		}
+		outputAddrDescs = append(outputAddrDescs, addrDesc)
 	}
 	dispatched := 0
 	for i := range tx.Vin {
@@ -142,10 +277,19 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPay
 			io = append(io, *ai)
 		}
 	}
+	// the transactions not tracked because of AddrDescFilter are not notified
+	if !m.acceptedByFilter(io) {
+		return io, mtx, true
+	}
+	if m.OnNewTxAddr != nil {
+		for _, addrDesc := range outputAddrDescs {
+			m.OnNewTxAddr(tx, addrDesc)
+		}
+	}
 	if m.OnNewTx != nil {
 		m.OnNewTx(mtx)
 	}
-	return io, true
+	return io, mtx, true
 }
 
 // Resync gets mempool transactions and maps outputs to transactions.
@@ -159,51 +303,311 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 		return 0, err
 	}
 	glog.V(2).Info("mempool: resync ", len(txs), " txs")
-	onNewEntry := func(txid string, entry txEntry) {
+	m.readmitEvicted()
+	txsMap := make(map[string]struct{}, len(txs))
+	newTxs := make([]string, 0)
+	for _, txid := range txs {
+		txsMap[txid] = struct{}{}
+		_, exists := m.txEntries[txid]
+		if !exists {
+			_, exists = m.expired[txid]
+		}
+		if !exists {
+			_, exists = m.filtered[txid]
+		}
+		if !exists {
+			_, exists = m.evicted[txid]
+		}
+		if !exists {
+			newTxs = append(newTxs, txid)
+		}
+	}
+	// the mempool entries of all the new transactions are requested at once instead of one call per transaction
+	entries := m.getEntries(newTxs)
+	txTime := uint32(time.Now().Unix())
+	onNewEntry := func(tio txidio) {
+		entry := txEntry{addrIndexes: tio.io, time: txTime, tx: tio.tx}
+		entry.nodeTime, entry.feeRate = m.entryData(entries[tio.txid], tio.feeRate)
+		txid := tio.txid
+		if m.AddrDescFilter != nil && len(entry.addrIndexes) > 0 {
+			entry.addrIndexes = m.filterAddrIndexes(entry.addrIndexes)
+			if len(entry.addrIndexes) == 0 {
+				m.filtered[txid] = struct{}{}
+			}
+		}
 		if len(entry.addrIndexes) > 0 {
 			m.mux.Lock()
 			m.txEntries[txid] = entry
 			for _, si := range entry.addrIndexes {
 				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
 			}
+			if entry.tx != nil {
+				for i := range entry.tx.Vin {
+					if vin := &entry.tx.Vin[i]; vin.Txid != "" {
+						m.spentBy[Outpoint{vin.Txid, int32(vin.Vout)}] = txid
+					}
+				}
+				delete(m.droppedTxs, txid)
+			}
 			m.mux.Unlock()
 		}
 	}
-	txsMap := make(map[string]struct{}, len(txs))
 	dispatched := 0
-	txTime := uint32(time.Now().Unix())
 	// get transaction in parallel using goroutines created in NewUTXOMempool
-	for _, txid := range txs {
-		txsMap[txid] = struct{}{}
-		_, exists := m.txEntries[txid]
-		if !exists {
-		loop:
-			for {
-				select {
-				// store as many processed transactions as possible
-				case tio := <-m.chanAddrIndex:
-					onNewEntry(tio.txid, txEntry{tio.io, txTime})
-					dispatched--
-				// send transaction to be processed
-				case m.chanTxid <- txid:
-					dispatched++
-					break loop
-				}
+	for _, txid := range newTxs {
+	loop:
+		for {
+			select {
+			// store as many processed transactions as possible
+			case tio := <-m.chanAddrIndex:
+				onNewEntry(tio)
+				dispatched--
+			// send transaction to be processed
+			case m.chanTxid <- txid:
+				dispatched++
+				break loop
 			}
 		}
 	}
 	for i := 0; i < dispatched; i++ {
-		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime})
+		onNewEntry(<-m.chanAddrIndex)
 	}
 
+	// transactions older than MaxAge are dropped even if the backend still reports them
+	var expiry uint32
+	if m.MaxAge > 0 {
+		expiry = uint32(time.Now().Add(-m.MaxAge).Unix())
+	}
+	var removed, removedExpired map[string]*MempoolTx
+	if m.tracksDroppedTxs() {
+		removed = make(map[string]*MempoolTx)
+		removedExpired = make(map[string]*MempoolTx)
+	}
 	for txid, entry := range m.txEntries {
-		if _, exists := txsMap[txid]; !exists {
+		_, exists := txsMap[txid]
+		if !exists || entry.nodeTime > 0 && entry.nodeTime < expiry {
+			if exists {
+				glog.V(1).Info("mempool: dropping expired transaction ", txid)
+				m.expired[txid] = struct{}{}
+			}
 			m.mux.Lock()
 			m.removeEntryFromMempool(txid, entry)
+			if entry.tx != nil {
+				m.removeSpentBy(txid, entry.tx)
+				if exists {
+					removedExpired[txid] = entry.tx
+				} else {
+					removed[txid] = entry.tx
+				}
+			}
 			m.mux.Unlock()
 		}
 	}
+	if m.tracksDroppedTxs() {
+		m.retainDroppedTxs(removed, removedExpired)
+	}
+	if m.MaxTransactions > 0 {
+		m.evictTransactions()
+	}
+	for txid := range m.expired {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.expired, txid)
+		}
+	}
+	for txid := range m.filtered {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.filtered, txid)
+		}
+	}
+	for txid := range m.evicted {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.evicted, txid)
+		}
+	}
 	glog.Info("mempool: resync finished in ", time.Since(start), ", ", len(m.txEntries), " transactions in mempool")
 	return len(m.txEntries), nil
 }
+
+type txidFeeRate struct {
+	txid    string
+	feeRate float64
+}
+
+// sortByFeeRate sorts the transactions by the fee rate in ascending order, the transactions with the same fee rate by txid
+func sortByFeeRate(txs []txidFeeRate) {
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].feeRate == txs[j].feeRate {
+			return txs[i].txid < txs[j].txid
+		}
+		return txs[i].feeRate < txs[j].feeRate
+	})
+}
+
+// lowWatermark returns the number of transactions to which the index is reduced by the eviction,
+// the evicted transactions are readmitted only below it, the gap prevents evicting and readmitting the same transactions on every resync
+func (m *MempoolBitcoinType) lowWatermark() int {
+	return m.MaxTransactions - m.MaxTransactions/10
+}
+
+// evictTransactions removes the transactions with the lowest fee rate from the index if there are more than MaxTransactions,
+// until the low watermark is reached; the evicted transactions are not tracked until there is space in the index, they are not considered dropped
+func (m *MempoolBitcoinType) evictTransactions() {
+	if len(m.txEntries) <= m.MaxTransactions {
+		return
+	}
+	excess := len(m.txEntries) - m.lowWatermark()
+	txs := make([]txidFeeRate, 0, len(m.txEntries))
+	for txid, entry := range m.txEntries {
+		txs = append(txs, txidFeeRate{txid, entry.feeRate})
+	}
+	sortByFeeRate(txs)
+	m.mux.Lock()
+	for _, t := range txs[:excess] {
+		entry := m.txEntries[t.txid]
+		m.removeEntryFromMempool(t.txid, entry)
+		if entry.tx != nil {
+			m.removeSpentBy(t.txid, entry.tx)
+		}
+		m.evicted[t.txid] = t.feeRate
+	}
+	m.mux.Unlock()
+	glog.Info("mempool: evicted ", excess, " transactions with the lowest fee rate, ", len(m.evicted), " evicted transactions")
+}
+
+// readmitEvicted allows the evicted transactions with the highest fee rate to be tracked again if the index is below the low watermark
+func (m *MempoolBitcoinType) readmitEvicted() {
+	free := m.lowWatermark() - len(m.txEntries)
+	if m.MaxTransactions == 0 || free <= 0 || len(m.evicted) == 0 {
+		return
+	}
+	txs := make([]txidFeeRate, 0, len(m.evicted))
+	for txid, feeRate := range m.evicted {
+		txs = append(txs, txidFeeRate{txid, feeRate})
+	}
+	sortByFeeRate(txs)
+	for i := len(txs) - 1; i >= 0 && free > 0; i-- {
+		delete(m.evicted, txs[i].txid)
+		free--
+	}
+}
+
+// removeSpentBy removes the outpoints spent by the transaction from spentBy. The caller is responsible for locking!
+func (m *MempoolBitcoinType) removeSpentBy(txid string, tx *MempoolTx) {
+	for i := range tx.Vin {
+		o := Outpoint{tx.Vin[i].Txid, int32(tx.Vin[i].Vout)}
+		if m.spentBy[o] == txid {
+			delete(m.spentBy, o)
+		}
+	}
+}
+
+// tracksDroppedTxs returns true if the transactions removed from the mempool are classified,
+// either to be retained for DroppedTxTTL or to notify the replacements by OnTxReplaced
+func (m *MempoolBitcoinType) tracksDroppedTxs() bool {
+	return m.DroppedTxTTL > 0 || m.OnTxReplaced != nil
+}
+
+// retainDroppedTxs stores the transactions removed from the mempool for the reason of their removal,
+// the removed transactions which were not replaced, conflicted or expired are considered confirmed and are not stored
+// the stored transactions older than DroppedTxTTL are forgotten, the replacements are notified by OnTxReplaced even if DroppedTxTTL is 0
+func (m *MempoolBitcoinType) retainDroppedTxs(removed map[string]*MempoolTx, removedExpired map[string]*MempoolTx) {
+	now := time.Now().Unix()
+	// the confirmed spenders of the inputs are looked up in the index before the mempool is locked,
+	// a transaction is confirmed if its input is spent by itself, otherwise it was replaced by the confirmed spender
+	confirmedReplacements := make(map[string]string)
+	if m.SpendingTxidForOutpoint != nil {
+		for txid, tx := range removed {
+			for i := range tx.Vin {
+				spender := m.SpendingTxidForOutpoint(Outpoint{tx.Vin[i].Txid, int32(tx.Vin[i].Vout)})
+				if spender == txid {
+					break
+				}
+				if spender != "" {
+					confirmedReplacements[txid] = spender
+					break
+				}
+			}
+		}
+	}
+	// the replacements are notified after the mempool lock is released (deferred calls run in reverse order)
+	var replaced [][2]string
+	defer func() {
+		if m.OnTxReplaced != nil {
+			for _, r := range replaced {
+				m.OnTxReplaced(r[0], r[1])
+			}
+		}
+	}()
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	drop := func(txid string, tx *MempoolTx, reason MempoolDropReason, replacedBy string) {
+		if m.DroppedTxTTL > 0 {
+			glog.V(1).Info("mempool: retaining ", reason, " transaction ", txid)
+			m.droppedTxs[txid] = &MempoolDroppedTx{Tx: tx, Reason: reason, ReplacedBy: replacedBy, DropTime: now}
+		}
+		delete(removed, txid)
+		if replacedBy != "" {
+			replaced = append(replaced, [2]string{txid, replacedBy})
+		}
+	}
+	for txid, tx := range removedExpired {
+		drop(txid, tx, MempoolDropExpired, "")
+	}
+	// replaced transactions have an input spent by a transaction still in the mempool
+	for txid, tx := range removed {
+		for i := range tx.Vin {
+			if spender, found := m.spentBy[Outpoint{tx.Vin[i].Txid, int32(tx.Vin[i].Vout)}]; found && spender != txid {
+				drop(txid, tx, MempoolDropReplaced, spender)
+				break
+			}
+		}
+	}
+	for txid, spender := range confirmedReplacements {
+		if tx, found := removed[txid]; found {
+			drop(txid, tx, MempoolDropReplaced, spender)
+		}
+	}
+	// conflicted transactions spend outputs of replaced or conflicted transactions, repeat to find all descendants
+	for found := true; found; {
+		found = false
+		for txid, tx := range removed {
+			for i := range tx.Vin {
+				if d := m.droppedTxs[tx.Vin[i].Txid]; d != nil && (d.Reason == MempoolDropReplaced || d.Reason == MempoolDropConflicted) {
+					drop(txid, tx, MempoolDropConflicted, "")
+					found = true
+					break
+				}
+			}
+		}
+	}
+	ttl := now - int64(m.DroppedTxTTL/time.Second)
+	for txid, d := range m.droppedTxs {
+		if d.DropTime < ttl {
+			delete(m.droppedTxs, txid)
+		}
+	}
+}
+
+// acceptedByFilter returns true if AddrDescFilter is not set or if it accepts any of the address indexes
+func (m *MempoolBitcoinType) acceptedByFilter(addrIndexes []addrIndex) bool {
+	if m.AddrDescFilter == nil {
+		return true
+	}
+	for _, ai := range addrIndexes {
+		if m.AddrDescFilter(AddressDescriptor(ai.addrDesc)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAddrIndexes returns the address indexes accepted by AddrDescFilter
+func (m *MempoolBitcoinType) filterAddrIndexes(addrIndexes []addrIndex) []addrIndex {
+	rv := addrIndexes[:0]
+	for _, ai := range addrIndexes {
+		if m.AddrDescFilter(AddressDescriptor(ai.addrDesc)) {
+			rv = append(rv, ai)
+		}
+	}
+	return rv
+}
@@ -0,0 +1,603 @@
+//go:build unittest
+
+package bchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testMempoolParser struct {
+	BlockChainParser
+}
+
+func (p *testMempoolParser) GetAddrDescFromVout(output *Vout) (AddressDescriptor, error) {
+	return hex.DecodeString(output.ScriptPubKey.Hex)
+}
+
+// GetAddrDescFromAddress expects the output script in hex as the address
+func (p *testMempoolParser) GetAddrDescFromAddress(address string) (AddressDescriptor, error) {
+	return hex.DecodeString(address)
+}
+
+// testMempoolChain is a backend with fixed mempool transactions and their mempool entry times and fees
+type testMempoolChain struct {
+	BlockChain
+	txs   map[string]*Tx
+	times map[string]uint64
+	fees  map[string]int64
+	// number of the transactions fetched by GetTransactionForMempool
+	fetched int
+}
+
+func (c *testMempoolChain) GetChainParser() BlockChainParser {
+	return &testMempoolParser{}
+}
+
+func (c *testMempoolChain) GetMempoolTransactions() ([]string, error) {
+	txids := make([]string, 0, len(c.txs))
+	for txid := range c.txs {
+		txids = append(txids, txid)
+	}
+	return txids, nil
+}
+
+func (c *testMempoolChain) GetTransactionForMempool(txid string) (*Tx, error) {
+	tx, found := c.txs[txid]
+	if !found {
+		return nil, errors.New("transaction not found")
+	}
+	c.fetched++
+	return tx, nil
+}
+
+// GetMempoolEntries returns the entries with the size of 100 vbytes, nil for the transactions without the time and the fee
+func (c *testMempoolChain) GetMempoolEntries(txids []string) ([]*MempoolEntry, error) {
+	entries := make([]*MempoolEntry, len(txids))
+	for i, txid := range txids {
+		t, foundTime := c.times[txid]
+		fee, foundFee := c.fees[txid]
+		if foundTime || foundFee {
+			entries[i] = &MempoolEntry{Time: t, Size: 100, FeeSat: *big.NewInt(fee)}
+		}
+	}
+	return entries, nil
+}
+
+func TestMempoolBitcoinType_MaxAge(t *testing.T) {
+	const (
+		addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+		addr2 = "76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac"
+	)
+	now := time.Now().Unix()
+	newTx := func(txid, script string) *Tx {
+		return &Tx{
+			Txid: txid,
+			Vin:  []Vin{{Coinbase: "03"}},
+			Vout: []Vout{{N: 0, ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: script}}},
+		}
+	}
+	chain := &testMempoolChain{
+		txs: map[string]*Tx{
+			"recent": newTx("recent", addr1),
+			"stale":  newTx("stale", addr2),
+		},
+		times: map[string]uint64{
+			"recent": uint64(now - 1800),
+			"stale":  uint64(now - 3*3600),
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1)
+	m.MaxAge = 24 * time.Hour
+
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("Resync() = %v, want %v", count, 2)
+	}
+
+	// the backend still reports both transactions, but the stale one is now too old
+	m.MaxAge = time.Hour
+	for i := 0; i < 2; i++ {
+		count, err = m.Resync()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("Resync() = %v, want %v", count, 1)
+		}
+	}
+	ad, _ := hex.DecodeString(addr2)
+	outpoints, err := m.GetAddrDescTransactions(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outpoints) != 0 {
+		t.Errorf("GetAddrDescTransactions() = %v, want no transactions", outpoints)
+	}
+	ad, _ = hex.DecodeString(addr1)
+	outpoints, err = m.GetAddrDescTransactions(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outpoints) != 1 || outpoints[0].Txid != "recent" {
+		t.Errorf("GetAddrDescTransactions() = %v, want transaction recent", outpoints)
+	}
+
+	// expired transaction is forgotten when the backend drops it
+	delete(chain.txs, "stale")
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.expired) != 0 {
+		t.Errorf("expired = %v, want empty", m.expired)
+	}
+}
+
+func TestMempoolBitcoinType_AddrDescFilter(t *testing.T) {
+	const (
+		addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+		addr2 = "76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac"
+		addr3 = "a9140394b3cf9a44782c10105b93962daa8dba304d7f87"
+	)
+	newTx := func(txid string, scripts ...string) *Tx {
+		tx := &Tx{
+			Txid: txid,
+			Vin:  []Vin{{Coinbase: "03"}},
+		}
+		for i, script := range scripts {
+			tx.Vout = append(tx.Vout, Vout{N: uint32(i), ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: script}})
+		}
+		return tx
+	}
+	chain := &testMempoolChain{
+		txs: map[string]*Tx{
+			"tx1":  newTx("tx1", addr1),
+			"tx2":  newTx("tx2", addr2),
+			"tx12": newTx("tx12", addr2, addr1),
+			"tx3":  newTx("tx3", addr3),
+		},
+	}
+	tests := []struct {
+		name      string
+		whitelist []string
+		blacklist []string
+		wantTxs   []string
+		wantAddrs map[string][]Outpoint
+		// txids of the notified outputs
+		wantNotifiedAddrs []string
+	}{
+		{
+			name:      "whitelist",
+			whitelist: []string{addr1},
+			wantTxs:   []string{"tx1", "tx12"},
+			wantAddrs: map[string][]Outpoint{
+				addr1: {{Txid: "tx1", Vout: 0}, {Txid: "tx12", Vout: 1}},
+				addr2: {},
+				addr3: {},
+			},
+			wantNotifiedAddrs: []string{"tx1", "tx12", "tx12"},
+		},
+		{
+			name:      "blacklist",
+			blacklist: []string{addr2},
+			wantTxs:   []string{"tx1", "tx12", "tx3"},
+			wantAddrs: map[string][]Outpoint{
+				addr1: {{Txid: "tx1", Vout: 0}, {Txid: "tx12", Vout: 1}},
+				addr2: {},
+				addr3: {{Txid: "tx3", Vout: 0}},
+			},
+			wantNotifiedAddrs: []string{"tx1", "tx12", "tx12", "tx3"},
+		},
+		{
+			name:      "whitelist and blacklist",
+			whitelist: []string{addr1, addr2},
+			blacklist: []string{addr1},
+			wantTxs:   []string{"tx12", "tx2"},
+			wantAddrs: map[string][]Outpoint{
+				addr1: {},
+				addr2: {{Txid: "tx12", Vout: 0}, {Txid: "tx2", Vout: 0}},
+				addr3: {},
+			},
+			wantNotifiedAddrs: []string{"tx12", "tx12", "tx2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewAddrDescFilter(chain.GetChainParser(), tt.whitelist, tt.blacklist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := NewMempoolBitcoinType(chain, 1, 1)
+			m.AddrDescFilter = filter
+			// only the tracked transactions are notified
+			var notified, notifiedAddrs []string
+			m.OnNewTx = func(tx *MempoolTx) { notified = append(notified, tx.Txid) }
+			m.OnNewTxAddr = func(tx *Tx, desc AddressDescriptor) { notifiedAddrs = append(notifiedAddrs, tx.Txid) }
+			// the second resync must not track the filtered transactions either
+			for i := 0; i < 2; i++ {
+				count, err := m.Resync()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != len(tt.wantTxs) {
+					t.Errorf("Resync() = %v, want %v", count, len(tt.wantTxs))
+				}
+			}
+			entries := m.GetAllEntries()
+			txids := make([]string, len(entries))
+			for i := range entries {
+				txids[i] = entries[i].Txid
+			}
+			sort.Strings(txids)
+			if !reflect.DeepEqual(txids, tt.wantTxs) {
+				t.Errorf("GetAllEntries() = %v, want %v", txids, tt.wantTxs)
+			}
+			for addr, want := range tt.wantAddrs {
+				ad, _ := hex.DecodeString(addr)
+				got, err := m.GetAddrDescTransactions(ad)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sort.Slice(got, func(i, j int) bool { return got[i].Txid < got[j].Txid })
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("GetAddrDescTransactions(%v) = %v, want %v", addr, got, want)
+				}
+			}
+			if len(m.filtered) != len(chain.txs)-len(tt.wantTxs) {
+				t.Errorf("filtered = %v, want %v transactions", m.filtered, len(chain.txs)-len(tt.wantTxs))
+			}
+			sort.Strings(notified)
+			if !reflect.DeepEqual(notified, tt.wantTxs) {
+				t.Errorf("OnNewTx notified %v, want %v", notified, tt.wantTxs)
+			}
+			sort.Strings(notifiedAddrs)
+			if !reflect.DeepEqual(notifiedAddrs, tt.wantNotifiedAddrs) {
+				t.Errorf("OnNewTxAddr notified %v, want %v", notifiedAddrs, tt.wantNotifiedAddrs)
+			}
+		})
+	}
+
+	// no filter is created without addresses
+	filter, err := NewAddrDescFilter(chain.GetChainParser(), nil, nil)
+	if err != nil || filter != nil {
+		t.Errorf("NewAddrDescFilter() = %v, %v, want nil filter", filter, err)
+	}
+}
+
+func TestMempoolBitcoinType_DroppedTxs(t *testing.T) {
+	const (
+		addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+		addr2 = "76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac"
+	)
+	now := time.Now().Unix()
+	newTx := func(txid string, spent Outpoint, script string) *Tx {
+		return &Tx{
+			Txid: txid,
+			Vin:  []Vin{{Txid: spent.Txid, Vout: uint32(spent.Vout)}},
+			Vout: []Vout{{N: 0, ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: script}}},
+		}
+	}
+	chain := &testMempoolChain{
+		txs: map[string]*Tx{
+			"original":    newTx("original", Outpoint{"parent", 0}, addr1),
+			"child":       newTx("child", Outpoint{"original", 0}, addr2),
+			"confirmed":   newTx("confirmed", Outpoint{"parent", 1}, addr2),
+			"stale":       newTx("stale", Outpoint{"parent", 2}, addr2),
+			"doublespent": newTx("doublespent", Outpoint{"parent", 3}, addr1),
+			"grandchild":  newTx("grandchild", Outpoint{"doublespent", 0}, addr2),
+		},
+		times: map[string]uint64{
+			"stale": uint64(now - 3*3600),
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1)
+	m.AddrDescForOutpoint = func(outpoint Outpoint) (AddressDescriptor, *big.Int) {
+		ad, _ := hex.DecodeString(addr1)
+		return ad, big.NewInt(2000)
+	}
+	// the inputs of the confirmed and of the double spent transactions are spent in a block
+	confirmedSpends := map[Outpoint]string{
+		{"parent", 1}: "confirmed",
+		{"parent", 3}: "blockspend",
+	}
+	m.SpendingTxidForOutpoint = func(outpoint Outpoint) string {
+		return confirmedSpends[outpoint]
+	}
+	m.MaxAge = time.Hour
+	m.DroppedTxTTL = time.Minute
+	replaced := make(map[string]string)
+	m.OnTxReplaced = func(replacedTxid, replacementTxid string) {
+		replaced[replacedTxid] = replacementTxid
+	}
+
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Fatalf("Resync() = %v, want %v", count, 5)
+	}
+
+	// the original transaction is replaced, its child is dropped with it and the confirmed transaction leaves the mempool,
+	// the double spent transaction is replaced by a transaction in a block and its child is dropped with it
+	delete(chain.txs, "original")
+	delete(chain.txs, "child")
+	delete(chain.txs, "confirmed")
+	delete(chain.txs, "doublespent")
+	delete(chain.txs, "grandchild")
+	chain.txs["replacement"] = newTx("replacement", Outpoint{"parent", 0}, addr2)
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	// only the replacement is notified, not the conflicted or confirmed transactions
+	if want := map[string]string{"original": "replacement", "doublespent": "blockspend"}; !reflect.DeepEqual(replaced, want) {
+		t.Errorf("OnTxReplaced notified %v, want %v", replaced, want)
+	}
+
+	tests := []struct {
+		txid           string
+		wantReason     MempoolDropReason
+		wantReplacedBy string
+	}{
+		{txid: "original", wantReason: MempoolDropReplaced, wantReplacedBy: "replacement"},
+		{txid: "child", wantReason: MempoolDropConflicted},
+		{txid: "stale", wantReason: MempoolDropExpired},
+		{txid: "doublespent", wantReason: MempoolDropReplaced, wantReplacedBy: "blockspend"},
+		{txid: "grandchild", wantReason: MempoolDropConflicted},
+		{txid: "confirmed"},
+		{txid: "replacement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.txid, func(t *testing.T) {
+			d := m.GetDroppedTransaction(tt.txid)
+			if tt.wantReason == "" {
+				if d != nil {
+					t.Errorf("GetDroppedTransaction() = %+v, want nil", d)
+				}
+				return
+			}
+			if d == nil {
+				t.Fatal("GetDroppedTransaction() = nil")
+			}
+			if d.Reason != tt.wantReason || d.ReplacedBy != tt.wantReplacedBy {
+				t.Errorf("GetDroppedTransaction() = %v %v, want %v %v", d.Reason, d.ReplacedBy, tt.wantReason, tt.wantReplacedBy)
+			}
+			if d.Tx == nil || d.Tx.Txid != tt.txid {
+				t.Errorf("GetDroppedTransaction().Tx = %+v, want transaction %v", d.Tx, tt.txid)
+			}
+		})
+	}
+
+	// the dropped transactions are forgotten after the TTL
+	for _, d := range m.droppedTxs {
+		d.DropTime -= 120
+	}
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if d := m.GetDroppedTransaction("original"); d != nil {
+		t.Errorf("GetDroppedTransaction() after TTL = %+v, want nil", d)
+	}
+	if len(m.spentBy) != 1 || m.spentBy[Outpoint{"parent", 0}] != "replacement" {
+		t.Errorf("spentBy = %v, want only outpoint spent by replacement", m.spentBy)
+	}
+}
+
+func TestMempoolBitcoinType_ReplacedWithoutRetention(t *testing.T) {
+	const addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+	newTx := func(txid string, spent Outpoint) *Tx {
+		return &Tx{
+			Txid: txid,
+			Vin:  []Vin{{Txid: spent.Txid, Vout: uint32(spent.Vout)}},
+			Vout: []Vout{{N: 0, ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: addr1}}},
+		}
+	}
+	chain := &testMempoolChain{
+		txs: map[string]*Tx{
+			"original":    newTx("original", Outpoint{"parent", 0}),
+			"doublespent": newTx("doublespent", Outpoint{"parent", 1}),
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1)
+	m.AddrDescForOutpoint = func(outpoint Outpoint) (AddressDescriptor, *big.Int) {
+		ad, _ := hex.DecodeString(addr1)
+		return ad, big.NewInt(2000)
+	}
+	m.SpendingTxidForOutpoint = func(outpoint Outpoint) string {
+		if outpoint == (Outpoint{"parent", 1}) {
+			return "blockspend"
+		}
+		return ""
+	}
+	// the dropped transactions are not retained, the replacements are still notified
+	replaced := make(map[string]string)
+	m.OnTxReplaced = func(replacedTxid, replacementTxid string) {
+		replaced[replacedTxid] = replacementTxid
+	}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	delete(chain.txs, "original")
+	delete(chain.txs, "doublespent")
+	chain.txs["replacement"] = newTx("replacement", Outpoint{"parent", 0})
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"original": "replacement", "doublespent": "blockspend"}; !reflect.DeepEqual(replaced, want) {
+		t.Errorf("OnTxReplaced notified %v, want %v", replaced, want)
+	}
+	if d := m.GetDroppedTransaction("original"); d != nil {
+		t.Errorf("GetDroppedTransaction() = %+v, want nil", d)
+	}
+}
+
+func TestMempoolBitcoinType_MaxTransactions(t *testing.T) {
+	const addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+	newTx := func(txid string) *Tx {
+		return &Tx{
+			Txid:  txid,
+			VSize: 50,
+			Vin:   []Vin{{Txid: "parent", Vout: 0}},
+			Vout:  []Vout{{N: 0, ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: addr1}}},
+		}
+	}
+	chain := &testMempoolChain{
+		txs: map[string]*Tx{
+			"lowest":  newTx("lowest"),
+			"low":     newTx("low"),
+			"high":    newTx("high"),
+			"highest": newTx("highest"),
+			// the fee is not reported by the backend, it is computed from the inputs and outputs: (2000-1000)/50 sat/vB
+			"computed": newTx("computed"),
+		},
+		fees: map[string]int64{
+			"lowest":  500,
+			"low":     1000,
+			"high":    5000,
+			"highest": 9000,
+		},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1)
+	m.AddrDescForOutpoint = func(outpoint Outpoint) (AddressDescriptor, *big.Int) {
+		ad, _ := hex.DecodeString(addr1)
+		return ad, big.NewInt(2000)
+	}
+	m.MaxTransactions = 3
+
+	checkMempool := func(want []string, wantEvicted []string) {
+		t.Helper()
+		got := make([]string, 0, len(m.txEntries))
+		for txid := range m.txEntries {
+			got = append(got, txid)
+		}
+		gotEvicted := make([]string, 0, len(m.evicted))
+		for txid := range m.evicted {
+			gotEvicted = append(gotEvicted, txid)
+		}
+		sort.Strings(got)
+		sort.Strings(gotEvicted)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("transactions = %v, want %v", got, want)
+		}
+		if !reflect.DeepEqual(gotEvicted, wantEvicted) {
+			t.Errorf("evicted = %v, want %v", gotEvicted, wantEvicted)
+		}
+	}
+
+	// the transactions with the lowest fee rate are evicted, the evicted transactions are not fetched again
+	for i := 0; i < 2; i++ {
+		count, err := m.Resync()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("Resync() = %v, want %v", count, 3)
+		}
+		checkMempool([]string{"computed", "high", "highest"}, []string{"low", "lowest"})
+	}
+	ad, _ := hex.DecodeString(addr1)
+	outpoints, err := m.GetAddrDescTransactions(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range outpoints {
+		if o.Txid == "low" || o.Txid == "lowest" {
+			t.Errorf("GetAddrDescTransactions() returned evicted transaction %v", o.Txid)
+		}
+	}
+
+	// after a transaction leaves the mempool, the evicted transaction with the highest fee rate is tracked again
+	delete(chain.txs, "highest")
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	checkMempool([]string{"computed", "high"}, []string{"low", "lowest"})
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	checkMempool([]string{"computed", "high", "low"}, []string{"lowest"})
+
+	// evicted transaction is forgotten when the backend drops it
+	delete(chain.txs, "lowest")
+	if _, err = m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	checkMempool([]string{"computed", "high", "low"}, []string{})
+}
+
+func TestMempoolBitcoinType_EvictionHysteresis(t *testing.T) {
+	const addr1 = "76a914010d39800f86122416e28f485029acf77507169288ac"
+	chain := &testMempoolChain{
+		txs:  map[string]*Tx{},
+		fees: map[string]int64{},
+	}
+	addTx := func(i int) string {
+		txid := fmt.Sprintf("tx%02d", i)
+		chain.txs[txid] = &Tx{
+			Txid: txid,
+			Vin:  []Vin{{Coinbase: "03"}},
+			Vout: []Vout{{N: 0, ValueSat: *big.NewInt(1000), ScriptPubKey: ScriptPubKey{Hex: addr1}}},
+		}
+		chain.fees[txid] = int64(i * 100)
+		return txid
+	}
+	for i := 1; i <= 12; i++ {
+		addTx(i)
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1)
+	m.MaxTransactions = 10
+
+	resync := func(wantCount int, wantFetched int) {
+		t.Helper()
+		chain.fetched = 0
+		count, err := m.Resync()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != wantCount {
+			t.Errorf("Resync() = %v, want %v", count, wantCount)
+		}
+		if chain.fetched != wantFetched {
+			t.Errorf("fetched transactions = %v, want %v", chain.fetched, wantFetched)
+		}
+	}
+
+	// the index is reduced to the low watermark of 9 transactions
+	resync(9, 12)
+	for _, txid := range []string{"tx01", "tx02", "tx03"} {
+		if _, found := m.evicted[txid]; !found {
+			t.Errorf("%v is not evicted", txid)
+		}
+	}
+	// the same mempool does not cause any eviction or readmission
+	resync(9, 0)
+
+	// a transaction leaving the mempool makes space for one evicted transaction on the next resync
+	delete(chain.txs, "tx12")
+	resync(8, 0)
+	resync(9, 1)
+	if _, found := m.txEntries["tx03"]; !found {
+		t.Error("tx03 is not readmitted")
+	}
+	resync(9, 0)
+
+	// new transactions fill the gap up to MaxTransactions without eviction
+	addTx(13)
+	resync(10, 1)
+	addTx(14)
+	resync(9, 1)
+	for _, txid := range []string{"tx03", "tx04"} {
+		if _, found := m.evicted[txid]; !found {
+			t.Errorf("%v is not evicted", txid)
+		}
+	}
+}
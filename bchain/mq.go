@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/juju/errors"
 	zmq "github.com/pebbe/zmq4"
 )
 
@@ -16,6 +17,7 @@ type MQ struct {
 	isRunning bool
 	finished  chan error
 	binding   string
+	topics    []string
 }
 
 // NotificationType is type of notification
@@ -30,9 +32,26 @@ const (
 	NotificationNewTx NotificationType = iota
 )
 
-// NewMQ creates new Bitcoind ZeroMQ listener
+// DefaultMQTopics are the ZeroMQ topics subscribed if no topics are configured
+var DefaultMQTopics = []string{"hashblock", "hashtx"}
+
+// NewMQ creates new Bitcoind ZeroMQ listener subscribed to the DefaultMQTopics
 // callback function receives messages
 func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
+	return NewMQWithTopics(binding, DefaultMQTopics, callback)
+}
+
+// NewMQWithTopics creates new Bitcoind ZeroMQ listener subscribed to the given topics
+// (hashblock, hashtx, rawblock, rawtx), callback function receives messages
+func NewMQWithTopics(binding string, topics []string, callback func(NotificationType)) (*MQ, error) {
+	if len(topics) == 0 {
+		topics = DefaultMQTopics
+	}
+	for _, t := range topics {
+		if mqTopicNotificationType(t) == NotificationUnknown {
+			return nil, errors.Errorf("MQ: unsupported topic %v", t)
+		}
+	}
 	context, err := zmq.NewContext()
 	if err != nil {
 		return nil, err
@@ -41,28 +60,34 @@ func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = socket.SetSubscribe("hashblock")
-	if err != nil {
-		return nil, err
-	}
-	err = socket.SetSubscribe("hashtx")
-	if err != nil {
-		return nil, err
+	// the raw topics are used only as a trigger, the payload is not processed
+	// on each notification we do sync or syncmempool respectively, therefore skipped/lost notifications do not matter
+	for _, t := range topics {
+		err = socket.SetSubscribe(t)
+		if err != nil {
+			return nil, err
+		}
 	}
-	// for now do not use raw subscriptions - we would have to handle skipped/lost notifications from zeromq
-	// on each notification we do sync or syncmempool respectively
-	// socket.SetSubscribe("rawblock")
-	// socket.SetSubscribe("rawtx")
 	err = socket.Connect(binding)
 	if err != nil {
 		return nil, err
 	}
-	glog.Info("MQ listening to ", binding)
-	mq := &MQ{context, socket, true, make(chan error), binding}
+	glog.Info("MQ listening to ", binding, ", topics ", topics)
+	mq := &MQ{context, socket, true, make(chan error), binding, topics}
 	go mq.run(callback)
 	return mq, nil
 }
 
+func mqTopicNotificationType(topic string) NotificationType {
+	switch topic {
+	case "hashblock", "rawblock":
+		return NotificationNewBlock
+	case "hashtx", "rawtx":
+		return NotificationNewTx
+	}
+	return NotificationUnknown
+}
+
 func (mq *MQ) run(callback func(NotificationType)) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -93,14 +118,8 @@ func (mq *MQ) run(callback func(NotificationType)) {
 			repeatedError = false
 		}
 		if len(msg) >= 3 {
-			var nt NotificationType
-			switch string(msg[0]) {
-			case "hashblock":
-				nt = NotificationNewBlock
-			case "hashtx":
-				nt = NotificationNewTx
-			default:
-				nt = NotificationUnknown
+			nt := mqTopicNotificationType(string(msg[0]))
+			if nt == NotificationUnknown {
 				glog.Infof("MQ: NotificationUnknown %v", string(msg[0]))
 			}
 			if glog.V(2) {
@@ -121,13 +140,11 @@ func (mq *MQ) Shutdown(ctx context.Context) error {
 	if mq.isRunning {
 		go func() {
 			// if errors in the closing sequence, let it close ungracefully
-			if err := mq.socket.SetUnsubscribe("hashtx"); err != nil {
-				mq.finished <- err
-				return
-			}
-			if err := mq.socket.SetUnsubscribe("hashblock"); err != nil {
-				mq.finished <- err
-				return
+			for _, t := range mq.topics {
+				if err := mq.socket.SetUnsubscribe(t); err != nil {
+					mq.finished <- err
+					return
+				}
 			}
 			if err := mq.socket.Unbind(mq.binding); err != nil {
 				mq.finished <- err
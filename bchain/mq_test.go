@@ -0,0 +1,86 @@
+//go:build unittest
+
+package bchain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	zmq "github.com/pebbe/zmq4"
+)
+
+func Test_mqTopicNotificationType(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  NotificationType
+	}{
+		{"hashblock", NotificationNewBlock},
+		{"rawblock", NotificationNewBlock},
+		{"hashtx", NotificationNewTx},
+		{"rawtx", NotificationNewTx},
+		{"sequence", NotificationUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			if got := mqTopicNotificationType(tt.topic); got != tt.want {
+				t.Errorf("mqTopicNotificationType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMQWithTopics_unsupportedTopic(t *testing.T) {
+	if _, err := NewMQWithTopics("tcp://127.0.0.1:1", []string{"sequence"}, func(NotificationType) {}); err == nil {
+		t.Error("NewMQWithTopics() expected error for unsupported topic")
+	}
+}
+
+func TestMQ_topicTriggersNotification(t *testing.T) {
+	tests := []struct {
+		topic   string
+		binding string
+		want    NotificationType
+	}{
+		{"hashblock", "tcp://127.0.0.1:38339", NotificationNewBlock},
+		{"rawblock", "tcp://127.0.0.1:38340", NotificationNewBlock},
+		{"rawtx", "tcp://127.0.0.1:38341", NotificationNewTx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			pub, err := zmq.NewSocket(zmq.PUB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pub.Close()
+			if err = pub.Bind(tt.binding); err != nil {
+				t.Fatal(err)
+			}
+			chanSync := make(chan NotificationType, 100)
+			mq, err := NewMQWithTopics(tt.binding, []string{"hashblock", "rawblock", "rawtx"}, func(nt NotificationType) {
+				chanSync <- nt
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mq.Shutdown(context.Background())
+			// the subscription is established asynchronously, publish until the notification arrives
+			timeout := time.After(5 * time.Second)
+			for {
+				if _, err = pub.SendMessage(tt.topic, []byte{0, 1, 2, 3}, []byte{1, 0, 0, 0}); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case nt := <-chanSync:
+					if nt != tt.want {
+						t.Fatalf("got notification %v, want %v", nt, tt.want)
+					}
+					return
+				case <-time.After(50 * time.Millisecond):
+				case <-timeout:
+					t.Fatal("timeout waiting for the notification")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bchain
+
+import "github.com/juju/errors"
+
+// GetInputsPrevouts returns the outputs spent by the inputs of the transaction
+// all distinct parent transactions are fetched from the backend in one batched call using GetTransactions
+// the items of coinbase inputs and of inputs spending unknown transactions or outputs are nil
+func GetInputsPrevouts(chain BlockChain, tx *Tx) ([]*Vout, error) {
+	prevouts := make([]*Vout, len(tx.Vin))
+	txids := make([]string, 0, len(tx.Vin))
+	parents := make(map[string]*Tx, len(tx.Vin))
+	for i := range tx.Vin {
+		txid := tx.Vin[i].Txid
+		if txid == "" {
+			continue
+		}
+		if _, found := parents[txid]; !found {
+			parents[txid] = nil
+			txids = append(txids, txid)
+		}
+	}
+	if len(txids) == 0 {
+		return prevouts, nil
+	}
+	txs, err := chain.GetTransactions(txids)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTransactions %v", tx.Txid)
+	}
+	if len(txs) != len(txids) {
+		return nil, errors.Errorf("GetTransactions %v: got %v transactions, expected %v", tx.Txid, len(txs), len(txids))
+	}
+	for i, txid := range txids {
+		parents[txid] = txs[i]
+	}
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if parent := parents[vin.Txid]; parent != nil && int(vin.Vout) < len(parent.Vout) {
+			prevouts[i] = &parent.Vout[vin.Vout]
+		}
+	}
+	return prevouts, nil
+}
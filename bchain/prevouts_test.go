@@ -0,0 +1,91 @@
+//go:build unittest
+
+package bchain
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// testBatchChain is a backend with fixed transactions counting the calls fetching them
+type testBatchChain struct {
+	BlockChain
+	txs        map[string]*Tx
+	batchCalls int
+	txCalls    int
+}
+
+func (c *testBatchChain) GetTransaction(txid string) (*Tx, error) {
+	c.txCalls++
+	tx, found := c.txs[txid]
+	if !found {
+		return nil, ErrTxNotFound
+	}
+	return tx, nil
+}
+
+func (c *testBatchChain) GetTransactions(txids []string) ([]*Tx, error) {
+	c.batchCalls++
+	txs := make([]*Tx, len(txids))
+	for i, txid := range txids {
+		txs[i] = c.txs[txid]
+	}
+	return txs, nil
+}
+
+func TestGetInputsPrevouts(t *testing.T) {
+	newTx := func(txid string, values ...int64) *Tx {
+		tx := &Tx{Txid: txid}
+		for i, v := range values {
+			tx.Vout = append(tx.Vout, Vout{N: uint32(i), ValueSat: *big.NewInt(v)})
+		}
+		return tx
+	}
+	chain := &testBatchChain{
+		txs: map[string]*Tx{
+			"parent1": newTx("parent1", 1000, 2000),
+			"parent2": newTx("parent2", 3000),
+			"parent3": newTx("parent3", 4000, 5000, 6000),
+		},
+	}
+	tx := &Tx{
+		Txid: "child",
+		Vin: []Vin{
+			{Txid: "parent1", Vout: 1},
+			{Txid: "parent2", Vout: 0},
+			{Txid: "parent3", Vout: 2},
+			{Txid: "parent1", Vout: 0},
+			{Txid: "unknown", Vout: 0},
+			{Txid: "parent2", Vout: 5},
+		},
+	}
+	prevouts, err := GetInputsPrevouts(chain, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.batchCalls != 1 || chain.txCalls != 0 {
+		t.Errorf("GetInputsPrevouts() made %v batched and %v single calls, want 1 batched call", chain.batchCalls, chain.txCalls)
+	}
+	want := []*Vout{
+		&chain.txs["parent1"].Vout[1],
+		&chain.txs["parent2"].Vout[0],
+		&chain.txs["parent3"].Vout[2],
+		&chain.txs["parent1"].Vout[0],
+		nil,
+		nil,
+	}
+	if !reflect.DeepEqual(prevouts, want) {
+		t.Errorf("GetInputsPrevouts() = %+v, want %+v", prevouts, want)
+	}
+
+	// coinbase transaction does not need any call
+	chain.batchCalls = 0
+	prevouts, err = GetInputsPrevouts(chain, &Tx{Txid: "coinbase", Vin: []Vin{{Coinbase: "03"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.batchCalls != 0 || !reflect.DeepEqual(prevouts, []*Vout{nil}) {
+		t.Errorf("GetInputsPrevouts() = %+v with %v batched calls, want [nil] with no calls", prevouts, chain.batchCalls)
+	}
+}
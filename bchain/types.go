@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/trezor/blockbook/common"
 )
@@ -35,6 +36,9 @@ var (
 	ErrTxidMissing = errors.New("Txid missing")
 	// ErrTxNotFound is returned if transaction was not found
 	ErrTxNotFound = errors.New("Tx not found")
+	// ErrNotSupported is returned if the requested data are not provided by the backend
+	// for example block filters without the block filter index of the backend
+	ErrNotSupported = errors.New("Not supported")
 )
 
 // Outpoint is txid together with output (or input) index
@@ -90,9 +94,27 @@ type Tx struct {
 	Confirmations    uint32      `json:"confirmations,omitempty"`
 	Time             int64       `json:"time,omitempty"`
 	Blocktime        int64       `json:"blocktime,omitempty"`
+	VersionGroupID   string      `json:"versiongroupid,omitempty"` // Zcash overwintered transactions
+	Mweb             MwebFlags   `json:"-"`                        // Litecoin MWEB components of the transaction
 	CoinSpecificData interface{} `json:"-"`
 }
 
+// MwebFlags describe the Litecoin MWEB (MimbleWimble Extension Block) components of a transaction
+type MwebFlags uint8
+
+const (
+	// MwebHogEx is set for the integrating (HogEx) transaction of the block, which holds the coins of the extension block
+	MwebHogEx MwebFlags = 1 << iota
+	// MwebPegin is set if the transaction moves coins from the canonical chain to the extension block
+	MwebPegin
+	// MwebPegout is set if the transaction moves coins from the extension block to the canonical chain
+	MwebPegout
+	// MwebInputs is set if the transaction spends confidential outputs of the extension block
+	MwebInputs
+	// MwebOutputs is set if the transaction creates confidential outputs in the extension block
+	MwebOutputs
+)
+
 // MempoolVin contains data about tx input
 type MempoolVin struct {
 	Vin
@@ -160,9 +182,18 @@ type BlockHeader struct {
 	Height        uint32 `json:"height"`
 	Confirmations int    `json:"confirmations"`
 	Size          int    `json:"size"`
+	StrippedSize  int    `json:"strippedsize,omitempty"` // size of the block without witness data
+	Weight        int    `json:"weight,omitempty"`
 	Time          int64  `json:"time,omitempty"`
 }
 
+// BlockFilter contains the BIP158 basic compact filter of a block and the BIP157 filter header
+// the filter header commits to the filter and to the filter header of the previous block
+type BlockFilter struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
 // BlockInfo contains extended block header data and a list of block txids
 type BlockInfo struct {
 	BlockHeader
@@ -192,6 +223,21 @@ type MempoolEntry struct {
 	Depends         []string          `json:"depends"`
 }
 
+// MempoolInfo contains the summary of the mempool of the backend
+type MempoolInfo struct {
+	Size        int
+	Bytes       int64
+	TotalFeeSat big.Int
+	MinFeeSat   big.Int
+}
+
+// MempoolFeeRateBucket is a bucket of the fee histogram of the mempool, VSize is the total virtual size of the transactions
+// paying at least FeeRate satoshis per vbyte and less than the FeeRate of the preceding bucket
+type MempoolFeeRateBucket struct {
+	FeeRate float64
+	VSize   int64
+}
+
 // ChainInfo is used to get information about blockchain
 type ChainInfo struct {
 	Chain            string      `json:"chain"`
@@ -205,6 +251,7 @@ type ChainInfo struct {
 	ProtocolVersion  string      `json:"protocolversion"`
 	Timeoffset       float64     `json:"timeoffset"`
 	Warnings         string      `json:"warnings"`
+	MinRelayTxFee    string      `json:"minrelaytxfee,omitempty"`
 	ConsensusVersion string      `json:"consensus_version,omitempty"`
 	Consensus        interface{} `json:"consensus,omitempty"`
 }
@@ -234,6 +281,9 @@ func AddressDescriptorFromString(s string) (AddressDescriptor, error) {
 	return nil, errors.New("invalid address descriptor")
 }
 
+// LockTimeThreshold - lock times lower than the threshold are block heights, the others are unix timestamps
+const LockTimeThreshold = 500000000
+
 // MempoolTxidEntry contains mempool txid with first seen time
 type MempoolTxidEntry struct {
 	Txid string
@@ -265,6 +315,26 @@ type XpubDescriptor struct {
 // MempoolTxidEntries is array of MempoolTxidEntry
 type MempoolTxidEntries []MempoolTxidEntry
 
+// MempoolDropReason is the reason why a transaction left the mempool without being confirmed
+type MempoolDropReason string
+
+const (
+	// MempoolDropReplaced - an input of the transaction is spent by another mempool transaction or by another confirmed transaction
+	MempoolDropReplaced = MempoolDropReason("replaced")
+	// MempoolDropConflicted - the transaction spends an output of a replaced or conflicted transaction
+	MempoolDropConflicted = MempoolDropReason("conflicted")
+	// MempoolDropExpired - the transaction is older than the maximum age of mempool transactions
+	MempoolDropExpired = MempoolDropReason("expired")
+)
+
+// MempoolDroppedTx is the last known state of a transaction dropped from the mempool
+type MempoolDroppedTx struct {
+	Tx         *MempoolTx
+	Reason     MempoolDropReason
+	ReplacedBy string
+	DropTime   int64
+}
+
 // OnNewBlockFunc is used to send notification about a new block
 type OnNewBlockFunc func(hash string, height uint32)
 
@@ -274,9 +344,15 @@ type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 // OnNewTxFunc is used to send notification about a new transaction/address
 type OnNewTxFunc func(tx *MempoolTx)
 
+// OnTxReplacedFunc is used to send notification about a mempool transaction replaced by another transaction
+type OnTxReplacedFunc func(replacedTxid, replacementTxid string)
+
 // AddrDescForOutpointFunc returns address descriptor and value for given outpoint or nil if outpoint not found
 type AddrDescForOutpointFunc func(outpoint Outpoint) (AddressDescriptor, *big.Int)
 
+// SpendingTxidForOutpointFunc returns the txid of the confirmed transaction spending given outpoint or empty string if not known
+type SpendingTxidForOutpointFunc func(outpoint Outpoint) string
+
 // BlockChain defines common interface to block chain daemon
 type BlockChain interface {
 	// life-cycle methods
@@ -285,7 +361,7 @@ type BlockChain interface {
 	// create mempool but do not initialize it
 	CreateMempool(BlockChain) (Mempool, error)
 	// initialize mempool, create ZeroMQ (or other) subscription
-	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc, OnNewTxFunc) error
+	InitializeMempool(AddrDescForOutpointFunc, SpendingTxidForOutpointFunc, OnNewTxAddrFunc, OnNewTxFunc, OnTxReplacedFunc) error
 	// shutdown mempool, ZeroMQ and block chain connections
 	Shutdown(ctx context.Context) error
 	// chain info
@@ -294,28 +370,46 @@ type BlockChain interface {
 	GetSubversion() string
 	GetCoinName() string
 	GetChainInfo() (*ChainInfo, error)
+	GetBackendChainInfoRaw() (json.RawMessage, error)
 	// requests
 	GetBestBlockHash() (string, error)
 	GetBestBlockHeight() (uint32, error)
 	GetBlockHash(height uint32) (string, error)
 	GetBlockHeader(hash string) (*BlockHeader, error)
+	GetBlockHeaderHex(hash string) (string, error)
 	GetBlock(hash string, height uint32) (*Block, error)
 	GetBlockInfo(hash string) (*BlockInfo, error)
 	GetBlockRaw(hash string) (string, error)
+	GetBlockFilter(hash string) (*BlockFilter, error)
+	GetBlockMiner(hash string) (string, error)
+	MatchBlockMiner(block *Block) string
 	GetMempoolTransactions() ([]string, error)
 	GetTransaction(txid string) (*Tx, error)
+	GetTransactions(txids []string) ([]*Tx, error)
 	GetTransactionForMempool(txid string) (*Tx, error)
+	GetTransactionHex(txid string) (string, error)
 	GetTransactionSpecific(tx *Tx) (json.RawMessage, error)
+	GetBackendNames() []string
+	GetTransactionSpecificFromBackend(backend string, tx *Tx) (json.RawMessage, error)
+	GetBlockSpecificFromBackend(backend string, hash string) (json.RawMessage, error)
+	GetBlockRawFromBackend(backend string, hash string) (string, error)
+	EstimateSmartFeeFromBackend(backend string, blocks int, conservative bool) (big.Int, error)
 	EstimateSmartFee(blocks int, conservative bool) (big.Int, error)
 	EstimateFee(blocks int) (big.Int, error)
 	SendRawTransaction(tx string) (string, error)
 	GetMempoolEntry(txid string) (*MempoolEntry, error)
+	GetMempoolEntries(txids []string) ([]*MempoolEntry, error)
+	GetMempoolMinFee() (*big.Int, error)
+	GetMempoolInfo() (*MempoolInfo, error)
+	GetMempoolFeeHistogram() ([]MempoolFeeRateBucket, error)
+	GetBlockFeeRatePercentiles(height uint32) ([]float64, error)
 	GetContractInfo(contractDesc AddressDescriptor) (*ContractInfo, error)
 	// parser
 	GetChainParser() BlockChainParser
 	// EthereumType specific
 	EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error)
 	EthereumTypeGetNonce(addrDesc AddressDescriptor) (uint64, error)
+	EthereumTypeGetCode(addrDesc AddressDescriptor) ([]byte, error)
 	EthereumTypeEstimateGas(params map[string]interface{}) (uint64, error)
 	EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc AddressDescriptor) (*big.Int, error)
 	GetTokenURI(contractDesc AddressDescriptor, tokenID *big.Int) (string, error)
@@ -336,9 +430,15 @@ type BlockChainParser interface {
 	MinimumCoinbaseConfirmations() int
 	// SupportsVSize returns true if vsize of a transaction should be computed and returned by API
 	SupportsVSize() bool
+	// IsCoinStake returns true if the transaction is a proof-of-stake coinstake transaction
+	IsCoinStake(tx *Tx) bool
+	// IsStandardTx returns false and the reason if the transaction is not standard by the relay policy of the backend,
+	// prevOutputs are the output scripts spent by the inputs of the transaction, if known
+	IsStandardTx(tx *Tx, prevOutputs []AddressDescriptor) (bool, string, error)
 	// AmountToDecimalString converts amount in big.Int to string with decimal point in the correct place
 	AmountToDecimalString(a *big.Int) string
 	// AmountToBigInt converts amount in common.JSONNumber (string) to big.Int
+	// amount with more decimal places than the coin supports is rejected
 	// it uses string operations to avoid problems with rounding
 	AmountToBigInt(n common.JSONNumber) (big.Int, error)
 	// address descriptor conversions
@@ -347,6 +447,12 @@ type BlockChainParser interface {
 	GetAddressesFromAddrDesc(addrDesc AddressDescriptor) ([]string, bool, error)
 	GetScriptFromAddrDesc(addrDesc AddressDescriptor) ([]byte, error)
 	IsAddrDescIndexable(addrDesc AddressDescriptor) bool
+	// NormalizeAddress converts the address entered by the user to the form accepted by GetAddrDescFromAddress
+	NormalizeAddress(address string) string
+	// GetAddressType returns the type of the output script of the address and false if the address is not for the network of the parser
+	GetAddressType(address string) (string, bool, error)
+	// GetLockTimeFromAddrDesc returns the absolute lock time required by OP_CHECKLOCKTIMEVERIFY in the output script, 0 if there is none
+	GetLockTimeFromAddrDesc(addrDesc AddressDescriptor) (uint32, error)
 	// transactions
 	PackedTxidLen() int
 	PackTxid(txid string) ([]byte, error)
@@ -360,6 +466,10 @@ type BlockChainParser interface {
 	PackBlockHash(hash string) ([]byte, error)
 	UnpackBlockHash(buf []byte) (string, error)
 	ParseBlock(b []byte) (*Block, error)
+	// emission
+	GetBlockSubsidy(height uint32) (*big.Int, error)
+	GetBlocksToNextHalving(height uint32) (uint32, error)
+	GetTargetBlockTime() (time.Duration, error)
 	// xpub
 	ParseXpub(xpub string) (*XpubDescriptor, error)
 	DerivationBasePath(descriptor *XpubDescriptor) (string, error)
@@ -378,4 +488,5 @@ type Mempool interface {
 	GetAddrDescTransactions(addrDesc AddressDescriptor) ([]Outpoint, error)
 	GetAllEntries() MempoolTxidEntries
 	GetTransactionTime(txid string) uint32
+	GetDroppedTransaction(txid string) *MempoolDroppedTx
 }
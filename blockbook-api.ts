@@ -52,6 +52,14 @@ export interface TokenTransfer {
     value?: string;
     multiTokenValues?: MultiTokenValue[];
 }
+export interface NftTransfer {
+    type: 'ERC721' | 'ERC1155';
+    contract: string;
+    from: string;
+    to: string;
+    tokenId: string;
+    amount: string;
+}
 export interface Vout {
     value?: string;
     n: number;
@@ -65,6 +73,8 @@ export interface Vout {
     isAddress: boolean;
     isOwn?: boolean;
     type?: string;
+    lockTimeHeight?: number;
+    lockTimeTimestamp?: number;
 }
 export interface Vin {
     txid?: string;
@@ -87,10 +97,12 @@ export interface Tx {
     vout: Vout[];
     blockHash?: string;
     blockHeight: number;
+    blockIndex?: number;
     confirmations: number;
     confirmationETABlocks?: number;
     confirmationETASeconds?: number;
     blockTime: number;
+    firstSeen?: number;
     size?: number;
     vsize?: number;
     value: string;
@@ -98,17 +110,72 @@ export interface Tx {
     fees?: string;
     hex?: string;
     rbf?: boolean;
+    standard?: boolean;
+    nonStandardReason?: string;
+    dropReason?: string;
+    replacedBy?: string;
+    replacementTxid?: string;
+    replacementConfirmations?: number;
+    replaces?: string[];
+    coinStake?: boolean;
+    coinJoinLikely?: boolean;
+    coinJoinAnonymitySet?: number;
+    memo?: string;
+    changeIndex?: number;
+    changeConfidence?: number;
     coinSpecificData?: any;
     tokenTransfers?: TokenTransfer[];
+    nftTransfers?: NftTransfer[];
     ethereumSpecific?: EthereumSpecific;
     addressAliases?: { [key: string]: AddressAlias };
 }
+export interface TxRecipient {
+    n: number;
+    addresses: string[];
+    isAddress: boolean;
+    value: string;
+}
+export interface TxRecipients {
+    txid: string;
+    blockHeight: number;
+    confirmations: number;
+    recipients: TxRecipient[];
+}
+export interface TxInputPrevout {
+    txid: string;
+    n: number;
+    prevTxid: string;
+    prevVout: number;
+    prevBlockHeight: number;
+    addresses: string[];
+    isAddress: boolean;
+    value: string;
+    hex?: string;
+}
 export interface FeeStats {
     txCount: number;
     totalFeesSat: string;
     averageFeePerKb: number;
     decilesFeePerKb: number[];
 }
+export interface MempoolInfo {
+    count: number;
+    totalVSize: number;
+    totalFees: string;
+    minFee: string;
+}
+export interface SyncStatus {
+    coin: string;
+    shortcut: string;
+    network: string;
+    bestHeight: number;
+    backendHeight: number;
+    lag: number;
+    inSync: boolean;
+    backendVersion?: string;
+    backendSubversion?: string;
+    backendError?: string;
+}
 export interface ContractInfo {
     type: string;
     contract: string;
@@ -134,6 +201,20 @@ export interface Token {
     totalReceived?: string;
     totalSent?: string;
 }
+export interface AddressSummary {
+    address: string;
+    balance: string;
+    totalReceived: string;
+    totalSent: string;
+    txs: number;
+    firstSeenHeight?: number;
+    firstSeenTime?: number;
+    firstSeenTxid?: string;
+    lastSeenHeight?: number;
+    lastSeenTime?: number;
+    reuseCount?: number;
+    firstReceivedHeight?: number;
+}
 export interface Address {
     page?: number;
     totalPages?: number;
@@ -150,6 +231,7 @@ export interface Address {
     transactions?: Tx[];
     txids?: string[];
     nonce?: string;
+    isContract?: boolean;
     usedTokens?: number;
     tokens?: Token[];
     secondaryValue?: number;
@@ -161,6 +243,29 @@ export interface Address {
     erc20Contract?: ContractInfo;
     addressAliases?: { [key: string]: AddressAlias };
 }
+export interface AddressBalance {
+    address: string;
+    balance: string;
+    totalReceived?: string;
+    totalSent?: string;
+    unconfirmedBalance: string;
+    unconfirmedTxs: number;
+    txs: number;
+}
+export interface AddressTokenTransfer {
+    txid: string;
+    blockHeight: number;
+    blockTime: number;
+    transfer: TokenTransfer;
+}
+export interface AddressTokenTransfers {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    address: string;
+    contract?: string;
+    transfers: AddressTokenTransfer[];
+}
 export interface Utxo {
     txid: string;
     vout: number;
@@ -169,6 +274,7 @@ export interface Utxo {
     confirmations: number;
     address?: string;
     path?: string;
+    script?: string;
     lockTime?: number;
     coinbase?: boolean;
 }
@@ -181,6 +287,19 @@ export interface BalanceHistory {
     rates?: { [key: string]: number };
     txid?: string;
 }
+export interface BalanceDelta {
+    height: number;
+    time: number;
+    txs: number;
+    delta: string;
+}
+export interface BalanceDeltas {
+    address: string;
+    fromHeight: number;
+    toHeight: number;
+    totalDelta: string;
+    deltas: BalanceDelta[];
+}
 export interface BlockInfo {
     Hash: string;
     Time: number;
@@ -204,7 +323,10 @@ export interface Block {
     height: number;
     confirmations: number;
     size: number;
+    strippedSize?: number;
+    weight?: number;
     time?: number;
+    medianTimePast?: number;
     version: string;
     merkleRoot: string;
     nonce: string;
@@ -212,12 +334,134 @@ export interface Block {
     difficulty: string;
     tx?: string[];
     txCount: number;
+    totalInputs?: number;
+    totalOutputs?: number;
+    reward?: BlockReward;
     txs?: Tx[];
     addressAliases?: { [key: string]: AddressAlias };
 }
+export interface BlockReward {
+    subsidy?: string;
+    fees: string;
+}
 export interface BlockRaw {
     hex: string;
 }
+export interface BlockFilter {
+    blockHash: string;
+    filter: string;
+    header: string;
+}
+export interface BlockTopTx {
+    txid: string;
+    value: string;
+    vsize?: number;
+    size?: number;
+}
+export interface BlockTopTxs {
+    hash: string;
+    height: number;
+    by: string;
+    txs: BlockTopTx[];
+}
+export interface ValueOutput {
+    txid: string;
+    vout: number;
+    value: string;
+    blockHeight: number;
+}
+export interface ValueOutputs {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    minValue: string;
+    maxValue: string;
+    outputs: ValueOutput[];
+}
+export interface PsbtInput {
+    txid: string;
+    vout: number;
+    value: string;
+    script: string;
+    addresses?: string[];
+    witnessUtxo: boolean;
+    nonWitnessUtxo: boolean;
+}
+export interface EnrichedPsbt {
+    psbt: string;
+    inputs: PsbtInput[];
+}
+export interface MinerBlocks {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    miner: string;
+    blocks: BlockInfo[];
+}
+export interface NewAddress {
+    address: string;
+    height: number;
+    txid: string;
+}
+export interface NewAddresses {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    fromHeight: number;
+    toHeight: number;
+    addresses: NewAddress[];
+}
+export interface ScriptOutputTx {
+    txid: string;
+    blockHeight: number;
+    vouts: number[];
+}
+export interface ScriptOutputTxs {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    script: string;
+    txs: ScriptOutputTx[];
+}
+export interface DifficultyPoint {
+    height: number;
+    time: number;
+    difficulty: number;
+    hashrate: number;
+}
+export interface DifficultyHistory {
+    targetBlockTime: number;
+    interval: number;
+    points: DifficultyPoint[];
+}
+export interface GenesisInfo {
+    hash: string;
+    time: number;
+    coinbaseTxid?: string;
+    coinbaseScript?: string;
+    coinbaseMessage?: string;
+}
+export interface AddressValidation {
+    address: string;
+    isValid: boolean;
+    scriptType?: string;
+    isForNetwork: boolean;
+    reason?: string;
+}
+export interface SpendingEdge {
+    txid: string;
+    vout: number;
+    spentTxid: string;
+    depth: number;
+    unknown?: boolean;
+}
+export interface SpendingGraph {
+    txid: string;
+    vout: number;
+    depth: number;
+    edges: SpendingEdge[];
+    truncated?: boolean;
+}
 export interface BackendInfo {
     error?: string;
     chain?: string;
@@ -231,6 +475,8 @@ export interface BackendInfo {
     protocolVersion?: string;
     timeOffset?: number;
     warnings?: string;
+    mempoolMinFee?: string;
+    minRelayTxFee?: string;
     consensus_version?: string;
     consensus?: any;
 }
@@ -279,6 +525,30 @@ export interface FiatTicker {
 export interface FiatTickers {
     tickers: FiatTicker[];
 }
+export interface TxConfirmationPolicy {
+    txid: string;
+    currency: string;
+    rate: number;
+    fiatValue: number;
+    confirmations: number;
+    requiredConfirmations: number;
+    met: boolean;
+}
+export interface ConfirmationProbability {
+    txid: string;
+    blocks: number;
+    probability: number;
+    confirmations?: number;
+    feeRate?: number;
+    mempoolVSizeAhead?: number;
+    blockInclusionRate?: number;
+}
+export interface NextBlockFee {
+    conservative: boolean;
+    estimatedFeeRate: number;
+    mempoolFeeRate: number;
+    mempoolVSize: number;
+}
 export interface AvailableVsCurrencies {
     ts?: number;
     available_currencies: string[];
@@ -326,6 +596,7 @@ export interface WsAccountInfoReq {
     contractFilter?: string;
     secondaryCurrency?: string;
     gap?: number;
+    changeGap?: number;
 }
 export interface WsBackendInfo {
     version?: string;
@@ -364,6 +635,7 @@ export interface WsBalanceHistoryReq {
     to?: number;
     currencies?: string[];
     gap?: number;
+    changeGap?: number;
     groupBy?: number;
 }
 export interface WsTransactionReq {
@@ -393,6 +665,7 @@ export interface WsSendTransactionReq {
 }
 export interface WsSubscribeAddressesReq {
     addresses: string[];
+    minConfirmations?: number;
 }
 export interface WsSubscribeFiatRatesReq {
     currency?: string;
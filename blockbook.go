@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"io/ioutil"
@@ -47,6 +48,8 @@ var (
 	dbPath         = flag.String("datadir", "./data", "path to database directory")
 	dbCache        = flag.Int("dbcache", 1<<29, "size of the rocksdb cache")
 	dbMaxOpenFiles = flag.Int("dbmaxopenfiles", 1<<14, "max open files by rocksdb")
+	dbCompression  = flag.String("dbcompression", "lz4hc", "compression of rocksdb column families (none, snappy, zlib, bz2, lz4, lz4hc, zstd)")
+	dbBloomBits    = flag.Int("dbbloombits", 10, "bits per key of rocksdb bloom filters, 0 disables the filters")
 
 	blockFrom      = flag.Int("blockheight", -1, "height of the starting block")
 	blockUntil     = flag.Int("blockuntil", -1, "height of the final block")
@@ -57,9 +60,10 @@ var (
 	fixUtxo     = flag.Bool("fixutxo", false, "check and fix utxo db and exit")
 	prof        = flag.String("prof", "", "http server binding [address]:port of the interface to profiling data /debug/pprof/ (default no profiling)")
 
-	syncChunk   = flag.Int("chunk", 100, "block chunk size for processing in bulk mode")
-	syncWorkers = flag.Int("workers", 8, "number of workers to process blocks in bulk mode")
-	dryRun      = flag.Bool("dryrun", false, "do not index blocks, only download")
+	syncChunk    = flag.Int("chunk", 100, "block chunk size for processing in bulk mode")
+	syncWorkers  = flag.Int("workers", 8, "number of workers to process blocks in bulk mode")
+	syncPrefetch = flag.Int("prefetchblocks", 0, "number of blocks fetched in parallel ahead of the indexed block in standard sync, 0 fetches blocks one by one")
+	dryRun       = flag.Bool("dryrun", false, "do not index blocks, only download")
 
 	debugMode = flag.Bool("debug", false, "debug mode, return more verbose errors, reload templates on each request")
 
@@ -73,11 +77,28 @@ var (
 
 	noTxCache = flag.Bool("notxcache", false, "disable tx cache")
 
+	addressTxsConcurrency = flag.Int("addresstxsconcurrency", 4, "maximum number of transactions of address or xpub history read in parallel, 1 means sequential reads")
+
+	maxBatchAddresses = flag.Int("maxbatchaddresses", 100, "maximum number of addresses in one batch balance query")
+
+	maxTokenTransfers = flag.Int("maxtokentransfers", 10000, "maximum number of token transfers of an address returned by one paged or exported query (Ethereum-type coins only)")
+
+	txMemo = flag.Bool("txmemo", false, "if true, decode OP_RETURN payloads consisting of printable ASCII characters to the memo of transactions (Bitcoin-type coins only)")
+
+	changeHeuristics = flag.String("changeheuristics", "", "comma separated list of heuristics detecting the change output of transactions (addressreuse, roundnumber, scripttype), empty disables the detection (Bitcoin-type coins only)")
+
 	enableSubNewTx = flag.Bool("enablesubnewtx", false, "enable support for subscribing to all new transactions")
 
+	wsPingInterval     = flag.Int("wspinginterval", 30, "interval of websocket keepalive pings in seconds, 0 disables the pings")
+	wsPongTimeout      = flag.Int("wspongtimeout", 30, "timeout in seconds for the response to websocket keepalive ping, clients not responding in time are disconnected")
+	wsMaxSubscriptions = flag.Int("wsmaxsubscriptions", 0, "maximum number of websocket subscriptions per connection, each subscribed address counts as one subscription, 0 means unlimited")
+	trustedProxies     = flag.String("trustedproxies", "", "comma separated list of IP addresses or CIDR ranges of trusted reverse proxies, the client IP is taken from the X-Forwarded-For header only from them; if not set, the X-Real-Ip header is honored from any client")
+
 	computeColumnStats  = flag.Bool("computedbstats", false, "compute column stats and exit")
 	computeFeeStatsFlag = flag.Bool("computefeestats", false, "compute fee stats for blocks in blockheight-blockuntil range and exit")
 	dbStatsPeriodHours  = flag.Int("dbstatsperiod", 24, "period of db stats collection in hours, 0 disables stats collection")
+	exportSnapshot      = flag.String("exportsnapshot", "", "export the index to the snapshot file and exit")
+	importSnapshot      = flag.String("importsnapshot", "", "import the index from the snapshot file to the empty database before start")
 
 	// resync index at least each resyncIndexPeriodMs (could be more often if invoked by message from ZeroMQ)
 	resyncIndexPeriodMs = flag.Int("resyncindexperiod", 935093, "resync index period in milliseconds")
@@ -86,6 +107,13 @@ var (
 	resyncMempoolPeriodMs = flag.Int("resyncmempoolperiod", 60017, "resync mempool period in milliseconds")
 
 	extendedIndex = flag.Bool("extendedindex", false, "if true, create index of input txids and spending transactions")
+
+	outputValueIndex      = flag.Bool("outputvalueindex", false, "if true, create index of outputs by value (Bitcoin-type coins only)")
+	minerIndex            = flag.Bool("minerindex", false, "if true, create index of blocks by mining pool recognized by the pool tags of the coin (Bitcoin-type coins only)")
+	scriptHashIndex       = flag.Bool("scripthashindex", false, "if true, create index of output scripts by their sha256 hash for the utxo query by script hash (Bitcoin-type coins only)")
+	addressFirstSeenIndex = flag.Bool("addressfirstseenindex", false, "if true, create index of the block and transaction in which each address appeared for the first time (Bitcoin-type coins only)")
+	spendIndexDepth       = flag.Int("spendindexdepth", 0, "if set, prune the spend data of the extended index of outputs spent more than this number of blocks ago, must be lower than block_addresses_to_keep (Bitcoin-type coins only)")
+	opReturnPrefixes      = flag.String("opreturnprefixes", "", "comma separated list of hex encoded OP_RETURN payload prefixes to index (default no OP_RETURN index)")
 )
 
 var (
@@ -107,6 +135,10 @@ var (
 	callbacksOnNewTx              []bchain.OnNewTxFunc
 	callbacksOnNewFiatRatesTicker []fiat.OnNewFiatRatesTicker
 	chanOsSignal                  chan os.Signal
+	// chanStopPruneSpendIndex stops the background pruning of the spend index on shutdown
+	chanStopPruneSpendIndex chan os.Signal
+	// chanStopPruneTxFirstSeen stops the background pruning of the first seen times of transactions on shutdown
+	chanStopPruneTxFirstSeen chan os.Signal
 )
 
 func init() {
@@ -135,6 +167,10 @@ func mainWithExitCode() int {
 
 	chanOsSignal = make(chan os.Signal, 1)
 	signal.Notify(chanOsSignal, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	chanStopPruneSpendIndex = make(chan os.Signal, 1)
+	signal.Notify(chanStopPruneSpendIndex, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	chanStopPruneTxFirstSeen = make(chan os.Signal, 1)
+	signal.Notify(chanStopPruneTxFirstSeen, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 
 	glog.Infof("Blockbook: %+v, debug mode %v", common.GetVersionInfo(), *debugMode)
 
@@ -174,18 +210,78 @@ func mainWithExitCode() int {
 		return exitCodeFatal
 	}
 
-	index, err = db.NewRocksDB(*dbPath, *dbCache, *dbMaxOpenFiles, chain.GetChainParser(), metrics, *extendedIndex)
+	dbOptions := db.DefaultOptions(*dbCache, *dbMaxOpenFiles)
+	dbOptions.Compression = *dbCompression
+	dbOptions.BloomFilterBits = *dbBloomBits
+	index, err = db.NewRocksDBWithOptions(*dbPath, dbOptions, chain.GetChainParser(), metrics, *extendedIndex)
 	if err != nil {
 		glog.Error("rocksDB: ", err)
 		return exitCodeFatal
 	}
 	defer index.Close()
 
+	if *importSnapshot != "" {
+		if err = importIndexSnapshot(*importSnapshot); err != nil {
+			glog.Error("importSnapshot: ", err)
+			return exitCodeFatal
+		}
+	}
+
+	if *opReturnPrefixes != "" {
+		var prefixes [][]byte
+		for _, p := range strings.Split(*opReturnPrefixes, ",") {
+			b, err := hex.DecodeString(strings.TrimSpace(p))
+			if err != nil || len(b) == 0 {
+				glog.Error("opreturnprefixes: invalid prefix ", p)
+				return exitCodeFatal
+			}
+			prefixes = append(prefixes, b)
+		}
+		index.SetOpReturnPrefixes(prefixes)
+		glog.Info("OP_RETURN index of prefixes ", *opReturnPrefixes)
+	}
+	if *outputValueIndex {
+		index.SetOutputValueIndex(true)
+		glog.Info("Index of outputs by value enabled")
+	}
+	if *minerIndex {
+		index.SetBlockMinerIndex(chain.MatchBlockMiner)
+		glog.Info("Index of blocks by miner enabled")
+	}
+	if *addressFirstSeenIndex {
+		index.SetAddressFirstSeenIndex(true)
+		glog.Info("Index of the first appearance of addresses enabled")
+	}
+	if *scriptHashIndex {
+		index.SetScriptHashIndex(true)
+		glog.Info("Index of output scripts by hash enabled")
+	}
+	if *spendIndexDepth > 0 {
+		if err = index.SetSpendIndexDepth(uint32(*spendIndexDepth)); err != nil {
+			glog.Error("spendindexdepth: ", err)
+			return exitCodeFatal
+		}
+		glog.Info("Pruning of spend index beyond depth ", *spendIndexDepth, " enabled")
+	}
+
 	internalState, err = newInternalState(coin, coinShortcut, coinLabel, index, *enableSubNewTx)
 	if err != nil {
 		glog.Error("internalState: ", err)
 		return exitCodeFatal
 	}
+	internalState.WsPingInterval = time.Duration(*wsPingInterval) * time.Second
+	internalState.WsPongTimeout = time.Duration(*wsPongTimeout) * time.Second
+	internalState.WsMaxSubscriptions = *wsMaxSubscriptions
+	if *trustedProxies != "" {
+		internalState.TrustedProxies = strings.Split(*trustedProxies, ",")
+	}
+	internalState.MaxBatchAddresses = *maxBatchAddresses
+	internalState.AddressTxsConcurrency = *addressTxsConcurrency
+	internalState.MaxTokenTransfers = *maxTokenTransfers
+	internalState.TxMemo = *txMemo
+	if *changeHeuristics != "" {
+		internalState.ChangeHeuristics = strings.Split(*changeHeuristics, ",")
+	}
 
 	// fix possible inconsistencies in the UTXO index
 	if *fixUtxo || !internalState.UtxoChecked {
@@ -225,6 +321,14 @@ func mainWithExitCode() int {
 		glog.Warning("internalState: database was left in open state, possibly previous ungraceful shutdown")
 	}
 
+	if *exportSnapshot != "" {
+		if err = exportIndexSnapshot(*exportSnapshot); err != nil {
+			glog.Error("exportSnapshot: ", err)
+			return exitCodeFatal
+		}
+		return exitCodeOK
+	}
+
 	if *computeFeeStatsFlag {
 		internalState.DbState = common.DbStateOpen
 		err = computeFeeStats(chanOsSignal, *blockFrom, *blockUntil, index, chain, txCache, internalState, metrics)
@@ -246,7 +350,7 @@ func mainWithExitCode() int {
 		return exitCodeOK
 	}
 
-	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, chanOsSignal, metrics, internalState)
+	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *syncPrefetch, *blockFrom, *dryRun, chanOsSignal, metrics, internalState)
 	if err != nil {
 		glog.Errorf("NewSyncWorker %v", err)
 		return exitCodeFatal
@@ -306,12 +410,21 @@ func mainWithExitCode() int {
 			}
 			return exitCodeOK
 		}
+		// the spend data of the blocks connected in bulk during the initial sync are pruned in the background
+		go pruneSpendIndex()
 		// initialize mempool after the initial sync is complete
 		var addrDescForOutpoint bchain.AddrDescForOutpointFunc
+		var spendingTxidForOutpoint bchain.SpendingTxidForOutpointFunc
+		var onTxReplaced bchain.OnTxReplacedFunc
 		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
 			addrDescForOutpoint = index.AddrDescForOutpoint
+			spendingTxidForOutpoint = index.SpendingTxidForOutpoint
+			// remember when the transactions were first seen, the time is returned also after the transactions are confirmed
+			callbacksOnNewTx = append(callbacksOnNewTx, index.StoreMempoolTxFirstSeen)
+			// remember the replace-by-fee replacements, the confirmed replacement reports the transactions it replaced
+			onTxReplaced = index.StoreMempoolTxReplacement
 		}
-		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onNewTx)
+		err = chain.InitializeMempool(addrDescForOutpoint, spendingTxidForOutpoint, onNewTxAddr, onNewTx, onTxReplaced)
 		if err != nil {
 			glog.Error("initializeMempool ", err)
 			return exitCodeFatal
@@ -322,6 +435,7 @@ func mainWithExitCode() int {
 			return exitCodeFatal
 		}
 		internalState.FinishedMempoolSync(mempoolCount)
+		updateMempoolMinFee()
 		go syncIndexLoop()
 		go syncMempoolLoop()
 		internalState.InitialSync = false
@@ -490,6 +604,27 @@ func blockbookAppInfoMetric(db *db.RocksDB, chain bchain.BlockChain, txCache *db
 	return nil
 }
 
+func exportIndexSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = index.ExportSnapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func importIndexSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return index.ImportSnapshot(f)
+}
+
 func newInternalState(coin, coinShortcut, coinLabel string, d *db.RocksDB, enableSubNewTx bool) (*common.InternalState, error) {
 	is, err := d.LoadInternalState(coin)
 	if err != nil {
@@ -524,12 +659,30 @@ func syncIndexLoop() {
 			time.Sleep(time.Millisecond * 2500)
 			if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
 				glog.Error("syncIndexLoop ", errors.ErrorStack(err))
+				return
 			}
 		}
+		go pruneSpendIndex()
 	})
 	glog.Info("syncIndexLoop stopped")
 }
 
+// pruneSpendIndex prunes the spend data of the blocks which got deeper than the spend index depth
+// and the first seen times of the transactions which were never confirmed, only one pruning of each kind runs at a time
+func pruneSpendIndex() {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("pruneSpendIndex recovered from panic: ", r)
+		}
+	}()
+	if err := index.PruneSpendIndex(chanStopPruneSpendIndex); err != nil {
+		glog.Error("pruneSpendIndex ", err)
+	}
+	if err := index.PruneTxFirstSeen(chanStopPruneTxFirstSeen); err != nil {
+		glog.Error("pruneTxFirstSeen ", err)
+	}
+}
+
 func onNewBlockHash(hash string, height uint32) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -563,12 +716,23 @@ func syncMempoolLoop() {
 			glog.Error("syncMempoolLoop ", errors.ErrorStack(err))
 		} else {
 			internalState.FinishedMempoolSync(count)
-
+			updateMempoolMinFee()
 		}
 	})
 	glog.Info("syncMempoolLoop stopped")
 }
 
+// updateMempoolMinFee stores the minimal fee of the backend mempool in the internal state,
+// the status requests then do not call the backend
+func updateMempoolMinFee() {
+	fee, err := chain.GetMempoolMinFee()
+	if err != nil {
+		glog.V(1).Info("GetMempoolMinFee error ", err)
+		return
+	}
+	internalState.SetMempoolMinFee(fee.String())
+}
+
 func storeInternalStateLoop() {
 	stopCompute := make(chan os.Signal)
 	defer func() {
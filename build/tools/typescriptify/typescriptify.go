@@ -24,16 +24,38 @@ func main() {
 	// API - REST and Websocket
 	t.Add(api.APIError{})
 	t.Add(api.Tx{})
+	t.Add(api.TxRecipients{})
+	t.Add(api.TxInputPrevout{})
 	t.Add(api.FeeStats{})
+	t.Add(api.MempoolInfo{})
+	t.Add(api.SyncStatus{})
+	t.Add(api.AddressSummary{})
 	t.Add(api.Address{})
+	t.Add(api.AddressBalance{})
+	t.Add(api.AddressTokenTransfers{})
 	t.Add(api.Utxo{})
 	t.Add(api.BalanceHistory{})
+	t.Add(api.BalanceDeltas{})
 	t.Add(api.Blocks{})
 	t.Add(api.Block{})
 	t.Add(api.BlockRaw{})
+	t.Add(api.BlockFilter{})
+	t.Add(api.BlockTopTxs{})
+	t.Add(api.ValueOutputs{})
+	t.Add(api.MinerBlocks{})
+	t.Add(api.NewAddresses{})
+	t.Add(api.EnrichedPsbt{})
+	t.Add(api.ScriptOutputTxs{})
+	t.Add(api.SpendingGraph{})
+	t.Add(api.DifficultyHistory{})
+	t.Add(api.GenesisInfo{})
+	t.Add(api.AddressValidation{})
 	t.Add(api.SystemInfo{})
 	t.Add(api.FiatTicker{})
 	t.Add(api.FiatTickers{})
+	t.Add(api.TxConfirmationPolicy{})
+	t.Add(api.ConfirmationProbability{})
+	t.Add(api.NextBlockFee{})
 	t.Add(api.AvailableVsCurrencies{})
 
 	// Websocket specific
@@ -45,6 +45,8 @@ type BackendInfo struct {
 	ProtocolVersion  string      `json:"protocolVersion,omitempty"`
 	Timeoffset       float64     `json:"timeOffset,omitempty"`
 	Warnings         string      `json:"warnings,omitempty"`
+	MempoolMinFee    string      `json:"mempoolMinFee,omitempty"`
+	MinRelayTxFee    string      `json:"minRelayTxFee,omitempty"`
 	ConsensusVersion string      `json:"consensus_version,omitempty"`
 	Consensus        interface{} `json:"consensus,omitempty"`
 }
@@ -73,13 +75,19 @@ type InternalState struct {
 	LastSync       time.Time `json:"lastSync"`
 	BlockTimes     []uint32  `json:"-"`
 	AvgBlockPeriod uint32    `json:"-"`
+	TotalTxs       uint64    `json:"-"`
 
 	IsMempoolSynchronized bool      `json:"isMempoolSynchronized"`
 	MempoolSize           int       `json:"mempoolSize"`
 	LastMempoolSync       time.Time `json:"lastMempoolSync"`
+	// the minimal fee per kilobyte of the backend mempool, updated during mempool synchronization
+	MempoolMinFee string `json:"-"`
 
 	DbColumns []InternalStateColumn `json:"dbColumns"`
 
+	// the spend data of the outputs spent in the blocks up to this height are pruned from the extended index
+	SpendIndexPrunedHeight uint32 `json:"spendIndexPrunedHeight"`
+
 	HasFiatRates                 bool                 `json:"-"`
 	HasTokenFiatRates            bool                 `json:"-"`
 	HistoricalFiatRatesTime      time.Time            `json:"historicalFiatRatesTime"`
@@ -88,6 +96,27 @@ type InternalState struct {
 
 	EnableSubNewTx bool `json:"-"`
 
+	// websocket keepalive, pings are not sent if WsPingInterval is 0
+	WsPingInterval time.Duration `json:"-"`
+	WsPongTimeout  time.Duration `json:"-"`
+	// maximum number of websocket subscriptions per connection, 0 means unlimited
+	WsMaxSubscriptions int `json:"-"`
+	// IP addresses and CIDR ranges of the reverse proxies trusted to report the client IP
+	TrustedProxies []string `json:"-"`
+
+	// maximum number of addresses in one batch balance query
+	MaxBatchAddresses int `json:"-"`
+	// maximum number of transactions of the address history read in parallel, 0 or 1 means sequential reads
+	AddressTxsConcurrency int `json:"-"`
+
+	// maximum number of token transfers of an address returned by one paged or exported query, 0 means default
+	MaxTokenTransfers int `json:"-"`
+
+	// decode printable ASCII OP_RETURN payloads to the memo of transactions
+	TxMemo bool `json:"-"`
+	// names of the heuristics detecting the change output of transactions, empty disables the detection
+	ChangeHeuristics []string `json:"-"`
+
 	BackendInfo BackendInfo `json:"-"`
 
 	// database migrations
@@ -157,6 +186,20 @@ func (is *InternalState) GetMempoolSyncState() (bool, time.Time, int) {
 	return is.IsMempoolSynchronized, is.LastMempoolSync, is.MempoolSize
 }
 
+// SetMempoolMinFee sets the minimal fee of the backend mempool
+func (is *InternalState) SetMempoolMinFee(fee string) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.MempoolMinFee = fee
+}
+
+// GetMempoolMinFee gets the minimal fee of the backend mempool
+func (is *InternalState) GetMempoolMinFee() string {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.MempoolMinFee
+}
+
 // AddDBColumnStats adds differences in column statistics to column stats
 func (is *InternalState) AddDBColumnStats(c int, rowsDiff int64, keyBytesDiff int64, valueBytesDiff int64) {
 	is.mux.Lock()
@@ -257,6 +300,37 @@ func (is *InternalState) RemoveLastBlockTimes(count int) {
 	is.computeAvgBlockPeriod()
 }
 
+// SetTotalTxs sets the total number of transactions in the indexed blocks
+func (is *InternalState) SetTotalTxs(totalTxs uint64) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.TotalTxs = totalTxs
+}
+
+// AddTotalTxs adds the transactions of a connected block to the total number of transactions
+func (is *InternalState) AddTotalTxs(txs uint64) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.TotalTxs += txs
+}
+
+// RemoveTotalTxs removes the transactions of a disconnected block from the total number of transactions
+func (is *InternalState) RemoveTotalTxs(txs uint64) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	if is.TotalTxs < txs {
+		txs = is.TotalTxs
+	}
+	is.TotalTxs -= txs
+}
+
+// GetTotalTxs returns the total number of transactions in the indexed blocks
+func (is *InternalState) GetTotalTxs() uint64 {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.TotalTxs
+}
+
 // GetBlockHeightOfTime returns block height of the first block with time greater or equal to the given time or MaxUint32 if no such block
 func (is *InternalState) GetBlockHeightOfTime(time uint32) uint32 {
 	is.mux.Lock()
@@ -20,6 +20,7 @@ type Metrics struct {
 	MempoolResyncDuration    prometheus.Histogram
 	TxCacheEfficiency        *prometheus.CounterVec
 	RPCLatency               *prometheus.HistogramVec
+	RPCSlowCalls             *prometheus.CounterVec
 	IndexResyncErrors        *prometheus.CounterVec
 	IndexDBSize              prometheus.Gauge
 	ExplorerViews            *prometheus.CounterVec
@@ -35,6 +36,9 @@ type Metrics struct {
 	WebsocketPendingRequests *prometheus.GaugeVec
 	SocketIOPendingRequests  *prometheus.GaugeVec
 	XPubCacheSize            prometheus.Gauge
+	XPubCacheEfficiency      *prometheus.CounterVec
+	IndexBlockDuration       *prometheus.HistogramVec
+	IndexBlocks              *prometheus.CounterVec
 }
 
 // Labels represents a collection of label name -> value mappings.
@@ -141,6 +145,14 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"method", "error"},
 	)
+	metrics.RPCSlowCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_rpc_slow_calls",
+			Help:        "Number of blockchain RPC calls exceeding the slow call threshold by method",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method"},
+	)
 	metrics.IndexResyncErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name:        "blockbook_index_resync_errors",
@@ -255,6 +267,31 @@ func GetMetrics(coin string) (*Metrics, error) {
 			ConstLabels: Labels{"coin": coin},
 		},
 	)
+	metrics.XPubCacheEfficiency = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_xpub_cache_efficiency",
+			Help:        "Number of derived address balances reused from xpub cache (hit) or loaded from db (miss)",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"status"},
+	)
+	metrics.IndexBlockDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_index_block_duration",
+			Help:        "Duration of indexing of a block by phase (in milliseconds)",
+			Buckets:     []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"phase"},
+	)
+	metrics.IndexBlocks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_index_blocks",
+			Help:        "Number of blocks processed by indexing by phase",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"phase"},
+	)
 
 	v := reflect.ValueOf(metrics)
 	for i := 0; i < v.NumField(); i++ {
@@ -15,8 +15,11 @@ import (
 // 2) rocksdb seems to handle better fewer larger batches than continuous stream of smaller batches
 
 type bulkAddresses struct {
-	bi        BlockInfo
-	addresses addressesMap
+	bi           BlockInfo
+	addresses    addressesMap
+	opReturns    addressesMap
+	outputValues addressesMap
+	miner        string
 }
 
 // BulkConnect is used to connect blocks in bulk, faster but if interrupted inconsistent way
@@ -157,10 +160,30 @@ func (b *BulkConnect) parallelStoreBalances(c chan error, all bool) {
 }
 
 func (b *BulkConnect) storeBulkAddresses(wb *grocksdb.WriteBatch) error {
+	// the addresses first seen in the blocks stored to the same write batch
+	var firstSeen map[string]struct{}
+	if b.d.HasAddressFirstSeenIndex() {
+		firstSeen = make(map[string]struct{})
+	}
 	for _, ba := range b.bulkAddresses {
 		if err := b.d.storeAddresses(wb, ba.bi.Height, ba.addresses); err != nil {
 			return err
 		}
+		if err := b.d.storeOpReturns(wb, ba.bi.Height, ba.opReturns); err != nil {
+			return err
+		}
+		if err := b.d.storeOutputValues(wb, ba.bi.Height, ba.outputValues); err != nil {
+			return err
+		}
+		b.d.storeBlockMiner(wb, ba.bi.Height, ba.miner)
+		if firstSeen != nil {
+			if err := b.d.storeAddressesFirstSeen(wb, ba.bi.Height, ba.addresses, firstSeen); err != nil {
+				return err
+			}
+		}
+		if b.d.HasScriptHashIndex() {
+			b.d.storeScriptHashes(wb, ba.addresses)
+		}
 		if err := b.d.writeHeight(wb, ba.bi.Height, &ba.bi, opInsert); err != nil {
 			return err
 		}
@@ -172,9 +195,27 @@ func (b *BulkConnect) storeBulkAddresses(wb *grocksdb.WriteBatch) error {
 
 func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
+	indexStart := time.Now()
 	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances); err != nil {
 		return err
 	}
+	storeStart := b.d.observeIndexPhase("index", indexStart)
+	defer b.d.observeIndexPhase("store", storeStart)
+	var opReturns addressesMap
+	if b.d.HasOpReturnIndex() {
+		opReturns = b.d.processOpReturnsBitcoinType(block)
+	}
+	var outputValues addressesMap
+	if b.d.HasOutputValueIndex() {
+		var err error
+		if outputValues, err = b.d.processOutputValuesBitcoinType(block); err != nil {
+			return err
+		}
+	}
+	var miner string
+	if b.d.HasBlockMinerIndex() {
+		miner = b.d.blockMiner(block)
+	}
 	var storeAddressesChan, storeBalancesChan chan error
 	var sa bool
 	if len(b.txAddressesMap) > maxBulkTxAddresses || len(b.balances) > maxBulkBalances {
@@ -196,9 +237,12 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 			Size:   uint32(block.Size),
 			Height: block.Height,
 		},
-		addresses: addresses,
+		addresses:    addresses,
+		opReturns:    opReturns,
+		outputValues: outputValues,
+		miner:        miner,
 	})
-	b.bulkAddressesCount += len(addresses)
+	b.bulkAddressesCount += len(addresses) + len(opReturns) + len(outputValues)
 	// open WriteBatch only if going to write
 	if sa || b.bulkAddressesCount > maxBulkAddresses || storeBlockTxs {
 		start := time.Now()
@@ -277,10 +321,13 @@ func (b *BulkConnect) parallelStoreAddressContracts(c chan error, all bool) {
 
 func (b *BulkConnect) connectBlockEthereumType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
+	indexStart := time.Now()
 	blockTxs, err := b.d.processAddressesEthereumType(block, addresses, b.addressContracts)
 	if err != nil {
 		return err
 	}
+	storeStart := b.d.observeIndexPhase("index", indexStart)
+	defer b.d.observeIndexPhase("store", storeStart)
 	b.ethBlockTxs = append(b.ethBlockTxs, blockTxs...)
 	var storeAddrContracts chan error
 	var sa bool
@@ -403,11 +450,12 @@ func (b *BulkConnect) Close() error {
 			return err
 		}
 	}
-	bt, err := b.d.loadBlockTimes()
+	bt, totalTxs, err := b.d.loadBlockTimes()
 	if err != nil {
 		return err
 	}
 	avg := b.d.is.SetBlockTimes(bt)
+	b.d.is.SetTotalTxs(totalTxs)
 	if b.d.metrics != nil {
 		b.d.metrics.AvgBlockPeriod.Set(float64(avg))
 	}
@@ -2,7 +2,12 @@ package db
 
 // #include "rocksdb/c.h"
 import "C"
-import "github.com/linxGnu/grocksdb"
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+)
 
 /*
 	possible additional tuning, using options not accessible by grocksdb
@@ -38,7 +43,50 @@ func boolToChar(b bool) C.uchar {
 }
 */
 
-func createAndSetDBOptions(bloomBits int, c *grocksdb.Cache, maxOpenFiles int) *grocksdb.Options {
+// Options are the tunable options of the database
+type Options struct {
+	// CacheSize is the size of the block cache shared by all column families
+	CacheSize int
+	// MaxOpenFiles is the maximum number of files opened by the database
+	MaxOpenFiles int
+	// Compression is the compression type of the column families: none, snappy, zlib, bz2, lz4, lz4hc or zstd, empty means lz4hc
+	Compression string
+	// BloomFilterBits is the number of bits per key of the bloom filter of the point lookup column families, 0 disables the filter
+	BloomFilterBits int
+}
+
+// DefaultOptions returns the options used by the database unless configured otherwise
+func DefaultOptions(cacheSize, maxOpenFiles int) Options {
+	return Options{
+		CacheSize:       cacheSize,
+		MaxOpenFiles:    maxOpenFiles,
+		Compression:     "lz4hc",
+		BloomFilterBits: 10,
+	}
+}
+
+var compressionTypes = map[string]grocksdb.CompressionType{
+	"none":   grocksdb.NoCompression,
+	"snappy": grocksdb.SnappyCompression,
+	"zlib":   grocksdb.ZLibCompression,
+	"bz2":    grocksdb.Bz2Compression,
+	"lz4":    grocksdb.LZ4Compression,
+	"lz4hc":  grocksdb.LZ4HCCompression,
+	"zstd":   grocksdb.ZSTDCompression,
+}
+
+func parseCompression(compression string) (grocksdb.CompressionType, error) {
+	if compression == "" {
+		return grocksdb.LZ4HCCompression, nil
+	}
+	c, ok := compressionTypes[strings.ToLower(compression)]
+	if !ok {
+		return 0, errors.Errorf("Unknown compression type %v", compression)
+	}
+	return c, nil
+}
+
+func createAndSetDBOptions(bloomBits int, c *grocksdb.Cache, maxOpenFiles int, compression grocksdb.CompressionType) *grocksdb.Options {
 	blockOpts := grocksdb.NewDefaultBlockBasedTableOptions()
 	blockOpts.SetBlockSize(32 << 10) // 32kB
 	blockOpts.SetBlockCache(c)
@@ -57,6 +105,6 @@ func createAndSetDBOptions(bloomBits int, c *grocksdb.Cache, maxOpenFiles int) *
 	opts.SetWriteBufferSize(1 << 27)      // 128MB
 	opts.SetMaxBytesForLevelBase(1 << 27) // 128MB
 	opts.SetMaxOpenFiles(maxOpenFiles)
-	opts.SetCompression(grocksdb.LZ4HCCompression)
+	opts.SetCompression(compression)
 	return opts
 }
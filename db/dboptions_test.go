@@ -0,0 +1,121 @@
+//go:build unittest
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linxGnu/grocksdb"
+)
+
+// filterPolicies returns the filter policies of the column families from the latest OPTIONS file of the database
+func filterPolicies(t *testing.T, path string) []string {
+	files, err := filepath.Glob(filepath.Join(path, "OPTIONS-*"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("OPTIONS file not found ", err)
+	}
+	latest := files[0]
+	for _, f := range files[1:] {
+		if len(f) > len(latest) || len(f) == len(latest) && f > latest {
+			latest = f
+		}
+	}
+	b, err := ioutil.ReadFile(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policies []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); strings.HasPrefix(l, "filter_policy=") {
+			policies = append(policies, l)
+		}
+	}
+	return policies
+}
+
+func TestRocksDB_Options(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         Options
+		wantCompression grocksdb.CompressionType
+		wantBloom       bool
+		wantErr         bool
+	}{
+		{
+			name:            "default",
+			options:         DefaultOptions(1<<20, -1),
+			wantCompression: grocksdb.LZ4HCCompression,
+			wantBloom:       true,
+		},
+		{
+			name:            "zstd without bloom filter",
+			options:         Options{CacheSize: 1 << 21, MaxOpenFiles: -1, Compression: "zstd"},
+			wantCompression: grocksdb.ZSTDCompression,
+		},
+		{
+			name:            "no compression",
+			options:         Options{CacheSize: 1 << 22, MaxOpenFiles: -1, Compression: "None", BloomFilterBits: 5},
+			wantCompression: grocksdb.NoCompression,
+			wantBloom:       true,
+		},
+		{
+			name:    "unknown compression",
+			options: Options{CacheSize: 1 << 20, MaxOpenFiles: -1, Compression: "xz"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "testdb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+			d, err := NewRocksDBWithOptions(tmp, tt.options, bitcoinTestnetParser(), nil, false)
+			if tt.wantErr {
+				if err == nil {
+					d.Close()
+					t.Fatal("NewRocksDBWithOptions() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			if got := d.cache.GetCapacity(); got != uint64(tt.options.CacheSize) {
+				t.Errorf("cache capacity = %v, want %v", got, tt.options.CacheSize)
+			}
+			cache := grocksdb.NewLRUCache(1 << 20)
+			defer cache.Destroy()
+			env := grocksdb.NewDefaultEnv()
+			defer env.Destroy()
+			lo, err := grocksdb.LoadLatestOptions(tmp, env, true, cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lo.Destroy()
+			if len(lo.ColumnFamilyNames()) != len(cfNames) {
+				t.Fatalf("column families = %v, want %v", lo.ColumnFamilyNames(), cfNames)
+			}
+			for i, o := range lo.ColumnFamilyOpts() {
+				if got := o.GetCompression(); got != tt.wantCompression {
+					t.Errorf("column family %v compression = %v, want %v", lo.ColumnFamilyNames()[i], got, tt.wantCompression)
+				}
+			}
+			bloom := false
+			for _, p := range filterPolicies(t, tmp) {
+				if strings.Contains(strings.ToLower(p), "bloom") {
+					bloom = true
+				}
+			}
+			if bloom != tt.wantBloom {
+				t.Errorf("bloom filter = %v, want %v", bloom, tt.wantBloom)
+			}
+		})
+	}
+}
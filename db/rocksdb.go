@@ -69,8 +69,32 @@ type RocksDB struct {
 	metrics       *common.Metrics
 	cache         *grocksdb.Cache
 	maxOpenFiles  int
+	compression   grocksdb.CompressionType
+	bloomBits     int
 	cbs           connectBlockStats
 	extendedIndex bool
+	// opReturnPrefixes are the prefixes of OP_RETURN payloads which are indexed, empty means no indexing
+	opReturnPrefixes [][]byte
+	// outputValueIndex enables the index of outputs by value
+	outputValueIndex bool
+	// blockMiner recognizes the miner of the block for the index of blocks by miner, nil means no indexing
+	blockMiner BlockMinerFunc
+	// addressFirstSeenIndex enables the index of the first appearance of addresses
+	addressFirstSeenIndex bool
+	// scriptHashIndex enables the index of output scripts by hash
+	scriptHashIndex bool
+	// spendIndexDepth is the number of the most recent blocks for which the spend data are kept, 0 means no pruning
+	spendIndexDepth uint32
+	// txAddressesMux orders the read and write of the spend index pruning with the other writes to the db
+	txAddressesMux sync.Mutex
+	// spendIndexGeneration is incremented when blocks are disconnected, the running pruning of the spend index is then stopped
+	spendIndexGeneration uint64
+	// spendIndexPruning is 1 while the spend index is being pruned
+	spendIndexPruning int32
+	// txFirstSeenPruning is 1 while the txFirstSeen column is being pruned
+	txFirstSeenPruning int32
+	// txFirstSeenPruned is the start time of the last completed pruning of the txFirstSeen column
+	txFirstSeenPruned time.Time
 }
 
 const (
@@ -83,6 +107,13 @@ const (
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
+	cfOpReturns
+	cfTxFirstSeen
+	cfOutputValues
+	cfBlockMiners
+	cfAddressFirstSeen
+	cfScriptHashes
+	cfTxReplaces
 
 	__break__
 
@@ -102,15 +133,15 @@ var cfNames []string
 var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "fiatRates"}
 
 // type specific columns
-var cfNamesBitcoinType = []string{"addressBalance", "txAddresses"}
+var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "opReturns", "txFirstSeen", "outputValues", "blockMiners", "addressFirstSeen", "scriptHashes", "txReplaces"}
 var cfNamesEthereumType = []string{"addressContracts", "internalData", "contracts", "functionSignatures", "blockInternalDataErrors", "addressAliases"}
 
-func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*grocksdb.ColumnFamilyHandle, error) {
+func openDB(path string, c *grocksdb.Cache, openFiles int, compression grocksdb.CompressionType, bloomBits int) (*grocksdb.DB, []*grocksdb.ColumnFamilyHandle, error) {
 	// opts with bloom filter
-	opts := createAndSetDBOptions(10, c, openFiles)
+	opts := createAndSetDBOptions(bloomBits, c, openFiles, compression)
 	// opts for addresses without bloom filter
 	// from documentation: if most of your queries are executed using iterators, you shouldn't set bloom filter
-	optsAddresses := createAndSetDBOptions(0, c, openFiles)
+	optsAddresses := createAndSetDBOptions(0, c, openFiles, compression)
 	// default, height, addresses, blockTxids, transactions
 	cfOptions := []*grocksdb.Options{opts, opts, optsAddresses, opts, opts, opts}
 	// append type specific options
@@ -128,7 +159,22 @@ func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*gro
 // NewRocksDB opens an internal handle to RocksDB environment.  Close
 // needs to be called to release it.
 func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockChainParser, metrics *common.Metrics, extendedIndex bool) (d *RocksDB, err error) {
-	glog.Infof("rocksdb: opening %s, required data version %v, cache size %v, max open files %v", path, dbVersion, cacheSize, maxOpenFiles)
+	return NewRocksDBWithOptions(path, DefaultOptions(cacheSize, maxOpenFiles), parser, metrics, extendedIndex)
+}
+
+// NewRocksDBWithOptions opens an internal handle to RocksDB environment with the tuned options.  Close
+// needs to be called to release it.
+func NewRocksDBWithOptions(path string, options Options, parser bchain.BlockChainParser, metrics *common.Metrics, extendedIndex bool) (d *RocksDB, err error) {
+	glog.Infof("rocksdb: opening %s, required data version %v, cache size %v, max open files %v, compression %v, bloom filter bits %v",
+		path, dbVersion, options.CacheSize, options.MaxOpenFiles, options.Compression, options.BloomFilterBits)
+
+	compression, err := parseCompression(options.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if options.BloomFilterBits < 0 {
+		return nil, errors.New("Bloom filter bits must not be negative")
+	}
 
 	cfNames = append([]string{}, cfBaseNames...)
 	chainType := parser.GetChainType()
@@ -141,14 +187,27 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 		return nil, errors.New("Unknown chain type")
 	}
 
-	c := grocksdb.NewLRUCache(uint64(cacheSize))
-	db, cfh, err := openDB(path, c, maxOpenFiles)
+	c := grocksdb.NewLRUCache(uint64(options.CacheSize))
+	db, cfh, err := openDB(path, c, options.MaxOpenFiles, compression, options.BloomFilterBits)
 	if err != nil {
 		return nil, err
 	}
 	wo := grocksdb.NewDefaultWriteOptions()
 	ro := grocksdb.NewDefaultReadOptions()
-	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, extendedIndex}, nil
+	return &RocksDB{
+		path:          path,
+		db:            db,
+		wo:            wo,
+		ro:            ro,
+		cfh:           cfh,
+		chainParser:   parser,
+		metrics:       metrics,
+		cache:         c,
+		maxOpenFiles:  options.MaxOpenFiles,
+		compression:   compression,
+		bloomBits:     options.BloomFilterBits,
+		extendedIndex: extendedIndex,
+	}, nil
 }
 
 func (d *RocksDB) closeDB() error {
@@ -186,7 +245,7 @@ func (d *RocksDB) Reopen() error {
 		return err
 	}
 	d.db = nil
-	db, cfh, err := openDB(d.path, d.cache, d.maxOpenFiles)
+	db, cfh, err := openDB(d.path, d.cache, d.maxOpenFiles, d.compression, d.bloomBits)
 	if err != nil {
 		return err
 	}
@@ -203,6 +262,8 @@ func atoUint64(s string) uint64 {
 }
 
 func (d *RocksDB) WriteBatch(wb *grocksdb.WriteBatch) error {
+	d.txAddressesMux.Lock()
+	defer d.txAddressesMux.Unlock()
 	return d.db.Write(d.wo, wb)
 }
 
@@ -267,12 +328,93 @@ func (d *RocksDB) GetTransactions(address string, lower uint32, higher uint32, f
 // GetAddrDescTransactions finds all input/output transactions for address descriptor
 // Transaction are passed to callback function in the order from newest block to the oldest
 func (d *RocksDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) (err error) {
-	txidUnpackedLen := d.chainParser.PackedTxidLen()
+	return d.getTxIndexesTransactions(cfAddresses, addrDesc, lower, higher, fn)
+}
+
+// GetScriptOutputTransactions finds the transactions which created outputs with the output script in blocks between lower and higher height
+// the output script is the address descriptor of Bitcoin type coins, the transactions only spending the outputs are skipped
+// the indexes passed to the callback are the outputs with the script
+func (d *RocksDB) GetScriptOutputTransactions(script bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) error {
+	outputs := make([]int32, 0, 4)
+	return d.GetAddrDescTransactions(script, lower, higher, func(txid string, height uint32, indexes []int32) error {
+		outputs = outputs[:0]
+		for _, index := range indexes {
+			if index >= 0 {
+				outputs = append(outputs, index)
+			}
+		}
+		if len(outputs) == 0 {
+			return nil
+		}
+		return fn(txid, height, outputs)
+	})
+}
+
+// GetAddrDescHeightRange returns the heights of the oldest and the newest block with a transaction of the address descriptor
+// found is false if the address descriptor has no transactions in the index
+func (d *RocksDB) GetAddrDescHeightRange(addrDesc bchain.AddressDescriptor) (first uint32, last uint32, found bool, err error) {
+	keyLen := len(addrDesc) + packedHeightBytes
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddresses])
+	defer it.Close()
+	// the keys are ordered from the newest to the oldest block, keys of longer address descriptors with the same prefix are skipped
+	for it.Seek(packAddressKey(addrDesc, ^uint32(0))); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, addrDesc) {
+			break
+		}
+		if len(key) == keyLen {
+			if _, last, err = unpackAddressKey(key); err != nil {
+				return 0, 0, false, err
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, 0, false, nil
+	}
+	for it.SeekForPrev(packAddressKey(addrDesc, 0)); it.Valid(); it.Prev() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, addrDesc) {
+			break
+		}
+		if len(key) == keyLen {
+			if _, first, err = unpackAddressKey(key); err != nil {
+				return 0, 0, false, err
+			}
+			break
+		}
+	}
+	return first, last, true, nil
+}
+
+// GetAddrDescReceivingTxs returns the number of distinct transactions with an output to the address descriptor
+// and the height of the oldest of them, a count greater than one means that the address was reused
+func (d *RocksDB) GetAddrDescReceivingTxs(addrDesc bchain.AddressDescriptor) (count int, firstHeight uint32, err error) {
+	err = d.GetAddrDescTransactions(addrDesc, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+		for _, index := range indexes {
+			// outputs have non-negative indexes
+			if index >= 0 {
+				count++
+				firstHeight = height
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, firstHeight, nil
+}
+
+// getTxIndexesTransactions iterates over column with keys in the format of packAddressKey and values packed by packTxIndexes
+func (d *RocksDB) getTxIndexesTransactions(cf int, addrDesc []byte, lower uint32, higher uint32, fn GetTransactionsCallback) (err error) {
 	addrDescLen := len(addrDesc)
 	startKey := packAddressKey(addrDesc, higher)
 	stopKey := packAddressKey(addrDesc, lower)
 	indexes := make([]int32, 0, 16)
-	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddresses])
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cf])
 	defer it.Close()
 	for it.Seek(startKey); it.Valid(); it.Next() {
 		key := it.Key().Data()
@@ -293,36 +435,46 @@ func (d *RocksDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, low
 		if err != nil {
 			return err
 		}
-		for len(val) > txidUnpackedLen {
-			tx, err := d.chainParser.UnpackTxid(val[:txidUnpackedLen])
-			if err != nil {
-				return err
-			}
-			indexes = indexes[:0]
-			val = val[txidUnpackedLen:]
-			for {
-				index, l := unpackVarint32(val)
-				indexes = append(indexes, index>>1)
-				val = val[l:]
-				if index&1 == 1 {
-					break
-				} else if len(val) == 0 {
-					glog.Warningf("rocksdb: addresses contain incorrect data %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
-					break
-				}
+		if indexes, err = d.unpackTxIndexesValue(key, val, height, indexes, fn); err != nil {
+			if _, ok := err.(*StopIteration); ok {
+				return nil
 			}
-			if err := fn(tx, height, indexes); err != nil {
-				if _, ok := err.(*StopIteration); ok {
-					return nil
-				}
-				return err
+			return err
+		}
+	}
+	return nil
+}
+
+// unpackTxIndexesValue calls fn for each transaction in the value packed by packTxIndexes, the indexes slice is reused
+// and returned for the next call; the error of fn, including StopIteration, is returned to the caller
+func (d *RocksDB) unpackTxIndexesValue(key []byte, val []byte, height uint32, indexes []int32, fn GetTransactionsCallback) ([]int32, error) {
+	txidUnpackedLen := d.chainParser.PackedTxidLen()
+	for len(val) > txidUnpackedLen {
+		tx, err := d.chainParser.UnpackTxid(val[:txidUnpackedLen])
+		if err != nil {
+			return indexes, err
+		}
+		indexes = indexes[:0]
+		val = val[txidUnpackedLen:]
+		for {
+			index, l := unpackVarint32(val)
+			indexes = append(indexes, index>>1)
+			val = val[l:]
+			if index&1 == 1 {
+				break
+			} else if len(val) == 0 {
+				glog.Warningf("rocksdb: addresses contain incorrect data %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
+				break
 			}
 		}
-		if len(val) != 0 {
-			glog.Warningf("rocksdb: addresses contain incorrect data %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
+		if err := fn(tx, height, indexes); err != nil {
+			return indexes, err
 		}
 	}
-	return nil
+	if len(val) != 0 {
+		glog.Warningf("rocksdb: addresses contain incorrect data %s: %s", hex.EncodeToString(key), hex.EncodeToString(val))
+	}
+	return indexes, nil
 }
 
 const (
@@ -345,12 +497,14 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		return err
 	}
 	addresses := make(addressesMap)
+	start := time.Now()
 	if chainType == bchain.ChainBitcoinType {
 		txAddressesMap := make(map[string]*TxAddresses)
 		balances := make(map[string]*AddrBalance)
 		if err := d.processAddressesBitcoinType(block, addresses, txAddressesMap, balances); err != nil {
 			return err
 		}
+		start = d.observeIndexPhase("index", start)
 		if err := d.storeTxAddresses(wb, txAddressesMap); err != nil {
 			return err
 		}
@@ -360,12 +514,38 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeAndCleanupBlockTxs(wb, block); err != nil {
 			return err
 		}
+		if d.HasOpReturnIndex() {
+			if err := d.storeOpReturns(wb, block.Height, d.processOpReturnsBitcoinType(block)); err != nil {
+				return err
+			}
+		}
+		if d.HasOutputValueIndex() {
+			outputValues, err := d.processOutputValuesBitcoinType(block)
+			if err != nil {
+				return err
+			}
+			if err := d.storeOutputValues(wb, block.Height, outputValues); err != nil {
+				return err
+			}
+		}
+		if d.HasBlockMinerIndex() {
+			d.storeBlockMiner(wb, block.Height, d.blockMiner(block))
+		}
+		if d.HasAddressFirstSeenIndex() {
+			if err := d.storeAddressesFirstSeen(wb, block.Height, addresses, nil); err != nil {
+				return err
+			}
+		}
+		if d.HasScriptHashIndex() {
+			d.storeScriptHashes(wb, addresses)
+		}
 	} else if chainType == bchain.ChainEthereumType {
 		addressContracts := make(map[string]*AddrContracts)
 		blockTxs, err := d.processAddressesEthereumType(block, addresses, addressContracts)
 		if err != nil {
 			return err
 		}
+		start = d.observeIndexPhase("index", start)
 		if err := d.storeAddressContracts(wb, addressContracts); err != nil {
 			return err
 		}
@@ -387,7 +567,9 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 	if err := d.WriteBatch(wb); err != nil {
 		return err
 	}
+	d.observeIndexPhase("store", start)
 	avg := d.is.AppendBlockTime(uint32(block.Time))
+	d.is.AddTotalTxs(uint64(len(block.Txs)))
 	if d.metrics != nil {
 		d.metrics.AvgBlockPeriod.Set(float64(avg))
 	}
@@ -416,8 +598,9 @@ type TxInput struct {
 	AddrDesc bchain.AddressDescriptor
 	ValueSat big.Int
 	// extended index properties
-	Txid string
-	Vout uint32
+	Txid     string
+	Vout     uint32
+	Sequence uint32
 }
 
 // Addresses converts AddressDescriptor of the input to array of strings
@@ -447,7 +630,12 @@ type TxAddresses struct {
 	Inputs  []TxInput
 	Outputs []TxOutput
 	// extended index properties
-	VSize uint32
+	VSize    uint32
+	LockTime uint32
+	// HasLockTime is true if LockTime and Sequence of the inputs are known, they are not stored by older versions
+	HasLockTime bool
+	// BlockIndex is the position of the transaction in its block, -1 if not known (not stored without extended index or by older versions)
+	BlockIndex int32
 }
 
 // Utxo holds information about unspent transaction output
@@ -583,6 +771,16 @@ func (d *RocksDB) resetValueSatToZero(valueSat *big.Int, addrDesc bchain.Address
 	valueSat.SetInt64(0)
 }
 
+// observeIndexPhase records duration of a phase of block indexing since start and returns the current time
+func (d *RocksDB) observeIndexPhase(phase string, start time.Time) time.Time {
+	now := time.Now()
+	if d.metrics != nil {
+		d.metrics.IndexBlockDuration.With(common.Labels{"phase": phase}).Observe(float64(now.Sub(start)) / 1e6) // in milliseconds
+		d.metrics.IndexBlocks.With(common.Labels{"phase": phase}).Inc()
+	}
+	return now
+}
+
 // GetAndResetConnectBlockStats gets statistics about cache usage in connect blocks and resets the counters
 func (d *RocksDB) GetAndResetConnectBlockStats() string {
 	s := fmt.Sprintf("%+v", d.cbs)
@@ -590,7 +788,36 @@ func (d *RocksDB) GetAndResetConnectBlockStats() string {
 	return s
 }
 
+// checkBlockConflicts returns error if the block contains the same transaction twice
+// or two inputs spending the same outpoint, such a block would corrupt the index
+func checkBlockConflicts(block *bchain.Block) error {
+	txids := make(map[string]struct{}, len(block.Txs))
+	spent := make(map[bchain.Outpoint]string)
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		if _, found := txids[tx.Txid]; found {
+			return errors.Errorf("Block %d %v: duplicate transaction %v", block.Height, block.Hash, tx.Txid)
+		}
+		txids[tx.Txid] = struct{}{}
+		for i := range tx.Vin {
+			input := &tx.Vin[i]
+			if input.Txid == "" {
+				continue
+			}
+			o := bchain.Outpoint{Txid: input.Txid, Vout: int32(input.Vout)}
+			if spendingTxid, found := spent[o]; found {
+				return errors.Errorf("Block %d %v: double spend of %v:%d by transactions %v and %v", block.Height, block.Hash, input.Txid, input.Vout, spendingTxid, tx.Txid)
+			}
+			spent[o] = tx.Txid
+		}
+	}
+	return nil
+}
+
 func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses addressesMap, txAddressesMap map[string]*TxAddresses, balances map[string]*AddrBalance) error {
+	if err := checkBlockConflicts(block); err != nil {
+		return err
+	}
 	blockTxIDs := make([][]byte, len(block.Txs))
 	blockTxAddresses := make([]*TxAddresses, len(block.Txs))
 	// first process all outputs so that inputs can refer to txs in this block
@@ -601,21 +828,29 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			return err
 		}
 		blockTxIDs[txi] = btxID
-		ta := TxAddresses{Height: block.Height}
+		ta := TxAddresses{Height: block.Height, BlockIndex: -1}
 		if d.extendedIndex {
 			if tx.VSize > 0 {
 				ta.VSize = uint32(tx.VSize)
 			} else {
 				ta.VSize = uint32(len(tx.Hex))
 			}
+			ta.LockTime = tx.LockTime
+			ta.HasLockTime = true
+			ta.BlockIndex = int32(txi)
 		}
 		ta.Outputs = make([]TxOutput, len(tx.Vout))
 		txAddressesMap[string(btxID)] = &ta
 		blockTxAddresses[txi] = &ta
+		coinStake := d.chainParser.IsCoinStake(tx)
 		for i := range tx.Vout {
 			output := &tx.Vout[i]
 			tao := &ta.Outputs[i]
 			tao.ValueSat = output.ValueSat
+			// the first output of a coinstake transaction is an empty marker, it does not belong to any address
+			if coinStake && i == 0 {
+				continue
+			}
 			addrDesc, err := d.chainParser.GetAddrDescFromVout(output)
 			if err != nil || len(addrDesc) == 0 || len(addrDesc) > maxAddrDescLen {
 				if err != nil {
@@ -669,6 +904,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 		for i := range tx.Vin {
 			input := &tx.Vin[i]
 			tai := &ta.Inputs[i]
+			if d.extendedIndex {
+				tai.Sequence = input.Sequence
+			}
 			btxID, err := d.chainParser.PackTxid(input.Txid)
 			if err != nil {
 				// do not process inputs without input txid
@@ -749,9 +987,34 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			}
 		}
 	}
+	sortAddressesByBlockPosition(addresses, blockTxIDs)
 	return nil
 }
 
+// sortAddressesByBlockPosition orders the transactions of each address by their position in the block, then by txid,
+// so that the order of address transactions does not depend on whether the address was found in outputs or inputs
+func sortAddressesByBlockPosition(addresses addressesMap, blockTxIDs [][]byte) {
+	var positions map[string]int
+	for _, txi := range addresses {
+		if len(txi) < 2 {
+			continue
+		}
+		if positions == nil {
+			positions = make(map[string]int, len(blockTxIDs))
+			for i, btxID := range blockTxIDs {
+				positions[string(btxID)] = i
+			}
+		}
+		sort.Slice(txi, func(i, j int) bool {
+			pi, pj := positions[string(txi[i].btxID)], positions[string(txi[j].btxID)]
+			if pi != pj {
+				return pi < pj
+			}
+			return bytes.Compare(txi[i].btxID, txi[j].btxID) < 0
+		})
+	}
+}
+
 // addToAddressesMap maintains mapping between addresses and transactions in one block
 // the method assumes that outputs in the block are processed before the inputs
 // the return value is true if the tx was processed before, to not to count the tx multiple times
@@ -916,6 +1179,36 @@ func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 	return bt, nil
 }
 
+// GetBlockAddresses returns address descriptors of inputs and outputs of the transactions in the block at given height
+// the data are available only for the blocks kept in the blockTxs column, otherwise false is returned
+func (d *RocksDB) GetBlockAddresses(height uint32) (map[string]struct{}, bool, error) {
+	bt, err := d.getBlockTxs(height)
+	if err != nil {
+		return nil, false, err
+	}
+	// every block contains at least the coinbase transaction
+	if len(bt) == 0 {
+		return nil, false, nil
+	}
+	addresses := make(map[string]struct{})
+	for i := range bt {
+		ta, err := d.getTxAddresses(bt[i].btxID)
+		if err != nil {
+			return nil, false, err
+		}
+		if ta == nil {
+			return nil, false, nil
+		}
+		for j := range ta.Inputs {
+			addresses[string(ta.Inputs[j].AddrDesc)] = struct{}{}
+		}
+		for j := range ta.Outputs {
+			addresses[string(ta.Outputs[j].AddrDesc)] = struct{}{}
+		}
+	}
+	return addresses, true, nil
+}
+
 // GetAddrDescBalance returns AddrBalance for given addrDesc
 func (d *RocksDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressBalance], addrDesc)
@@ -940,6 +1233,52 @@ func (d *RocksDB) GetAddressBalance(address string, detail AddressBalanceDetail)
 	return d.GetAddrDescBalance(addrDesc, detail)
 }
 
+// GetAddressBalanceAtHeight returns the balance of the address after the block of the given height
+// the balance is computed from the transactions of the address in blocks up to the height, later transactions are ignored
+func (d *RocksDB) GetAddressBalanceAtHeight(address string, height uint32) (*big.Int, error) {
+	addrDesc, err := d.chainParser.GetAddrDescFromAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetAddrDescBalanceAtHeight(addrDesc, height)
+}
+
+// GetAddrDescBalanceAtHeight returns the balance of the address descriptor after the block of the given height
+func (d *RocksDB) GetAddrDescBalanceAtHeight(addrDesc bchain.AddressDescriptor, height uint32) (*big.Int, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, errors.New("GetAddrDescBalanceAtHeight: not supported")
+	}
+	var balance big.Int
+	err := d.GetAddrDescTransactions(addrDesc, 0, height, func(txid string, _ uint32, indexes []int32) error {
+		ta, err := d.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		if ta == nil {
+			return errors.Errorf("GetAddrDescBalanceAtHeight: tx %s not found in txAddresses", txid)
+		}
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+				if int(index) >= len(ta.Inputs) {
+					return errors.Errorf("GetAddrDescBalanceAtHeight: invalid input index %d of tx %s", index, txid)
+				}
+				balance.Sub(&balance, &ta.Inputs[index].ValueSat)
+			} else {
+				if int(index) >= len(ta.Outputs) {
+					return errors.Errorf("GetAddrDescBalanceAtHeight: invalid output index %d of tx %s", index, txid)
+				}
+				balance.Add(&balance, &ta.Outputs[index].ValueSat)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
 func (d *RocksDB) getTxAddresses(btxID []byte) (*TxAddresses, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfTxAddresses], btxID)
 	if err != nil {
@@ -982,6 +1321,19 @@ func (d *RocksDB) AddrDescForOutpoint(outpoint bchain.Outpoint) (bchain.AddressD
 	return ta.Outputs[outpoint.Vout].AddrDesc, &ta.Outputs[outpoint.Vout].ValueSat
 }
 
+// SpendingTxidForOutpoint returns the txid of the confirmed transaction spending given output,
+// empty string if the output is not spent or its spend data are not known (they are stored only in the extended index and can be pruned)
+func (d *RocksDB) SpendingTxidForOutpoint(outpoint bchain.Outpoint) string {
+	if !d.extendedIndex || outpoint.Vout < 0 {
+		return ""
+	}
+	ta, err := d.GetTxAddresses(outpoint.Txid)
+	if err != nil || ta == nil || len(ta.Outputs) <= int(outpoint.Vout) {
+		return ""
+	}
+	return ta.Outputs[outpoint.Vout].SpentTxid
+}
+
 func (d *RocksDB) packTxAddresses(ta *TxAddresses, buf []byte, varBuf []byte) []byte {
 	buf = buf[:0]
 	l := packVaruint(uint(ta.Height), varBuf)
@@ -1000,6 +1352,22 @@ func (d *RocksDB) packTxAddresses(ta *TxAddresses, buf []byte, varBuf []byte) []
 	for i := range ta.Outputs {
 		buf = d.appendTxOutput(&ta.Outputs[i], buf, varBuf)
 	}
+	// the lock time is not written if it is not known, so that a rewritten old record is not given a fabricated lock time and sequences
+	if d.extendedIndex && ta.HasLockTime {
+		// lock time and sequences of inputs follow the outputs, they are missing in the records stored by older versions
+		l = packVaruint(uint(ta.LockTime), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		for i := range ta.Inputs {
+			// sequence is stored negated so that the usual final sequence 0xffffffff takes only one byte
+			l = packVaruint(uint(^ta.Inputs[i].Sequence), varBuf)
+			buf = append(buf, varBuf[:l]...)
+		}
+		// block index follows, it is missing in the records stored by older versions
+		if ta.BlockIndex >= 0 {
+			l = packVaruint(uint(ta.BlockIndex), varBuf)
+			buf = append(buf, varBuf[:l]...)
+		}
+	}
 	return buf
 }
 
@@ -1038,8 +1406,16 @@ func (d *RocksDB) appendTxInput(txi *TxInput, buf []byte, varBuf []byte) []byte
 	return buf
 }
 
+// spendPrunedFlag marks in the length of the address descriptor of a spent output in the extended index that the spend data
+// were pruned and are not stored, the length of the stored address descriptors is limited by maxAddrDescLen below the flag
+const spendPrunedFlag = 1 << 11
+
 func (d *RocksDB) appendTxOutput(txo *TxOutput, buf []byte, varBuf []byte) []byte {
 	la := len(txo.AddrDesc)
+	spendPruned := d.extendedIndex && txo.Spent && txo.SpentTxid == "" && txo.SpentHeight == 0
+	if spendPruned {
+		la |= spendPrunedFlag
+	}
 	if txo.Spent {
 		la = ^la
 	}
@@ -1048,7 +1424,7 @@ func (d *RocksDB) appendTxOutput(txo *TxOutput, buf []byte, varBuf []byte) []byt
 	buf = append(buf, txo.AddrDesc...)
 	l = packBigint(&txo.ValueSat, varBuf)
 	buf = append(buf, varBuf[:l]...)
-	if d.extendedIndex && txo.Spent {
+	if d.extendedIndex && txo.Spent && !spendPruned {
 		btxID, err := d.chainParser.PackTxid(txo.SpentTxid)
 		if err != nil {
 			if err != bchain.ErrTxidMissing {
@@ -1128,7 +1504,7 @@ func packAddrBalance(ab *AddrBalance, buf, varBuf []byte) []byte {
 }
 
 func (d *RocksDB) unpackTxAddresses(buf []byte) (*TxAddresses, error) {
-	ta := TxAddresses{}
+	ta := TxAddresses{BlockIndex: -1}
 	height, l := unpackVaruint(buf)
 	ta.Height = uint32(height)
 	if d.extendedIndex {
@@ -1148,6 +1524,21 @@ func (d *RocksDB) unpackTxAddresses(buf []byte) (*TxAddresses, error) {
 	for i := uint(0); i < outputs; i++ {
 		l += d.unpackTxOutput(&ta.Outputs[i], buf[l:])
 	}
+	if d.extendedIndex && l < len(buf) {
+		lockTime, ll := unpackVaruint(buf[l:])
+		ta.LockTime = uint32(lockTime)
+		ta.HasLockTime = true
+		l += ll
+		for i := range ta.Inputs {
+			sequence, ll := unpackVaruint(buf[l:])
+			ta.Inputs[i].Sequence = ^uint32(sequence)
+			l += ll
+		}
+		if l < len(buf) {
+			blockIndex, _ := unpackVaruint(buf[l:])
+			ta.BlockIndex = int32(blockIndex)
+		}
+	}
 	return &ta, nil
 }
 
@@ -1184,15 +1575,20 @@ func (d *RocksDB) unpackTxInput(ti *TxInput, buf []byte) int {
 
 func (d *RocksDB) unpackTxOutput(to *TxOutput, buf []byte) int {
 	al, l := unpackVarint(buf)
+	spendPruned := false
 	if al < 0 {
 		to.Spent = true
 		al = ^al
+		if d.extendedIndex && al&spendPrunedFlag != 0 {
+			spendPruned = true
+			al &^= spendPrunedFlag
+		}
 	}
 	to.AddrDesc = append([]byte(nil), buf[l:l+al]...)
 	al += l
 	to.ValueSat, l = unpackBigint(buf[al:])
 	al += l
-	if d.extendedIndex && to.Spent {
+	if d.extendedIndex && to.Spent && !spendPruned {
 		l = d.chainParser.PackedTxidLen()
 		to.SpentTxid, _ = d.chainParser.UnpackTxid(buf[al : al+l])
 		al += l
@@ -1203,6 +1599,10 @@ func (d *RocksDB) unpackTxOutput(to *TxOutput, buf []byte) int {
 		i, l = unpackVaruint(buf[al:])
 		to.SpentHeight = uint32(i)
 		al += l
+		// the pruned spend data stored without the spendPrunedFlag have zero txid and zero height
+		if to.SpentHeight == 0 {
+			to.SpentTxid = ""
+		}
 	}
 	return al
 }
@@ -1359,6 +1759,42 @@ func (d *RocksDB) GetBestBlock() (uint32, string, error) {
 	return 0, "", nil
 }
 
+// GetBlockLocator returns the block locator of the best chain in the db, i.e. hashes of blocks starting at the tip,
+// the first 10 blocks are consecutive, then the step doubles; the last hash is of the lowest block in the db
+func (d *RocksDB) GetBlockLocator() ([]string, error) {
+	bestHeight, bestHash, err := d.GetBestBlock()
+	if err != nil || bestHash == "" {
+		return nil, err
+	}
+	var lowestHeight uint32
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
+	defer it.Close()
+	if it.SeekToFirst(); it.Valid() {
+		lowestHeight = unpackUint(it.Key().Data())
+	}
+	locator := []string{bestHash}
+	step := uint32(1)
+	for height := bestHeight; height > lowestHeight; {
+		if len(locator) >= 10 {
+			step *= 2
+		}
+		if height-lowestHeight > step {
+			height -= step
+		} else {
+			height = lowestHeight
+		}
+		hash, err := d.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		if hash == "" {
+			return nil, errors.Errorf("GetBlockLocator: missing block at height %v", height)
+		}
+		locator = append(locator, hash)
+	}
+	return locator, nil
+}
+
 // GetBlockHash returns block hash at given height or empty string if not found
 func (d *RocksDB) GetBlockHash(height uint32) (string, error) {
 	key := packUint(height)
@@ -1390,6 +1826,15 @@ func (d *RocksDB) GetBlockInfo(height uint32) (*BlockInfo, error) {
 	return bi, err
 }
 
+// GetTotalTxCount returns the total number of transactions in the indexed blocks
+// the count is maintained incrementally when blocks are connected and disconnected
+func (d *RocksDB) GetTotalTxCount() (uint64, error) {
+	if d.is == nil {
+		return 0, errors.New("GetTotalTxCount: internal state not loaded")
+	}
+	return d.is.GetTotalTxs(), nil
+}
+
 func (d *RocksDB) writeHeightFromBlock(wb *grocksdb.WriteBatch, block *bchain.Block, op int) error {
 	return d.writeHeight(wb, block.Height, &BlockInfo{
 		Hash:   block.Hash,
@@ -1625,6 +2070,12 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 		key := packAddressKey([]byte(a), height)
 		wb.DeleteCF(d.cfh[cfAddresses], key)
 	}
+	d.disconnectOpReturns(wb, height)
+	d.disconnectOutputValues(wb, height)
+	if err := d.disconnectBlockMiner(wb, height); err != nil {
+		return err
+	}
+	d.disconnectAddressesFirstSeen(wb, height)
 	key := packUint(height)
 	wb.DeleteCF(d.cfh[cfBlockTxs], key)
 	wb.DeleteCF(d.cfh[cfHeight], key)
@@ -1634,6 +2085,7 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
 		wb.DeleteCF(d.cfh[cfTxAddresses], b)
+		wb.DeleteCF(d.cfh[cfTxFirstSeen], b)
 	}
 	return d.WriteBatch(wb)
 }
@@ -1642,6 +2094,7 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 // it is able to disconnect only blocks for which there are data in the blockTxs column
 func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error {
 	blocks := make([][]blockTxs, higher-lower+1)
+	blockTxsCounts := make([]uint64, higher-lower+1)
 	for height := lower; height <= higher; height++ {
 		blockTxs, err := d.getBlockTxs(height)
 		if err != nil {
@@ -1651,13 +2104,28 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 			return errors.Errorf("Cannot disconnect blocks with height %v and lower. It is necessary to rebuild index.", height)
 		}
 		blocks[height-lower] = blockTxs
+		bi, err := d.GetBlockInfo(height)
+		if err != nil {
+			return err
+		}
+		if bi != nil {
+			blockTxsCounts[height-lower] = uint64(bi.Txs)
+		}
 	}
 	for height := higher; height >= lower; height-- {
 		err := d.disconnectBlock(height, blocks[height-lower])
 		if err != nil {
 			return err
 		}
+		d.is.RemoveTotalTxs(blockTxsCounts[height-lower])
+	}
+	// the reconnected blocks contain new spend data, which must be pruned again, the running pruning is stopped
+	d.txAddressesMux.Lock()
+	d.spendIndexGeneration++
+	if d.is.SpendIndexPrunedHeight >= lower && lower > 0 {
+		d.is.SpendIndexPrunedHeight = lower - 1
 	}
+	d.txAddressesMux.Unlock()
 	d.is.RemoveLastBlockTimes(int(higher-lower) + 1)
 	glog.Infof("rocksdb: blocks %d-%d disconnected", lower, higher)
 	return nil
@@ -1785,8 +2253,10 @@ func (d *RocksDB) internalDeleteTx(wb *grocksdb.WriteBatch, key []byte) {
 // internal state
 const internalStateKey = "internalState"
 
-func (d *RocksDB) loadBlockTimes() ([]uint32, error) {
+// loadBlockTimes returns the times of the blocks in the db and the total number of their transactions
+func (d *RocksDB) loadBlockTimes() ([]uint32, uint64, error) {
 	var times []uint32
+	var totalTxs uint64
 	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
 	defer it.Close()
 	counter := uint32(0)
@@ -1802,14 +2272,15 @@ func (d *RocksDB) loadBlockTimes() ([]uint32, error) {
 		counter++
 		info, err := d.unpackBlockInfo(it.Value().Data())
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		if info != nil {
 			time = uint32(info.Time)
+			totalTxs += uint64(info.Txs)
 		}
 		times = append(times, time)
 	}
-	return times, nil
+	return times, totalTxs, nil
 }
 
 func (d *RocksDB) checkColumns(is *common.InternalState) ([]common.InternalStateColumn, error) {
@@ -1879,11 +2350,12 @@ func (d *RocksDB) LoadInternalState(rpcCoin string) (*common.InternalState, erro
 		return nil, err
 	}
 	is.DbColumns = nc
-	bt, err := d.loadBlockTimes()
+	bt, totalTxs, err := d.loadBlockTimes()
 	if err != nil {
 		return nil, err
 	}
 	avg := is.SetBlockTimes(bt)
+	is.SetTotalTxs(totalTxs)
 	if d.metrics != nil {
 		d.metrics.AvgBlockPeriod.Set(float64(avg))
 	}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"bytes"
+
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// address first seen index
+// the index is optional, it stores the height of the block and the txid of the transaction in which the address appeared for the first time
+// key is 0x01+addrDesc, value is (height uint32)+btxID
+// in addition, key 0x00+height+addrDesc with value btxID groups the new addresses by block,
+// it is used to find the addresses first seen in a range of blocks and to remove the addresses from the index on disconnect
+
+const (
+	addressFirstSeenHeightPrefix = 0
+	addressFirstSeenAddrPrefix   = 1
+)
+
+// SetAddressFirstSeenIndex enables or disables the index of the first appearance of addresses
+func (d *RocksDB) SetAddressFirstSeenIndex(enable bool) {
+	d.addressFirstSeenIndex = enable
+}
+
+// HasAddressFirstSeenIndex returns true if the first appearance of addresses is indexed
+func (d *RocksDB) HasAddressFirstSeenIndex() bool {
+	return d.addressFirstSeenIndex && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+func packAddressFirstSeenAddrKey(addrDesc bchain.AddressDescriptor) []byte {
+	return append([]byte{addressFirstSeenAddrPrefix}, addrDesc...)
+}
+
+func packAddressFirstSeenHeightKey(height uint32, addrDesc bchain.AddressDescriptor) []byte {
+	buf := make([]byte, 1+packedHeightBytes+len(addrDesc))
+	buf[0] = addressFirstSeenHeightPrefix
+	copy(buf[1:], packUint(height))
+	copy(buf[1+packedHeightBytes:], addrDesc)
+	return buf
+}
+
+// storeAddressesFirstSeen stores the addresses of the block which are not yet in the index
+// the transaction of the first appearance is the first transaction of the address in the block, the outputs are processed before the inputs
+// seen contains the addresses stored to the write batch which was not yet written to the db, it can be nil
+func (d *RocksDB) storeAddressesFirstSeen(wb *grocksdb.WriteBatch, height uint32, addresses addressesMap, seen map[string]struct{}) error {
+	for addrDesc, txi := range addresses {
+		if len(txi) == 0 {
+			continue
+		}
+		if _, found := seen[addrDesc]; found {
+			continue
+		}
+		ad := bchain.AddressDescriptor(addrDesc)
+		key := packAddressFirstSeenAddrKey(ad)
+		val, err := d.db.GetCF(d.ro, d.cfh[cfAddressFirstSeen], key)
+		if err != nil {
+			return err
+		}
+		exists := val.Size() > 0
+		val.Free()
+		if exists {
+			continue
+		}
+		btxID := txi[0].btxID
+		wb.PutCF(d.cfh[cfAddressFirstSeen], key, append(packUint(height), btxID...))
+		wb.PutCF(d.cfh[cfAddressFirstSeen], packAddressFirstSeenHeightKey(height, ad), btxID)
+		if seen != nil {
+			seen[addrDesc] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (d *RocksDB) disconnectAddressesFirstSeen(wb *grocksdb.WriteBatch, height uint32) {
+	if !d.HasAddressFirstSeenIndex() {
+		return
+	}
+	prefix := packAddressFirstSeenHeightKey(height, nil)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddressFirstSeen])
+	defer it.Close()
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		addrDesc := bchain.AddressDescriptor(key[len(prefix):])
+		wb.DeleteCF(d.cfh[cfAddressFirstSeen], packAddressFirstSeenAddrKey(addrDesc))
+		wb.DeleteCF(d.cfh[cfAddressFirstSeen], packAddressFirstSeenHeightKey(height, addrDesc))
+	}
+}
+
+// GetAddressFirstSeen returns the height of the block and the txid of the transaction in which the address appeared for the first time
+// found is false if the address is not in the index
+func (d *RocksDB) GetAddressFirstSeen(addrDesc bchain.AddressDescriptor) (height uint32, txid string, found bool, err error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressFirstSeen], packAddressFirstSeenAddrKey(addrDesc))
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) <= packedHeightBytes {
+		return 0, "", false, nil
+	}
+	txid, err = d.chainParser.UnpackTxid(buf[packedHeightBytes:])
+	if err != nil {
+		return 0, "", false, err
+	}
+	return unpackUint(buf), txid, true, nil
+}
+
+// GetAddressesFirstSeenCallback is called by GetAddressesFirstSeen for each found address
+type GetAddressesFirstSeenCallback func(addrDesc bchain.AddressDescriptor, height uint32, txid string) error
+
+// GetAddressesFirstSeen finds all addresses which appeared for the first time in blocks between lower and higher height
+// the addresses are passed to the callback in the order from the oldest block to the newest
+func (d *RocksDB) GetAddressesFirstSeen(lower uint32, higher uint32, fn GetAddressesFirstSeenCallback) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddressFirstSeen])
+	defer it.Close()
+	for it.Seek(packAddressFirstSeenHeightKey(lower, nil)); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if len(key) <= 1+packedHeightBytes || key[0] != addressFirstSeenHeightPrefix {
+			break
+		}
+		height := unpackUint(key[1 : 1+packedHeightBytes])
+		if height > higher {
+			break
+		}
+		txid, err := d.chainParser.UnpackTxid(it.Value().Data())
+		if err != nil {
+			return err
+		}
+		addrDesc := append(bchain.AddressDescriptor(nil), key[1+packedHeightBytes:]...)
+		if err := fn(addrDesc, height, txid); err != nil {
+			if _, ok := err.(*StopIteration); ok {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
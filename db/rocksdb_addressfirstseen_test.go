@@ -0,0 +1,171 @@
+//go:build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+type addressFirstSeen struct {
+	height uint32
+	txid   string
+}
+
+func TestRocksDB_AddressFirstSeenIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetAddressFirstSeenIndex(true)
+
+	getFirstSeen := func(address string) (addressFirstSeen, bool) {
+		addrDesc, err := d.chainParser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		height, txid, found, err := d.GetAddressFirstSeen(addrDesc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addressFirstSeen{height: height, txid: txid}, found
+	}
+	getNewAddresses := func(lower, higher uint32) map[string]addressFirstSeen {
+		r := make(map[string]addressFirstSeen)
+		if err := d.GetAddressesFirstSeen(lower, higher, func(addrDesc bchain.AddressDescriptor, height uint32, txid string) error {
+			addresses, _, err := d.chainParser.GetAddressesFromAddrDesc(addrDesc)
+			if err != nil || len(addresses) != 1 {
+				t.Fatalf("GetAddressesFromAddrDesc %x: %v %v", addrDesc, addresses, err)
+			}
+			r[addresses[0]] = addressFirstSeen{height: height, txid: txid}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := getFirstSeen(dbtestdata.AddrA); found {
+		t.Errorf("GetAddressFirstSeen(AddrA) found before the address appeared")
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	block1 := map[string]addressFirstSeen{
+		dbtestdata.Addr1: {225493, dbtestdata.TxidB1T1},
+		dbtestdata.Addr2: {225493, dbtestdata.TxidB1T1},
+		dbtestdata.Addr3: {225493, dbtestdata.TxidB1T2},
+		dbtestdata.Addr4: {225493, dbtestdata.TxidB1T2},
+		dbtestdata.Addr5: {225493, dbtestdata.TxidB1T2},
+	}
+	block2 := map[string]addressFirstSeen{
+		dbtestdata.Addr6: {225494, dbtestdata.TxidB2T1},
+		dbtestdata.Addr7: {225494, dbtestdata.TxidB2T1},
+		dbtestdata.Addr8: {225494, dbtestdata.TxidB2T2},
+		dbtestdata.Addr9: {225494, dbtestdata.TxidB2T2},
+		dbtestdata.AddrA: {225494, dbtestdata.TxidB2T4},
+	}
+	both := make(map[string]addressFirstSeen)
+	for _, m := range []map[string]addressFirstSeen{block1, block2} {
+		for a, fs := range m {
+			both[a] = fs
+		}
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    addressFirstSeen
+	}{
+		{
+			name:    "address of block 1",
+			address: dbtestdata.Addr1,
+			want:    addressFirstSeen{225493, dbtestdata.TxidB1T1},
+		},
+		{
+			// Addr5 receives funds again in TxidB2T3
+			name:    "reused address",
+			address: dbtestdata.Addr5,
+			want:    addressFirstSeen{225493, dbtestdata.TxidB1T2},
+		},
+		{
+			// Addr2 and Addr3 are spent in block 2
+			name:    "spent address",
+			address: dbtestdata.Addr3,
+			want:    addressFirstSeen{225493, dbtestdata.TxidB1T2},
+		},
+		{
+			name:    "address of block 2",
+			address: dbtestdata.Addr8,
+			want:    addressFirstSeen{225494, dbtestdata.TxidB2T2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := getFirstSeen(tt.address)
+			if !found || got != tt.want {
+				t.Errorf("GetAddressFirstSeen() = %v, %v, want %v", got, found, tt.want)
+			}
+		})
+	}
+
+	rangeTests := []struct {
+		name   string
+		lower  uint32
+		higher uint32
+		want   map[string]addressFirstSeen
+	}{
+		{
+			name:   "block 1",
+			lower:  225493,
+			higher: 225493,
+			want:   block1,
+		},
+		{
+			name:   "block 2",
+			lower:  225494,
+			higher: 225494,
+			want:   block2,
+		},
+		{
+			name:   "all blocks",
+			lower:  0,
+			higher: ^uint32(0),
+			want:   both,
+		},
+		{
+			name:   "no blocks",
+			lower:  225495,
+			higher: ^uint32(0),
+			want:   map[string]addressFirstSeen{},
+		},
+	}
+	for _, tt := range rangeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getNewAddresses(tt.lower, tt.higher); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAddressesFirstSeen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the addresses first seen in the disconnected block are removed from the index, the older addresses are kept
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if got := getNewAddresses(0, ^uint32(0)); !reflect.DeepEqual(got, block1) {
+		t.Errorf("GetAddressesFirstSeen() after disconnect = %v, want %v", got, block1)
+	}
+	if _, found := getFirstSeen(dbtestdata.Addr8); found {
+		t.Errorf("GetAddressFirstSeen(Addr8) found after disconnect")
+	}
+	if got, found := getFirstSeen(dbtestdata.Addr5); !found || got != block1[dbtestdata.Addr5] {
+		t.Errorf("GetAddressFirstSeen(Addr5) after disconnect = %v, %v, want %v", got, found, block1[dbtestdata.Addr5])
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"bytes"
+
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// block miner index
+// the index is optional, it groups the blocks by the mining pool recognized by the coinbase tags of the coin
+// key is packBlockMiner(miner)+^height, value is empty
+// in addition, key 0x00+height stores the miner of the block so that the block can be removed from the index on disconnect
+
+// BlockMinerFunc returns the name of the mining pool of the block or empty string if the pool is not recognized
+type BlockMinerFunc func(block *bchain.Block) string
+
+// SetBlockMinerIndex enables the index of blocks by miner, fn recognizes the miner of the block, nil disables the index
+func (d *RocksDB) SetBlockMinerIndex(fn BlockMinerFunc) {
+	d.blockMiner = fn
+}
+
+// HasBlockMinerIndex returns true if the blocks are indexed by miner
+func (d *RocksDB) HasBlockMinerIndex() bool {
+	return d.blockMiner != nil && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+// packBlockMiner prepends the length to the name of the miner, the names longer than maxPrefixLen are truncated
+// the length is never 0, which is used by the keys of the miners of the blocks
+func packBlockMiner(miner string) []byte {
+	if len(miner) > maxPrefixLen {
+		miner = miner[:maxPrefixLen]
+	}
+	buf := make([]byte, len(miner)+1)
+	buf[0] = byte(len(miner))
+	copy(buf[1:], miner)
+	return buf
+}
+
+func packBlockMinerHeightKey(height uint32) []byte {
+	return append([]byte{0}, packUint(height)...)
+}
+
+func (d *RocksDB) storeBlockMiner(wb *grocksdb.WriteBatch, height uint32, miner string) {
+	if miner == "" {
+		return
+	}
+	p := packBlockMiner(miner)
+	wb.PutCF(d.cfh[cfBlockMiners], packAddressKey(p, height), []byte{})
+	wb.PutCF(d.cfh[cfBlockMiners], packBlockMinerHeightKey(height), p[1:])
+}
+
+func (d *RocksDB) disconnectBlockMiner(wb *grocksdb.WriteBatch, height uint32) error {
+	if !d.HasBlockMinerIndex() {
+		return nil
+	}
+	key := packBlockMinerHeightKey(height)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockMiners], key)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	if val.Size() == 0 {
+		return nil
+	}
+	wb.DeleteCF(d.cfh[cfBlockMiners], packAddressKey(packBlockMiner(string(val.Data())), height))
+	wb.DeleteCF(d.cfh[cfBlockMiners], key)
+	return nil
+}
+
+// GetBlockMinerCallback is called by GetBlocksByMiner for each found block
+type GetBlockMinerCallback func(height uint32) error
+
+// GetBlocksByMiner finds all blocks mined by the miner in blocks between lower and higher height
+// the heights are passed to the callback in the order from newest block to the oldest
+func (d *RocksDB) GetBlocksByMiner(miner string, lower uint32, higher uint32, fn GetBlockMinerCallback) error {
+	p := packBlockMiner(miner)
+	startKey := packAddressKey(p, higher)
+	stopKey := packAddressKey(p, lower)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfBlockMiners])
+	defer it.Close()
+	for it.Seek(startKey); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if bytes.Compare(key, stopKey) > 0 {
+			break
+		}
+		if len(key) != len(p)+packedHeightBytes {
+			continue
+		}
+		_, height, err := unpackAddressKey(key)
+		if err != nil {
+			return err
+		}
+		if err := fn(height); err != nil {
+			if _, ok := err.(*StopIteration); ok {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+//go:build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_BlockMinerIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
+	miners := map[string]string{
+		block1.Hash: "Pool A",
+		block2.Hash: "Pool B",
+	}
+	d.SetBlockMinerIndex(func(block *bchain.Block) string {
+		return miners[block.Hash]
+	})
+
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+
+	getBlocks := func(miner string, lower, higher uint32) []uint32 {
+		var heights []uint32
+		if err := d.GetBlocksByMiner(miner, lower, higher, func(height uint32) error {
+			heights = append(heights, height)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return heights
+	}
+
+	tests := []struct {
+		name   string
+		miner  string
+		lower  uint32
+		higher uint32
+		want   []uint32
+	}{
+		{
+			name:   "Pool A",
+			miner:  "Pool A",
+			higher: ^uint32(0),
+			want:   []uint32{225493},
+		},
+		{
+			name:   "Pool B",
+			miner:  "Pool B",
+			higher: ^uint32(0),
+			want:   []uint32{225494},
+		},
+		{
+			name:   "height range",
+			miner:  "Pool B",
+			lower:  0,
+			higher: 225493,
+		},
+		{
+			name:   "prefix of miner",
+			miner:  "Pool",
+			higher: ^uint32(0),
+		},
+		{
+			name:   "unknown miner",
+			miner:  "Pool C",
+			higher: ^uint32(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBlocks(tt.miner, tt.lower, tt.higher); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetBlocksByMiner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// disconnected block is removed from the index
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if got := getBlocks("Pool B", 0, ^uint32(0)); len(got) != 0 {
+		t.Errorf("GetBlocksByMiner() after disconnect = %v, want none", got)
+	}
+
+	// the block replacing the disconnected one is attributed to its own miner
+	miners[block2.Hash] = "Pool A"
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := getBlocks("Pool A", 0, ^uint32(0)), []uint32{225494, 225493}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBlocksByMiner() after reconnect = %v, want %v", got, want)
+	}
+	if got := getBlocks("Pool B", 0, ^uint32(0)); len(got) != 0 {
+		t.Errorf("GetBlocksByMiner() after reconnect = %v, want none", got)
+	}
+}
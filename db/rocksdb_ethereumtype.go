@@ -1364,6 +1364,7 @@ func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height
 // it is able to disconnect only blocks for which there are data in the blockTxs column
 func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32) error {
 	blocks := make([][]ethBlockTx, higher-lower+1)
+	var totalTxs uint64
 	for height := lower; height <= higher; height++ {
 		blockTxs, err := d.getBlockTxsEthereumType(height)
 		if err != nil {
@@ -1374,6 +1375,13 @@ func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32)
 			return errors.Errorf("Cannot disconnect blocks with height %v and lower. It is necessary to rebuild index.", height)
 		}
 		blocks[height-lower] = blockTxs
+		bi, err := d.GetBlockInfo(height)
+		if err != nil {
+			return err
+		}
+		if bi != nil {
+			totalTxs += uint64(bi.Txs)
+		}
 	}
 	wb := grocksdb.NewWriteBatch()
 	defer wb.Destroy()
@@ -1391,6 +1399,7 @@ func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32)
 	err := d.WriteBatch(wb)
 	if err == nil {
 		d.is.RemoveLastBlockTimes(int(higher-lower) + 1)
+		d.is.RemoveTotalTxs(totalTxs)
 		glog.Infof("rocksdb: blocks %d-%d disconnected", lower, higher)
 	}
 	return err
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// first seen times of transactions
+// key is packed txid, value is the unix time when the transaction was first seen in the mempool (packVaruint)
+// the times of the transactions of a disconnected block are removed with the block, the times of the transactions
+// which were not confirmed within txFirstSeenMaxAge are removed by PruneTxFirstSeen
+
+// txFirstSeenMaxAge is the age in seconds after which the first seen time of an unconfirmed transaction is removed,
+// it matches the default mempool expiry of Bitcoin Core (336 hours)
+const txFirstSeenMaxAge = 336 * 3600
+
+// txFirstSeenPruneInterval is the minimal interval between two runs of PruneTxFirstSeen
+const txFirstSeenPruneInterval = 24 * time.Hour
+
+// txFirstSeenPruneChunk is the number of records of the txFirstSeen column checked in one chunk
+const txFirstSeenPruneChunk = 10000
+
+// StoreTxFirstSeen stores the time when the transaction was first seen in the mempool
+// an already stored time is not overwritten, the first seen time is kept
+func (d *RocksDB) StoreTxFirstSeen(txid string, firstSeen int64) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType || firstSeen <= 0 {
+		return nil
+	}
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxFirstSeen], btxID)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	if val.Size() > 0 {
+		return nil
+	}
+	buf := make([]byte, vlq.MaxLen64)
+	l := packVaruint(uint(firstSeen), buf)
+	return d.db.PutCF(d.wo, d.cfh[cfTxFirstSeen], btxID, buf[:l])
+}
+
+// StoreMempoolTxFirstSeen stores the first seen time of a new mempool transaction, it is used as bchain.OnNewTxFunc
+func (d *RocksDB) StoreMempoolTxFirstSeen(tx *bchain.MempoolTx) {
+	if err := d.StoreTxFirstSeen(tx.Txid, tx.Blocktime); err != nil {
+		glog.Error("StoreTxFirstSeen ", tx.Txid, ": ", err)
+	}
+}
+
+// GetTxFirstSeen returns the time when the transaction was first seen in the mempool, 0 if it was never seen
+func (d *RocksDB) GetTxFirstSeen(txid string) (int64, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return 0, nil
+	}
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return 0, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxFirstSeen], btxID)
+	if err != nil {
+		return 0, err
+	}
+	defer val.Free()
+	if val.Size() == 0 {
+		return 0, nil
+	}
+	t, _ := unpackVaruint(val.Data())
+	return int64(t), nil
+}
+
+// PruneTxFirstSeen removes the first seen times of the transactions which were not confirmed within txFirstSeenMaxAge,
+// it is run in the background together with the pruning of the spend index, at most once per txFirstSeenPruneInterval;
+// the column is processed in chunks of txFirstSeenPruneChunk records, the pruning is stopped by the stop channel
+// and starts again from the beginning in the next run
+func (d *RocksDB) PruneTxFirstSeen(stop chan os.Signal) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt32(&d.txFirstSeenPruning, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&d.txFirstSeenPruning, 0)
+	start := time.Now()
+	if start.Sub(d.txFirstSeenPruned) < txFirstSeenPruneInterval {
+		return nil
+	}
+	expiry := start.Unix() - txFirstSeenMaxAge
+	ro := grocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetFillCache(false)
+	var records, pruned uint64
+	var seekKey []byte
+	for done := false; !done; {
+		select {
+		case <-stop:
+			glog.Info("PruneTxFirstSeen: interrupted")
+			return nil
+		default:
+		}
+		err := func() error {
+			it := d.db.NewIteratorCF(ro, d.cfh[cfTxFirstSeen])
+			defer it.Close()
+			if seekKey == nil {
+				it.SeekToFirst()
+			} else {
+				it.Seek(seekKey)
+				it.Next()
+			}
+			wb := grocksdb.NewWriteBatch()
+			defer wb.Destroy()
+			for count := 0; it.Valid() && count < txFirstSeenPruneChunk; it.Next() {
+				count++
+				records++
+				seekKey = append(seekKey[:0], it.Key().Data()...)
+				t, _ := unpackVaruint(it.Value().Data())
+				if int64(t) >= expiry {
+					continue
+				}
+				// the transaction is confirmed if it is in the txAddresses column
+				val, err := d.db.GetCF(ro, d.cfh[cfTxAddresses], seekKey)
+				if err != nil {
+					return err
+				}
+				confirmed := val.Size() > 0
+				val.Free()
+				if confirmed {
+					continue
+				}
+				wb.DeleteCF(d.cfh[cfTxFirstSeen], append([]byte(nil), seekKey...))
+				pruned++
+			}
+			if err := it.Err(); err != nil {
+				return err
+			}
+			done = !it.Valid()
+			return d.db.Write(d.wo, wb)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	d.txFirstSeenPruned = start
+	glog.Info("PruneTxFirstSeen: pruned ", pruned, " of ", records, " records, finished in ", time.Since(start))
+	return nil
+}
@@ -0,0 +1,84 @@
+//go:build unittest
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_TxFirstSeen(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 3}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	const txidUnconfirmedOld = "1111111111111111111111111111111111111111111111111111111111111111"
+	const txidUnconfirmedNew = "2222222222222222222222222222222222222222222222222222222222222222"
+	now := time.Now().Unix()
+	old := now - txFirstSeenMaxAge - 3600
+	firstSeen := map[string]int64{
+		dbtestdata.TxidB1T1: old,
+		dbtestdata.TxidB2T1: old,
+		txidUnconfirmedOld:  old,
+		txidUnconfirmedNew:  now,
+	}
+	for txid, ts := range firstSeen {
+		if err := d.StoreTxFirstSeen(txid, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	checkFirstSeen := func(want map[string]int64) {
+		t.Helper()
+		for txid, w := range want {
+			got, err := d.GetTxFirstSeen(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != w {
+				t.Errorf("GetTxFirstSeen(%s) = %d, want %d", txid, got, w)
+			}
+		}
+	}
+	checkFirstSeen(firstSeen)
+
+	// the old record of the unconfirmed transaction is pruned, the records of the confirmed transactions are kept
+	if err := d.PruneTxFirstSeen(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkFirstSeen(map[string]int64{
+		dbtestdata.TxidB1T1: old,
+		dbtestdata.TxidB2T1: old,
+		txidUnconfirmedOld:  0,
+		txidUnconfirmedNew:  now,
+	})
+
+	// the next pruning is skipped within the prune interval
+	if err := d.StoreTxFirstSeen(txidUnconfirmedOld, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PruneTxFirstSeen(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkFirstSeen(map[string]int64{txidUnconfirmedOld: old})
+
+	// the records of the transactions of a disconnected block are removed
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	checkFirstSeen(map[string]int64{
+		dbtestdata.TxidB1T1: old,
+		dbtestdata.TxidB2T1: 0,
+		txidUnconfirmedNew:  now,
+	})
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// OP_RETURN index
+// the index is optional, it stores transactions with OP_RETURN outputs carrying one of the configured payload prefixes
+// key is packOpReturnPrefix(prefix)+^height, value is the same as in the addresses column (packTxIndexes)
+
+const (
+	opReturn     = 0x6a
+	opPushData1  = 0x4c
+	opPushData2  = 0x4d
+	maxPrefixLen = 255
+)
+
+// SetOpReturnPrefixes sets the prefixes of OP_RETURN payloads which are indexed
+func (d *RocksDB) SetOpReturnPrefixes(prefixes [][]byte) {
+	d.opReturnPrefixes = nil
+	for _, p := range prefixes {
+		if len(p) > 0 && len(p) <= maxPrefixLen {
+			d.opReturnPrefixes = append(d.opReturnPrefixes, p)
+		}
+	}
+}
+
+// HasOpReturnIndex returns true if OP_RETURN payloads are indexed
+func (d *RocksDB) HasOpReturnIndex() bool {
+	return len(d.opReturnPrefixes) > 0 && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+// packOpReturnPrefix prepends the length to the prefix so that a prefix of another prefix is not mixed with it
+func packOpReturnPrefix(prefix []byte) []byte {
+	buf := make([]byte, len(prefix)+1)
+	buf[0] = byte(len(prefix))
+	copy(buf[1:], prefix)
+	return buf
+}
+
+// GetOpReturnPayload returns data pushed by OP_RETURN script or nil if the script is not OP_RETURN
+func GetOpReturnPayload(script []byte) []byte {
+	if len(script) < 2 || script[0] != opReturn {
+		return nil
+	}
+	var l, o int
+	switch op := script[1]; {
+	case op > 0 && op < opPushData1:
+		l, o = int(op), 2
+	case op == opPushData1 && len(script) > 2:
+		l, o = int(script[2]), 3
+	case op == opPushData2 && len(script) > 3:
+		l, o = int(binary.LittleEndian.Uint16(script[2:4])), 4
+	default:
+		return nil
+	}
+	if o+l > len(script) {
+		l = len(script) - o
+	}
+	return script[o : o+l]
+}
+
+// processOpReturnsBitcoinType returns map of packed OP_RETURN prefixes to transactions in the block
+func (d *RocksDB) processOpReturnsBitcoinType(block *bchain.Block) addressesMap {
+	opReturns := make(addressesMap)
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		var btxID []byte
+		for i := range tx.Vout {
+			addrDesc, err := d.chainParser.GetAddrDescFromVout(&tx.Vout[i])
+			if err != nil {
+				continue
+			}
+			payload := GetOpReturnPayload(addrDesc)
+			if len(payload) == 0 {
+				continue
+			}
+			for _, p := range d.opReturnPrefixes {
+				if bytes.HasPrefix(payload, p) {
+					if btxID == nil {
+						if btxID, err = d.chainParser.PackTxid(tx.Txid); err != nil {
+							break
+						}
+					}
+					addToAddressesMap(opReturns, string(packOpReturnPrefix(p)), btxID, int32(i))
+				}
+			}
+		}
+	}
+	return opReturns
+}
+
+func (d *RocksDB) storeOpReturns(wb *grocksdb.WriteBatch, height uint32, opReturns addressesMap) error {
+	for prefix, txi := range opReturns {
+		key := packAddressKey([]byte(prefix), height)
+		val := d.packTxIndexes(txi)
+		wb.PutCF(d.cfh[cfOpReturns], key, val)
+	}
+	return nil
+}
+
+func (d *RocksDB) disconnectOpReturns(wb *grocksdb.WriteBatch, height uint32) {
+	for _, p := range d.opReturnPrefixes {
+		wb.DeleteCF(d.cfh[cfOpReturns], packAddressKey(packOpReturnPrefix(p), height))
+	}
+}
+
+// GetOpReturnTransactions finds all transactions with OP_RETURN output with payload starting with the prefix
+// the prefix must be one of the indexed prefixes, indexes passed to the callback are the OP_RETURN outputs
+// Transaction are passed to callback function in the order from newest block to the oldest
+func (d *RocksDB) GetOpReturnTransactions(prefix []byte, lower uint32, higher uint32, fn GetTransactionsCallback) error {
+	return d.getTxIndexesTransactions(cfOpReturns, packOpReturnPrefix(prefix), lower, higher, fn)
+}
+
+// IsOpReturnPrefixIndexed returns true if the prefix is one of the indexed OP_RETURN prefixes
+func (d *RocksDB) IsOpReturnPrefixIndexed(prefix []byte) bool {
+	for _, p := range d.opReturnPrefixes {
+		if bytes.Equal(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
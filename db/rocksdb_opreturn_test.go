@@ -0,0 +1,124 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func TestGetOpReturnPayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"not OP_RETURN", "76a914010d39800f86122416e28f485029acf77507169288ac", ""},
+		{"empty OP_RETURN", "6a", ""},
+		{"OP_RETURN push", "6a072020f1686f6a20", "2020f1686f6a20"},
+		{"OP_RETURN OP_PUSHDATA1", "6a4c0a446c6f75687920746578", "446c6f75687920746578"},
+		{"OP_RETURN OP_PUSHDATA2", "6a4d0400aabbccdd", "aabbccdd"},
+		{"OP_RETURN truncated", "6a10aabb", "aabb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.script)
+			if got := hex.EncodeToString(GetOpReturnPayload(script)); got != tt.want {
+				t.Errorf("GetOpReturnPayload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func opReturnTestTx(txid string, scripts ...string) bchain.Tx {
+	tx := bchain.Tx{
+		Txid: txid,
+		Vin:  []bchain.Vin{{Coinbase: "03bf1e1504aede765b726567696f6e312f50726f6a65637420425443506f6f6c2f"}},
+	}
+	for i, s := range scripts {
+		tx.Vout = append(tx.Vout, bchain.Vout{
+			N:            uint32(i),
+			ValueSat:     *big.NewInt(0),
+			ScriptPubKey: bchain.ScriptPubKey{Hex: s},
+		})
+	}
+	return tx
+}
+
+func TestRocksDB_OpReturnIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	omni := []byte("omni")
+	cntrprty := []byte("CNTRPRTY")
+	d.SetOpReturnPrefixes([][]byte{omni, cntrprty})
+
+	p2pkh := "76a914010d39800f86122416e28f485029acf77507169288ac"
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225493,
+			Hash:   "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997",
+			Time:   1521515026,
+		},
+		Txs: []bchain.Tx{
+			// omni payload in the second output
+			opReturnTestTx("00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840", p2pkh, "6a146f6d6e690000000000000001000000000bebc200"),
+			// counterparty payload
+			opReturnTestTx("effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75", "6a1c434e54525052545900000000000000000000000000000000000000ff"),
+			// other payload
+			opReturnTestTx("7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25", "6a072020f1686f6a20", p2pkh),
+			// omni payload using OP_PUSHDATA1
+			opReturnTestTx("3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71", "6a4c056f6d6e6901"),
+		},
+	}
+	if err := d.ConnectBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	type opReturnTx struct {
+		txid    string
+		height  uint32
+		indexes []int32
+	}
+	getTxs := func(prefix []byte) []opReturnTx {
+		var txs []opReturnTx
+		if err := d.GetOpReturnTransactions(prefix, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+			txs = append(txs, opReturnTx{txid, height, append([]int32(nil), indexes...)})
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return txs
+	}
+
+	// the transactions of the same block are returned from the last one
+	gotOmni := getTxs(omni)
+	wantOmni := []opReturnTx{
+		{"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71", 225493, []int32{0}},
+		{"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840", 225493, []int32{1}},
+	}
+	if !reflect.DeepEqual(gotOmni, wantOmni) {
+		t.Errorf("GetOpReturnTransactions(omni) = %+v, want %+v", gotOmni, wantOmni)
+	}
+
+	gotCntrprty := getTxs(cntrprty)
+	wantCntrprty := []opReturnTx{
+		{"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75", 225493, []int32{0}},
+	}
+	if !reflect.DeepEqual(gotCntrprty, wantCntrprty) {
+		t.Errorf("GetOpReturnTransactions(CNTRPRTY) = %+v, want %+v", gotCntrprty, wantCntrprty)
+	}
+
+	if got := getTxs([]byte("om")); len(got) != 0 {
+		t.Errorf("GetOpReturnTransactions(om) = %+v, want none", got)
+	}
+	if !d.IsOpReturnPrefixIndexed(omni) || d.IsOpReturnPrefixIndexed([]byte("om")) {
+		t.Error("IsOpReturnPrefixIndexed returned unexpected result")
+	}
+}
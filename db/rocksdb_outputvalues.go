@@ -0,0 +1,192 @@
+package db
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// output value index
+// the index is optional, it groups the outputs by the bucket of their value, the bucket is the bit length of the value in satoshis
+// key is (bucket uint8)+^height, value is the same as in the addresses column (packTxIndexes)
+
+// maxValueBucket is the bucket of the values which do not fit into uint64
+const maxValueBucket = 64
+
+// SetOutputValueIndex enables or disables the index of outputs by value
+func (d *RocksDB) SetOutputValueIndex(enable bool) {
+	d.outputValueIndex = enable
+}
+
+// HasOutputValueIndex returns true if the outputs are indexed by value
+func (d *RocksDB) HasOutputValueIndex() bool {
+	return d.outputValueIndex && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+// valueBucket returns the bucket of the value in the output value index
+func valueBucket(value *big.Int) byte {
+	b := value.BitLen()
+	if b > maxValueBucket {
+		b = maxValueBucket
+	}
+	return byte(b)
+}
+
+// processOutputValuesBitcoinType returns map of value buckets to transactions in the block
+func (d *RocksDB) processOutputValuesBitcoinType(block *bchain.Block) (addressesMap, error) {
+	outputValues := make(addressesMap)
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		coinStake := d.chainParser.IsCoinStake(tx)
+		for i := range tx.Vout {
+			// the first output of a coinstake transaction is an empty marker
+			if coinStake && i == 0 {
+				continue
+			}
+			addToAddressesMap(outputValues, string([]byte{valueBucket(&tx.Vout[i].ValueSat)}), btxID, int32(i))
+		}
+	}
+	return outputValues, nil
+}
+
+func (d *RocksDB) storeOutputValues(wb *grocksdb.WriteBatch, height uint32, outputValues addressesMap) error {
+	for bucket, txi := range outputValues {
+		key := packAddressKey([]byte(bucket), height)
+		val := d.packTxIndexes(txi)
+		wb.PutCF(d.cfh[cfOutputValues], key, val)
+	}
+	return nil
+}
+
+func (d *RocksDB) disconnectOutputValues(wb *grocksdb.WriteBatch, height uint32) {
+	if !d.HasOutputValueIndex() {
+		return
+	}
+	for b := 0; b <= maxValueBucket; b++ {
+		wb.DeleteCF(d.cfh[cfOutputValues], packAddressKey([]byte{byte(b)}, height))
+	}
+}
+
+// ErrOutputsScanLimit is returned by GetOutputsByValue if the search would read more than the allowed number of transactions
+var ErrOutputsScanLimit = errors.New("Outputs scan limit exceeded")
+
+// GetOutputsCallback is called by GetOutputsByValue for each found output
+type GetOutputsCallback func(txid string, height uint32, vout int32, value *big.Int) error
+
+// GetOutputsByValue finds all outputs with value between minValue and maxValue (inclusive) in blocks between lower and higher height
+// the outputs are passed to the callback from the newest block to the oldest, in a block by the value buckets;
+// the value buckets are iterated in parallel, the iteration is stopped without an error if fn returns StopIteration;
+// at most maxTxs transactions are read (0 means no limit), ErrOutputsScanLimit is returned if the limit is exceeded
+func (d *RocksDB) GetOutputsByValue(minValue *big.Int, maxValue *big.Int, lower uint32, higher uint32, maxTxs int, fn GetOutputsCallback) error {
+	if minValue.Cmp(maxValue) > 0 {
+		return nil
+	}
+	type bucketIterator struct {
+		it      *grocksdb.Iterator
+		stopKey []byte
+		height  uint32
+		valid   bool
+	}
+	// next moves the iterator to the next entry of its bucket and unpacks its height
+	next := func(b *bucketIterator, first bool) error {
+		if !first {
+			b.it.Next()
+		}
+		b.valid = false
+		for ; b.it.Valid(); b.it.Next() {
+			key := b.it.Key().Data()
+			if bytes.Compare(key, b.stopKey) > 0 {
+				return nil
+			}
+			if len(key) != 1+packedHeightBytes {
+				continue
+			}
+			_, height, err := unpackAddressKey(key)
+			if err != nil {
+				return err
+			}
+			b.height, b.valid = height, true
+			return nil
+		}
+		return nil
+	}
+	buckets := make([]*bucketIterator, 0, maxValueBucket+1)
+	defer func() {
+		for _, b := range buckets {
+			b.it.Close()
+		}
+	}()
+	for bucket := valueBucket(minValue); bucket <= valueBucket(maxValue); bucket++ {
+		b := &bucketIterator{
+			it:      d.db.NewIteratorCF(d.ro, d.cfh[cfOutputValues]),
+			stopKey: packAddressKey([]byte{bucket}, lower),
+		}
+		buckets = append(buckets, b)
+		b.it.Seek(packAddressKey([]byte{bucket}, higher))
+		if err := next(b, true); err != nil {
+			return err
+		}
+	}
+	indexes := make([]int32, 0, 16)
+	scanned := 0
+	for {
+		// the entries of all the buckets in the newest remaining block
+		var height uint32
+		found := false
+		for _, b := range buckets {
+			if b.valid && (!found || b.height > height) {
+				height, found = b.height, true
+			}
+		}
+		if !found {
+			return nil
+		}
+		for _, b := range buckets {
+			if !b.valid || b.height != height {
+				continue
+			}
+			var err error
+			indexes, err = d.unpackTxIndexesValue(b.it.Key().Data(), b.it.Value().Data(), height, indexes, func(txid string, height uint32, indexes []int32) error {
+				scanned++
+				if maxTxs > 0 && scanned > maxTxs {
+					return ErrOutputsScanLimit
+				}
+				ta, err := d.GetTxAddresses(txid)
+				if err != nil {
+					return err
+				}
+				if ta == nil {
+					return nil
+				}
+				for _, i := range indexes {
+					if i < 0 || int(i) >= len(ta.Outputs) {
+						continue
+					}
+					v := &ta.Outputs[i].ValueSat
+					if v.Cmp(minValue) >= 0 && v.Cmp(maxValue) <= 0 {
+						if err := fn(txid, height, i, v); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				if _, ok := err.(*StopIteration); ok {
+					return nil
+				}
+				return err
+			}
+			if err := next(b, false); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+//go:build unittest
+
+package db
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func Test_valueBucket(t *testing.T) {
+	tests := []struct {
+		value string
+		want  byte
+	}{
+		{"0", 0},
+		{"1", 1},
+		{"2", 2},
+		{"3", 2},
+		{"12345", 14},
+		{"16383", 14},
+		{"16384", 15},
+		{"18446744073709551615", 64},
+		{"18446744073709551616", 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			v, _ := new(big.Int).SetString(tt.value, 10)
+			if got := valueBucket(v); got != tt.want {
+				t.Errorf("valueBucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRocksDB_OutputValueIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetOutputValueIndex(true)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the outputs are returned from the newest block, in a block by the value buckets, in each bucket the transactions of a block from the last one
+
+	type valueOutput struct {
+		txid   string
+		height uint32
+		vout   int32
+		value  string
+	}
+	getOutputs := func(minValue, maxValue int64, lower, higher uint32) []valueOutput {
+		var outputs []valueOutput
+		if err := d.GetOutputsByValue(big.NewInt(minValue), big.NewInt(maxValue), lower, higher, 0, func(txid string, height uint32, vout int32, value *big.Int) error {
+			outputs = append(outputs, valueOutput{txid, height, vout, value.String()})
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return outputs
+	}
+
+	tests := []struct {
+		name     string
+		minValue int64
+		maxValue int64
+		lower    uint32
+		higher   uint32
+		want     []valueOutput
+	}{
+		{
+			name:     "single bucket",
+			minValue: 9000,
+			maxValue: 12345,
+			higher:   ^uint32(0),
+			want: []valueOutput{
+				{dbtestdata.TxidB2T3, 225494, 0, "9000"},
+				{dbtestdata.TxidB1T2, 225493, 2, "9876"},
+				{dbtestdata.TxidB1T1, 225493, 1, "12345"},
+				{dbtestdata.TxidB1T1, 225493, 2, "12345"},
+			},
+		},
+		{
+			name:     "multiple buckets",
+			minValue: 9001,
+			maxValue: 1000000000,
+			higher:   ^uint32(0),
+			want: []valueOutput{
+				{dbtestdata.TxidB1T2, 225493, 2, "9876"},
+				{dbtestdata.TxidB1T1, 225493, 1, "12345"},
+				{dbtestdata.TxidB1T1, 225493, 2, "12345"},
+				{dbtestdata.TxidB1T1, 225493, 0, "100000000"},
+			},
+		},
+		{
+			name:     "height range",
+			minValue: 0,
+			maxValue: 10000,
+			lower:    225494,
+			higher:   225494,
+			want: []valueOutput{
+				{dbtestdata.TxidB2T4, 225494, 1, "0"},
+				{dbtestdata.TxidB2T1, 225494, 2, "0"},
+				{dbtestdata.TxidB2T3, 225494, 0, "9000"},
+			},
+		},
+		{
+			name:     "exact value",
+			minValue: 1,
+			maxValue: 1,
+			higher:   ^uint32(0),
+			want: []valueOutput{
+				{dbtestdata.TxidB1T2, 225493, 1, "1"},
+			},
+		},
+		{
+			name:     "empty range",
+			minValue: 20000,
+			maxValue: 90000000,
+			higher:   ^uint32(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getOutputs(tt.minValue, tt.maxValue, tt.lower, tt.higher); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOutputsByValue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// the search is stopped if it would read more transactions than allowed
+	if err := d.GetOutputsByValue(big.NewInt(9000), big.NewInt(12345), 0, ^uint32(0), 1, func(txid string, height uint32, vout int32, value *big.Int) error {
+		return nil
+	}); err != ErrOutputsScanLimit {
+		t.Errorf("GetOutputsByValue() with scan limit error = %v, want %v", err, ErrOutputsScanLimit)
+	}
+	// the iteration is stopped by the callback
+	var stopped []string
+	if err := d.GetOutputsByValue(big.NewInt(0), big.NewInt(1000000000), 0, ^uint32(0), 0, func(txid string, height uint32, vout int32, value *big.Int) error {
+		stopped = append(stopped, txid)
+		return &StopIteration{}
+	}); err != nil || len(stopped) != 1 {
+		t.Errorf("GetOutputsByValue() stopped = %v, %v, want one output", stopped, err)
+	}
+
+	// disconnected block is removed from the index
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if got := getOutputs(0, 10000, 225494, ^uint32(0)); len(got) != 0 {
+		t.Errorf("GetOutputsByValue() after disconnect = %+v, want none", got)
+	}
+	want := []valueOutput{
+		{dbtestdata.TxidB1T2, 225493, 1, "1"},
+		{dbtestdata.TxidB1T2, 225493, 2, "9876"},
+	}
+	if got := getOutputs(0, 10000, 0, ^uint32(0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetOutputsByValue() after disconnect = %+v, want %+v", got, want)
+	}
+}
+
+func TestRocksDB_OutputValueIndexDisabled(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkColumn(d, cfOutputValues, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+}
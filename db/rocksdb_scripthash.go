@@ -0,0 +1,44 @@
+package db
+
+import (
+	"crypto/sha256"
+
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// script hash index
+// the index is optional, it maps the sha256 hash of the output script to the script (address descriptor),
+// which allows to query the outputs of any script, including the non-standard ones, by its hash
+// key is sha256(addrDesc), value is addrDesc
+// the records are not removed on disconnect, the mapping of the hash to the script stays valid
+
+// SetScriptHashIndex enables or disables the index of output scripts by hash
+func (d *RocksDB) SetScriptHashIndex(enable bool) {
+	d.scriptHashIndex = enable
+}
+
+// HasScriptHashIndex returns true if the output scripts are indexed by hash
+func (d *RocksDB) HasScriptHashIndex() bool {
+	return d.scriptHashIndex && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+func (d *RocksDB) storeScriptHashes(wb *grocksdb.WriteBatch, addresses addressesMap) {
+	for addrDesc := range addresses {
+		h := sha256.Sum256([]byte(addrDesc))
+		wb.PutCF(d.cfh[cfScriptHashes], h[:], []byte(addrDesc))
+	}
+}
+
+// GetAddrDescByScriptHash returns the address descriptor (output script) with the sha256 hash or nil if the script is not indexed
+func (d *RocksDB) GetAddrDescByScriptHash(hash []byte) (bchain.AddressDescriptor, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfScriptHashes], hash)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	if val.Size() == 0 {
+		return nil, nil
+	}
+	return append(bchain.AddressDescriptor(nil), val.Data()...), nil
+}
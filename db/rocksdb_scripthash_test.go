@@ -0,0 +1,127 @@
+//go:build unittest
+
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+// non-standard script OP_2 OP_EQUAL without an address
+const testCustomScript = "5287"
+
+const (
+	txidCustomScript1 = "0a4f9b4cc7e3d4b28e5c3e6c4e0ef3c2c8d8b4e6f0a1b2c3d4e5f60718293a4b"
+	txidCustomScript2 = "1b5fac5dd8f4e5c39f6d4f7d5f1f04d3d9e9c5f701b2c3d4e5f6071829304b5c"
+)
+
+func getCustomScriptTestBlocks(parser bchain.BlockChainParser) (*bchain.Block, *bchain.Block) {
+	block1 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225493,
+			Hash:   "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997",
+			Time:   1521515026,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: txidCustomScript1,
+				Vout: []bchain.Vout{
+					{
+						N:            0,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: testCustomScript},
+						ValueSat:     *big.NewInt(1000),
+					},
+					{
+						N:            1,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: testCustomScript},
+						ValueSat:     *big.NewInt(2000),
+					},
+					{
+						N:            2,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, parser)},
+						ValueSat:     *big.NewInt(3000),
+					},
+				},
+			},
+		},
+	}
+	block2 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225494,
+			Hash:   "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
+			Time:   1521595678,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: txidCustomScript2,
+				Vin: []bchain.Vin{
+					{
+						Txid: txidCustomScript1,
+						Vout: 0,
+					},
+				},
+				Vout: []bchain.Vout{
+					{
+						N:            0,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr2, parser)},
+						ValueSat:     *big.NewInt(900),
+					},
+				},
+			},
+		},
+	}
+	return block1, block2
+}
+
+func TestRocksDB_ScriptHashIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	d.SetScriptHashIndex(true)
+
+	block1, block2 := getCustomScriptTestBlocks(d.chainParser)
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+
+	script := hexToBytes(testCustomScript)
+	hash := sha256.Sum256(script)
+	addrDesc, err := d.GetAddrDescByScriptHash(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(addrDesc, script) {
+		t.Fatalf("GetAddrDescByScriptHash() = %x, want %x", addrDesc, script)
+	}
+
+	// only the output 1 is unspent, the output 0 is spent by the second block
+	ba, err := d.GetAddrDescBalance(addrDesc, AddressBalanceDetailUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ba == nil || len(ba.Utxos) != 1 {
+		t.Fatalf("GetAddrDescBalance() = %+v, want 1 utxo", ba)
+	}
+	txid, err := d.chainParser.UnpackTxid(ba.Utxos[0].BtxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txid != txidCustomScript1 || ba.Utxos[0].Vout != 1 || ba.Utxos[0].Height != 225493 || ba.Utxos[0].ValueSat.Int64() != 2000 {
+		t.Errorf("utxo = %v:%d at %d, value %v, want %v:1 at 225493, value 2000", txid, ba.Utxos[0].Vout, ba.Utxos[0].Height, ba.Utxos[0].ValueSat.String(), txidCustomScript1)
+	}
+
+	// scripts which never appeared in a block are not found
+	unknown := sha256.Sum256(hexToBytes("5387"))
+	if addrDesc, err = d.GetAddrDescByScriptHash(unknown[:]); err != nil || addrDesc != nil {
+		t.Errorf("GetAddrDescByScriptHash(unknown) = %x, %v, want nil", addrDesc, err)
+	}
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+)
+
+// snapshot of the index
+// the snapshot is a stream of all records of all column families, it is independent of the files of RocksDB and can be imported
+// to an empty database of the same data version and the same column families
+// header: snapshotMagic+(snapshotVersion uint32)+(dbVersion uint32)+(number of column families vuint)+[](name of column family []byte)
+// records: [](column family index byte)+(key []byte)+(value []byte), the byte arrays are prefixed by the length as vuint
+// trailer: (snapshotEnd byte)+(number of records uint64)
+
+var snapshotMagic = []byte("blockbook-snapshot")
+
+const snapshotVersion = 1
+const snapshotEnd = 0xff
+const snapshotBatchSize = 64 << 20 // 64MB
+const snapshotMaxRecordSize = 1 << 30
+
+func writeSnapshotBytes(w *bufio.Writer, b []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(buf[:], uint64(len(b)))
+	if _, err := w.Write(buf[:l]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readSnapshotBytes(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > snapshotMaxRecordSize {
+		return nil, errors.Errorf("record too long (%d bytes)", l)
+	}
+	b := make([]byte, l)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ExportSnapshot writes all records of the index to w, the records are read from a consistent point in time snapshot of the database
+func (d *RocksDB) ExportSnapshot(w io.Writer) error {
+	start := time.Now()
+	bw := bufio.NewWriterSize(w, 1<<20)
+	bw.Write(snapshotMagic)
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], snapshotVersion)
+	bw.Write(buf[:])
+	binary.BigEndian.PutUint32(buf[:], dbVersion)
+	bw.Write(buf[:])
+	var vbuf [binary.MaxVarintLen64]byte
+	bw.Write(vbuf[:binary.PutUvarint(vbuf[:], uint64(len(cfNames)))])
+	for _, name := range cfNames {
+		if err := writeSnapshotBytes(bw, []byte(name)); err != nil {
+			return err
+		}
+	}
+	snap := d.db.NewSnapshot()
+	defer d.db.ReleaseSnapshot(snap)
+	ro := grocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetSnapshot(snap)
+	ro.SetFillCache(false)
+	var records uint64
+	for cf := range d.cfh {
+		it := d.db.NewIteratorCF(ro, d.cfh[cf])
+		for it.SeekToFirst(); it.Valid(); it.Next() {
+			bw.WriteByte(byte(cf))
+			if err := writeSnapshotBytes(bw, it.Key().Data()); err != nil {
+				it.Close()
+				return err
+			}
+			if err := writeSnapshotBytes(bw, it.Value().Data()); err != nil {
+				it.Close()
+				return err
+			}
+			records++
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+		glog.Info("ExportSnapshot: column ", cfNames[cf], " exported, ", records, " records total")
+	}
+	bw.WriteByte(snapshotEnd)
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], records)
+	bw.Write(count[:])
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	glog.Info("ExportSnapshot: finished, ", records, " records, ", time.Since(start))
+	return nil
+}
+
+func (d *RocksDB) isEmpty() bool {
+	for _, h := range d.cfh {
+		it := d.db.NewIteratorCF(d.ro, h)
+		it.SeekToFirst()
+		valid := it.Valid()
+		it.Close()
+		if valid {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportSnapshot reads the records of the index exported by ExportSnapshot from r and stores them to the database,
+// the database must be empty and must have the same data version and column families as the exported database;
+// the internal state must be loaded after the import; if the import fails, the database must be deleted
+func (d *RocksDB) ImportSnapshot(r io.Reader) error {
+	start := time.Now()
+	if !d.isEmpty() {
+		return errors.New("ImportSnapshot: database is not empty")
+	}
+	br := bufio.NewReaderSize(r, 1<<20)
+	header := make([]byte, len(snapshotMagic)+8)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return errors.Annotatef(err, "ImportSnapshot: header")
+	}
+	if !bytes.HasPrefix(header, snapshotMagic) {
+		return errors.New("ImportSnapshot: not a snapshot of the index")
+	}
+	if v := binary.BigEndian.Uint32(header[len(snapshotMagic):]); v != snapshotVersion {
+		return errors.Errorf("ImportSnapshot: snapshot version %d is not supported, required %d", v, snapshotVersion)
+	}
+	if v := binary.BigEndian.Uint32(header[len(snapshotMagic)+4:]); v != dbVersion {
+		return errors.Errorf("ImportSnapshot: data version %d of the snapshot does not match the required version %d", v, dbVersion)
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return errors.Annotatef(err, "ImportSnapshot: header")
+	}
+	if n != uint64(len(cfNames)) {
+		return errors.Errorf("ImportSnapshot: snapshot has %d column families, database %d", n, len(cfNames))
+	}
+	for i := range cfNames {
+		name, err := readSnapshotBytes(br)
+		if err != nil {
+			return errors.Annotatef(err, "ImportSnapshot: header")
+		}
+		if string(name) != cfNames[i] {
+			return errors.Errorf("ImportSnapshot: column family %s of the snapshot does not match the column family %s of the database", name, cfNames[i])
+		}
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	var records uint64
+	size := 0
+	for {
+		cf, err := br.ReadByte()
+		if err != nil {
+			return errors.Annotatef(err, "ImportSnapshot: record %d", records)
+		}
+		if cf == snapshotEnd {
+			break
+		}
+		if int(cf) >= len(d.cfh) {
+			return errors.Errorf("ImportSnapshot: record %d has invalid column family %d", records, cf)
+		}
+		key, err := readSnapshotBytes(br)
+		if err != nil {
+			return errors.Annotatef(err, "ImportSnapshot: record %d", records)
+		}
+		value, err := readSnapshotBytes(br)
+		if err != nil {
+			return errors.Annotatef(err, "ImportSnapshot: record %d", records)
+		}
+		wb.PutCF(d.cfh[cf], key, value)
+		records++
+		size += len(key) + len(value)
+		if size >= snapshotBatchSize {
+			if err := d.WriteBatch(wb); err != nil {
+				return err
+			}
+			wb.Clear()
+			size = 0
+		}
+	}
+	var count [8]byte
+	if _, err := io.ReadFull(br, count[:]); err != nil {
+		return errors.Annotatef(err, "ImportSnapshot: trailer")
+	}
+	if c := binary.BigEndian.Uint64(count[:]); c != records {
+		return errors.Errorf("ImportSnapshot: snapshot is corrupted, read %d records, expected %d", records, c)
+	}
+	if err := d.WriteBatch(wb); err != nil {
+		return err
+	}
+	glog.Info("ImportSnapshot: finished, ", records, " records, ", time.Since(start))
+	return nil
+}
@@ -0,0 +1,113 @@
+//go:build unittest
+
+package db
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+type snapshotTestData struct {
+	bestHeight uint32
+	bestHash   string
+	balances   map[string]*AddrBalance
+	txs        map[string][]string
+	txAddrs    map[string]*TxAddresses
+}
+
+func getSnapshotTestData(t *testing.T, d *RocksDB) *snapshotTestData {
+	var err error
+	r := &snapshotTestData{
+		balances: make(map[string]*AddrBalance),
+		txs:      make(map[string][]string),
+		txAddrs:  make(map[string]*TxAddresses),
+	}
+	if r.bestHeight, r.bestHash, err = d.GetBestBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []string{dbtestdata.Addr1, dbtestdata.Addr4, dbtestdata.Addr5, dbtestdata.Addr8, dbtestdata.AddrA} {
+		if r.balances[a], err = d.GetAddressBalance(a, AddressBalanceDetailUTXO); err != nil {
+			t.Fatal(err)
+		}
+		var txs []string
+		if err = d.GetTransactions(a, 0, 1000000, func(txid string, height uint32, indexes []int32) error {
+			txs = append(txs, txid)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		r.txs[a] = txs
+	}
+	for _, txid := range []string{dbtestdata.TxidB1T1, dbtestdata.TxidB2T1, dbtestdata.TxidB2T4} {
+		if r.txAddrs[txid], err = d.GetTxAddresses(txid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestRocksDB_Snapshot(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.StoreInternalState(d.is); err != nil {
+		t.Fatal(err)
+	}
+	want := getSnapshotTestData(t, d)
+
+	var snapshot bytes.Buffer
+	if err := d.ExportSnapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := NewRocksDB(tmp, 100000, -1, d.chainParser, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAndDestroyRocksDB(t, i)
+
+	// truncated snapshot is rejected, the small snapshot fits into one batch which is not written
+	if err := i.ImportSnapshot(bytes.NewReader(snapshot.Bytes()[:snapshot.Len()-1])); err == nil {
+		t.Fatal("ImportSnapshot() of truncated snapshot expected error")
+	}
+	if !i.isEmpty() {
+		t.Fatal("database not empty after failed import")
+	}
+
+	if err := i.ImportSnapshot(bytes.NewReader(snapshot.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	is, err := i.LoadInternalState("coin-unittest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.SetInternalState(is)
+	got := getSnapshotTestData(t, i)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imported index = %+v, want %+v", got, want)
+	}
+	if is.Coin != d.is.Coin {
+		t.Errorf("imported internal state coin %v, want %v", is.Coin, d.is.Coin)
+	}
+
+	// import to not empty database fails
+	if err := i.ImportSnapshot(bytes.NewReader(snapshot.Bytes())); err == nil {
+		t.Fatal("ImportSnapshot() to not empty database expected error")
+	}
+}
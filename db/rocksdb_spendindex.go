@@ -0,0 +1,244 @@
+package db
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// pruning of the spend index
+// with the extended index, the spending transaction, its input index and height are stored with the spent outputs in the txAddresses column
+// the pruning removes these spend data of the outputs spent deeper than spendIndexDepth blocks below the best block,
+// the outputs stay marked as spent, the pruned outputs are stored with the spendPrunedFlag in the length of the address descriptor
+// and without the spend data (see appendTxOutput)
+// the pruning runs outside of ConnectBlock, PruneSpendIndex is called in the background after the synchronization;
+// the blocks which are still in the blockTxs column are pruned by the inputs of their transactions, otherwise the whole
+// txAddresses column is pruned in chunks; each chunk is read and written under txAddressesMux, which orders the writes
+// of the pruning with the other writes of the db
+
+// spendIndexPruneChunk is the number of transactions pruned in one chunk of the full pruning
+const spendIndexPruneChunk = 10000
+
+// errSpendIndexPruneInterrupted is returned by PruneSpendIndex if the pruning was stopped or blocks were disconnected meanwhile
+var errSpendIndexPruneInterrupted = errors.New("Spend index pruning interrupted")
+
+// SetSpendIndexDepth sets the number of the most recent blocks for which the spend data are kept, 0 disables the pruning
+func (d *RocksDB) SetSpendIndexDepth(depth uint32) error {
+	if depth > 0 {
+		if !d.extendedIndex {
+			return errors.New("Spend index pruning requires the extended index")
+		}
+		if keep := d.chainParser.KeepBlockAddresses(); int(depth) >= keep {
+			return errors.Errorf("Spend index depth %d must be lower than the number of kept blocks %d", depth, keep)
+		}
+	}
+	d.spendIndexDepth = depth
+	return nil
+}
+
+// HasSpendIndexPruning returns true if the spend data of the extended index are pruned
+func (d *RocksDB) HasSpendIndexPruning() bool {
+	return d.spendIndexDepth > 0 && d.extendedIndex && d.chainParser.GetChainType() == bchain.ChainBitcoinType
+}
+
+// pruneSpends removes the spend data of the outputs of the transaction spent in the blocks from the height from to the height to,
+// returns true if the transaction was modified
+func pruneSpends(ta *TxAddresses, from, to uint32) bool {
+	pruned := false
+	for i := range ta.Outputs {
+		o := &ta.Outputs[i]
+		if o.Spent && o.SpentHeight >= from && o.SpentHeight <= to && o.SpentHeight != 0 {
+			o.SpentTxid = ""
+			o.SpentIndex = 0
+			o.SpentHeight = 0
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// spendIndexPruneStep runs fn under txAddressesMux, unless the blocks were disconnected since the start of the pruning
+func (d *RocksDB) spendIndexPruneStep(generation uint64, fn func() error) error {
+	d.txAddressesMux.Lock()
+	defer d.txAddressesMux.Unlock()
+	if d.spendIndexGeneration != generation {
+		return errSpendIndexPruneInterrupted
+	}
+	return fn()
+}
+
+// pruneSpendIndexBlocks prunes the spend data of the outputs spent in the blocks up to the height target using the blockTxs column,
+// returns false if some of the blocks are no longer in the blockTxs column and the full pruning must be run
+func (d *RocksDB) pruneSpendIndexBlocks(bestHeight, target uint32, generation uint64, stop chan os.Signal) (bool, error) {
+	from := d.is.SpendIndexPrunedHeight + 1
+	if keep := uint32(d.chainParser.KeepBlockAddresses()); bestHeight >= keep && from <= bestHeight-keep {
+		// the indexed blocks older than the kept blocks are not in the blockTxs column
+		bi, err := d.GetBlockInfo(bestHeight - keep)
+		if err != nil || bi != nil {
+			return false, err
+		}
+		// the index starts above the height from
+		from = bestHeight - keep + 1
+	}
+	varBuf := make([]byte, maxPackedBigintBytes)
+	for h := from; h <= target; h++ {
+		select {
+		case <-stop:
+			return true, errSpendIndexPruneInterrupted
+		default:
+		}
+		complete := true
+		err := d.spendIndexPruneStep(generation, func() error {
+			bt, err := d.getBlockTxs(h)
+			if err != nil {
+				return err
+			}
+			if len(bt) == 0 {
+				bi, err := d.GetBlockInfo(h)
+				if err != nil {
+					return err
+				}
+				// the block was connected in bulk or is older than the kept blocks
+				if bi != nil {
+					complete = false
+					return nil
+				}
+			}
+			wb := grocksdb.NewWriteBatch()
+			defer wb.Destroy()
+			pruned := make(map[string]*TxAddresses)
+			for i := range bt {
+				for _, o := range bt[i].inputs {
+					stxID := string(o.btxID)
+					ta, found := pruned[stxID]
+					if !found {
+						if ta, err = d.getTxAddresses(o.btxID); err != nil {
+							return err
+						}
+						if ta == nil || !pruneSpends(ta, h, h) {
+							continue
+						}
+						pruned[stxID] = ta
+					}
+				}
+			}
+			for stxID, ta := range pruned {
+				wb.PutCF(d.cfh[cfTxAddresses], []byte(stxID), d.packTxAddresses(ta, make([]byte, 0, 1024), varBuf))
+			}
+			if err := d.db.Write(d.wo, wb); err != nil {
+				return err
+			}
+			d.is.SpendIndexPrunedHeight = h
+			return nil
+		})
+		if err != nil || !complete {
+			return complete, err
+		}
+	}
+	return true, nil
+}
+
+// pruneSpendIndexFull removes the spend data up to the height target from all transactions in the txAddresses column,
+// the column is processed in chunks of spendIndexPruneChunk transactions
+func (d *RocksDB) pruneSpendIndexFull(target uint32, generation uint64, stop chan os.Signal) error {
+	start := time.Now()
+	glog.Info("PruneSpendIndex: pruning spend data of all transactions up to height ", target)
+	ro := grocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetFillCache(false)
+	varBuf := make([]byte, maxPackedBigintBytes)
+	var txs, prunedTxs uint64
+	var seekKey []byte
+	for done := false; !done; {
+		select {
+		case <-stop:
+			return errSpendIndexPruneInterrupted
+		default:
+		}
+		err := d.spendIndexPruneStep(generation, func() error {
+			it := d.db.NewIteratorCF(ro, d.cfh[cfTxAddresses])
+			defer it.Close()
+			if seekKey == nil {
+				it.SeekToFirst()
+			} else {
+				it.Seek(seekKey)
+				it.Next()
+			}
+			wb := grocksdb.NewWriteBatch()
+			defer wb.Destroy()
+			for count := 0; it.Valid() && count < spendIndexPruneChunk; it.Next() {
+				count++
+				txs++
+				seekKey = append(seekKey[:0], it.Key().Data()...)
+				ta, err := d.unpackTxAddresses(it.Value().Data())
+				if err != nil {
+					return err
+				}
+				if !pruneSpends(ta, 1, target) {
+					continue
+				}
+				wb.PutCF(d.cfh[cfTxAddresses], append([]byte(nil), seekKey...), d.packTxAddresses(ta, make([]byte, 0, 1024), varBuf))
+				prunedTxs++
+			}
+			if err := it.Err(); err != nil {
+				return err
+			}
+			done = !it.Valid()
+			if err := d.db.Write(d.wo, wb); err != nil {
+				return err
+			}
+			if done {
+				d.is.SpendIndexPrunedHeight = target
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// the space of the pruned data is reclaimed by the regular compactions of the column
+	glog.Info("PruneSpendIndex: pruned ", prunedTxs, " of ", txs, " transactions, finished in ", time.Since(start))
+	return nil
+}
+
+// PruneSpendIndex removes the spend data deeper than the spend index depth, it is run in the background after the synchronization,
+// only one pruning runs at a time; the pruning is stopped by the stop channel or when blocks are disconnected, in which case
+// it continues in the next run
+func (d *RocksDB) PruneSpendIndex(stop chan os.Signal) error {
+	if !d.HasSpendIndexPruning() {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt32(&d.spendIndexPruning, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&d.spendIndexPruning, 0)
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	if bestHeight <= d.spendIndexDepth {
+		return nil
+	}
+	target := bestHeight - d.spendIndexDepth
+	d.txAddressesMux.Lock()
+	generation := d.spendIndexGeneration
+	prunedHeight := d.is.SpendIndexPrunedHeight
+	d.txAddressesMux.Unlock()
+	if prunedHeight >= target {
+		return nil
+	}
+	complete, err := d.pruneSpendIndexBlocks(bestHeight, target, generation, stop)
+	if err == nil && !complete {
+		err = d.pruneSpendIndexFull(target, generation, stop)
+	}
+	if err == errSpendIndexPruneInterrupted {
+		glog.Info("PruneSpendIndex: interrupted, pruned up to height ", d.is.SpendIndexPrunedHeight)
+		return nil
+	}
+	return err
+}
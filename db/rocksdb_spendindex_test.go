@@ -0,0 +1,217 @@
+//go:build unittest
+
+package db
+
+import (
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+const txidB3T1 = "3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"
+
+// getSpendIndexTestBlock3 returns a block spending the so far unspent output 1 of TxidB2T1
+func getSpendIndexTestBlock3(parser bchain.BlockChainParser) *bchain.Block {
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height:        225495,
+			Hash:          "000000000000003c4a53c4a3d2d1cd9d9a5ae7fd3a37e0f86f6cbe1d3c8bd3b5",
+			Size:          1234,
+			Time:          1521595978,
+			Confirmations: 1,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: txidB3T1,
+				Vin: []bchain.Vin{
+					{
+						Txid: dbtestdata.TxidB2T1,
+						Vout: 1,
+					},
+				},
+				Vout: []bchain.Vout{
+					{
+						N: 0,
+						ScriptPubKey: bchain.ScriptPubKey{
+							Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr9, parser),
+						},
+						ValueSat: *big.NewInt(1000),
+					},
+				},
+				Blocktime:     1521595978,
+				Time:          1521595978,
+				Confirmations: 1,
+			},
+		},
+	}
+}
+
+func setupSpendIndexRocksDB(t *testing.T) *RocksDB {
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 3}),
+	}
+	d, err := NewRocksDB(tmp, 100000, -1, parser, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := d.LoadInternalState("coin-unittest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInternalState(is)
+	return d
+}
+
+type spendIndexTest struct {
+	txid        string
+	vout        int
+	spentTxid   string
+	spentHeight uint32
+}
+
+func checkSpendIndex(t *testing.T, d *RocksDB, tests []spendIndexTest) {
+	for _, tt := range tests {
+		ta, err := d.GetTxAddresses(tt.txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ta == nil {
+			t.Fatalf("tx %v not found", tt.txid)
+		}
+		o := &ta.Outputs[tt.vout]
+		if !o.Spent {
+			t.Errorf("%v:%d is not spent", tt.txid, tt.vout)
+		}
+		if o.SpentTxid != tt.spentTxid || o.SpentHeight != tt.spentHeight {
+			t.Errorf("%v:%d spent by %v at %d, want %v at %d", tt.txid, tt.vout, o.SpentTxid, o.SpentHeight, tt.spentTxid, tt.spentHeight)
+		}
+		if got := d.SpendingTxidForOutpoint(bchain.Outpoint{Txid: tt.txid, Vout: int32(tt.vout)}); got != tt.spentTxid {
+			t.Errorf("SpendingTxidForOutpoint(%v:%d) = %v, want %v", tt.txid, tt.vout, got, tt.spentTxid)
+		}
+	}
+}
+
+func getPackedTxAddresses(t *testing.T, d *RocksDB, txid string) []byte {
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxAddresses], btxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer val.Free()
+	return append([]byte(nil), val.Data()...)
+}
+
+var (
+	spendIndexNotPruned = []spendIndexTest{
+		{txid: dbtestdata.TxidB1T2, vout: 0, spentTxid: dbtestdata.TxidB2T1, spentHeight: 225494},
+		{txid: dbtestdata.TxidB1T2, vout: 1, spentTxid: dbtestdata.TxidB2T2, spentHeight: 225494},
+		{txid: dbtestdata.TxidB2T1, vout: 0, spentTxid: dbtestdata.TxidB2T2, spentHeight: 225494},
+	}
+	spendIndexPruned = []spendIndexTest{
+		{txid: dbtestdata.TxidB1T2, vout: 0},
+		{txid: dbtestdata.TxidB1T2, vout: 1},
+		{txid: dbtestdata.TxidB1T2, vout: 2},
+		{txid: dbtestdata.TxidB2T1, vout: 0},
+		// spent in the block within the depth
+		{txid: dbtestdata.TxidB2T1, vout: 1, spentTxid: txidB3T1, spentHeight: 225495},
+	}
+)
+
+func TestRocksDB_SpendIndexPruning(t *testing.T) {
+	d := setupSpendIndexRocksDB(t)
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.SetSpendIndexDepth(3); err == nil {
+		t.Fatal("SetSpendIndexDepth(3) expected error, depth must be lower than the number of kept blocks")
+	}
+	if err := d.SetSpendIndexDepth(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkSpendIndex(t, d, spendIndexNotPruned)
+
+	if err := d.ConnectBlock(getSpendIndexTestBlock3(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	// the blocks are not pruned by ConnectBlock
+	checkSpendIndex(t, d, spendIndexNotPruned)
+	packed := getPackedTxAddresses(t, d, dbtestdata.TxidB1T2)
+	if err := d.PruneSpendIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkSpendIndex(t, d, spendIndexPruned)
+	// the spend data of the pruned outputs are not stored
+	if pruned := getPackedTxAddresses(t, d, dbtestdata.TxidB1T2); len(packed)-len(pruned) < 2*d.chainParser.PackedTxidLen() {
+		t.Errorf("packed txAddresses length %d after pruning, before %d", len(pruned), len(packed))
+	}
+	if d.is.SpendIndexPrunedHeight != 225494 {
+		t.Errorf("SpendIndexPrunedHeight = %d, want 225494", d.is.SpendIndexPrunedHeight)
+	}
+
+	// disconnect of the pruned block lowers the pruned height, the reconnected block is pruned again
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225495); err != nil {
+		t.Fatal(err)
+	}
+	if d.is.SpendIndexPrunedHeight != 225493 {
+		t.Errorf("SpendIndexPrunedHeight after disconnect = %d, want 225493", d.is.SpendIndexPrunedHeight)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkSpendIndex(t, d, spendIndexNotPruned)
+	if err := d.ConnectBlock(getSpendIndexTestBlock3(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PruneSpendIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkSpendIndex(t, d, spendIndexPruned)
+}
+
+func TestRocksDB_PruneSpendIndex(t *testing.T) {
+	d := setupSpendIndexRocksDB(t)
+	defer closeAndDestroyRocksDB(t, d)
+
+	// the blocks are indexed without the pruning and without the blockTxs column, as in the case of the bulk import
+	for _, b := range []*bchain.Block{
+		dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser),
+		dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser),
+		getSpendIndexTestBlock3(d.chainParser),
+	} {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.db.DeleteCF(d.wo, d.cfh[cfBlockTxs], packUint(b.Height)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkSpendIndex(t, d, spendIndexNotPruned)
+
+	if err := d.SetSpendIndexDepth(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PruneSpendIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkSpendIndex(t, d, spendIndexPruned)
+	if d.is.SpendIndexPrunedHeight != 225494 {
+		t.Errorf("SpendIndexPrunedHeight = %d, want 225494", d.is.SpendIndexPrunedHeight)
+	}
+}
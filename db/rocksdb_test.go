@@ -5,6 +5,7 @@ package db
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -15,6 +16,7 @@ import (
 
 	vlq "github.com/bsm/go-vlq"
 	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
 	"github.com/martinboehm/btcutil/chaincfg"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/bchain/coins/btc"
@@ -713,7 +715,8 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 		t.Fatal(err)
 	}
 	taw := &TxAddresses{
-		Height: 225494,
+		Height:     225494,
+		BlockIndex: -1,
 		Inputs: []TxInput{
 			{
 				AddrDesc: addressToAddrDesc(dbtestdata.Addr3, d.chainParser),
@@ -802,6 +805,565 @@ func Test_BulkConnect_BitcoinType(t *testing.T) {
 	}
 }
 
+// getTestBitcoinTypeBlock3 returns a block with several transactions touching Addr7,
+// the spending transaction is first in the block but it is processed after the outputs
+func getTestBitcoinTypeBlock3(parser bchain.BlockChainParser) *bchain.Block {
+	output := func(n uint32, value int64, addr string) bchain.Vout {
+		return bchain.Vout{
+			N:        n,
+			ValueSat: *big.NewInt(value),
+			ScriptPubKey: bchain.ScriptPubKey{
+				Hex:       dbtestdata.AddressToPubKeyHex(addr, parser),
+				Addresses: []string{addr},
+			},
+		}
+	}
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225495,
+			Hash:   "0000000000000006d6f3c5e4a26da8fa1f6f2aeca0ea23e6b3d0bd7bbac5e1a0",
+			Time:   1521596000,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: "f0e1d2c3b4a5968778695a4b3c2d1e0ff0e1d2c3b4a5968778695a4b3c2d1e0f",
+				Vin:  []bchain.Vin{{Txid: dbtestdata.TxidB2T1, Vout: 1}},
+				Vout: []bchain.Vout{output(0, 917283900000, dbtestdata.Addr1)},
+			},
+			{
+				Txid: "b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8091a2b",
+				Vin:  []bchain.Vin{{Coinbase: "03c7700304"}},
+				Vout: []bchain.Vout{output(0, 12345, dbtestdata.Addr7)},
+			},
+			{
+				Txid: "10a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f",
+				Vin:  []bchain.Vin{{Coinbase: "03c7700305"}},
+				Vout: []bchain.Vout{output(0, 23456, dbtestdata.Addr7)},
+			},
+		},
+	}
+}
+
+func TestRocksDB_GetAddrDescReceivingTxs(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	for _, b := range []*bchain.Block{
+		dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser),
+		dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser),
+		getTestBitcoinTypeBlock3(d.chainParser),
+	} {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name            string
+		address         string
+		wantCount       int
+		wantFirstHeight uint32
+	}{
+		{
+			name:            "received in three transactions",
+			address:         dbtestdata.Addr7,
+			wantCount:       3,
+			wantFirstHeight: 225494,
+		},
+		{
+			name:            "received in two transactions",
+			address:         dbtestdata.Addr1,
+			wantCount:       2,
+			wantFirstHeight: 225493,
+		},
+		{
+			name:            "received once",
+			address:         dbtestdata.Addr9,
+			wantCount:       1,
+			wantFirstHeight: 225494,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrDesc, err := d.chainParser.GetAddrDescFromAddress(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, firstHeight, err := d.GetAddrDescReceivingTxs(addrDesc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount || firstHeight != tt.wantFirstHeight {
+				t.Errorf("GetAddrDescReceivingTxs() = %v, %v, want %v, %v", count, firstHeight, tt.wantCount, tt.wantFirstHeight)
+			}
+		})
+	}
+}
+
+func TestRocksDB_GetScriptOutputTransactions(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	// non-standard script OP_2 OP_ADD OP_3 OP_EQUAL, which cannot be converted to an address
+	script := "52935387"
+	p2pkh := "76a914010d39800f86122416e28f485029acf77507169288ac"
+	txid1 := "00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"
+	txid2 := "effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"
+	txid3 := "7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"
+	txid4 := "3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"
+	spendingTx := func(txid string, prevTxid string, prevVout uint32, scripts ...string) bchain.Tx {
+		tx := opReturnTestTx(txid, scripts...)
+		tx.Vin = []bchain.Vin{{Txid: prevTxid, Vout: prevVout}}
+		return tx
+	}
+	blocks := []*bchain.Block{
+		{
+			BlockHeader: bchain.BlockHeader{
+				Height: 225493,
+				Hash:   "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997",
+				Time:   1521515026,
+			},
+			Txs: []bchain.Tx{
+				opReturnTestTx(txid1, script, p2pkh),
+				opReturnTestTx(txid2, p2pkh, script, script),
+			},
+		},
+		{
+			BlockHeader: bchain.BlockHeader{
+				Height: 225494,
+				Hash:   "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
+				Time:   1521595678,
+			},
+			Txs: []bchain.Tx{
+				// spends the script output and creates a new one
+				spendingTx(txid3, txid1, 0, p2pkh, script),
+				// only spends the script output
+				spendingTx(txid4, txid2, 1, p2pkh),
+			},
+		},
+	}
+	for _, b := range blocks {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type scriptTx struct {
+		txid    string
+		height  uint32
+		indexes []int32
+	}
+	tests := []struct {
+		name   string
+		lower  uint32
+		higher uint32
+		want   []scriptTx
+	}{
+		{
+			name:   "all blocks",
+			lower:  0,
+			higher: ^uint32(0),
+			want: []scriptTx{
+				{txid3, 225494, []int32{1}},
+				{txid2, 225493, []int32{1, 2}},
+				{txid1, 225493, []int32{0}},
+			},
+		},
+		{
+			name:   "only the first block",
+			lower:  225493,
+			higher: 225493,
+			want: []scriptTx{
+				{txid2, 225493, []int32{1, 2}},
+				{txid1, 225493, []int32{0}},
+			},
+		},
+		{
+			name:   "only the second block",
+			lower:  225494,
+			higher: 225494,
+			want: []scriptTx{
+				{txid3, 225494, []int32{1}},
+			},
+		},
+	}
+	s, _ := hex.DecodeString(script)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []scriptTx
+			if err := d.GetScriptOutputTransactions(s, tt.lower, tt.higher, func(txid string, height uint32, indexes []int32) error {
+				got = append(got, scriptTx{txid, height, append([]int32(nil), indexes...)})
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetScriptOutputTransactions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRocksDB_AddressTransactionsOrder(t *testing.T) {
+	connect := func(d *RocksDB, bulk bool) {
+		blocks := []*bchain.Block{
+			dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser),
+			dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser),
+			getTestBitcoinTypeBlock3(d.chainParser),
+		}
+		if !bulk {
+			for _, b := range blocks {
+				if err := d.ConnectBlock(b); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return
+		}
+		bc, err := d.InitBulkConnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, b := range blocks {
+			if err := bc.ConnectBlock(b, i == len(blocks)-1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block3 := getTestBitcoinTypeBlock3(bitcoinTestnetParser())
+	// newest first, within the block by the reverse position of the transaction in the block
+	want := []txidIndex{
+		{block3.Txs[2].Txid, 0},
+		{block3.Txs[1].Txid, 0},
+		{block3.Txs[0].Txid, ^0},
+		{dbtestdata.TxidB2T1, 1},
+	}
+	for _, bulk := range []bool{false, true} {
+		d := setupRocksDB(t, &testBitcoinParser{
+			BitcoinParser: bitcoinTestnetParser(),
+		})
+		connect(d, bulk)
+		// the order must be the same in repeated calls
+		for i := 0; i < 3; i++ {
+			verifyGetTransactions(t, d, dbtestdata.Addr7, 0, 1000000, want, nil)
+		}
+		// and after the block is reindexed
+		if !bulk {
+			if err := d.DisconnectBlockRangeBitcoinType(225495, 225495); err != nil {
+				t.Fatal(err)
+			}
+			verifyGetTransactions(t, d, dbtestdata.Addr7, 0, 1000000, want[3:], nil)
+			if err := d.ConnectBlock(getTestBitcoinTypeBlock3(d.chainParser)); err != nil {
+				t.Fatal(err)
+			}
+			verifyGetTransactions(t, d, dbtestdata.Addr7, 0, 1000000, want, nil)
+		}
+		closeAndDestroyRocksDB(t, d)
+	}
+}
+
+func TestRocksDB_GetTotalTxCount(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	checkTotalTxCount := func(step string, want uint64) {
+		t.Helper()
+		got, err := d.GetTotalTxCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%v: GetTotalTxCount() = %v, want %v", step, got, want)
+		}
+	}
+
+	checkTotalTxCount("empty db", 0)
+	// block 225493 contains 2 transactions, block 225494 contains 4 transactions
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkTotalTxCount("connect block 1", 2)
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkTotalTxCount("connect block 2", 6)
+
+	// reorg removes the transactions of the disconnected block
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	checkTotalTxCount("disconnect block 2", 2)
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkTotalTxCount("reconnect block 2", 6)
+
+	// the count is restored from the db when the internal state is loaded
+	is, err := d.LoadInternalState("coin-unittest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInternalState(is)
+	checkTotalTxCount("load internal state", 6)
+}
+
+func TestRocksDB_TxBlockIndex(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewRocksDB(tmp, 100000, -1, &testBitcoinParser{BitcoinParser: bitcoinTestnetParser()}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAndDestroyRocksDB(t, d)
+	is, err := d.LoadInternalState("coin-unittest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInternalState(is)
+
+	for _, block := range []*bchain.Block{
+		dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser),
+		dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser),
+	} {
+		if err := d.ConnectBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		bt, err := d.getBlockTxs(block.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bt) != len(block.Txs) {
+			t.Fatalf("getBlockTxs(%d) returned %d txs, want %d", block.Height, len(bt), len(block.Txs))
+		}
+		// the first (coinbase) transaction has index 0, the others follow in the order of the block
+		for i := range bt {
+			txid, err := d.chainParser.UnpackTxid(bt[i].btxID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txid != block.Txs[i].Txid {
+				t.Errorf("getBlockTxs(%d)[%d] = %v, want %v", block.Height, i, txid, block.Txs[i].Txid)
+			}
+			ta, err := d.GetTxAddresses(txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ta.BlockIndex != int32(i) {
+				t.Errorf("GetTxAddresses(%v).BlockIndex = %d, want %d", txid, ta.BlockIndex, i)
+			}
+		}
+	}
+}
+
+func TestRocksDB_GetAddressBalanceAtHeight(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		height  uint32
+		want    string
+	}{
+		{
+			name:    "before first transaction",
+			address: dbtestdata.Addr5,
+			height:  225492,
+			want:    "0",
+		},
+		{
+			name:    "received in block 1, spent in block 2 excluded",
+			address: dbtestdata.Addr5,
+			height:  225493,
+			want:    "9876",
+		},
+		{
+			name:    "spent and received in block 2",
+			address: dbtestdata.Addr5,
+			height:  225494,
+			want:    "9000",
+		},
+		{
+			name:    "above best height",
+			address: dbtestdata.Addr5,
+			height:  1000000,
+			want:    "9000",
+		},
+		{
+			name:    "before spent in block 2",
+			address: dbtestdata.Addr3,
+			height:  225493,
+			want:    "1234567890123",
+		},
+		{
+			name:    "spent in block 2",
+			address: dbtestdata.Addr3,
+			height:  225494,
+			want:    "0",
+		},
+		{
+			name:    "received in block 2",
+			address: dbtestdata.AddrA,
+			height:  225493,
+			want:    "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetAddressBalanceAtHeight(tt.address, tt.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("GetAddressBalanceAtHeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRocksDB_GetBlockLocator(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	locator, err := d.GetBlockLocator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locator) != 0 {
+		t.Errorf("GetBlockLocator() = %v, want empty locator of empty db", locator)
+	}
+
+	hash := func(height uint32) string {
+		return fmt.Sprintf("%064x", height)
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for h := uint32(0); h < 1000; h++ {
+		if err := d.writeHeight(wb, h, &BlockInfo{Hash: hash(h), Height: h}, opInsert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.WriteBatch(wb); err != nil {
+		t.Fatal(err)
+	}
+	locator, err = d.GetBlockLocator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// starts at the tip, 10 consecutive blocks, then exponentially spaced, ends at genesis
+	heights := []uint32{999, 998, 997, 996, 995, 994, 993, 992, 991, 990, 988, 984, 976, 960, 928, 864, 736, 480, 0}
+	want := make([]string, len(heights))
+	for i, h := range heights {
+		want[i] = hash(h)
+	}
+	if !reflect.DeepEqual(locator, want) {
+		t.Errorf("GetBlockLocator() = %v, want %v", locator, want)
+	}
+}
+
+func TestRocksDB_ConnectBlock_Conflicts(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	conflictingTx := func(txid string) bchain.Tx {
+		return bchain.Tx{
+			Txid: txid,
+			Vin: []bchain.Vin{
+				{
+					Txid: dbtestdata.TxidB1T2,
+					Vout: 0,
+				},
+			},
+			Vout: []bchain.Vout{
+				{
+					N: 0,
+					ScriptPubKey: bchain.ScriptPubKey{
+						Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr9, d.chainParser),
+					},
+					ValueSat: *big.NewInt(1000),
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		txs     []bchain.Tx
+		wantErr string
+	}{
+		{
+			name: "double spend",
+			txs: []bchain.Tx{
+				conflictingTx("3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"),
+				conflictingTx("05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"),
+			},
+			wantErr: "double spend of " + dbtestdata.TxidB1T2 + ":0",
+		},
+		{
+			name: "duplicate transaction",
+			txs: []bchain.Tx{
+				conflictingTx("3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"),
+				conflictingTx("3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"),
+			},
+			wantErr: "duplicate transaction 3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Height: 225494,
+					Hash:   "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
+				},
+				Txs: tt.txs,
+			}
+			err := d.ConnectBlock(block)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ConnectBlock() error = %v, want error containing %q", err, tt.wantErr)
+			}
+			// the block must not be indexed
+			height, hash, err := d.GetBestBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if height != 225493 || hash != "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997" {
+				t.Errorf("GetBestBlock() = %d %v, want 225493", height, hash)
+			}
+			ta, err := d.GetTxAddresses(dbtestdata.TxidB1T2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ta.Outputs[0].Spent {
+				t.Errorf("%v:0 is spent, want unspent", dbtestdata.TxidB1T2)
+			}
+		})
+	}
+}
+
 func Test_packBigint_unpackBigint(t *testing.T) {
 	bigbig1, _ := big.NewInt(0).SetString("123456789123456789012345", 10)
 	bigbig2, _ := big.NewInt(0).SetString("12345678912345678901234512389012345123456789123456789012345123456789123456789012345", 10)
@@ -912,7 +1474,8 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 			name: "1",
 			hex:  "7b0216001443aac20a116e09ea4f7914be1c55e4c17aa600b70016001454633aa8bd2e552bd4e89c01e73c1b7905eb58460811207cb68a199872012d001443aac20a116e09ea4f7914be1c55e4c17aa600b70101",
 			data: &TxAddresses{
-				Height: 123,
+				Height:     123,
+				BlockIndex: -1,
 				Inputs: []TxInput{
 					{
 						AddrDesc: addressToAddrDesc("tb1qgw4vyzs3dcy75nmezjlpc40yc9a2vq9hghdyt2", parser),
@@ -937,7 +1500,8 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 			name: "2",
 			hex:  "e0390317a9149eb21980dc9d413d8eac27314938b9da920ee53e8705021918f2c017a91409f70b896169c37981d2b54b371df0d81a136a2c870501dd7e28c017a914e371782582a4addb541362c55565d2cdf56f6498870501a1e35ec0052fa9141d9ca71efa36d814424ea6ca1437e67287aebe348705012aadcac02ea91424fbc77cdc62702ade74dcf989c15e5d3f9240bc870501664894c02fa914afbfb74ee994c7d45f6698738bc4226d065266f7870501a1e35ec03276a914d2a37ce20ac9ec4f15dd05a7c6e8e9fbdb99850e88ac043b9943603376a9146b2044146a4438e6e5bfbc65f147afeb64d14fbb88ac05012a05f200",
 			data: &TxAddresses{
-				Height: 12345,
+				Height:     12345,
+				BlockIndex: -1,
 				Inputs: []TxInput{
 					{
 						AddrDesc: addressToAddrDesc("2N7iL7AvS4LViugwsdjTB13uN4T7XhV1bCP", parser),
@@ -984,7 +1548,8 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 			name: "empty address",
 			hex:  "baef9a1501000204d2020002162e010162",
 			data: &TxAddresses{
-				Height: 123456789,
+				Height:     123456789,
+				BlockIndex: -1,
 				Inputs: []TxInput{
 					{
 						AddrDesc: []byte(nil),
@@ -1009,35 +1574,42 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 			name: "empty",
 			hex:  "000000",
 			data: &TxAddresses{
-				Inputs:  []TxInput{},
-				Outputs: []TxOutput{},
+				Inputs:     []TxInput{},
+				Outputs:    []TxOutput{},
+				BlockIndex: -1,
 			},
 			rocksDB: &RocksDB{chainParser: parser, extendedIndex: false},
 		},
 		{
 			name: "extendedIndex 1",
-			hex:  "e0398241032ea9149eb21980dc9d413d8eac27314938b9da920ee53e8705021918f2c0c50c7ce2f5670fd52de738288299bd854a85ef1bb304f62f35ced1bd49a8a810002ea91409f70b896169c37981d2b54b371df0d81a136a2c870501dd7e28c0e96672c7fcc8da131427fcea7e841028614813496a56c11e8a6185c16861c495012ea914e371782582a4addb541362c55565d2cdf56f6498870501a1e35ec0ed308c72f9804dfeefdbb483ef8fd1e638180ad81d6b33f4b58d36d19162fa6d8106052fa9141d9ca71efa36d814424ea6ca1437e67287aebe348705012aadcac000b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa38400081ce8685592ea91424fbc77cdc62702ade74dcf989c15e5d3f9240bc870501664894c02fa914afbfb74ee994c7d45f6698738bc4226d065266f7870501a1e35ec0effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75ef17a1f4233276a914d2a37ce20ac9ec4f15dd05a7c6e8e9fbdb99850e88ac043b9943603376a9146b2044146a4438e6e5bfbc65f147afeb64d14fbb88ac05012a05f2007c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25a9956d8396f32a",
+			hex:  "e0398241032ea9149eb21980dc9d413d8eac27314938b9da920ee53e8705021918f2c0c50c7ce2f5670fd52de738288299bd854a85ef1bb304f62f35ced1bd49a8a810002ea91409f70b896169c37981d2b54b371df0d81a136a2c870501dd7e28c0e96672c7fcc8da131427fcea7e841028614813496a56c11e8a6185c16861c495012ea914e371782582a4addb541362c55565d2cdf56f6498870501a1e35ec0ed308c72f9804dfeefdbb483ef8fd1e638180ad81d6b33f4b58d36d19162fa6d8106052fa9141d9ca71efa36d814424ea6ca1437e67287aebe348705012aadcac000b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa38400081ce8685592ea91424fbc77cdc62702ade74dcf989c15e5d3f9240bc870501664894c02fa914afbfb74ee994c7d45f6698738bc4226d065266f7870501a1e35ec0effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75ef17a1f4233276a914d2a37ce20ac9ec4f15dd05a7c6e8e9fbdb99850e88ac043b9943603376a9146b2044146a4438e6e5bfbc65f147afeb64d14fbb88ac05012a05f2007c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25a9956d8396f32a9ec22000020107",
 			data: &TxAddresses{
-				Height: 12345,
-				VSize:  321,
+				Height:      12345,
+				VSize:       321,
+				LockTime:    500000,
+				HasLockTime: true,
+				BlockIndex:  7,
 				Inputs: []TxInput{
 					{
 						AddrDesc: addressToAddrDesc("2N7iL7AvS4LViugwsdjTB13uN4T7XhV1bCP", parser),
 						ValueSat: *big.NewInt(9011000000),
 						Txid:     "c50c7ce2f5670fd52de738288299bd854a85ef1bb304f62f35ced1bd49a8a810",
 						Vout:     0,
+						Sequence: 4294967295,
 					},
 					{
 						AddrDesc: addressToAddrDesc("2Mt9v216YiNBAzobeNEzd4FQweHrGyuRHze", parser),
 						ValueSat: *big.NewInt(8011000000),
 						Txid:     "e96672c7fcc8da131427fcea7e841028614813496a56c11e8a6185c16861c495",
 						Vout:     1,
+						Sequence: 4294967293,
 					},
 					{
 						AddrDesc: addressToAddrDesc("2NDyqJpHvHnqNtL1F9xAeCWMAW8WLJmEMyD", parser),
 						ValueSat: *big.NewInt(7011000000),
 						Txid:     "ed308c72f9804dfeefdbb483ef8fd1e638180ad81d6b33f4b58d36d19162fa6d",
 						Vout:     134,
+						Sequence: 4294967294,
 					},
 				},
 				Outputs: []TxOutput{
@@ -1079,10 +1651,42 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 		},
 		{
 			name: "extendedIndex empty address",
+			hex:  "baef9a152d01010204d2020002162e010162fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db03e039008fffffff7f00",
+			data: &TxAddresses{
+				Height:      123456789,
+				VSize:       45,
+				HasLockTime: true,
+				Inputs: []TxInput{
+					{
+						AddrDesc: []byte(nil),
+						ValueSat: *big.NewInt(1234),
+					},
+				},
+				Outputs: []TxOutput{
+					{
+						AddrDesc: []byte(nil),
+						ValueSat: *big.NewInt(5678),
+					},
+					{
+						AddrDesc:    []byte(nil),
+						ValueSat:    *big.NewInt(98),
+						Spent:       true,
+						SpentTxid:   dbtestdata.TxidB2T4,
+						SpentIndex:  3,
+						SpentHeight: 12345,
+					},
+				},
+			},
+			rocksDB: &RocksDB{chainParser: parser, extendedIndex: true},
+		},
+		{
+			// records stored before the lock time and sequences were added, they stay without them when rewritten
+			name: "extendedIndex old format without lock time",
 			hex:  "baef9a152d01010204d2020002162e010162fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db03e039",
 			data: &TxAddresses{
-				Height: 123456789,
-				VSize:  45,
+				Height:     123456789,
+				VSize:      45,
+				BlockIndex: -1,
 				Inputs: []TxInput{
 					{
 						AddrDesc: []byte(nil),
@@ -1126,6 +1730,16 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 			}
 		})
 	}
+	// records stored before the block index was added
+	d := &RocksDB{chainParser: parser, extendedIndex: true}
+	b, _ := hex.DecodeString("baef9a152d01010204d2020002162e010162fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db03e039008fffffff7f")
+	ta, err := d.unpackTxAddresses(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ta.Height != 123456789 || !ta.HasLockTime || ta.BlockIndex != -1 || len(ta.Outputs) != 2 {
+		t.Errorf("unpackTxAddresses() format without block index = %+v", ta)
+	}
 }
 
 func Test_packAddrBalance_unpackAddrBalance(t *testing.T) {
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"bytes"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// replace-by-fee replacements of transactions
+// key is packed txid of the replacement, value is the concatenation of the packed txids of the replaced transactions
+// the records are kept after the replacement is confirmed, so that the confirmed transaction reports the transactions it replaced
+
+// StoreTxReplacement stores that the transaction replacedTxid was replaced in the mempool by the transaction replacementTxid
+func (d *RocksDB) StoreTxReplacement(replacedTxid, replacementTxid string) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil
+	}
+	key, err := d.chainParser.PackTxid(replacementTxid)
+	if err != nil {
+		return err
+	}
+	btxID, err := d.chainParser.PackTxid(replacedTxid)
+	if err != nil {
+		return err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxReplaces], key)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	buf := val.Data()
+	pl := d.chainParser.PackedTxidLen()
+	for i := 0; i+pl <= len(buf); i += pl {
+		if bytes.Equal(buf[i:i+pl], btxID) {
+			return nil
+		}
+	}
+	buf = append(append(make([]byte, 0, len(buf)+pl), buf...), btxID...)
+	return d.db.PutCF(d.wo, d.cfh[cfTxReplaces], key, buf)
+}
+
+// StoreMempoolTxReplacement stores the replacement of a mempool transaction, it is used as bchain.OnTxReplacedFunc
+func (d *RocksDB) StoreMempoolTxReplacement(replacedTxid, replacementTxid string) {
+	if err := d.StoreTxReplacement(replacedTxid, replacementTxid); err != nil {
+		glog.Error("StoreTxReplacement ", replacedTxid, " by ", replacementTxid, ": ", err)
+	}
+}
+
+// GetTxReplaces returns the txids of the transactions replaced by the transaction, nil if it did not replace any transaction
+func (d *RocksDB) GetTxReplaces(txid string) ([]string, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxReplaces], key)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	pl := d.chainParser.PackedTxidLen()
+	var r []string
+	for i := 0; i+pl <= len(buf); i += pl {
+		replaced, err := d.chainParser.UnpackTxid(buf[i : i+pl])
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, replaced)
+	}
+	return r, nil
+}
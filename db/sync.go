@@ -17,6 +17,7 @@ type SyncWorker struct {
 	db                     *RocksDB
 	chain                  bchain.BlockChain
 	syncWorkers, syncChunk int
+	prefetchBlocks         int
 	dryRun                 bool
 	startHeight            uint32
 	startHash              string
@@ -26,20 +27,22 @@ type SyncWorker struct {
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
-func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
+// prefetchBlocks is the number of blocks fetched in parallel ahead of the connected block in standard sync, values below 2 fetch blocks one by one
+func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk, prefetchBlocks int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
 	if minStartHeight < 0 {
 		minStartHeight = 0
 	}
 	return &SyncWorker{
-		db:           db,
-		chain:        chain,
-		syncWorkers:  syncWorkers,
-		syncChunk:    syncChunk,
-		dryRun:       dryRun,
-		startHeight:  uint32(minStartHeight),
-		chanOsSignal: chanOsSignal,
-		metrics:      metrics,
-		is:           is,
+		db:             db,
+		chain:          chain,
+		syncWorkers:    syncWorkers,
+		syncChunk:      syncChunk,
+		prefetchBlocks: prefetchBlocks,
+		dryRun:         dryRun,
+		startHeight:    uint32(minStartHeight),
+		chanOsSignal:   chanOsSignal,
+		metrics:        metrics,
+		is:             is,
 	}, nil
 }
 
@@ -70,6 +73,7 @@ func (w *SyncWorker) updateBackendInfo() {
 		Timeoffset:       ci.Timeoffset,
 		Version:          ci.Version,
 		Warnings:         ci.Warnings,
+		MinRelayTxFee:    ci.MinRelayTxFee,
 		ConsensusVersion: ci.ConsensusVersion,
 		Consensus:        ci.Consensus,
 	})
@@ -213,7 +217,11 @@ func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync
 	done := make(chan struct{})
 	defer close(done)
 
-	go w.getBlockChain(bch, done)
+	if w.prefetchBlocks > 1 {
+		go w.getBlockChainParallel(bch, done)
+	} else {
+		go w.getBlockChain(bch, done)
+	}
 
 	var lastRes, empty blockResult
 
@@ -329,6 +337,7 @@ func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
 	GetBlockLoop:
 		for hh := range hch {
 			for {
+				start := time.Now()
 				block, err = w.chain.GetBlock(hh.hash, hh.height)
 				if err != nil {
 					// signal came while looping in the error loop
@@ -340,6 +349,7 @@ func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
 					w.metrics.IndexResyncErrors.With(common.Labels{"error": "failure"}).Inc()
 					time.Sleep(time.Millisecond * 500)
 				} else {
+					w.db.observeIndexPhase("fetch", start)
 					break
 				}
 			}
@@ -426,6 +436,7 @@ func (w *SyncWorker) getBlockChain(out chan blockResult, done chan struct{}) {
 			return
 		default:
 		}
+		start := time.Now()
 		block, err := w.chain.GetBlock(hash, height)
 		if err != nil {
 			if err == bchain.ErrBlockNotFound {
@@ -434,6 +445,7 @@ func (w *SyncWorker) getBlockChain(out chan blockResult, done chan struct{}) {
 			out <- blockResult{err: err}
 			return
 		}
+		w.db.observeIndexPhase("fetch", start)
 		if block.Prev != "" && prevHash != "" && prevHash != block.Prev {
 			glog.Infof("sync: fork detected at height %d %s, local prevHash %s, remote prevHash %s", height, block.Hash, prevHash, block.Prev)
 			out <- blockResult{err: errFork}
@@ -446,6 +458,99 @@ func (w *SyncWorker) getBlockChain(out chan blockResult, done chan struct{}) {
 	}
 }
 
+// getBlockChainParallel gets blocks from the backend using prefetchBlocks parallel workers
+// the blocks are sent to out strictly in the order of their heights
+func (w *SyncWorker) getBlockChainParallel(out chan blockResult, done chan struct{}) {
+	defer close(out)
+
+	type fetchResult struct {
+		height uint32
+		block  *bchain.Block
+		err    error
+	}
+	heights := make(chan uint32)
+	results := make(chan fetchResult, w.prefetchBlocks)
+	terminating := make(chan struct{})
+	defer close(terminating)
+	defer close(heights)
+	for i := 0; i < w.prefetchBlocks; i++ {
+		go func() {
+			for height := range heights {
+				start := time.Now()
+				var block *bchain.Block
+				hash, err := w.chain.GetBlockHash(height)
+				if err == nil {
+					block, err = w.chain.GetBlock(hash, height)
+				}
+				if err == nil {
+					w.db.observeIndexPhase("fetch", start)
+				}
+				select {
+				case results <- fetchResult{height, block, err}:
+				case <-terminating:
+					return
+				}
+			}
+		}()
+	}
+
+	next := w.startHeight
+	dispatch := w.startHeight
+	// stop dispatching new heights after the end of the chain was reached
+	endReached := false
+	pending := make(map[uint32]fetchResult)
+	prevHash := ""
+	for {
+		var dispatchHeights chan uint32
+		if !endReached && dispatch-next < uint32(w.prefetchBlocks) {
+			dispatchHeights = heights
+		}
+		select {
+		case <-done:
+			return
+		case dispatchHeights <- dispatch:
+			dispatch++
+		case r := <-results:
+			if r.err == bchain.ErrBlockNotFound {
+				endReached = true
+			}
+			pending[r.height] = r
+			// send the fetched blocks in the order of heights
+			for {
+				r, found := pending[next]
+				if !found {
+					break
+				}
+				delete(pending, next)
+				if r.err != nil {
+					if r.err != bchain.ErrBlockNotFound {
+						out <- blockResult{err: r.err}
+					}
+					return
+				}
+				// the first block is fetched by height, check that it is the block the sync was started from
+				if r.height == w.startHeight && w.startHash != "" && r.block.Hash != w.startHash {
+					glog.Infof("sync: fork detected at height %d %s, start hash %s", r.height, r.block.Hash, w.startHash)
+					out <- blockResult{err: errFork}
+					return
+				}
+				if r.block.Prev != "" && prevHash != "" && prevHash != r.block.Prev {
+					glog.Infof("sync: fork detected at height %d %s, local prevHash %s, remote prevHash %s", r.height, r.block.Hash, prevHash, r.block.Prev)
+					out <- blockResult{err: errFork}
+					return
+				}
+				prevHash = r.block.Hash
+				select {
+				case out <- blockResult{block: r.block}:
+				case <-done:
+					return
+				}
+				next++
+			}
+		}
+	}
+}
+
 // DisconnectBlocks removes all data belonging to blocks in range lower-higher,
 func (w *SyncWorker) DisconnectBlocks(lower uint32, higher uint32, hashes []string) error {
 	glog.Infof("sync: disconnecting blocks %d-%d", lower, higher)
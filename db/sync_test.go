@@ -0,0 +1,266 @@
+//go:build unittest
+
+package db
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestSyncWorker_IndexBlockMetrics(t *testing.T) {
+	const coin = "SyncTestCoin"
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	metrics, err := common.GetMetrics(coin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.metrics = metrics
+	chain, err := dbtestdata.NewFakeBlockChain(d.chainParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewSyncWorker(d, chain, 1, 0, 0, 225493, false, nil, metrics, d.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.ResyncIndex(nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	durations := make(map[string]uint64)
+	blocks := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			var phase, c string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "phase":
+					phase = l.GetValue()
+				case "coin":
+					c = l.GetValue()
+				}
+			}
+			if c != coin {
+				continue
+			}
+			switch mf.GetName() {
+			case "blockbook_index_block_duration":
+				durations[phase] = m.GetHistogram().GetSampleCount()
+			case "blockbook_index_blocks":
+				blocks[phase] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	// the fake chain has two blocks
+	for _, phase := range []string{"fetch", "index", "store"} {
+		if durations[phase] != 2 {
+			t.Errorf("blockbook_index_block_duration{phase=%q} samples = %v, want 2", phase, durations[phase])
+		}
+		if blocks[phase] != 2 {
+			t.Errorf("blockbook_index_blocks{phase=%q} = %v, want 2", phase, blocks[phase])
+		}
+	}
+}
+
+// delayedBlockChain is a backend with a chain of simple blocks, each call to GetBlock takes the given delay
+type delayedBlockChain struct {
+	bchain.BlockChain
+	blocks []*bchain.Block
+	delay  time.Duration
+}
+
+func newDelayedBlockChain(t *testing.T, parser bchain.BlockChainParser, first uint32, count int, delay time.Duration) *delayedBlockChain {
+	fake, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &delayedBlockChain{BlockChain: fake, delay: delay}
+	for i := 0; i < count; i++ {
+		height := first + uint32(i)
+		b := &bchain.Block{
+			BlockHeader: bchain.BlockHeader{
+				Height: height,
+				Hash:   fmt.Sprintf("%064x", height),
+				Time:   1521515026 + int64(i)*600,
+			},
+			Txs: []bchain.Tx{
+				{
+					Txid: fmt.Sprintf("%064x", 1<<32+int64(height)),
+					Vin:  []bchain.Vin{{Coinbase: "03"}},
+					Vout: []bchain.Vout{
+						{
+							N:        0,
+							ValueSat: *big.NewInt(5000000000),
+							ScriptPubKey: bchain.ScriptPubKey{
+								Hex:       dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, parser),
+								Addresses: []string{dbtestdata.Addr1},
+							},
+						},
+					},
+				},
+			},
+		}
+		if i > 0 {
+			b.Prev = c.blocks[i-1].Hash
+			c.blocks[i-1].Next = b.Hash
+		}
+		c.blocks = append(c.blocks, b)
+	}
+	return c
+}
+
+func (c *delayedBlockChain) getBlock(height uint32) *bchain.Block {
+	if height < c.blocks[0].Height || int(height-c.blocks[0].Height) >= len(c.blocks) {
+		return nil
+	}
+	return c.blocks[height-c.blocks[0].Height]
+}
+
+func (c *delayedBlockChain) GetBestBlockHash() (string, error) {
+	return c.blocks[len(c.blocks)-1].Hash, nil
+}
+
+func (c *delayedBlockChain) GetBestBlockHeight() (uint32, error) {
+	return c.blocks[len(c.blocks)-1].Height, nil
+}
+
+func (c *delayedBlockChain) GetBlockHash(height uint32) (string, error) {
+	b := c.getBlock(height)
+	if b == nil {
+		return "", bchain.ErrBlockNotFound
+	}
+	return b.Hash, nil
+}
+
+func (c *delayedBlockChain) GetBlock(hash string, height uint32) (*bchain.Block, error) {
+	b := c.getBlock(height)
+	if b == nil || hash != "" && hash != b.Hash {
+		return nil, bchain.ErrBlockNotFound
+	}
+	time.Sleep(c.delay)
+	return b, nil
+}
+
+func TestSyncWorker_PrefetchBlocks(t *testing.T) {
+	const (
+		first = 1000
+		count = 12
+		delay = 50 * time.Millisecond
+	)
+	resync := func(prefetchBlocks int) ([]uint32, time.Duration) {
+		d := setupRocksDB(t, &testBitcoinParser{
+			BitcoinParser: bitcoinTestnetParser(),
+		})
+		defer closeAndDestroyRocksDB(t, d)
+		chain := newDelayedBlockChain(t, d.chainParser, first, count, delay)
+		metrics := &common.Metrics{
+			IndexResyncDuration: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_resync_duration"}),
+			IndexDBSize:         prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_db_size"}),
+			BackendBestHeight:   prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_backend_height"}),
+			BlockbookBestHeight: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_blockbook_height"}),
+		}
+		w, err := NewSyncWorker(d, chain, 1, 0, prefetchBlocks, first, false, nil, metrics, d.is)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var connected []uint32
+		start := time.Now()
+		err = w.ResyncIndex(func(hash string, height uint32) {
+			connected = append(connected, height)
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elapsed := time.Since(start)
+		for _, b := range chain.blocks {
+			hash, err := d.GetBlockHash(b.Height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hash != b.Hash {
+				t.Errorf("GetBlockHash(%d) = %v, want %v", b.Height, hash, b.Hash)
+			}
+		}
+		return connected, elapsed
+	}
+
+	want := make([]uint32, count)
+	for i := range want {
+		want[i] = first + uint32(i)
+	}
+	serial, serialTime := resync(0)
+	if !reflect.DeepEqual(serial, want) {
+		t.Errorf("serial sync connected %v, want %v", serial, want)
+	}
+	parallel, parallelTime := resync(4)
+	if !reflect.DeepEqual(parallel, want) {
+		t.Errorf("parallel sync connected %v, want %v", parallel, want)
+	}
+	if parallelTime >= serialTime/2 {
+		t.Errorf("parallel sync took %v, serial %v, expected at least twice faster", parallelTime, serialTime)
+	}
+}
+
+func TestSyncWorker_PrefetchBlocksFork(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	chain := newDelayedBlockChain(t, d.chainParser, 1000, 4, 0)
+	w := &SyncWorker{db: d, chain: chain, prefetchBlocks: 2, startHeight: 1000}
+	tests := []struct {
+		name      string
+		startHash string
+		wantErr   error
+	}{
+		{
+			name:      "start hash matches",
+			startHash: chain.blocks[0].Hash,
+		},
+		{
+			name:      "start hash replaced by fork",
+			startHash: fmt.Sprintf("%064x", 1),
+			wantErr:   errFork,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w.startHash = tt.startHash
+			out := make(chan blockResult)
+			done := make(chan struct{})
+			defer close(done)
+			go w.getBlockChainParallel(out, done)
+			var blocks int
+			var err error
+			for r := range out {
+				if r.err != nil {
+					err = r.err
+					break
+				}
+				blocks++
+			}
+			if err != tt.wantErr {
+				t.Fatalf("getBlockChainParallel error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && blocks != len(chain.blocks) {
+				t.Errorf("getBlockChainParallel returned %d blocks, want %d", blocks, len(chain.blocks))
+			}
+		})
+	}
+}
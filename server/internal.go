@@ -66,6 +66,7 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 	serveMux.HandleFunc(path+"metrics", promhttp.Handler().ServeHTTP)
 	serveMux.HandleFunc(path, s.index)
 	serveMux.HandleFunc(path+"admin", s.htmlTemplateHandler(s.adminIndex))
+	serveMux.HandleFunc(path+"admin/backend-chain-info", s.backendChainInfo)
 	if s.chainParser.GetChainType() == bchain.ChainEthereumType {
 		serveMux.HandleFunc(path+"admin/internal-data-errors", s.htmlTemplateHandler(s.internalDataErrors))
 	}
@@ -163,6 +164,18 @@ func (s *InternalServer) parseTemplates() []*template.Template {
 	return t
 }
 
+// backendChainInfo returns the chain info exactly as returned by the backend, for diagnostics
+func (s *InternalServer) backendChainInfo(w http.ResponseWriter, r *http.Request) {
+	ci, err := s.chain.GetBackendChainInfoRaw()
+	if err != nil {
+		glog.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(ci)
+}
+
 func (s *InternalServer) adminIndex(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
 	data := s.newTemplateData(r)
 	return adminIndexTpl, data, nil
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -32,6 +34,9 @@ const blocksOnPage = 50
 const mempoolTxsOnPage = 50
 const txsInAPI = 1000
 
+// maxPsbtRequestSize is the maximal size of the base64 encoded PSBT in the request body
+const maxPsbtRequestSize = 4 << 20
+
 const secondaryCoinCookieName = "secondary_coin"
 
 const (
@@ -191,17 +196,41 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiDefault))
 	// v2 format
 	serveMux.HandleFunc(path+"api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-locator/", s.jsonHandler(s.apiBlockLocator, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/spending-graph/", s.jsonHandler(s.apiSpendingGraph, apiV2))
 	serveMux.HandleFunc(path+"api/v2/address/", s.jsonHandler(s.apiAddress, apiV2))
+	serveMux.HandleFunc(path+"api/v2/addresses/balances", s.jsonHandler(s.apiAddressesBalances, apiV2))
 	serveMux.HandleFunc(path+"api/v2/xpub/", s.jsonHandler(s.apiXpub, apiV2))
 	serveMux.HandleFunc(path+"api/v2/utxo/", s.jsonHandler(s.apiUtxo, apiV2))
+	serveMux.HandleFunc(path+"api/v2/utxo-by-xpub/", s.jsonHandler(s.apiUtxoByXpub, apiV2))
+	serveMux.HandleFunc(path+"api/v2/utxo-by-scripthash/", s.jsonHandler(s.apiUtxoByScriptHash, apiV2))
+	serveMux.HandleFunc(path+"api/v2/confirmation-policy/", s.jsonHandler(s.apiConfirmationPolicy, apiV2))
+	serveMux.HandleFunc(path+"api/v2/confirmation-probability/", s.jsonHandler(s.apiConfirmationProbability, apiV2))
+	serveMux.HandleFunc(path+"api/v2/next-block-fee", s.jsonHandler(s.apiNextBlockFee, apiV2))
 	serveMux.HandleFunc(path+"api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
 	serveMux.HandleFunc(path+"api/v2/rawblock/", s.jsonHandler(s.apiBlockRaw, apiDefault))
+	serveMux.HandleFunc(path+"api/v2/block-filter/", s.jsonHandler(s.apiBlockFilter, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-top-txs/", s.jsonHandler(s.apiBlockTopTxs, apiV2))
 	serveMux.HandleFunc(path+"api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/psbt/enrich", s.jsonHandler(s.apiPsbtEnrich, apiV2))
 	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
 	serveMux.HandleFunc(path+"api/v2/feestats/", s.jsonHandler(s.apiFeeStats, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempoolinfo", s.jsonHandler(s.apiMempoolInfo, apiV2))
+	serveMux.HandleFunc(path+"api/v2/sync-status", s.jsonHandler(s.apiSyncStatus, apiV2))
+	serveMux.HandleFunc(path+"api/v2/opreturn/", s.jsonHandler(s.apiOpReturn, apiV2))
+	serveMux.HandleFunc(path+"api/v2/script-txs/", s.jsonHandler(s.apiScriptTxs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/outputs-by-value/", s.jsonHandler(s.apiOutputsByValue, apiV2))
+	serveMux.HandleFunc(path+"api/v2/miner-blocks/", s.jsonHandler(s.apiMinerBlocks, apiV2))
+	serveMux.HandleFunc(path+"api/v2/new-addresses", s.jsonHandler(s.apiNewAddresses, apiV2))
+	serveMux.HandleFunc(path+"api/v2/difficulty-history", s.jsonHandler(s.apiDifficultyHistory, apiV2))
+	serveMux.HandleFunc(path+"api/v2/genesis", s.jsonHandler(s.apiGenesis, apiV2))
+	serveMux.HandleFunc(path+"api/v2/validate/", s.jsonHandler(s.apiValidateAddress, apiV2))
 	serveMux.HandleFunc(path+"api/v2/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiDefault))
+	serveMux.HandleFunc(path+"api/v2/balance-deltas/", s.jsonHandler(s.apiBalanceDeltas, apiV2))
+	serveMux.HandleFunc(path+"api/v2/token-transfers/", s.jsonHandler(s.apiTokenTransfers, apiV2))
+	serveMux.HandleFunc(path+"api/v2/token-transfers-export/", s.apiTokenTransfersExport)
 	serveMux.HandleFunc(path+"api/v2/tickers/", s.jsonHandler(s.apiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/multi-tickers/", s.jsonHandler(s.apiMultiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers-list/", s.jsonHandler(s.apiAvailableVsCurrencies, apiV2))
@@ -845,7 +874,7 @@ func (s *PublicServer) explorerSpendingTx(w http.ResponseWriter, r *http.Request
 	return errorTpl, nil, err
 }
 
-func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api.AccountDetails, maxPageSize int) (int, int, api.AccountDetails, *api.AddressFilter, string, int) {
+func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api.AccountDetails, maxPageSize int) (int, int, api.AccountDetails, *api.AddressFilter, string) {
 	var voutFilter = api.AddressFilterVoutOff
 	page, ec := strconv.Atoi(r.URL.Query().Get("page"))
 	if ec != nil {
@@ -899,18 +928,83 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 	case "nonzero":
 		tokensToReturn = api.TokensToReturnNonzeroBalance
 	}
-	gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
-	if ec != nil {
-		gap = 0
-	}
 	contract := r.URL.Query().Get("contract")
+	var minValue *big.Int
+	if m, ok := new(big.Int).SetString(r.URL.Query().Get("minValue"), 10); ok && m.Sign() > 0 {
+		minValue = m
+	}
 	return page, pageSize, accountDetails, &api.AddressFilter{
 		Vout:           voutFilter,
 		TokensToReturn: tokensToReturn,
 		FromHeight:     uint32(from),
 		ToHeight:       uint32(to),
 		Contract:       contract,
-	}, filterParam, gap
+		MinValue:       minValue,
+	}, filterParam
+}
+
+// getGapQueryParams returns the gap limits of the receive and change addresses of xpub, 0 means default
+func getGapQueryParams(r *http.Request) (int, int) {
+	gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
+	if ec != nil {
+		gap = 0
+	}
+	changeGap, ec := strconv.Atoi(r.URL.Query().Get("changeGap"))
+	if ec != nil {
+		changeGap = 0
+	}
+	return gap, changeGap
+}
+
+// selectFields returns the JSON of data with only the top-level fields listed in the comma separated parameter fields,
+// in the order of the data; unknown fields are ignored, the data is returned unchanged if no fields are requested
+func selectFields(data interface{}, fields string) (interface{}, error) {
+	if fields == "" {
+		return data, nil
+	}
+	selected := make(map[string]struct{})
+	for _, f := range strings.Split(fields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			selected[f] = struct{}{}
+		}
+	}
+	if len(selected) == 0 {
+		return data, nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		// not an object, nothing to select from
+		return data, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err = d.Decode(&value); err != nil {
+			return nil, err
+		}
+		key := t.(string)
+		if _, found := selected[key]; !found {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return json.RawMessage(buf.Bytes()), nil
 }
 
 func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
@@ -923,7 +1017,7 @@ func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (
 		return errorTpl, nil, api.NewAPIError("Missing address", true)
 	}
 	s.metrics.ExplorerViews.With(common.Labels{"action": "address"}).Inc()
-	page, _, _, filter, filterParam, _ := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	page, _, _, filter, filterParam := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
 	// do not allow details to be changed by query params
 	data := s.newTemplateData(r)
 	address, err := s.api.GetAddress(addressParam, page, txsOnPage, api.AccountDetailsTxHistoryLight, filter, strings.ToLower(data.SecondaryCoin))
@@ -977,9 +1071,10 @@ func (s *PublicServer) explorerXpub(w http.ResponseWriter, r *http.Request) (tpl
 	}
 	s.metrics.ExplorerViews.With(common.Labels{"action": "xpub"}).Inc()
 	// do not allow txsOnPage and details to be changed by query params
-	page, _, _, filter, filterParam, gap := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	page, _, _, filter, filterParam := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	gap, changeGap := getGapQueryParams(r)
 	data := s.newTemplateData(r)
-	address, err := s.api.GetXpubAddress(xpub, page, txsOnPage, api.AccountDetailsTxHistoryLight, filter, gap, strings.ToLower(data.SecondaryCoin))
+	address, err := s.api.GetXpubAddress(xpub, page, txsOnPage, api.AccountDetailsTxHistoryLight, filter, gap, changeGap, strings.ToLower(data.SecondaryCoin))
 	if err != nil {
 		if err == api.ErrUnsupportedXpub {
 			err = api.NewAPIError("XPUB functionality is not supported", true)
@@ -1059,7 +1154,7 @@ func (s *PublicServer) explorerSearch(w http.ResponseWriter, r *http.Request) (t
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "search"}).Inc()
 	if len(q) > 0 {
-		address, err = s.api.GetXpubAddress(q, 0, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, 0, "")
+		address, err = s.api.GetXpubAddress(q, 0, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, 0, 0, "")
 		if err == nil {
 			http.Redirect(w, r, joinURL("/xpub/", url.QueryEscape(address.AddrStr)), http.StatusFound)
 			return noTpl, nil, nil
@@ -1213,6 +1308,21 @@ func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface
 	}, nil
 }
 
+func (s *PublicServer) apiBlockLocator(r *http.Request, apiVersion int) (interface{}, error) {
+	type resBlockLocator struct {
+		BlockHashes []string `json:"blockHashes"`
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-locator"}).Inc()
+	locator, err := s.db.GetBlockLocator()
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	return resBlockLocator{
+		BlockHashes: locator,
+	}, nil
+}
+
 func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, error) {
 	var txid string
 	i := strings.LastIndexByte(r.URL.Path, '/')
@@ -1222,6 +1332,12 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 	if len(txid) == 0 {
 		return nil, api.NewAPIError("Missing txid", true)
 	}
+	if txid == "recipients" && apiVersion == apiV2 {
+		return s.apiTxRecipients(r.URL.Path[:i])
+	}
+	if strings.HasSuffix(r.URL.Path[:i], "/input") && apiVersion == apiV2 {
+		return s.apiTxInputPrevout(r.URL.Path[:i-len("/input")], txid)
+	}
 	var tx *api.Tx
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx"}).Inc()
@@ -1234,10 +1350,61 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 		}
 	}
 	tx, err = s.api.GetTransaction(txid, spendingTxs, false)
-	if err == nil && apiVersion == apiV1 {
-		return s.api.TxToV1(tx), nil
+	if err != nil {
+		return nil, err
 	}
-	return tx, err
+	if apiVersion == apiV1 {
+		return selectFields(s.api.TxToV1(tx), r.URL.Query().Get("fields"))
+	}
+	return selectFields(tx, r.URL.Query().Get("fields"))
+}
+
+// apiTxRecipients handles /api/v2/tx/<txid>/recipients, the path is passed without the /recipients suffix
+func (s *PublicServer) apiTxRecipients(path string) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(path, '/')
+	if i > 0 {
+		txid = path[i+1:]
+	}
+	if len(txid) == 0 || !strings.HasSuffix(path[:i+1], "tx/") {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-recipients"}).Inc()
+	return s.api.GetTxRecipients(txid)
+}
+
+// apiTxInputPrevout handles /api/v2/tx/<txid>/input/<n>, the path is passed without the /input/<n> suffix
+func (s *PublicServer) apiTxInputPrevout(path string, input string) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(path, '/')
+	if i > 0 {
+		txid = path[i+1:]
+	}
+	if len(txid) == 0 || !strings.HasSuffix(path[:i+1], "tx/") {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil {
+		return nil, api.NewAPIError("Parameter 'input' is not a number", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-input"}).Inc()
+	return s.api.GetTxInputPrevout(txid, n)
+}
+
+// backendFromHeader returns the back-end named by the X-Backend header or an empty string if the header is not set,
+// the header routes the read-only back-end calls of the tx-specific, block, raw block and estimatefee endpoints
+// to the named back-end, it is used for debugging of back-ends divergence
+func (s *PublicServer) backendFromHeader(r *http.Request) (string, error) {
+	backend := r.Header.Get("X-Backend")
+	if backend == "" {
+		return "", nil
+	}
+	for _, b := range s.chain.GetBackendNames() {
+		if b == backend {
+			return backend, nil
+		}
+	}
+	return "", api.NewAPIError(fmt.Sprintf("Unknown backend '%v'", backend), true)
 }
 
 func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface{}, error) {
@@ -1250,9 +1417,16 @@ func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface
 		return nil, api.NewAPIError("Missing txid", true)
 	}
 	var tx json.RawMessage
-	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-specific"}).Inc()
-	tx, err = s.chain.GetTransactionSpecific(&bchain.Tx{Txid: txid})
+	backend, err := s.backendFromHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if backend != "" {
+		tx, err = s.chain.GetTransactionSpecificFromBackend(backend, &bchain.Tx{Txid: txid})
+	} else {
+		tx, err = s.chain.GetTransactionSpecific(&bchain.Tx{Txid: txid})
+	}
 	if err == bchain.ErrTxNotFound {
 		return nil, api.NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 	}
@@ -1268,16 +1442,56 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	if len(addressParam) == 0 {
 		return nil, api.NewAPIError("Missing address", true)
 	}
+	if addressParam == "summary" && apiVersion == apiV2 {
+		return s.apiAddressSummary(r, r.URL.Path[:i])
+	}
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address"}).Inc()
-	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	page, pageSize, details, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
 	address, err = s.api.GetAddress(addressParam, page, pageSize, details, filter, secondaryCoin)
-	if err == nil && apiVersion == apiV1 {
-		return s.api.AddressToV1(address), nil
+	if err != nil {
+		return nil, err
+	}
+	if apiVersion == apiV1 {
+		return selectFields(s.api.AddressToV1(address), r.URL.Query().Get("fields"))
+	}
+	return selectFields(address, r.URL.Query().Get("fields"))
+}
+
+// apiAddressesBalances handles POST /api/v2/addresses/balances with JSON array of addresses in the body
+func (s *PublicServer) apiAddressesBalances(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-addresses-balances"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Only POST method is supported", true)
 	}
-	return address, err
+	var addresses []string
+	if err := json.NewDecoder(r.Body).Decode(&addresses); err != nil {
+		return nil, api.NewAPIError("Invalid list of addresses, "+err.Error(), true)
+	}
+	return s.api.GetAddressesBalances(addresses)
+}
+
+// apiAddressSummary handles /api/v2/address/<address>/summary, the path is passed without the /summary suffix
+func (s *PublicServer) apiAddressSummary(r *http.Request, path string) (interface{}, error) {
+	var addressParam string
+	i := strings.LastIndexByte(path, '/')
+	if i > 0 {
+		addressParam = path[i+1:]
+	}
+	if len(addressParam) == 0 || !strings.HasSuffix(path[:i+1], "address/") {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-summary"}).Inc()
+	reuse := false
+	if p := r.URL.Query().Get("reuse"); len(p) > 0 {
+		var err error
+		if reuse, err = strconv.ParseBool(p); err != nil {
+			return nil, api.NewAPIError("Parameter 'reuse' cannot be converted to boolean", true)
+		}
+	}
+	return s.api.GetAddressSummary(addressParam, reuse)
 }
 
 func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, error) {
@@ -1292,16 +1506,20 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub"}).Inc()
-	page, pageSize, details, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	page, pageSize, details, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	gap, changeGap := getGapQueryParams(r)
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
-	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap, secondaryCoin)
-	if err == nil && apiVersion == apiV1 {
-		return s.api.AddressToV1(address), nil
-	}
+	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap, changeGap, secondaryCoin)
 	if err == api.ErrUnsupportedXpub {
-		err = api.NewAPIError("XPUB functionality is not supported", true)
+		return nil, api.NewAPIError("XPUB functionality is not supported", true)
 	}
-	return address, err
+	if err != nil {
+		return nil, err
+	}
+	if apiVersion == apiV1 {
+		return selectFields(s.api.AddressToV1(address), r.URL.Query().Get("fields"))
+	}
+	return selectFields(address, r.URL.Query().Get("fields"))
 }
 
 func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, error) {
@@ -1317,17 +1535,21 @@ func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, er
 				return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
 			}
 		}
-		gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
-		if ec != nil {
-			gap = 0
+		var byAge bool
+		if byAge, err = getUtxoSortQueryParam(r); err != nil {
+			return nil, err
 		}
-		utxo, err = s.api.GetXpubUtxo(desc, onlyConfirmed, gap)
+		gap, changeGap := getGapQueryParams(r)
+		utxo, err = s.api.GetXpubUtxo(desc, onlyConfirmed, gap, changeGap)
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-utxo"}).Inc()
 		} else {
 			utxo, err = s.api.GetAddressUtxo(desc, onlyConfirmed)
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-utxo"}).Inc()
 		}
+		if err == nil && byAge {
+			api.Utxos(utxo).SortByAge()
+		}
 		if err == nil && apiVersion == apiV1 {
 			return s.api.AddressUtxoToV1(utxo), nil
 		}
@@ -1335,15 +1557,77 @@ func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, er
 	return utxo, err
 }
 
+func (s *PublicServer) apiUtxoByXpub(r *http.Request, apiVersion int) (interface{}, error) {
+	var utxo []api.Utxo
+	var err error
+	if i := strings.LastIndex(r.URL.Path, "utxo-by-xpub/"); i > 0 {
+		xpub := r.URL.Path[i+13:]
+		onlyConfirmed := false
+		c := r.URL.Query().Get("confirmed")
+		if len(c) > 0 {
+			onlyConfirmed, err = strconv.ParseBool(c)
+			if err != nil {
+				return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
+			}
+		}
+		var byAge bool
+		if byAge, err = getUtxoSortQueryParam(r); err != nil {
+			return nil, err
+		}
+		gap, changeGap := getGapQueryParams(r)
+		utxo, err = s.api.GetXpubUtxoWithScripts(xpub, onlyConfirmed, gap, changeGap)
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-utxo-by-xpub"}).Inc()
+		if err == nil && byAge {
+			api.Utxos(utxo).SortByAge()
+		}
+	}
+	return utxo, err
+}
+
+// apiUtxoByScriptHash handles /api/v2/utxo-by-scripthash/<scripthash>
+func (s *PublicServer) apiUtxoByScriptHash(r *http.Request, apiVersion int) (interface{}, error) {
+	var utxo []api.Utxo
+	var err error
+	if i := strings.LastIndex(r.URL.Path, "utxo-by-scripthash/"); i > 0 {
+		scriptHash := r.URL.Path[i+19:]
+		onlyConfirmed := false
+		c := r.URL.Query().Get("confirmed")
+		if len(c) > 0 {
+			onlyConfirmed, err = strconv.ParseBool(c)
+			if err != nil {
+				return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
+			}
+		}
+		var byAge bool
+		if byAge, err = getUtxoSortQueryParam(r); err != nil {
+			return nil, err
+		}
+		utxo, err = s.api.GetScriptHashUtxo(scriptHash, onlyConfirmed)
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-utxo-by-scripthash"}).Inc()
+		if err == nil && byAge {
+			api.Utxos(utxo).SortByAge()
+		}
+	}
+	return utxo, err
+}
+
+// getUtxoSortQueryParam returns true if the utxos are requested sorted by age, from the oldest
+func getUtxoSortQueryParam(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("sort") {
+	case "":
+		return false, nil
+	case "age":
+		return true, nil
+	}
+	return false, api.NewAPIError("Parameter 'sort' must be 'age'", true)
+}
+
 func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (interface{}, error) {
 	var history []api.BalanceHistory
 	var fromTimestamp, toTimestamp int64
 	var err error
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
-		gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
-		if ec != nil {
-			gap = 0
-		}
+		gap, changeGap := getGapQueryParams(r)
 		from := r.URL.Query().Get("from")
 		if from != "" {
 			fromTimestamp, err = strconv.ParseInt(from, 10, 64)
@@ -1368,7 +1652,7 @@ func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (inter
 		if fiat != "" {
 			fiatArray = []string{fiat}
 		}
-		history, err = s.api.GetXpubBalanceHistory(r.URL.Path[i+1:], fromTimestamp, toTimestamp, fiatArray, gap, uint32(groupBy))
+		history, err = s.api.GetXpubBalanceHistory(r.URL.Path[i+1:], fromTimestamp, toTimestamp, fiatArray, gap, changeGap, uint32(groupBy))
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-balancehistory"}).Inc()
 		} else {
@@ -1381,14 +1665,25 @@ func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (inter
 
 func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, error) {
 	var block *api.Block
-	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block"}).Inc()
+	backend, err := s.backendFromHeader(r)
+	if err != nil {
+		return nil, err
+	}
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		// the back-end returns only the block header data and the txids, as is
+		if backend != "" {
+			return s.api.GetBlockSpecificFromBackend(backend, r.URL.Path[i+1:])
+		}
 		page, ec := strconv.Atoi(r.URL.Query().Get("page"))
 		if ec != nil {
 			page = 0
 		}
-		block, err = s.api.GetBlock(r.URL.Path[i+1:], page, txsInAPI)
+		pageSize, ec := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if ec != nil || pageSize <= 0 || pageSize > txsInAPI {
+			pageSize = txsInAPI
+		}
+		block, err = s.api.GetBlock(r.URL.Path[i+1:], page, pageSize)
 		if err == nil && apiVersion == apiV1 {
 			return s.api.BlockToV1(block), nil
 		}
@@ -1398,14 +1693,51 @@ func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, e
 
 func (s *PublicServer) apiBlockRaw(r *http.Request, apiVersion int) (interface{}, error) {
 	var block *api.BlockRaw
-	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-raw"}).Inc()
+	backend, err := s.backendFromHeader(r)
+	if err != nil {
+		return nil, err
+	}
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
-		block, err = s.api.GetBlockRaw(r.URL.Path[i+1:])
+		if backend != "" {
+			block, err = s.api.GetBlockRawFromBackend(backend, r.URL.Path[i+1:])
+		} else {
+			block, err = s.api.GetBlockRaw(r.URL.Path[i+1:])
+		}
 	}
 	return block, err
 }
 
+func (s *PublicServer) apiBlockFilter(r *http.Request, apiVersion int) (interface{}, error) {
+	var blockFilter *api.BlockFilter
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-filter"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		blockFilter, err = s.api.GetBlockFilter(r.URL.Path[i+1:])
+	}
+	return blockFilter, err
+}
+
+// apiBlockTopTxs handles /api/v2/block-top-txs/<block height|block hash>?by=<value|vsize>&limit=<n>
+func (s *PublicServer) apiBlockTopTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	var block string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		block = r.URL.Path[i+1:]
+	}
+	if len(block) == 0 {
+		return nil, api.NewAPIError("Missing block", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-top-txs"}).Inc()
+	var limit int
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			return nil, api.NewAPIError("Parameter 'limit' is not a valid number", true)
+		}
+	}
+	return s.api.GetBlockTopTxs(block, r.URL.Query().Get("by"), limit)
+}
+
 func (s *PublicServer) apiFeeStats(r *http.Request, apiVersion int) (interface{}, error) {
 	var feeStats *api.FeeStats
 	var err error
@@ -1416,6 +1748,254 @@ func (s *PublicServer) apiFeeStats(r *http.Request, apiVersion int) (interface{}
 	return feeStats, err
 }
 
+func (s *PublicServer) apiMempoolInfo(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempoolinfo"}).Inc()
+	return s.api.GetMempoolInfo()
+}
+
+func (s *PublicServer) apiSyncStatus(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-sync-status"}).Inc()
+	return s.api.GetSyncStatus()
+}
+
+func (s *PublicServer) apiOpReturn(r *http.Request, apiVersion int) (interface{}, error) {
+	var txs *api.OpReturnTxs
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-opreturn"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		page, pageSize, _, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+		txs, err = s.api.GetOpReturnTxs(r.URL.Path[i+1:], page, pageSize, filter)
+	}
+	return txs, err
+}
+
+func (s *PublicServer) apiScriptTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	var txs *api.ScriptOutputTxs
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-script-txs"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		page, pageSize, _, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+		txs, err = s.api.GetScriptOutputTxs(r.URL.Path[i+1:], page, pageSize, filter)
+	}
+	return txs, err
+}
+
+// apiSpendingGraph handles /api/v2/spending-graph/<txid>/<vout>?depth=<depth>
+func (s *PublicServer) apiSpendingGraph(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-spending-graph"}).Inc()
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 {
+		return nil, api.NewAPIError("Missing txid and vout", true)
+	}
+	vout, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return nil, api.NewAPIError("Parameter 'vout' is not a number", true)
+	}
+	var depth int
+	if d := r.URL.Query().Get("depth"); d != "" {
+		if depth, err = strconv.Atoi(d); err != nil {
+			return nil, api.NewAPIError("Parameter 'depth' is not a number", true)
+		}
+	}
+	return s.api.GetSpendingGraph(parts[len(parts)-2], vout, depth)
+}
+
+func (s *PublicServer) apiOutputsByValue(r *http.Request, apiVersion int) (interface{}, error) {
+	var outputs *api.ValueOutputs
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-outputs-by-value"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		values := strings.Split(r.URL.Path[i+1:], "-")
+		if len(values) != 2 {
+			return nil, api.NewAPIError("Invalid value range", true)
+		}
+		page, pageSize, _, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+		outputs, err = s.api.GetOutputsByValue(values[0], values[1], page, pageSize, filter)
+	}
+	return outputs, err
+}
+
+// apiDifficultyHistory handles /api/v2/difficulty-history?from=<height>&to=<height>&interval=<blocks>
+func (s *PublicServer) apiDifficultyHistory(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-difficulty-history"}).Inc()
+	params := map[string]int{"from": -1, "to": -1, "interval": 0}
+	for name := range params {
+		if v := r.URL.Query().Get(name); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				return nil, api.NewAPIError("Parameter '"+name+"' is not a valid number", true)
+			}
+			params[name] = i
+		}
+	}
+	return s.api.GetDifficultyHistory(params["from"], params["to"], params["interval"])
+}
+
+// apiBalanceDeltas handles /api/v2/balance-deltas/<address>?from=<height>&to=<height>
+func (s *PublicServer) apiBalanceDeltas(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-balance-deltas"}).Inc()
+	params := map[string]int{"from": 0, "to": -1}
+	for name := range params {
+		if v := r.URL.Query().Get(name); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				return nil, api.NewAPIError("Parameter '"+name+"' is not a valid number", true)
+			}
+			params[name] = i
+		}
+	}
+	return s.api.GetBalanceDeltas(address, params["from"], params["to"])
+}
+
+// apiTokenTransfers handles /api/v2/token-transfers/<address>?contract=<contract>&page=<page>&pageSize=<size>
+func (s *PublicServer) apiTokenTransfers(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-token-transfers"}).Inc()
+	page, ec := strconv.Atoi(r.URL.Query().Get("page"))
+	if ec != nil {
+		page = 0
+	}
+	pageSize, ec := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if ec != nil || pageSize <= 0 || pageSize > txsInAPI {
+		pageSize = txsInAPI
+	}
+	return s.api.GetAddressTokenTransfers(address, r.URL.Query().Get("contract"), page, pageSize)
+}
+
+// apiTokenTransfersExport handles /api/v2/token-transfers-export/<address>?contract=<contract>
+// the token transfers are streamed as newline delimited JSON, one transfer per line, the response is not buffered
+func (s *PublicServer) apiTokenTransfersExport(w http.ResponseWriter, r *http.Request) {
+	type jsonError struct {
+		Text string `json:"error"`
+	}
+	s.metrics.ExplorerPendingRequests.With((common.Labels{"method": "apiTokenTransfersExport"})).Inc()
+	defer s.metrics.ExplorerPendingRequests.With((common.Labels{"method": "apiTokenTransfersExport"})).Dec()
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-token-transfers-export"}).Inc()
+	var address string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	encoder := json.NewEncoder(w)
+	if len(address) == 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(jsonError{"Missing address"})
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	written := 0
+	err := s.api.ForEachAddressTokenTransfer(address, r.URL.Query().Get("contract"), func(t *api.AddressTokenTransfer) error {
+		if written == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		}
+		if err := encoder.Encode(t); err != nil {
+			// the client closed the connection
+			return err
+		}
+		written++
+		if flusher != nil && written%100 == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		text := "Internal server error"
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(*api.APIError); ok {
+			text = apiErr.Error()
+			if apiErr.Public {
+				status = http.StatusBadRequest
+			}
+		} else {
+			glog.Error("apiTokenTransfersExport error: ", err)
+		}
+		// the status cannot be changed after the first transfer, the error is reported on the last line of the stream
+		if written == 0 {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(status)
+		}
+		encoder.Encode(jsonError{text})
+		return
+	}
+	if written == 0 {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+}
+
+// apiValidateAddress handles /api/v2/validate/<address>
+func (s *PublicServer) apiValidateAddress(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-validate-address"}).Inc()
+	return s.api.ValidateAddress(address)
+}
+
+// apiGenesis handles /api/v2/genesis
+func (s *PublicServer) apiGenesis(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-genesis"}).Inc()
+	return s.api.GetGenesisInfo()
+}
+
+func (s *PublicServer) apiMinerBlocks(r *http.Request, apiVersion int) (interface{}, error) {
+	var blocks *api.MinerBlocks
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-miner-blocks"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		var miner string
+		if miner, err = url.PathUnescape(r.URL.Path[i+1:]); err != nil {
+			return nil, api.NewAPIError("Invalid miner", true)
+		}
+		page, pageSize, _, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+		blocks, err = s.api.GetBlocksByMiner(miner, page, pageSize, filter)
+	}
+	return blocks, err
+}
+
+// apiNewAddresses handles /api/v2/new-addresses?from=<height>&to=<height>
+func (s *PublicServer) apiNewAddresses(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-new-addresses"}).Inc()
+	page, pageSize, _, filter, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	return s.api.GetNewAddresses(page, pageSize, filter)
+}
+
+func (s *PublicServer) apiPsbtEnrich(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-psbt-enrich"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Only POST method is supported", true)
+	}
+	// the response writer is not available here, the reader only fails after the limit
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxPsbtRequestSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, api.NewAPIError(fmt.Sprintf("PSBT is larger than %d bytes", maxPsbtRequestSize), true)
+		}
+		return nil, api.NewAPIError("Missing PSBT", true)
+	}
+	if len(data) == 0 {
+		return nil, api.NewAPIError("Missing PSBT", true)
+	}
+	return s.api.EnrichPsbt(strings.TrimSpace(string(data)))
+}
+
 type resultSendTransaction struct {
 	Result string `json:"result"`
 }
@@ -1459,6 +2039,75 @@ func (s *PublicServer) apiAvailableVsCurrencies(r *http.Request, apiVersion int)
 	return result, err
 }
 
+// parseConfirmationThresholds parses thresholds in the format minValue:confirmations separated by commas, e.g. 0:1,1000:3
+func parseConfirmationThresholds(p string) ([]api.ConfirmationThreshold, error) {
+	var thresholds []api.ConfirmationThreshold
+	for _, t := range strings.Split(p, ",") {
+		parts := strings.Split(t, ":")
+		if len(parts) != 2 {
+			return nil, api.NewAPIError("Invalid threshold '"+t+"'", true)
+		}
+		minValue, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil || minValue < 0 {
+			return nil, api.NewAPIError("Invalid threshold value '"+parts[0]+"'", true)
+		}
+		confirmations, err := strconv.Atoi(parts[1])
+		if err != nil || confirmations < 0 {
+			return nil, api.NewAPIError("Invalid threshold confirmations '"+parts[1]+"'", true)
+		}
+		thresholds = append(thresholds, api.ConfirmationThreshold{MinValue: minValue, Confirmations: confirmations})
+	}
+	return thresholds, nil
+}
+
+func (s *PublicServer) apiConfirmationPolicy(r *http.Request, apiVersion int) (interface{}, error) {
+	var policy *api.TxConfirmationPolicy
+	var err error
+	if i := strings.LastIndex(r.URL.Path, "confirmation-policy/"); i > 0 {
+		txid := r.URL.Path[i+20:]
+		t := r.URL.Query().Get("thresholds")
+		if t == "" {
+			return nil, api.NewAPIError("Missing parameter 'thresholds'", true)
+		}
+		thresholds, err := parseConfirmationThresholds(t)
+		if err != nil {
+			return nil, err
+		}
+		policy, err = s.api.GetTxConfirmationPolicy(txid, r.URL.Query().Get("currency"), thresholds)
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-confirmation-policy"}).Inc()
+		return policy, err
+	}
+	return policy, err
+}
+
+func (s *PublicServer) apiConfirmationProbability(r *http.Request, apiVersion int) (interface{}, error) {
+	var probability *api.ConfirmationProbability
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-confirmation-probability"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		var blocks int
+		if b := r.URL.Query().Get("blocks"); b != "" {
+			if blocks, err = strconv.Atoi(b); err != nil {
+				return nil, api.NewAPIError("Parameter 'blocks' is not a number", true)
+			}
+		}
+		probability, err = s.api.GetConfirmationProbability(r.URL.Path[i+1:], blocks)
+	}
+	return probability, err
+}
+
+func (s *PublicServer) apiNextBlockFee(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-next-block-fee"}).Inc()
+	conservative := true
+	if c := r.URL.Query().Get("conservative"); c != "" {
+		var err error
+		if conservative, err = strconv.ParseBool(c); err != nil {
+			return nil, api.NewAPIError("Parameter 'conservative' cannot be converted to boolean", true)
+		}
+	}
+	return s.api.GetNextBlockFee(conservative)
+}
+
 // apiTickers returns FiatRates ticker prices for the specified block or timestamp.
 func (s *PublicServer) apiTickers(r *http.Request, apiVersion int) (interface{}, error) {
 	var result *api.FiatTicker
@@ -1537,6 +2186,27 @@ type resultEstimateFeeAsString struct {
 	Result string `json:"result"`
 }
 
+// units of the fee estimate, the backend returns the fee in satoshis per kilobyte
+const (
+	feeUnitBtcPerKb = "btckb"
+	feeUnitSatPerKb = "satkb"
+	feeUnitSatPerVb = "satvb"
+)
+
+// formatFeeInUnit converts the fee in satoshis per kilobyte to the given unit
+func formatFeeInUnit(fee *big.Int, unit string, parser bchain.BlockChainParser) (string, error) {
+	switch unit {
+	case "", feeUnitBtcPerKb:
+		return parser.AmountToDecimalString(fee), nil
+	case feeUnitSatPerKb:
+		return fee.String(), nil
+	case feeUnitSatPerVb:
+		// 1 kilobyte is 1000 virtual bytes
+		return bchain.AmountToDecimalString(fee, 3), nil
+	}
+	return "", api.NewAPIError("Unknown fee unit '"+unit+"'", true)
+}
+
 func (s *PublicServer) apiEstimateFee(r *http.Request, apiVersion int) (interface{}, error) {
 	var res resultEstimateFeeAsString
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-estimatefee"}).Inc()
@@ -1555,15 +2225,28 @@ func (s *PublicServer) apiEstimateFee(r *http.Request, apiVersion int) (interfac
 					return nil, api.NewAPIError("Parameter 'conservative' cannot be converted to boolean", true)
 				}
 			}
-			var fee big.Int
-			fee, err = s.chain.EstimateSmartFee(blocks, conservative)
+			unit := r.URL.Query().Get("unit")
+			if unit != "" && s.chainParser.GetChainType() != bchain.ChainBitcoinType {
+				return nil, api.NewAPIError("Parameter 'unit' is supported only by Bitcoin-type coins", true)
+			}
+			backend, err := s.backendFromHeader(r)
 			if err != nil {
+				return nil, err
+			}
+			var fee big.Int
+			if backend != "" {
+				if fee, err = s.chain.EstimateSmartFeeFromBackend(backend, blocks, conservative); err != nil {
+					return nil, err
+				}
+			} else if fee, err = s.chain.EstimateSmartFee(blocks, conservative); err != nil {
 				fee, err = s.chain.EstimateFee(blocks)
 				if err != nil {
 					return nil, err
 				}
 			}
-			res.Result = s.chainParser.AmountToDecimalString(&fee)
+			if res.Result, err = formatFeeInUnit(&fee, unit, s.chainParser); err != nil {
+				return nil, err
+			}
 			return res, nil
 		}
 	}
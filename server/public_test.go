@@ -3,23 +3,31 @@
 package server
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/gorilla/websocket"
 	"github.com/linxGnu/grocksdb"
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 	gosocketio "github.com/martinboehm/golang-socketio"
 	"github.com/martinboehm/golang-socketio/transport"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/trezor/blockbook/api"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/bchain/coins/btc"
 	"github.com/trezor/blockbook/common"
@@ -113,6 +121,10 @@ func setupPublicHTTPServer(parser bchain.BlockChainParser, chain bchain.BlockCha
 	if err != nil {
 		glog.Fatal("mempool: ", err)
 	}
+	// the mempool min fee is stored in the internal state by the mempool synchronization
+	if fee, err := chain.GetMempoolMinFee(); err == nil {
+		is.SetMempoolMinFee(fee.String())
+	}
 
 	// caching is switched off because test transactions do not have hex data
 	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
@@ -259,6 +271,54 @@ func performHttpTests(tests []httpTests, t *testing.T, ts *httptest.Server) {
 	}
 }
 
+// workerTests are the tests of the api.Worker methods not exposed by the public interface
+// or requiring the worker with a different blockchain or mempool than the public server
+type workerTests struct {
+	name    string
+	chain   bchain.BlockChain // blockchain of the worker, the blockchain of the server if nil
+	mempool bchain.Mempool    // mempool of the worker, the mempool of the server if nil
+	f       func(w *api.Worker) (interface{}, error)
+	want    string // JSON of the result or the error
+}
+
+func performWorkerTests(tests []workerTests, t *testing.T, s *PublicServer) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.api
+			if tt.chain != nil || tt.mempool != nil {
+				chain, mempool := tt.chain, tt.mempool
+				if chain == nil {
+					chain = s.chain
+				}
+				if mempool == nil {
+					mempool = s.mempool
+				}
+				txCache, err := db.NewTxCache(s.db, chain, metrics, s.is, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if w, err = api.NewWorker(s.db, chain, mempool, txCache, metrics, s.is); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got string
+			r, err := tt.f(w)
+			if err != nil {
+				got = err.Error()
+			} else {
+				b, err := json.Marshal(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(b)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
 func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 	tests := []httpTests{
 		{
@@ -418,6 +478,16 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`"decimals":8`,
 				`"backend":{"chain":"fakecoin","blocks":2,"headers":2,"bestBlockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"`,
 				`"version":"001001","subversion":"/Fakecoin:0.0.1/"`,
+				`"mempoolMinFee":"2000","minRelayTxFee":"1000"`,
+			},
+		},
+		{
+			name:        "apiBlockLocator",
+			r:           newGetRequest(ts.URL + "/api/v2/block-locator/"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"blockHashes":["00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997"]}`,
 			},
 		},
 		{
@@ -456,6 +526,15 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"n":0,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true,"value":"9876"}],"vout":[{"value":"9000","n":0,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"confirmations":1,"blockTime":1521595678,"value":"9000","valueIn":"9876","fees":"876"}`,
 			},
 		},
+		{
+			name:        "apiTx v2 fields",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07?fields=fees,txid,unknown,"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","fees":"876"}`,
+			},
+		},
 		{
 			name:        "apiTx - not found v2",
 			r:           newGetRequest(ts.URL + "/api/v2/tx/1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"),
@@ -465,6 +544,51 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Transaction '1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}`,
 			},
 		},
+		{
+			name:        "apiTxRecipients",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25/recipients"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","blockHeight":225494,"confirmations":1,"recipients":[{"n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"isAddress":true,"value":"317283951061"},{"n":1,"addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"],"isAddress":true,"value":"917283951061"},{"n":2,"addresses":["OP_RETURN 2020f1686f6a20"],"isAddress":false,"value":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiTxInputPrevout",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/input/0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","n":0,"prevTxid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","prevVout":2,"prevBlockHeight":225493,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true,"value":"9876","hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87"}`,
+			},
+		},
+		{
+			name:        "apiTxInputPrevout - input not found",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/input/1"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Input 1 of transaction '05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}`,
+			},
+		},
+		{
+			name:        "apiTxInputPrevout - invalid input",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/input/x"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'input' is not a number"}`,
+			},
+		},
+		{
+			name:        "apiTxRecipients - not found",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/recipients"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}`,
+			},
+		},
 		{
 			name:        "apiTxSpecific",
 			r:           newGetRequest(ts.URL + "/api/tx-specific/00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"),
@@ -474,6 +598,78 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"hex":"","txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","version":0,"locktime":0,"vin":[],"vout":[{"ValueSat":100000000,"value":0,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac","addresses":null}},{"ValueSat":12345,"value":0,"n":1,"scriptPubKey":{"hex":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","addresses":null}},{"ValueSat":12345,"value":0,"n":2,"scriptPubKey":{"hex":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","addresses":null}}],"confirmations":2,"time":1521515026,"blocktime":1521515026}`,
 			},
 		},
+		{
+			name: "apiTxSpecific unknown backend",
+			r: func() *http.Request {
+				r := newGetRequest(ts.URL + "/api/v2/tx-specific/00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840")
+				r.Header.Set("X-Backend", "unknown:8030")
+				return r
+			}(),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown backend 'unknown:8030'"}`,
+			},
+		},
+		{
+			name: "apiBlock unknown backend",
+			r: func() *http.Request {
+				r := newGetRequest(ts.URL + "/api/v2/block/225494")
+				r.Header.Set("X-Backend", "unknown:8030")
+				return r
+			}(),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown backend 'unknown:8030'"}`,
+			},
+		},
+		{
+			name: "apiBlockRaw unknown backend",
+			r: func() *http.Request {
+				r := newGetRequest(ts.URL + "/api/v2/rawblock/225494")
+				r.Header.Set("X-Backend", "unknown:8030")
+				return r
+			}(),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown backend 'unknown:8030'"}`,
+			},
+		},
+		{
+			name: "apiEstimateFee unknown backend",
+			r: func() *http.Request {
+				r := newGetRequest(ts.URL + "/api/v2/estimatefee/2")
+				r.Header.Set("X-Backend", "unknown:8030")
+				return r
+			}(),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown backend 'unknown:8030'"}`,
+			},
+		},
+		{
+			name:        "apiMempoolInfo",
+			r:           newGetRequest(ts.URL + "/api/v2/mempoolinfo"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"count":5,"totalVSize":600,"totalFees":"9300","minFee":"1000"}`,
+			},
+		},
+		{
+			name:        "apiSyncStatus",
+			r:           newGetRequest(ts.URL + "/api/v2/sync-status"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"coin":"Fakecoin",`,
+				`"network":"fakecoin","bestHeight":225494,"backendHeight":2,"lag":0,`,
+				`"backendVersion":"001001","backendSubversion":"/Fakecoin:0.0.1/"}`,
+			},
+		},
 		{
 			name:        "apiFeeStats",
 			r:           newGetRequest(ts.URL + "/api/v2/feestats/225494"),
@@ -636,6 +832,69 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2}`,
 			},
 		},
+		{
+			name:        "apiAddress v2 fields",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?details=basic&fields=txs,balance,unknown"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"balance":"0","txs":2}`,
+			},
+		},
+		{
+			name:        "apiValidateAddress P2PKH",
+			r:           newGetRequest(ts.URL + "/api/v2/validate/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","isValid":true,"scriptType":"p2pkh","isForNetwork":true}`,
+			},
+		},
+		{
+			name:        "apiValidateAddress P2WPKH",
+			r:           newGetRequest(ts.URL + "/api/v2/validate/tb1qupjdck20as3y4l95cd5wepkv0grcz0p7d8rd5s"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"tb1qupjdck20as3y4l95cd5wepkv0grcz0p7d8rd5s","isValid":true,"scriptType":"p2wpkh","isForNetwork":true}`,
+			},
+		},
+		{
+			name:        "apiValidateAddress P2TR",
+			r:           newGetRequest(ts.URL + "/api/v2/validate/tb1pqsv2qyp8hsma46422ecfd3ek02jayumkkzjx7vkf3cqpmfd4ucpsx0cc9h"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"tb1pqsv2qyp8hsma46422ecfd3ek02jayumkkzjx7vkf3cqpmfd4ucpsx0cc9h","isValid":true,"scriptType":"p2tr","isForNetwork":true}`,
+			},
+		},
+		{
+			name:        "apiValidateAddress invalid checksum",
+			r:           newGetRequest(ts.URL + "/api/v2/validate/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAx"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAx","isValid":false,"isForNetwork":false,"reason":"`,
+			},
+		},
+		{
+			name:        "apiAddress v2 minValue=500",
+			r:           newGetRequest(ts.URL + "/api/v2/address/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1?minValue=500"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 minValue=1000",
+			r:           newGetRequest(ts.URL + "/api/v2/address/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1?minValue=1000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
 		{
 			name:        "apiAddress v2 details=txs",
 			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?details=txs"),
@@ -654,6 +913,33 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Missing address"}`,
 			},
 		},
+		{
+			name:        "apiAddressSummary v2",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw/summary"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","txs":2,"firstSeenHeight":225493,"firstSeenTime":1521515026,"lastSeenHeight":225494,"lastSeenTime":1521595678}`,
+			},
+		},
+		{
+			name:        "apiAddressSummary v2 reuse",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw/summary?reuse=true"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","txs":2,"firstSeenHeight":225493,"firstSeenTime":1521515026,"lastSeenHeight":225494,"lastSeenTime":1521595678,"reuseCount":1,"firstReceivedHeight":225493}`,
+			},
+		},
+		{
+			name:        "apiAddressSummary v2 missing address",
+			r:           newGetRequest(ts.URL + "/api/v2/address/summary"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing address"}`,
+			},
+		},
 		{
 			name:        "apiXpub v2 default",
 			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub),
@@ -753,6 +1039,24 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":1,"value":"917283951061","height":225494,"confirmations":1}]`,
 			},
 		},
+		{
+			name:        "apiUtxo v2 sort by age",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL?sort=age"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":1,"value":"917283951061","height":225494,"confirmations":1}]`,
+			},
+		},
+		{
+			name:        "apiUtxo v2 invalid sort",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL?sort=value"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'sort' must be 'age'"}`,
+			},
+		},
 		{
 			name:        "apiUtxo v2 xpub",
 			r:           newGetRequest(ts.URL + "/api/v2/utxo/" + dbtestdata.Xpub),
@@ -771,6 +1075,53 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`[]`,
 			},
 		},
+		{
+			name:        "apiUtxoByXpub v2",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo-by-xpub/" + dbtestdata.Xpub),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":0,"value":"118641975500","height":225494,"confirmations":1,"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","script":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287"}]`,
+			},
+		},
+		{
+			name:        "apiUtxoByXpub v2 address",
+			r:           newGetRequest(ts.URL + "/api/v2/utxo-by-xpub/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid xpub 'mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL'`,
+			},
+		},
+		{
+			name:        "apiConfirmationPolicy high value",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-policy/" + dbtestdata.TxidB2T1 + "?currency=usd&thresholds=0:1,1000:3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"` + dbtestdata.TxidB2T1 + `","currency":"usd","rate":2003,`,
+				`"confirmations":1,"requiredConfirmations":3,"met":false}`,
+			},
+		},
+		{
+			name:        "apiConfirmationPolicy low value",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-policy/" + dbtestdata.TxidB2T3 + "?currency=usd&thresholds=0:1,1000:3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"` + dbtestdata.TxidB2T3 + `","currency":"usd","rate":2003,`,
+				`"confirmations":1,"requiredConfirmations":1,"met":true}`,
+			},
+		},
+		{
+			name:        "apiConfirmationPolicy invalid thresholds",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-policy/" + dbtestdata.TxidB2T3 + "?currency=usd&thresholds=1000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid threshold '1000'"}`,
+			},
+		},
 		{
 			name:        "apiBalanceHistory Addr2 v2",
 			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"),
@@ -789,6 +1140,42 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`[{"time":1521514800,"txs":1,"received":"9876","sent":"0","sentToSelf":"0","rates":{"eur":1301,"usd":2001}},{"time":1521594000,"txs":1,"received":"9000","sent":"9876","sentToSelf":"9000","rates":{"eur":1303,"usd":2003}}]`,
 			},
 		},
+		{
+			name:        "apiBalanceDeltas Addr5",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-deltas/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","fromHeight":0,"toHeight":225494,"totalDelta":"9000","deltas":[{"height":225493,"time":1521515026,"txs":1,"delta":"9876"},{"height":225494,"time":1521595678,"txs":1,"delta":"-876"}]}`,
+			},
+		},
+		{
+			name:        "apiBalanceDeltas Addr2 from=225494",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-deltas/mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz?from=225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","fromHeight":225494,"toHeight":225494,"totalDelta":"-12345","deltas":[{"height":225494,"time":1521595678,"txs":1,"delta":"-12345"}]}`,
+			},
+		},
+		{
+			name:        "apiBalanceDeltas Addr3 to=225493",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-deltas/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?to=225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","fromHeight":0,"toHeight":225493,"totalDelta":"1234567890123","deltas":[{"height":225493,"time":1521515026,"txs":1,"delta":"1234567890123"}]}`,
+			},
+		},
+		{
+			name:        "apiBalanceDeltas invalid range",
+			r:           newGetRequest(ts.URL + "/api/v2/balance-deltas/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=225494&to=225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid block range"}`,
+			},
+		},
 		{
 			name:        "apiBalanceHistory Addr5 v2 fiatcurrency=eur",
 			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1?fiatcurrency=eur"),
@@ -880,29 +1267,495 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 			},
 		},
 		{
-			name:        "apiGetBlock",
-			r:           newGetRequest(ts.URL + "/api/v2/block/225493"),
+			name:        "apiPsbtEnrich invalid PSBT",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/enrich", "cHNidP8="),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid PSBT, global: EOF"}`,
+			},
+		},
+		{
+			name:        "apiPsbtEnrich too large PSBT",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/enrich", strings.Repeat("A", maxPsbtRequestSize+1)),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"PSBT is larger than 4194304 bytes"}`,
+			},
+		},
+		{
+			name:        "apiPsbtEnrich missing PSBT",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/enrich", ""),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing PSBT"}`,
+			},
+		},
+		{
+			name:        "apiEstimateFee unit btckb",
+			r:           newGetRequest(ts.URL + "/api/v2/estimatefee/123?conservative=false&unit=btckb"),
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","nextBlockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1521515026,"version":0,"merkleRoot":"","nonce":"","bits":"","difficulty":"","txCount":2,"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"],"isAddress":true},{"value":"12345","n":1,"spent":true,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true},{"value":"12345","n":2,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"100024690","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true},{"value":"1","n":1,"spent":true,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+				`{"result":"0.00012299"}`,
 			},
 		},
 		{
-			name:        "apiGetRawBlock",
-			r:           newGetRequest(ts.URL + "/api/v2/rawblock/225493"),
+			name:        "apiEstimateFee unit satkb",
+			r:           newGetRequest(ts.URL + "/api/v2/estimatefee/123?conservative=false&unit=satkb"),
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"hex":"00e0ff3fd42677a86f1515bafcf9802c1765e02226655a9b97fd44132602000000000000"}`,
+				`{"result":"12299"}`,
 			},
 		},
-	}
-	performHttpTests(tests, t, ts)
-}
-
-func socketioTestsBitcoinType(t *testing.T, ts *httptest.Server) {
-	type socketioReq struct {
+		{
+			name:        "apiEstimateFee unit satvb",
+			r:           newGetRequest(ts.URL + "/api/v2/estimatefee/123?conservative=false&unit=satvb"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"result":"12.299"}`,
+			},
+		},
+		{
+			name:        "apiEstimateFee unit satvb whole number",
+			r:           newGetRequest(ts.URL + "/api/v2/estimatefee/20?unit=satvb"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"result":"2"}`,
+			},
+		},
+		{
+			name:        "apiEstimateFee unknown unit",
+			r:           newGetRequest(ts.URL + "/api/v2/estimatefee/123?unit=satb"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown fee unit 'satb'"}`,
+			},
+		},
+		{
+			name:        "apiGetBlock",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","nextBlockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1521515026,"version":0,"merkleRoot":"","nonce":"","bits":"","difficulty":"","txCount":2,"totalOutputs":6,"reward":{"fees":"0"},"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"],"isAddress":true},{"value":"12345","n":1,"spent":true,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true},{"value":"12345","n":2,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"100024690","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true},{"value":"1","n":1,"spent":true,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiGetBlock totals",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"height":225494,`,
+				`"txCount":4,"totalInputs":6,"totalOutputs":8,"reward":{"fees":"1284"},"txs":[`,
+			},
+		},
+		{
+			name:        "apiBlockTopTxs",
+			r:           newGetRequest(ts.URL + "/api/v2/block-top-txs/225494?limit=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"by":"value","txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","value":"1234567902122"},{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","value":"317283951000"}]}`,
+			},
+		},
+		{
+			name:        "apiBlockTopTxs all",
+			r:           newGetRequest(ts.URL + "/api/v2/block-top-txs/225494?limit=4"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db","value":"1360030331"},{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","value":"9000"}]}`,
+			},
+		},
+		{
+			name:        "apiBlockTopTxs unknown order",
+			r:           newGetRequest(ts.URL + "/api/v2/block-top-txs/225494?by=fees"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown sort order 'fees', use value or vsize"}`,
+			},
+		},
+		{
+			name:        "apiGetRawBlock",
+			r:           newGetRequest(ts.URL + "/api/v2/rawblock/225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"hex":"00e0ff3fd42677a86f1515bafcf9802c1765e02226655a9b97fd44132602000000000000"}`,
+			},
+		},
+		{
+			name:        "apiTx v2 firstSeen and replaces",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"blockTime":1521515026,"firstSeen":1521514900,"value":"1234567900000"`,
+				`"replaces":["1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b","2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c"]`,
+			},
+		},
+		{
+			name:        "apiTx v2 mempool firstSeen and replaces",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/2b7a3c1d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"2b7a3c1d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b",`,
+				`"confirmations":0,`,
+				`"blockTime":1521595700,"firstSeen":1521595700,"vsize":200,"value":"917283950861","valueIn":"917283951061","fees":"200"`,
+				`"replaces":["3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d"]`,
+			},
+		},
+		{
+			name:        "apiTx v2 replaced",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/7b2f1c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9f"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"7b2f1c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9f",`,
+				`"blockHeight":-1,"confirmations":0,`,
+				`"dropReason":"replaced","replacedBy":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","replacementTxid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","replacementConfirmations":1`,
+			},
+		},
+		{
+			name:        "apiTx v2 replaced twice",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/6a1e0b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"6a1e0b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e",`,
+				`"blockHeight":-1,"confirmations":0,`,
+				`"dropReason":"replaced","replacedBy":"7b2f1c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9f","replacementTxid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","replacementConfirmations":1`,
+			},
+		},
+		{
+			name:        "apiTx v2 replacement not confirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/8c3a2d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"8c3a2d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0",`,
+				`"blockHeight":-1,"confirmations":0,`,
+				`"dropReason":"replaced","replacedBy":"9d4b3e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0b1","replacementTxid":"9d4b3e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0b1"`,
+			},
+		},
+		{
+			name:        "apiAddress v2 normalized address",
+			r:           newGetRequest(ts.URL + "/api/v2/address/%202NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1%0A"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 unconfirmed balance",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mkBg6GwqZ4XdYQ72vTEqiwfgb6T6WRSDm5?details=basic"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mkBg6GwqZ4XdYQ72vTEqiwfgb6T6WRSDm5","balance":"0","totalReceived":"0","totalSent":"0","unconfirmedBalance":"198641974500","unconfirmedTxs":1,"txs":0}`,
+			},
+		},
+		{
+			name:        "apiAddressesBalances",
+			r:           newPostRequest(ts.URL+"/api/v2/addresses/balances", `["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","2MzmAKayJmja784jyHvRUW1bXPget1csRRG","2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"address":"mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","balance":"100000000","totalReceived":"100000000","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1},{"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","balance":"0","totalReceived":"1","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2},{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2}]`,
+			},
+		},
+		{
+			name:        "apiAddressesBalances too many addresses",
+			r:           newPostRequest(ts.URL+"/api/v2/addresses/balances", `["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti","mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Too many addresses, the limit is 3"}`,
+			},
+		},
+		{
+			name:        "apiGetBlock page=1&pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=1&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":4,"itemsOnPage":1,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"`,
+			},
+		},
+		{
+			name:        "apiGetBlock page=2&pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=2&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":2,"totalPages":4,"itemsOnPage":1,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"`,
+			},
+		},
+		{
+			name:        "apiGetBlock page=4&pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=4&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":4,"totalPages":4,"itemsOnPage":1,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db"`,
+			},
+		},
+		{
+			name:        "apiGetBlock page=2&pageSize=3",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=2&pageSize=3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":2,"totalPages":2,"itemsOnPage":3,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db"`,
+			},
+		},
+		{
+			name:        "apiGetBlock page out of range",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=3&pageSize=3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":2,"totalPages":2,"itemsOnPage":3,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db"`,
+			},
+		},
+		{
+			name:        "apiGetBlock page=1&pageSize=4",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?page=1&pageSize=4"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":4,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"`,
+			},
+		},
+		{
+			name:        "apiGetBlock pageSize=0",
+			r:           newGetRequest(ts.URL + "/api/v2/block/225494?pageSize=0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,`,
+				`"txCount":4,`,
+				`"txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability high fee",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b","blocks":1,"probability":1,"feeRate":50,"mempoolVSizeAhead":50000,"blockInclusionRate":1}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability low fee",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0?blocks=3"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0","blocks":3,"probability":0,"feeRate":1,"mempoolVSizeAhead":4100000,"blockInclusionRate":0.125}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability low fee more blocks",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0?blocks=10"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0","blocks":10,"probability":0.5512046813964844,"feeRate":1,"mempoolVSizeAhead":4100000,"blockInclusionRate":0.125}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability parent paid by child",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/2b7a3c1d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"2b7a3c1d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b","blocks":1,"probability":1,"feeRate":10.666666666666666,"mempoolVSizeAhead":600000,"blockInclusionRate":1}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability child with parent",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f","blocks":1,"probability":1,"feeRate":10.666666666666666,"mempoolVSizeAhead":600000,"blockInclusionRate":1}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability confirmed",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","blocks":1,"probability":1,"confirmations":1}`,
+			},
+		},
+		{
+			name:        "apiConfirmationProbability too many blocks",
+			r:           newGetRequest(ts.URL + "/api/v2/confirmation-probability/4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b?blocks=145"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Blocks must be between 1 and 144"}`,
+			},
+		},
+		{
+			name:        "apiNextBlockFee",
+			r:           newGetRequest(ts.URL + "/api/v2/next-block-fee"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"conservative":true,"estimatedFeeRate":0.1,"mempoolFeeRate":20,"mempoolVSize":4700000}`,
+			},
+		},
+		{
+			name:        "apiDifficultyHistory",
+			r:           newGetRequest(ts.URL + "/api/v2/difficulty-history?from=225493&interval=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"targetBlockTime":600,"interval":1,"points":[{"height":225493,"time":1521515026,"difficulty":1,"hashrate":7158278.826666667},{"height":225494,"time":1521595678,"difficulty":2,"hashrate":14316557.653333334}]}`,
+			},
+		},
+		{
+			name:        "apiDifficultyHistory interval",
+			r:           newGetRequest(ts.URL + "/api/v2/difficulty-history?from=225493&to=225494&interval=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"targetBlockTime":600,"interval":2,"points":[{"height":225494,"time":1521595678,"difficulty":2,"hashrate":14316557.653333334}]}`,
+			},
+		},
+		{
+			name:        "apiDifficultyHistory too many points",
+			r:           newGetRequest(ts.URL + "/api/v2/difficulty-history?from=0&interval=1"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Too many points, the limit is 500, increase the interval"}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph depth=1",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/0?depth=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"depth":1,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"spentTxid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","depth":1}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph depth=2",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/0?depth=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"depth":2,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"spentTxid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","depth":1},{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":0,"spentTxid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","depth":2}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph depth=5",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/0?depth=5"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"depth":5,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"spentTxid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","depth":1},{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":0,"spentTxid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","depth":2}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph default depth",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"depth":3,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"spentTxid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","depth":1}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph vout=2",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/2?depth=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"depth":1,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"spentTxid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","depth":1}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph unspent",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vout":0,"depth":3,"edges":[]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph depth too large",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/0?depth=11"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Depth must be between 1 and 10"}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph incorrect vout",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/3"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Passed incorrect vout index 3 for tx effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75, len vout 3"}`,
+			},
+		},
+		{
+			name:        "apiEnrichPsbt",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/enrich", "cHNidP8BAH4CAAAAAnWstJSG1rsiQP2+8qQh9fuOTEO/9Yoca1M9OAn1nv3vAAAAAAD/////day0lIbWuyJA/b7ypCH1+45MQ7/1ihxrUz04CfWe/e8CAAAAAP////8BUAT7cR8BAAAZdqkUAQ05gA+GEiQW4o9IUCms93UHFpKIrAAAAAAAAAEEFgAUoI6ukwB/ImaKteSpyDyM0cMl4+AAAA=="),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"psbt":"cHNidP8BAH4CAAAAAnWstJSG1rsiQP2+8qQh9fuOTEO/9Yoca1M9OAn1nv3vAAAAAAD/////day0lIbWuyJA/b7ypCH1+45MQ7/1ihxrUz04CfWe/e8CAAAAAP////8BUAT7cR8BAAAZdqkUAQ05gA+GEiQW4o9IUCms93UHFpKIrAAAAAAAAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQQWABSgjq6TAH8iZoq15KnIPIzRwyXj4AEBIJQmAAAAAAAAF6kU6SH8SRKjFQePNw2VnyxPe20qaDyHAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA==","inputs":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"value":"1234567890123","script":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"witnessUtxo":false,"nonWitnessUtxo":true},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"value":"9876","script":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"witnessUtxo":true,"nonWitnessUtxo":true}]}`,
+			},
+		},
+		{
+			name:        "apiEnrichPsbt already enriched",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/enrich", "cHNidP8BAH4CAAAAAnWstJSG1rsiQP2+8qQh9fuOTEO/9Yoca1M9OAn1nv3vAAAAAAD/////day0lIbWuyJA/b7ypCH1+45MQ7/1ihxrUz04CfWe/e8CAAAAAP////8BUAT7cR8BAAAZdqkUAQ05gA+GEiQW4o9IUCms93UHFpKIrAAAAAAAAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQQWABSgjq6TAH8iZoq15KnIPIzRwyXj4AEBIJQmAAAAAAAAF6kU6SH8SRKjFQePNw2VnyxPe20qaDyHAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=="),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"psbt":"cHNidP8BAH4CAAAAAnWstJSG1rsiQP2+8qQh9fuOTEO/9Yoca1M9OAn1nv3vAAAAAAD/////day0lIbWuyJA/b7ypCH1+45MQ7/1ihxrUz04CfWe/e8CAAAAAP////8BUAT7cR8BAAAZdqkUAQ05gA+GEiQW4o9IUCms93UHFpKIrAAAAAAAAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQQWABSgjq6TAH8iZoq15KnIPIzRwyXj4AEBIJQmAAAAAAAAF6kU6SH8SRKjFQePNw2VnyxPe20qaDyHAQApAQAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA==","inputs":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"value":"1234567890123","script":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"witnessUtxo":false,"nonWitnessUtxo":true},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"value":"9876","script":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"witnessUtxo":true,"nonWitnessUtxo":true}]}`,
+			},
+		},
+	}
+	performHttpTests(tests, t, ts)
+}
+
+func socketioTestsBitcoinType(t *testing.T, ts *httptest.Server) {
+	type socketioReq struct {
 		Method string        `json:"method"`
 		Params []interface{} `json:"params"`
 	}
@@ -1417,79 +2270,672 @@ func websocketTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 					"currencies": []string{},
 				},
 			},
-			want: `{"id":"37","data":[{"time":1521514800,"txs":1,"received":"1","sent":"0","sentToSelf":"0","rates":{"eur":1301,"usd":2001}}]}`,
+			want: `{"id":"37","data":[{"time":1521514800,"txs":1,"received":"1","sent":"0","sentToSelf":"0","rates":{"eur":1301,"usd":2001}}]}`,
+		},
+		{
+			name: "websocket subscribeNewTransaction",
+			req: websocketReq{
+				Method: "subscribeNewTransaction",
+			},
+			want: `{"id":"38","data":{"subscribed":false,"message":"subscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}}`,
+		},
+		{
+			name: "websocket unsubscribeNewTransaction",
+			req: websocketReq{
+				Method: "unsubscribeNewTransaction",
+			},
+			want: `{"id":"39","data":{"subscribed":false,"message":"unsubscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}}`,
+		},
+		{
+			name: "websocket getBlock",
+			req: websocketReq{
+				Method: "getBlock",
+				Params: map[string]interface{}{
+					"id": "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
+				},
+			},
+			want: `{"id":"40","data":{"error":{"message":"Not supported"}}}`,
+		},
+	}
+
+	// send all requests at once
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ID = strconv.Itoa(i)
+			err = s.WriteJSON(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// wait for all responses
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; i < len(tests); i++ {
+			_, message, err := s.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resp websocketResp
+			err = json.Unmarshal(message, &resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := strconv.Atoi(resp.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.TrimSpace(string(message))
+			if got != tests[id].want {
+				t.Errorf("%s: got %v, want %v", tests[id].name, got, tests[id].want)
+			} else {
+				tests[id].want = "already checked, should not check twice"
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		break
+	case <-time.After(time.Second * 10):
+		t.Error("Timeout while waiting for websocket responses")
+	}
+}
+
+func workerTestsBitcoinType(t *testing.T, s *PublicServer, tc *testChain) {
+	addrDesc := func(address string) string {
+		ad, err := s.chainParser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(ad)
+	}
+	xd, err := s.chainParser.ParseXpub(dbtestdata.Xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	derive := func(change uint32, index uint32) string {
+		ad, err := s.chainParser.DeriveAddressDescriptorsFromTo(xd, change, index, index+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(ad[0])
+	}
+	// getAddress reads the transactions of Addr5 from the backend with the delay and returns if they were read one by one
+	const delay = 200 * time.Millisecond
+	getAddress := func(w *api.Worker, concurrency int) (interface{}, error) {
+		s.is.AddressTxsConcurrency = concurrency
+		defer func() { s.is.AddressTxsConcurrency = 0 }()
+		start := time.Now()
+		a, err := w.GetAddress(dbtestdata.Addr5, 1, 1000, api.AccountDetailsTxHistory, &api.AddressFilter{Vout: api.AddressFilterVoutOff, OnlyConfirmed: true}, "")
+		if err != nil {
+			return nil, err
+		}
+		r := struct {
+			Txids      []string `json:"txids"`
+			Sequential bool     `json:"sequential"`
+		}{Sequential: time.Since(start) >= 2*delay}
+		for _, tx := range a.Transactions {
+			r.Txids = append(r.Txids, tx.Txid)
+		}
+		return r, nil
+	}
+	getBlockReward := func(w *api.Worker, block string) (interface{}, error) {
+		b, err := w.GetBlock(block, 1, 1000)
+		if err != nil {
+			return nil, err
+		}
+		return b.Reward, nil
+	}
+	// the subsidy of the testnet is halved every 210000 blocks
+	subsidyParser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{
+		BlockAddressesToKeep: 1,
+		InitialBlockSubsidy:  5000000000,
+	})
+	tests := []workerTests{
+		{
+			name:  "GetSyncStatus backend ahead",
+			chain: &testChain{BlockChain: tc, chainInfo: &bchain.ChainInfo{Blocks: 225500, Version: "270000", Subversion: "/Satoshi:27.0.0/"}},
+			f:     func(w *api.Worker) (interface{}, error) { return w.GetSyncStatus() },
+			want:  `{"coin":"Fakecoin","shortcut":"FAKE","network":"fakecoin","bestHeight":225494,"backendHeight":225500,"lag":6,"inSync":true,"backendVersion":"270000","backendSubversion":"/Satoshi:27.0.0/"}`,
+		},
+		{
+			name:  "GetSyncStatus in sync",
+			chain: &testChain{BlockChain: tc, chainInfo: &bchain.ChainInfo{Blocks: 225494, Version: "270000"}},
+			f:     func(w *api.Worker) (interface{}, error) { return w.GetSyncStatus() },
+			want:  `{"coin":"Fakecoin","shortcut":"FAKE","network":"fakecoin","bestHeight":225494,"backendHeight":225494,"lag":0,"inSync":true,"backendVersion":"270000"}`,
+		},
+		{
+			name:  "GetSyncStatus backend behind",
+			chain: &testChain{BlockChain: tc, chainInfo: &bchain.ChainInfo{Blocks: 225490, Version: "270000"}},
+			f:     func(w *api.Worker) (interface{}, error) { return w.GetSyncStatus() },
+			want:  `{"coin":"Fakecoin","shortcut":"FAKE","network":"fakecoin","bestHeight":225494,"backendHeight":225490,"lag":0,"inSync":true,"backendVersion":"270000"}`,
+		},
+		{
+			name:  "GetSyncStatus backend error",
+			chain: &testChain{BlockChain: tc, chainInfoErr: errors.New("connection refused")},
+			f:     func(w *api.Worker) (interface{}, error) { return w.GetSyncStatus() },
+			want:  `{"coin":"Fakecoin","shortcut":"FAKE","network":"fakecoin","bestHeight":225494,"backendHeight":0,"lag":0,"inSync":false,"backendError":"GetChainInfo: connection refused"}`,
+		},
+		{
+			// 600k vbytes pay at least 20 sat/vB, the block fills up in the bucket 10-20 sat/vB
+			name: "GetNextBlockFee full mempool",
+			chain: &testChain{BlockChain: tc, histogram: []bchain.MempoolFeeRateBucket{
+				{FeeRate: 100, VSize: 50000},
+				{FeeRate: 50, VSize: 150000},
+				{FeeRate: 20, VSize: 400000},
+				{FeeRate: 10, VSize: 1200000},
+				{FeeRate: 5, VSize: 1500000},
+				{FeeRate: 0, VSize: 0},
+			}},
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetNextBlockFee(true) },
+			want: `{"conservative":true,"estimatedFeeRate":0.1,"mempoolFeeRate":20,"mempoolVSize":3300000}`,
+		},
+		{
+			name: "GetNextBlockFee block filled by the highest bucket",
+			chain: &testChain{BlockChain: tc, histogram: []bchain.MempoolFeeRateBucket{
+				{FeeRate: 1000, VSize: 1500000},
+				{FeeRate: 0, VSize: 100000},
+			}},
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetNextBlockFee(false) },
+			want: `{"conservative":false,"estimatedFeeRate":0.099,"mempoolFeeRate":1000,"mempoolVSize":1600000}`,
+		},
+		{
+			name: "GetNextBlockFee mempool fits to one block",
+			chain: &testChain{BlockChain: tc, histogram: []bchain.MempoolFeeRateBucket{
+				{FeeRate: 10, VSize: 300000},
+				{FeeRate: 1, VSize: 200000},
+			}},
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetNextBlockFee(true) },
+			want: `{"conservative":true,"estimatedFeeRate":0.1,"mempoolFeeRate":0,"mempoolVSize":500000}`,
+		},
+		{
+			name:  "GetEffectiveFeeRate confirmed",
+			chain: &testChain{BlockChain: tc, vsizes: map[string]int64{dbtestdata.TxidB2T1: 173}},
+			f:     func(w *api.Worker) (interface{}, error) { return w.GetEffectiveFeeRate(dbtestdata.TxidB2T1) },
+			want:  `2`,
+		},
+		{
+			name: "GetEffectiveFeeRate parent paid by child",
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetEffectiveFeeRate(testTxidParent) },
+			want: `10.666666666666666`,
+		},
+		{
+			name: "GetEffectiveFeeRate child with parent",
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetEffectiveFeeRate(testTxidChild) },
+			want: `10.666666666666666`,
+		},
+		{
+			name: "GetMempoolTransactionsForXpub",
+			mempool: &testMempool{Mempool: s.mempool, txs: map[string][]bchain.Outpoint{
+				// unused external address within the gap
+				derive(0, 5): {{Txid: "1c4e1be4a0b1ab6e4a9d8bb56a1ab95ca1a4b1d7ac6d1d5a2a4b4b2e9a1f2f3c", Vout: 0}},
+				// change address, the first tx is spending from it
+				derive(1, 0): {
+					{Txid: "9a6b4f2c5e3d7a8b1c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8f0a2b4c6d8e0f2a4b", Vout: -1},
+					{Txid: "1c4e1be4a0b1ab6e4a9d8bb56a1ab95ca1a4b1d7ac6d1d5a2a4b4b2e9a1f2f3c", Vout: 1},
+				},
+				// address beyond the gap
+				derive(0, 100): {{Txid: "5f2e8d1c3b4a5968778695a4b3c2d1e0f0e1d2c3b4a5968778695a4b3c2d1e0f", Vout: 0}},
+				// address not derived from the xpub
+				addrDesc(dbtestdata.Addr1): {{Txid: "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0", Vout: 0}},
+			}},
+			f: func(w *api.Worker) (interface{}, error) {
+				return w.GetMempoolTransactionsForXpub(dbtestdata.Xpub, 0, 0)
+			},
+			want: `["1c4e1be4a0b1ab6e4a9d8bb56a1ab95ca1a4b1d7ac6d1d5a2a4b4b2e9a1f2f3c","9a6b4f2c5e3d7a8b1c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8f0a2b4c6d8e0f2a4b"]`,
+		},
+		{
+			// the transactions of the block have no inputs
+			name:  "GetBlock reward without fees",
+			chain: &testChain{BlockChain: tc, parser: subsidyParser},
+			f:     func(w *api.Worker) (interface{}, error) { return getBlockReward(w, "225493") },
+			want:  `{"subsidy":"2500000000","fees":"0"}`,
+		},
+		{
+			// fees 346 of TxidB2T1, 62 of TxidB2T2 and 876 of TxidB2T3, the coinbase TxidB2T4 is not counted
+			name:  "GetBlock reward with fees",
+			chain: &testChain{BlockChain: tc, parser: subsidyParser},
+			f:     func(w *api.Worker) (interface{}, error) { return getBlockReward(w, "225494") },
+			want:  `{"subsidy":"2500000000","fees":"1284"}`,
+		},
+		{
+			name: "GetSpendingTxs all spent",
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetSpendingTxs(dbtestdata.TxidB1T2) },
+			want: `["` + dbtestdata.TxidB2T1 + `","` + dbtestdata.TxidB2T2 + `","` + dbtestdata.TxidB2T3 + `"]`,
+		},
+		{
+			// output 1 is spent only by the mempool transaction, output 2 is OP_RETURN
+			name: "GetSpendingTxs one of three spent",
+			f:    func(w *api.Worker) (interface{}, error) { return w.GetSpendingTxs(dbtestdata.TxidB2T1) },
+			want: `["` + dbtestdata.TxidB2T2 + `","",""]`,
+		},
+		{
+			name: "GetTxReplaces of replaced transaction",
+			f:    func(w *api.Worker) (interface{}, error) { return s.db.GetTxReplaces(testTxidReplaced1) },
+			want: `null`,
+		},
+		{
+			name:  "GetAddress sequential",
+			chain: &testChain{BlockChain: tc, delay: delay},
+			f:     func(w *api.Worker) (interface{}, error) { return getAddress(w, 1) },
+			want:  `{"txids":["` + dbtestdata.TxidB2T3 + `","` + dbtestdata.TxidB1T2 + `"],"sequential":true}`,
+		},
+		{
+			name:  "GetAddress parallel",
+			chain: &testChain{BlockChain: tc, delay: delay},
+			f:     func(w *api.Worker) (interface{}, error) { return getAddress(w, 4) },
+			want:  `{"txids":["` + dbtestdata.TxidB2T3 + `","` + dbtestdata.TxidB1T2 + `"],"sequential":false}`,
+		},
+	}
+	performWorkerTests(tests, t, s)
+}
+
+func websocketKeepaliveTests(t *testing.T, s *PublicServer, ts *httptest.Server) {
+	pingInterval, pongTimeout := s.websocket.pingInterval, s.websocket.pongTimeout
+	defer func() { s.websocket.pingInterval, s.websocket.pongTimeout = pingInterval, pongTimeout }()
+	s.websocket.pingInterval = 100 * time.Millisecond
+	s.websocket.pongTimeout = 200 * time.Millisecond
+	url := strings.Replace(ts.URL, "http://", "ws://", 1) + "/websocket"
+
+	// dial connects a client counting the received pings, the client responds with pong only if respond is set
+	dial := func(respond bool) (*websocket.Conn, *int32, chan error) {
+		c, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pings int32
+		c.SetPingHandler(func(data string) error {
+			atomic.AddInt32(&pings, 1)
+			if respond {
+				return c.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+			}
+			return nil
+		})
+		// the control frames are processed only when reading from the connection
+		done := make(chan error, 1)
+		go func() {
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					done <- err
+					return
+				}
+			}
+		}()
+		return c, &pings, done
+	}
+
+	t.Run("websocket keepalive responsive client", func(t *testing.T) {
+		c, pings, done := dial(true)
+		defer c.Close()
+		select {
+		case err := <-done:
+			t.Fatalf("connection closed: %v", err)
+		case <-time.After(550 * time.Millisecond):
+		}
+		if p := atomic.LoadInt32(pings); p < 3 {
+			t.Errorf("received %d pings, want at least 3", p)
+		}
+	})
+
+	t.Run("websocket keepalive non-responsive client", func(t *testing.T) {
+		c, pings, done := dial(false)
+		defer c.Close()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("non-responsive client was not disconnected")
+		}
+		if p := atomic.LoadInt32(pings); p < 1 {
+			t.Errorf("received %d pings, want at least 1", p)
+		}
+	})
+}
+
+func websocketMaxSubscriptionsTests(t *testing.T, s *PublicServer) {
+	maxSubscriptions := s.websocket.maxSubscriptions
+	defer func() { s.websocket.maxSubscriptions = maxSubscriptions }()
+	s.websocket.maxSubscriptions = 3
+	c := &websocketChannel{id: 1, out: make(chan *WsRes, outChannelSize), alive: true}
+	addrDescs := func(addresses ...string) []string {
+		t.Helper()
+		rv := make([]string, len(addresses))
+		for i, a := range addresses {
+			ad, err := s.chainParser.GetAddrDescFromAddress(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rv[i] = string(ad)
+		}
+		return rv
+	}
+	tests := []struct {
+		name    string
+		do      func() (interface{}, error)
+		wantErr bool
+	}{
+		{
+			name: "subscribeNewBlock",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeNewBlock(c, &WsReq{ID: "1"})
+			},
+		},
+		{
+			name: "subscribeAddresses up to the limit",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeAddresses(c, addrDescs(dbtestdata.Addr1, dbtestdata.Addr2), 0, &WsReq{ID: "2"})
+			},
+		},
+		{
+			name: "subscribeFiatRates over the limit",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeFiatRates(c, &WsSubscribeFiatRatesReq{}, &WsReq{ID: "3"})
+			},
+			wantErr: true,
 		},
 		{
-			name: "websocket subscribeNewTransaction",
-			req: websocketReq{
-				Method: "subscribeNewTransaction",
+			name: "subscribeAddresses replacing the addresses over the limit",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeAddresses(c, addrDescs(dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5), 0, &WsReq{ID: "4"})
 			},
-			want: `{"id":"38","data":{"subscribed":false,"message":"subscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}}`,
+			wantErr: true,
 		},
 		{
-			name: "websocket unsubscribeNewTransaction",
-			req: websocketReq{
-				Method: "unsubscribeNewTransaction",
+			name: "unsubscribeNewBlock",
+			do: func() (interface{}, error) {
+				return s.websocket.unsubscribeNewBlock(c)
 			},
-			want: `{"id":"39","data":{"subscribed":false,"message":"unsubscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}}`,
 		},
 		{
-			name: "websocket getBlock",
-			req: websocketReq{
-				Method: "getBlock",
-				Params: map[string]interface{}{
-					"id": "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
-				},
+			name: "subscribeFiatRates after unsubscribe",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeFiatRates(c, &WsSubscribeFiatRatesReq{}, &WsReq{ID: "5"})
 			},
-			want: `{"id":"40","data":{"error":{"message":"Not supported"}}}`,
+		},
+		{
+			name: "subscribeNewBlock over the limit",
+			do: func() (interface{}, error) {
+				return s.websocket.subscribeNewBlock(c, &WsReq{ID: "6"})
+			},
+			wantErr: true,
 		},
 	}
-
-	// send all requests at once
-	for i, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.req.ID = strconv.Itoa(i)
-			err = s.WriteJSON(tt.req)
-			if err != nil {
-				t.Fatal(err)
-			}
-		})
+	for _, tt := range tests {
+		if _, err := tt.do(); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
 	}
 
-	// wait for all responses
-	done := make(chan struct{})
+	// the rejected subscriptions did not replace the previous ones
+	if len(c.addrDescs) != 2 {
+		t.Errorf("subscribed addresses = %v, want 2", len(c.addrDescs))
+	}
+	if _, ok := s.websocket.newBlockSubscriptions[c]; ok {
+		t.Error("rejected subscribeNewBlock was subscribed")
+	}
+	if _, err := s.websocket.unsubscribeAddresses(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.websocket.unsubscribeFiatRates(c); err != nil {
+		t.Fatal(err)
+	}
+}
 
-	go func() {
-		defer close(done)
-		for i := 0; i < len(tests); i++ {
-			_, message, err := s.ReadMessage()
-			if err != nil {
-				t.Fatal(err)
+// subscribeAddressesMinConfirmationsTests reconnects the block 225494 several times, the block is connected at the end
+func subscribeAddressesMinConfirmationsTests(t *testing.T, s *PublicServer) {
+	type notification struct {
+		Address   string  `json:"address"`
+		Tx        *api.Tx `json:"tx"`
+		Txid      string  `json:"txid"`
+		Retracted bool    `json:"retracted"`
+	}
+	c := &websocketChannel{id: 1, out: make(chan *WsRes, outChannelSize), alive: true}
+	// newBlock simulates notification about a new block and returns the sent notifications
+	newBlock := func() []notification {
+		t.Helper()
+		s.websocket.onNewBlockMinConfirmations()
+		var rv []notification
+		for len(c.out) > 0 {
+			res := <-c.out
+			if res.ID != "1" {
+				t.Errorf("notification id = %v, want 1", res.ID)
 			}
-			var resp websocketResp
-			err = json.Unmarshal(message, &resp)
+			b, err := json.Marshal(res.Data)
 			if err != nil {
 				t.Fatal(err)
 			}
-			id, err := strconv.Atoi(resp.ID)
-			if err != nil {
+			var n notification
+			if err := json.Unmarshal(b, &n); err != nil {
 				t.Fatal(err)
 			}
-			got := strings.TrimSpace(string(message))
-			if got != tests[id].want {
-				t.Errorf("%s: got %v, want %v", tests[id].name, got, tests[id].want)
-			} else {
-				tests[id].want = "already checked, should not check twice"
+			rv = append(rv, n)
+		}
+		return rv
+	}
+	checkNotifications := func(name string, got []notification, wantTxid string, wantRetracted bool) {
+		t.Helper()
+		if wantTxid == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: got notifications %+v, want none", name, got)
 			}
+			return
 		}
-	}()
+		if len(got) != 1 {
+			t.Fatalf("%s: got notifications %+v, want one", name, got)
+		}
+		n := got[0]
+		txid := n.Txid
+		if n.Tx != nil {
+			txid = n.Tx.Txid
+		}
+		if n.Address != dbtestdata.Addr5 || txid != wantTxid || n.Retracted != wantRetracted || (n.Tx == nil) != wantRetracted {
+			t.Errorf("%s: got notification %+v, want txid %v, retracted %v", name, n, wantTxid, wantRetracted)
+		}
+	}
+	connectBlock2 := func() {
+		t.Helper()
+		if err := s.db.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(s.chainParser)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disconnectBlock2 := func() {
+		t.Helper()
+		if err := s.db.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	select {
-	case <-done:
-		break
-	case <-time.After(time.Second * 10):
-		t.Error("Timeout while waiting for websocket responses")
+	// subscribe at height 225493, the transactions are notified after 2 confirmations
+	disconnectBlock2()
+	addrDesc, err := s.chainParser.GetAddrDescFromAddress(dbtestdata.Addr5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.websocket.subscribeAddresses(c, []string{string(addrDesc)}, 2, &WsReq{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	checkNotifications("1 confirmation", newBlock(), "", false)
+
+	// the transaction of block 225493 reaches 2 confirmations, the transaction of block 225494 has only 1
+	connectBlock2()
+	checkNotifications("2 confirmations", newBlock(), dbtestdata.TxidB1T2, false)
+	checkNotifications("no new confirmations", newBlock(), "", false)
+
+	// reorg drops the transaction below the required confirmations
+	disconnectBlock2()
+	checkNotifications("reorg", newBlock(), dbtestdata.TxidB1T2, true)
+
+	// the transaction is notified again after the chain advances
+	connectBlock2()
+	checkNotifications("2 confirmations after reorg", newBlock(), dbtestdata.TxidB1T2, false)
+
+	// no notifications after unsubscribe
+	if _, err := s.websocket.unsubscribeAddresses(c); err != nil {
+		t.Fatal(err)
+	}
+	disconnectBlock2()
+	checkNotifications("unsubscribed", newBlock(), "", false)
+	connectBlock2()
+}
+
+func xpubCacheEfficiency(t *testing.T, status string) float64 {
+	var m dto.Metric
+	if err := metrics.XPubCacheEfficiency.With(common.Labels{"status": status}).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// xpubIncrementalCacheTests reconnects the block 225494, the block is connected at the end
+func xpubIncrementalCacheTests(t *testing.T, s *PublicServer) {
+	// start with the first block only
+	if err := s.db.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	// gap not used by other tests so that the xpub is not in the cache
+	const gap = 7
+	filter := &api.AddressFilter{Vout: api.AddressFilterVoutOff}
+	a, err := s.api.GetXpubAddress(dbtestdata.Xpub, 1, 1000, api.AccountDetailsBasic, filter, gap, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.BalanceSat.String(); got != "1" {
+		t.Errorf("GetXpubAddress() balance = %v, want 1", got)
+	}
+
+	if err := s.db.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(s.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	hits, misses := xpubCacheEfficiency(t, "hit"), xpubCacheEfficiency(t, "miss")
+	a, err = s.api.GetXpubAddress(dbtestdata.Xpub, 1, 1000, api.AccountDetailsBasic, filter, gap, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.BalanceSat.String(); got != "118641975500" {
+		t.Errorf("GetXpubAddress() balance = %v, want 118641975500", got)
+	}
+	if a.Txs != 3 {
+		t.Errorf("GetXpubAddress() txs = %v, want 3", a.Txs)
+	}
+	// only the addresses touched by the new block are reloaded, newly derived addresses are always loaded
+	hits, misses = xpubCacheEfficiency(t, "hit")-hits, xpubCacheEfficiency(t, "miss")-misses
+	if hits == 0 || misses == 0 {
+		t.Errorf("GetXpubAddress() cache hits %v, misses %v, want both non zero", hits, misses)
+	}
+}
+
+// minedBlockTests connects the block 225495 mining the mempool transaction testTxidMined
+// and funding the xpub change address m/49'/1'/33'/1/30, beyond the default gap
+func minedBlockTests(t *testing.T, s *PublicServer, tc *testChain, ts *httptest.Server) {
+	xd, err := s.chainParser.ParseXpub(dbtestdata.Xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad, err := s.chainParser.DeriveAddressDescriptorsFromTo(xd, 1, 30, 31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, _, err := s.chainParser.GetAddressesFromAddrDesc(ad[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mined := *tc.txs[testTxidMined]
+	mined.Confirmations = 1
+	mined.Blocktime = 1521596000
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225495,
+			Hash:   "0000000000000006d6f3c5e4a26da8fa1f6f2aeca0ea23e6b3d0bd7bbac5e1a0",
+			Time:   1521596000,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: testTxidCoinbase,
+				Vin:  []bchain.Vin{{Coinbase: "03c7700304"}},
+				Vout: []bchain.Vout{
+					{
+						N:        0,
+						ValueSat: *big.NewInt(12345),
+						ScriptPubKey: bchain.ScriptPubKey{
+							Hex:       hex.EncodeToString(ad[0]),
+							Addresses: addresses,
+						},
+					},
+				},
+			},
+			mined,
+		},
+	}
+	if err := s.db.ConnectBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	tc.txs[testTxidMined] = &mined
+	addrDesc, err := s.chainParser.GetAddrDescFromAddress(testAddrMined)
+	if err != nil {
+		t.Fatal(err)
 	}
+	delete(tc.mempool.txs, string(addrDesc))
+
+	tests := []httpTests{
+		{
+			name:        "apiAddress v2 mined",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mkBg6GwqZ4XdYQ72vTEqiwfgb6T6WRSDm5?details=basic"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"mkBg6GwqZ4XdYQ72vTEqiwfgb6T6WRSDm5","balance":"198641974500","totalReceived":"198641974500","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph mined",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71/1?depth=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":1,"depth":2,"edges":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":1,"spentTxid":"d3a1b2c4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90","depth":1}]}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 default gap",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=basic"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"balance":"118641975500"`,
+			},
+		},
+		{
+			name:        "apiXpub v2 raised gap",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=basic&gap=30"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"balance":"118641987845"`,
+			},
+		},
+		{
+			name:        "apiXpub v2 raised changeGap",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=basic&changeGap=30"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"balance":"118641987845"`,
+			},
+		},
+		{
+			name:        "apiXpub v2 raised gap, low changeGap",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=basic&gap=30&changeGap=5"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"balance":"118641975500"`,
+			},
+		},
+	}
+	performHttpTests(tests, t, ts)
 }
 
 // fixedTimeNow returns always 2022-09-15 12:43:56 UTC
@@ -1516,11 +2962,291 @@ func setupChain(t *testing.T) (bchain.BlockChainParser, bchain.BlockChain) {
 	return parser, chain
 }
 
+// the transactions of the test mempool and of the dropped transactions, they are not in the blocks of the test chain
+const (
+	testTxidParent             = "2b7a3c1d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b"
+	testTxidChild              = "8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f"
+	testTxidHighFee            = "4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b"
+	testTxidLowFee             = "9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0"
+	testTxidMined              = "d3a1b2c4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+	testTxidCoinbase           = "a4d6c7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6"
+	testTxidOriginal           = "6a1e0b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e"
+	testTxidBumped             = "7b2f1c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9f"
+	testTxidPending            = "8c3a2d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0"
+	testTxidPendingReplacement = "9d4b3e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5061728394a5b6c7d8e9fa0b1"
+	testTxidReplaced1          = "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b"
+	testTxidReplaced2          = "2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c"
+	testTxidReplaced3          = "3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d"
+
+	// addresses not used by the blocks of the test chain
+	testAddrParent = "mh5CE8Nbj38iND267s4XnvhSmhDW7yWc6Q"
+	testAddrChild  = "midSACfDe3qAxJZZXA9gkwBZgPqJJUpy1w"
+	testAddrMined  = "mkBg6GwqZ4XdYQ72vTEqiwfgb6T6WRSDm5"
+)
+
+// testChain is the test blockchain extended by the data which the fake blockchain does not provide,
+// the methods fall back to the embedded blockchain if the data are not set
+type testChain struct {
+	bchain.BlockChain
+	parser        bchain.BlockChainParser
+	txs           map[string]*bchain.Tx
+	vsizes        map[string]int64
+	hex           map[string]string
+	mempool       *testMempool
+	mempoolInfo   *bchain.MempoolInfo
+	chainInfo     *bchain.ChainInfo
+	chainInfoErr  error
+	histogram     []bchain.MempoolFeeRateBucket
+	blockFeeRates []float64
+	bits          map[string]uint32
+	delay         time.Duration
+}
+
+func (c *testChain) GetChainParser() bchain.BlockChainParser {
+	if c.parser != nil {
+		return c.parser
+	}
+	return c.BlockChain.GetChainParser()
+}
+
+func (c *testChain) GetTransaction(txid string) (*bchain.Tx, error) {
+	time.Sleep(c.delay)
+	tx, found := c.txs[txid]
+	if !found {
+		var err error
+		if tx, err = c.BlockChain.GetTransaction(txid); err != nil {
+			return nil, err
+		}
+	}
+	if vsize, found := c.vsizes[txid]; found {
+		t := *tx
+		t.VSize = vsize
+		tx = &t
+	}
+	return tx, nil
+}
+
+func (c *testChain) GetTransactionSpecific(tx *bchain.Tx) (json.RawMessage, error) {
+	if _, found := c.txs[tx.Txid]; found {
+		return json.Marshal(tx)
+	}
+	return c.BlockChain.GetTransactionSpecific(tx)
+}
+
+func (c *testChain) GetTransactionHex(txid string) (string, error) {
+	if h, found := c.hex[txid]; found {
+		return h, nil
+	}
+	return "", bchain.ErrTxNotFound
+}
+
+func (c *testChain) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
+	m, err := c.BlockChain.CreateMempool(chain)
+	if err != nil || c.mempool == nil {
+		return m, err
+	}
+	c.mempool.Mempool = m
+	return c.mempool, nil
+}
+
+func (c *testChain) GetMempoolInfo() (*bchain.MempoolInfo, error) {
+	if c.mempoolInfo != nil {
+		return c.mempoolInfo, nil
+	}
+	return c.BlockChain.GetMempoolInfo()
+}
+
+func (c *testChain) GetChainInfo() (*bchain.ChainInfo, error) {
+	if c.chainInfo != nil || c.chainInfoErr != nil {
+		return c.chainInfo, c.chainInfoErr
+	}
+	return c.BlockChain.GetChainInfo()
+}
+
+func (c *testChain) GetMempoolFeeHistogram() ([]bchain.MempoolFeeRateBucket, error) {
+	if c.histogram != nil {
+		return c.histogram, nil
+	}
+	return c.BlockChain.GetMempoolFeeHistogram()
+}
+
+func (c *testChain) GetBlockFeeRatePercentiles(height uint32) ([]float64, error) {
+	if c.blockFeeRates != nil {
+		return c.blockFeeRates, nil
+	}
+	return c.BlockChain.GetBlockFeeRatePercentiles(height)
+}
+
+// GetBlockHeaderHex returns the block header with the compact target given by the block hash
+func (c *testChain) GetBlockHeaderHex(hash string) (string, error) {
+	if c.bits == nil {
+		return c.BlockChain.GetBlockHeaderHex(hash)
+	}
+	bits, found := c.bits[hash]
+	if !found {
+		bits = 0x1d00ffff
+	}
+	h := wire.BlockHeader{Version: 1, Bits: bits, Timestamp: time.Unix(1521595678, 0)}
+	var buf bytes.Buffer
+	if err := h.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// testMempool is the mempool returning fixed transactions, the methods fall back to the embedded mempool
+// if the data are not set
+type testMempool struct {
+	bchain.Mempool
+	txs     map[string][]bchain.Outpoint
+	times   map[string]uint32
+	entries bchain.MempoolTxidEntries
+	dropped map[string]*bchain.MempoolDroppedTx
+}
+
+func (m *testMempool) GetAllEntries() bchain.MempoolTxidEntries {
+	if m.entries != nil {
+		return m.entries
+	}
+	return m.Mempool.GetAllEntries()
+}
+
+func (m *testMempool) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor) ([]bchain.Outpoint, error) {
+	if o, found := m.txs[string(addrDesc)]; found {
+		return o, nil
+	}
+	return m.Mempool.GetAddrDescTransactions(addrDesc)
+}
+
+func (m *testMempool) GetTransactionTime(txid string) uint32 {
+	if t, found := m.times[txid]; found {
+		return t
+	}
+	return m.Mempool.GetTransactionTime(txid)
+}
+
+func (m *testMempool) GetDroppedTransaction(txid string) *bchain.MempoolDroppedTx {
+	if d, found := m.dropped[txid]; found {
+		return d
+	}
+	return m.Mempool.GetDroppedTransaction(txid)
+}
+
+// newTestChain returns the test blockchain with the transactions in the mempool:
+// the low fee parent with the high fee child, the high and low fee transactions, the transaction mined by minedBlockTests
+// and the replaced transactions dropped from the mempool
+func newTestChain(t *testing.T, parser bchain.BlockChainParser, chain bchain.BlockChain) *testChain {
+	addrDesc := func(address string) string {
+		ad, err := parser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(ad)
+	}
+	newTx := func(txid string, vin bchain.Vin, value int64, address string, vsize int64) *bchain.Tx {
+		return &bchain.Tx{
+			Txid: txid,
+			Vin:  []bchain.Vin{vin},
+			Vout: []bchain.Vout{
+				{
+					ValueSat: *big.NewInt(value),
+					ScriptPubKey: bchain.ScriptPubKey{
+						Hex:       dbtestdata.AddressToPubKeyHex(address, parser),
+						Addresses: []string{address},
+					},
+				},
+			},
+			VSize: vsize,
+		}
+	}
+	newDroppedTx := func(txid string, replacedBy string) *bchain.MempoolDroppedTx {
+		return &bchain.MempoolDroppedTx{
+			Tx: &bchain.MempoolTx{
+				Txid: txid,
+				Vin:  []bchain.MempoolVin{{Vin: bchain.Vin{Txid: dbtestdata.TxidB1T2, Sequence: 0xfffffffd}}},
+				Vout: []bchain.Vout{{ValueSat: *big.NewInt(1000)}},
+			},
+			Reason:     bchain.MempoolDropReplaced,
+			ReplacedBy: replacedBy,
+		}
+	}
+	times := map[string]uint32{
+		testTxidParent:  1521595700,
+		testTxidChild:   1521595710,
+		testTxidHighFee: 1521595720,
+		testTxidLowFee:  1521595730,
+		testTxidMined:   1521595740,
+	}
+	var entries bchain.MempoolTxidEntries
+	for _, txid := range []string{testTxidParent, testTxidChild, testTxidHighFee, testTxidLowFee, testTxidMined} {
+		entries = append(entries, bchain.MempoolTxidEntry{Txid: txid, Time: times[txid]})
+	}
+	info := &bchain.MempoolInfo{Size: 5, Bytes: 600}
+	info.TotalFeeSat.SetInt64(9300)
+	info.MinFeeSat.SetInt64(1000)
+	return &testChain{
+		BlockChain: chain,
+		txs: map[string]*bchain.Tx{
+			// low fee parent, fee 200 sat, vsize 200, and high fee child spending it, fee 3000 sat, vsize 100
+			testTxidParent: newTx(testTxidParent, bchain.Vin{Txid: dbtestdata.TxidB2T1, Vout: 1}, dbtestdata.SatB2T1A7.Int64()-200, testAddrParent, 200),
+			testTxidChild:  newTx(testTxidChild, bchain.Vin{Txid: testTxidParent, Vout: 0}, dbtestdata.SatB2T1A7.Int64()-3200, testAddrChild, 100),
+			// fee rates 50 and 1 sat/vB
+			testTxidHighFee: newTx(testTxidHighFee, bchain.Vin{Txid: dbtestdata.TxidB2T2, Vout: 0}, dbtestdata.SatB2T2A8.Int64()-5000, testAddrChild, 100),
+			testTxidLowFee:  newTx(testTxidLowFee, bchain.Vin{Txid: dbtestdata.TxidB2T3, Vout: 0}, dbtestdata.SatB2T3A5.Int64()-100, testAddrChild, 100),
+			testTxidMined:   newTx(testTxidMined, bchain.Vin{Txid: dbtestdata.TxidB2T2, Vout: 1}, dbtestdata.SatB2T2A9.Int64()-1000, testAddrMined, 100),
+		},
+		hex: map[string]string{
+			dbtestdata.TxidB1T2: "0100000001000000000000000000000000000000000000000000000000000000000000000000000000",
+		},
+		mempool: &testMempool{
+			txs: map[string][]bchain.Outpoint{
+				addrDesc(testAddrParent): {{Txid: testTxidParent, Vout: 0}, {Txid: testTxidChild, Vout: ^int32(0)}},
+				addrDesc(testAddrChild):  {{Txid: testTxidChild, Vout: 0}, {Txid: testTxidHighFee, Vout: 0}, {Txid: testTxidLowFee, Vout: 0}},
+				addrDesc(testAddrMined):  {{Txid: testTxidMined, Vout: 0}},
+			},
+			times:   times,
+			entries: entries,
+			dropped: map[string]*bchain.MempoolDroppedTx{
+				// replaced twice, the last replacement is confirmed
+				testTxidOriginal: newDroppedTx(testTxidOriginal, testTxidBumped),
+				testTxidBumped:   newDroppedTx(testTxidBumped, dbtestdata.TxidB2T3),
+				// the replacement is not confirmed
+				testTxidPending: newDroppedTx(testTxidPending, testTxidPendingReplacement),
+			},
+		},
+		mempoolInfo: info,
+		// 1.5M vbytes pay at least 5 sat/vB
+		histogram: []bchain.MempoolFeeRateBucket{
+			{FeeRate: 100, VSize: 50000},
+			{FeeRate: 50, VSize: 150000},
+			{FeeRate: 20, VSize: 400000},
+			{FeeRate: 10, VSize: 1200000},
+			{FeeRate: 5, VSize: 1500000},
+			{FeeRate: 2, VSize: 800000},
+			{FeeRate: 1, VSize: 600000},
+			{FeeRate: 0, VSize: 0},
+		},
+		blockFeeRates: []float64{8, 12, 20, 35, 60},
+		// the difficulty of the block 225494 is 2
+		bits: map[string]uint32{"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6": 0x1c7fff80},
+	}
+}
+
 func Test_PublicServer_BitcoinType(t *testing.T) {
 	parser, chain := setupChain(t)
+	tc := newTestChain(t, parser, chain)
 
-	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	s, dbpath := setupPublicHTTPServer(parser, tc, t, false)
 	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.is.MaxBatchAddresses = 3
+	// the mempool resync notifies about the transactions, a later notification does not overwrite the first seen time
+	// and a repeated notification of the replacement is ignored
+	s.db.StoreMempoolTxFirstSeen(&bchain.MempoolTx{Txid: dbtestdata.TxidB1T2, Blocktime: 1521514900})
+	s.db.StoreMempoolTxFirstSeen(&bchain.MempoolTx{Txid: dbtestdata.TxidB1T2, Blocktime: 1521515000})
+	s.db.StoreMempoolTxReplacement(testTxidReplaced1, dbtestdata.TxidB1T2)
+	s.db.StoreMempoolTxReplacement(testTxidReplaced2, dbtestdata.TxidB1T2)
+	s.db.StoreMempoolTxReplacement(testTxidReplaced1, dbtestdata.TxidB1T2)
+	s.db.StoreMempoolTxReplacement(testTxidReplaced3, testTxidParent)
 	s.ConnectFullPublicInterface()
 	// take the handler of the public server and pass it to the test server
 	ts := httptest.NewServer(s.https.Handler)
@@ -1529,6 +3255,13 @@ func Test_PublicServer_BitcoinType(t *testing.T) {
 	httpTestsBitcoinType(t, ts)
 	socketioTestsBitcoinType(t, ts)
 	websocketTestsBitcoinType(t, ts)
+	workerTestsBitcoinType(t, s, tc)
+	// the tests changing the state of the server run after the tests of the initial state
+	websocketKeepaliveTests(t, s, ts)
+	websocketMaxSubscriptionsTests(t, s)
+	subscribeAddressesMinConfirmationsTests(t, s)
+	xpubIncrementalCacheTests(t, s)
+	minedBlockTests(t, s, tc, ts)
 }
 
 func httpTestsExtendedIndex(t *testing.T, ts *httptest.Server) {
@@ -1545,7 +3278,7 @@ func httpTestsExtendedIndex(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true,"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentHeight":225494,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"isAddress":true},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"],"isAddress":true},{"value":"0","n":2,"hex":"6a072020f1686f6a20","addresses":["OP_RETURN 2020f1686f6a20"],"isAddress":false}],"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"confirmations":1,"blockTime":1521595678,"value":"1234567902122","valueIn":"1234567902468","fees":"346"}`,
+				`{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true,"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentHeight":225494,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"isAddress":true},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"],"isAddress":true},{"value":"0","n":2,"hex":"6a072020f1686f6a20","addresses":["OP_RETURN 2020f1686f6a20"],"isAddress":false}],"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"blockIndex":0,"confirmations":1,"blockTime":1521595678,"value":"1234567902122","valueIn":"1234567902468","fees":"346"}`,
 			},
 		},
 		{
@@ -1554,7 +3287,7 @@ func httpTestsExtendedIndex(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"isOwn":true,"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true,"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentHeight":225494,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"isAddress":true},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"],"isAddress":true},{"value":"0","n":2,"hex":"6a072020f1686f6a20","addresses":["OP_RETURN 2020f1686f6a20"],"isAddress":false}],"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"confirmations":1,"blockTime":1521595678,"value":"1234567902122","valueIn":"1234567902468","fees":"346"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494,"hex":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"isOwn":true},{"value":"1","n":1,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentIndex":1,"spentHeight":225494,"hex":"a91452724c5178682f70e0ba31c6ec0633755a3b41d987","addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"spentTxId":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","spentHeight":225494,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"isOwn":true,"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true,"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentHeight":225494,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"isAddress":true},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"],"isAddress":true},{"value":"0","n":2,"hex":"6a072020f1686f6a20","addresses":["OP_RETURN 2020f1686f6a20"],"isAddress":false}],"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"blockIndex":0,"confirmations":1,"blockTime":1521595678,"value":"1234567902122","valueIn":"1234567902468","fees":"346"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494,"hex":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true,"isOwn":true},{"value":"1","n":1,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentIndex":1,"spentHeight":225494,"hex":"a91452724c5178682f70e0ba31c6ec0633755a3b41d987","addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"spentTxId":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","spentHeight":225494,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"blockIndex":1,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
 			},
 		},
 		{
@@ -1563,7 +3296,34 @@ func httpTestsExtendedIndex(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","nextBlockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1521515026,"version":0,"merkleRoot":"","nonce":"","bits":"","difficulty":"","txCount":2,"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"],"isAddress":true},{"value":"12345","n":1,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentIndex":1,"spentHeight":225494,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true},{"value":"12345","n":2,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"100024690","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true},{"value":"1","n":1,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentIndex":1,"spentHeight":225494,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"spentTxId":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","spentHeight":225494,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","nextBlockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225493,"confirmations":2,"size":1234567,"time":1521515026,"version":0,"merkleRoot":"","nonce":"","bits":"","difficulty":"","txCount":2,"totalOutputs":6,"reward":{"fees":"0"},"txs":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vin":[],"vout":[{"value":"100000000","n":0,"addresses":["mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti"],"isAddress":true},{"value":"12345","n":1,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentIndex":1,"spentHeight":225494,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true},{"value":"12345","n":2,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"blockIndex":0,"confirmations":2,"blockTime":1521515026,"value":"100024690","valueIn":"0","fees":"0"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"spentTxId":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","spentHeight":225494,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"isAddress":true},{"value":"1","n":1,"spent":true,"spentTxId":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","spentIndex":1,"spentHeight":225494,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"isAddress":true},{"value":"9876","n":2,"spent":true,"spentTxId":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","spentHeight":225494,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"isAddress":true}],"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"blockIndex":1,"confirmations":2,"blockTime":1521515026,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/0?depth=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"depth":2,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":0,"spentTxid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","depth":1},{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":0,"spentTxid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","depth":2}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph default depth",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75/1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"depth":3,"edges":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"spentTxid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","depth":1}]}`,
+			},
+		},
+		{
+			name:        "apiSpendingGraph unspent",
+			r:           newGetRequest(ts.URL + "/api/v2/spending-graph/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07/0"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vout":0,"depth":3,"edges":[]}`,
 			},
 		},
 	}
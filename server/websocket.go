@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"math/big"
+	"net"
 	"net/http"
 	"runtime/debug"
 	"strconv"
@@ -27,6 +28,9 @@ const defaultTimeout = 60 * time.Second
 // allRates is a special "currency" parameter that means all available currencies
 const allFiatRates = "!ALL!"
 
+// minConfReorgDepth is the number of blocks after reaching minConfirmations, for which the notified transactions are checked for reorg
+const minConfReorgDepth = 100
+
 var (
 	// ErrorMethodNotAllowed is returned when client tries to upgrade method other than GET
 	ErrorMethodNotAllowed = errors.New("Method not allowed")
@@ -43,6 +47,9 @@ type websocketChannel struct {
 	alive         bool
 	aliveLock     sync.Mutex
 	addrDescs     []string // subscribed address descriptors as strings
+	// number of subscriptions by the subscribe method, maintained only if the number of subscriptions per connection is limited
+	subscriptions     map[string]int
+	subscriptionsLock sync.Mutex
 }
 
 // WebsocketServer is a handle to websocket server
@@ -67,6 +74,33 @@ type WebsocketServer struct {
 	fiatRatesSubscriptions          map[string]map[*websocketChannel]string
 	fiatRatesTokenSubscriptions     map[*websocketChannel][]string
 	fiatRatesSubscriptionsLock      sync.Mutex
+	minConfSubscriptions            map[*websocketChannel]*minConfSubscription
+	minConfSubscriptionsLock        sync.Mutex
+	pingInterval                    time.Duration
+	pongTimeout                     time.Duration
+	maxSubscriptions                int
+	// trustedProxies are the networks of the reverse proxies whose X-Forwarded-For and X-Real-Ip headers are honored
+	trustedProxies []*net.IPNet
+}
+
+// minConfSubscription is a subscription of addresses notified only when their transactions reach minConfirmations
+type minConfSubscription struct {
+	id               string
+	addrDescs        []string
+	minConfirmations uint32
+	// fromHeight is the lowest block height of notified transactions, transactions confirmed enough before the subscription are not notified
+	fromHeight uint32
+	notified   map[minConfTx]minConfBlock
+}
+
+type minConfTx struct {
+	addrDesc string
+	txid     string
+}
+
+type minConfBlock struct {
+	height uint32
+	hash   string
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
@@ -79,6 +113,10 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 	if err != nil {
 		return nil, err
 	}
+	trustedProxies, err := parseTrustedProxies(is.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 	s := &WebsocketServer{
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize:  1024 * 32,
@@ -100,6 +138,11 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 		addressSubscriptions:        make(map[string]map[*websocketChannel]string),
 		fiatRatesSubscriptions:      make(map[string]map[*websocketChannel]string),
 		fiatRatesTokenSubscriptions: make(map[*websocketChannel][]string),
+		minConfSubscriptions:        make(map[*websocketChannel]*minConfSubscription),
+		pingInterval:                is.WsPingInterval,
+		pongTimeout:                 is.WsPongTimeout,
+		maxSubscriptions:            is.WsMaxSubscriptions,
+		trustedProxies:              trustedProxies,
 	}
 	return s, nil
 }
@@ -109,12 +152,84 @@ func checkOrigin(r *http.Request) bool {
 	return true
 }
 
-func getIP(r *http.Request) string {
-	ip := r.Header.Get("X-Real-Ip")
-	if ip != "" {
+// parseTrustedProxies parses the list of IP addresses and CIDR ranges of trusted reverse proxies
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var rv []*net.IPNet
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.Errorf("Invalid trusted proxy %v", p)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			rv = append(rv, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Annotatef(err, "Invalid trusted proxy %v", p)
+		}
+		rv = append(rv, n)
+	}
+	return rv, nil
+}
+
+func isTrustedProxy(ip string, trustedProxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// getIP returns the IP address of the client
+// if no trusted proxies are configured, the X-Real-Ip header is honored from any client
+// otherwise the X-Forwarded-For (or X-Real-Ip) header is honored only if the request comes from a trusted proxy,
+// the client is the last address in X-Forwarded-For which is not a trusted proxy
+func getIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	if len(trustedProxies) == 0 {
+		ip := r.Header.Get("X-Real-Ip")
+		if ip != "" {
+			return ip
+		}
+		return r.RemoteAddr
+	}
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		var ip string
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip = strings.TrimSpace(hops[i])
+			if !isTrustedProxy(ip, trustedProxies) {
+				break
+			}
+		}
+		if ip != "" {
+			return ip
+		}
+	}
+	if ip := r.Header.Get("X-Real-Ip"); ip != "" {
 		return ip
 	}
-	return r.RemoteAddr
+	return remote
 }
 
 // ServeHTTP sets up handler of websocket channel
@@ -132,10 +247,19 @@ func (s *WebsocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		id:            atomic.AddUint64(&connectionCounter, 1),
 		conn:          conn,
 		out:           make(chan *WsRes, outChannelSize),
-		ip:            getIP(r),
+		ip:            getIP(r, s.trustedProxies),
 		requestHeader: r.Header,
 		alive:         true,
 	}
+	if s.pingInterval > 0 {
+		// the client must respond to a ping within pongTimeout, otherwise the read in inputLoop times out and the channel is closed
+		readTimeout := s.pingInterval + s.pongTimeout
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(readTimeout))
+		})
+		go s.pingLoop(c)
+	}
 	go s.inputLoop(c)
 	go s.outputLoop(c)
 	s.onConnect(c)
@@ -168,6 +292,12 @@ func (c *websocketChannel) CloseOut() bool {
 	return false
 }
 
+func (c *websocketChannel) IsAlive() bool {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+	return c.alive
+}
+
 func (c *websocketChannel) DataOut(data *WsRes) {
 	c.aliveLock.Lock()
 	defer c.aliveLock.Unlock()
@@ -239,6 +369,23 @@ func (s *WebsocketServer) outputLoop(c *websocketChannel) {
 	}
 }
 
+// pingLoop sends keepalive pings to the client until the channel is closed
+func (s *WebsocketServer) pingLoop(c *websocketChannel) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !c.IsAlive() {
+			return
+		}
+		// WriteControl can be called concurrently with the writes in outputLoop
+		if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(defaultTimeout)); err != nil {
+			glog.Error("Error sending ping to ", c.id, ", ", err)
+			s.closeChannel(c)
+			return
+		}
+	}
+}
+
 func (s *WebsocketServer) onConnect(c *websocketChannel) {
 	glog.Info("Client connected ", c.id, ", ", c.ip)
 	s.metrics.WebsocketClients.Inc()
@@ -307,7 +454,7 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 			if r.GroupBy <= 0 {
 				r.GroupBy = 3600
 			}
-			rv, err = s.api.GetXpubBalanceHistory(r.Descriptor, r.From, r.To, r.Currencies, r.Gap, r.GroupBy)
+			rv, err = s.api.GetXpubBalanceHistory(r.Descriptor, r.From, r.To, r.Currencies, r.Gap, r.ChangeGap, r.GroupBy)
 			if err != nil {
 				rv, err = s.api.GetBalanceHistory(r.Descriptor, r.From, r.To, r.Currencies, r.GroupBy)
 			}
@@ -354,9 +501,9 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		return s.unsubscribeNewTransaction(c)
 	},
 	"subscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
-		ad, err := s.unmarshalAddresses(req.Params)
+		ad, minConfirmations, err := s.unmarshalAddresses(req.Params)
 		if err == nil {
-			rv, err = s.subscribeAddresses(c, ad, req)
+			rv, err = s.subscribeAddresses(c, ad, minConfirmations, req)
 		}
 		return
 	},
@@ -495,7 +642,7 @@ func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Addres
 	if req.PageSize == 0 {
 		req.PageSize = txsOnPage
 	}
-	a, err := s.api.GetXpubAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, req.Gap, strings.ToLower(req.SecondaryCurrency))
+	a, err := s.api.GetXpubAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, req.Gap, req.ChangeGap, strings.ToLower(req.SecondaryCurrency))
 	if err != nil {
 		return s.api.GetAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, strings.ToLower(req.SecondaryCurrency))
 	}
@@ -503,7 +650,7 @@ func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Addres
 }
 
 func (s *WebsocketServer) getAccountUtxo(descriptor string) (api.Utxos, error) {
-	utxo, err := s.api.GetXpubUtxo(descriptor, false, 0)
+	utxo, err := s.api.GetXpubUtxo(descriptor, false, 0, 0)
 	if err != nil {
 		return s.api.GetAddressUtxo(descriptor, false)
 	}
@@ -639,7 +786,41 @@ type subscriptionResponseMessage struct {
 	Message    string `json:"message"`
 }
 
+// reserveSubscriptions checks that count subscriptions of the method fit into the limit of subscriptions per connection
+// the subscriptions replace the previous subscriptions of the same method of the channel
+func (s *WebsocketServer) reserveSubscriptions(c *websocketChannel, method string, count int) error {
+	if s.maxSubscriptions <= 0 {
+		return nil
+	}
+	c.subscriptionsLock.Lock()
+	defer c.subscriptionsLock.Unlock()
+	total := count
+	for m, n := range c.subscriptions {
+		if m != method {
+			total += n
+		}
+	}
+	if total > s.maxSubscriptions {
+		return errors.New("Too many subscriptions, the limit is " + strconv.Itoa(s.maxSubscriptions) + " per connection")
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]int)
+	}
+	c.subscriptions[method] = count
+	return nil
+}
+
+// releaseSubscriptions frees the slots of the subscriptions of the method
+func (s *WebsocketServer) releaseSubscriptions(c *websocketChannel, method string) {
+	c.subscriptionsLock.Lock()
+	defer c.subscriptionsLock.Unlock()
+	delete(c.subscriptions, method)
+}
+
 func (s *WebsocketServer) subscribeNewBlock(c *websocketChannel, req *WsReq) (res interface{}, err error) {
+	if err := s.reserveSubscriptions(c, "subscribeNewBlock", 1); err != nil {
+		return nil, err
+	}
 	s.newBlockSubscriptionsLock.Lock()
 	defer s.newBlockSubscriptionsLock.Unlock()
 	s.newBlockSubscriptions[c] = req.ID
@@ -651,6 +832,7 @@ func (s *WebsocketServer) unsubscribeNewBlock(c *websocketChannel) (res interfac
 	s.newBlockSubscriptionsLock.Lock()
 	defer s.newBlockSubscriptionsLock.Unlock()
 	delete(s.newBlockSubscriptions, c)
+	s.releaseSubscriptions(c, "subscribeNewBlock")
 	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeNewBlock"})).Set(float64(len(s.newBlockSubscriptions)))
 	return &subscriptionResponse{false}, nil
 }
@@ -661,6 +843,9 @@ func (s *WebsocketServer) subscribeNewTransaction(c *websocketChannel, req *WsRe
 	if !s.newTransactionEnabled {
 		return &subscriptionResponseMessage{false, "subscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}, nil
 	}
+	if err := s.reserveSubscriptions(c, "subscribeNewTransaction", 1); err != nil {
+		return nil, err
+	}
 	s.newTransactionSubscriptions[c] = req.ID
 	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeNewTransaction"})).Set(float64(len(s.newTransactionSubscriptions)))
 	return &subscriptionResponse{true}, nil
@@ -673,25 +858,29 @@ func (s *WebsocketServer) unsubscribeNewTransaction(c *websocketChannel) (res in
 		return &subscriptionResponseMessage{false, "unsubscribeNewTransaction not enabled, use -enablesubnewtx flag to enable."}, nil
 	}
 	delete(s.newTransactionSubscriptions, c)
+	s.releaseSubscriptions(c, "subscribeNewTransaction")
 	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeNewTransaction"})).Set(float64(len(s.newTransactionSubscriptions)))
 	return &subscriptionResponse{false}, nil
 }
 
-func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]string, error) {
+func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]string, uint32, error) {
 	r := WsSubscribeAddressesReq{}
 	err := json.Unmarshal(params, &r)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if r.MinConfirmations < 0 {
+		return nil, 0, errors.New("Invalid minConfirmations")
 	}
 	rv := make([]string, len(r.Addresses))
 	for i, a := range r.Addresses {
-		ad, err := s.chainParser.GetAddrDescFromAddress(a)
+		ad, err := s.chainParser.GetAddrDescFromAddress(s.chainParser.NormalizeAddress(a))
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		rv[i] = string(ad)
 	}
-	return rv, nil
+	return rv, uint32(r.MinConfirmations), nil
 }
 
 // unsubscribe addresses without addressSubscriptionsLock - can be called only from subscribeAddresses and unsubscribeAddresses
@@ -710,13 +899,43 @@ func (s *WebsocketServer) doUnsubscribeAddresses(c *websocketChannel) {
 		}
 	}
 	c.addrDescs = nil
+	s.minConfSubscriptionsLock.Lock()
+	delete(s.minConfSubscriptions, c)
+	s.minConfSubscriptionsLock.Unlock()
 }
 
-func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []string, req *WsReq) (res interface{}, err error) {
+// subscribeAddresses subscribes notifications about transactions of the addresses
+// with minConfirmations the transactions are notified when they reach the number of confirmations instead of when they enter the mempool
+func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []string, minConfirmations uint32, req *WsReq) (res interface{}, err error) {
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
+	// each address is counted as one subscription, the previous subscriptions are kept if the limit is exceeded
+	if err := s.reserveSubscriptions(c, "subscribeAddresses", len(addrDesc)); err != nil {
+		return nil, err
+	}
 	// unsubscribe all previous subscriptions
 	s.doUnsubscribeAddresses(c)
+	if minConfirmations > 0 {
+		bestHeight, _, err := s.db.GetBestBlock()
+		if err != nil {
+			return nil, err
+		}
+		// transactions of blocks up to this height have already minConfirmations
+		var fromHeight uint32
+		if bestHeight+2 > minConfirmations {
+			fromHeight = bestHeight + 2 - minConfirmations
+		}
+		s.minConfSubscriptionsLock.Lock()
+		s.minConfSubscriptions[c] = &minConfSubscription{
+			id:               req.ID,
+			addrDescs:        addrDesc,
+			minConfirmations: minConfirmations,
+			fromHeight:       fromHeight,
+			notified:         make(map[minConfTx]minConfBlock),
+		}
+		s.minConfSubscriptionsLock.Unlock()
+		return &subscriptionResponse{true}, nil
+	}
 	for _, ads := range addrDesc {
 		as, ok := s.addressSubscriptions[ads]
 		if !ok {
@@ -735,6 +954,7 @@ func (s *WebsocketServer) unsubscribeAddresses(c *websocketChannel) (res interfa
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
 	s.doUnsubscribeAddresses(c)
+	s.releaseSubscriptions(c, "subscribeAddresses")
 	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeAddresses"})).Set(float64(len(s.addressSubscriptions)))
 	return &subscriptionResponse{false}, nil
 }
@@ -756,6 +976,9 @@ func (s *WebsocketServer) doUnsubscribeFiatRates(c *websocketChannel) {
 
 // subscribeFiatRates subscribes all FiatRates subscriptions by this channel
 func (s *WebsocketServer) subscribeFiatRates(c *websocketChannel, d *WsSubscribeFiatRatesReq, req *WsReq) (res interface{}, err error) {
+	if err := s.reserveSubscriptions(c, "subscribeFiatRates", 1); err != nil {
+		return nil, err
+	}
 	s.fiatRatesSubscriptionsLock.Lock()
 	defer s.fiatRatesSubscriptionsLock.Unlock()
 	// unsubscribe all previous subscriptions
@@ -784,6 +1007,7 @@ func (s *WebsocketServer) unsubscribeFiatRates(c *websocketChannel) (res interfa
 	s.fiatRatesSubscriptionsLock.Lock()
 	defer s.fiatRatesSubscriptionsLock.Unlock()
 	s.doUnsubscribeFiatRates(c)
+	s.releaseSubscriptions(c, "subscribeFiatRates")
 	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeFiatRates"})).Set(float64(len(s.fiatRatesSubscriptions)))
 	return &subscriptionResponse{false}, nil
 }
@@ -807,9 +1031,107 @@ func (s *WebsocketServer) onNewBlockAsync(hash string, height uint32) {
 	glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
 }
 
+// onNewBlockMinConfirmations notifies the transactions of subscribed addresses which reached the required confirmations
+// and retracts the notified transactions which fell below the required confirmations because of a reorg
+// the current best block from the db is used, therefore the order of the calls does not matter
+func (s *WebsocketServer) onNewBlockMinConfirmations() {
+	s.minConfSubscriptionsLock.Lock()
+	defer s.minConfSubscriptionsLock.Unlock()
+	if len(s.minConfSubscriptions) == 0 {
+		return
+	}
+	bestHeight, _, err := s.db.GetBestBlock()
+	if err != nil {
+		glog.Error("GetBestBlock error ", err)
+		return
+	}
+	for c, sub := range s.minConfSubscriptions {
+		s.checkMinConfSubscription(c, sub, bestHeight)
+	}
+}
+
+func (s *WebsocketServer) checkMinConfSubscription(c *websocketChannel, sub *minConfSubscription, bestHeight uint32) {
+	for k, b := range sub.notified {
+		hash, err := s.db.GetBlockHash(b.height)
+		if err != nil {
+			glog.Error("GetBlockHash error ", err, " for ", b.height)
+			continue
+		}
+		if hash != b.hash || bestHeight+1 < b.height+sub.minConfirmations {
+			s.sendMinConfTxAddr(c, sub.id, k.addrDesc, k.txid, nil)
+			delete(sub.notified, k)
+		} else if b.height+sub.minConfirmations+minConfReorgDepth <= bestHeight {
+			// too deep to be affected by a reorg
+			delete(sub.notified, k)
+		}
+	}
+	if bestHeight+1 < sub.minConfirmations {
+		return
+	}
+	// the transactions in blocks up to this height have minConfirmations
+	to := bestHeight + 1 - sub.minConfirmations
+	from := sub.fromHeight
+	if to > minConfReorgDepth && to-minConfReorgDepth > from {
+		from = to - minConfReorgDepth
+	}
+	if from > to {
+		return
+	}
+	for _, ad := range sub.addrDescs {
+		err := s.db.GetAddrDescTransactions(bchain.AddressDescriptor(ad), from, to, func(txid string, height uint32, indexes []int32) error {
+			k := minConfTx{ad, txid}
+			if _, found := sub.notified[k]; found {
+				return nil
+			}
+			hash, err := s.db.GetBlockHash(height)
+			if err != nil {
+				return err
+			}
+			tx, err := s.api.GetTransaction(txid, false, false)
+			if err != nil {
+				return err
+			}
+			sub.notified[k] = minConfBlock{height, hash}
+			s.sendMinConfTxAddr(c, sub.id, ad, txid, tx)
+			return nil
+		})
+		if err != nil {
+			glog.Error("checkMinConfSubscription error ", err, " for ", ad)
+		}
+	}
+}
+
+// sendMinConfTxAddr sends notification about a transaction which reached the required confirmations,
+// nil tx means that the transaction is retracted because it no longer has the required confirmations
+func (s *WebsocketServer) sendMinConfTxAddr(c *websocketChannel, id string, stringAddressDescriptor string, txid string, tx *api.Tx) {
+	addr, _, err := s.chainParser.GetAddressesFromAddrDesc(bchain.AddressDescriptor(stringAddressDescriptor))
+	if err != nil || len(addr) != 1 {
+		glog.Error("GetAddressesFromAddrDesc error ", err, " for ", stringAddressDescriptor)
+		return
+	}
+	data := struct {
+		Address   string  `json:"address"`
+		Tx        *api.Tx `json:"tx,omitempty"`
+		Txid      string  `json:"txid,omitempty"`
+		Retracted bool    `json:"retracted,omitempty"`
+	}{
+		Address: addr[0],
+		Tx:      tx,
+	}
+	if tx == nil {
+		data.Txid = txid
+		data.Retracted = true
+	}
+	c.DataOut(&WsRes{
+		ID:   id,
+		Data: &data,
+	})
+}
+
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
 func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 	go s.onNewBlockAsync(hash, height)
+	go s.onNewBlockMinConfirmations()
 }
 
 func (s *WebsocketServer) sendOnNewTx(tx *api.Tx) {
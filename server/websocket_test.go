@@ -0,0 +1,104 @@
+//go:build unittest
+
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_getIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		header         http.Header
+		trustedProxies bool
+		want           string
+	}{
+		{
+			name:       "no trusted proxies, X-Real-Ip honored",
+			remoteAddr: "203.0.113.5:4321",
+			header:     http.Header{"X-Real-Ip": []string{"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "no trusted proxies, X-Forwarded-For ignored",
+			remoteAddr: "203.0.113.5:4321",
+			header:     http.Header{"X-Forwarded-For": []string{"198.51.100.7"}},
+			want:       "203.0.113.5:4321",
+		},
+		{
+			name:           "trusted proxy",
+			remoteAddr:     "10.1.2.3:4321",
+			header:         http.Header{"X-Forwarded-For": []string{"198.51.100.7"}},
+			trustedProxies: true,
+			want:           "198.51.100.7",
+		},
+		{
+			name:           "chain of trusted proxies",
+			remoteAddr:     "192.168.1.1:4321",
+			header:         http.Header{"X-Forwarded-For": []string{"1.2.3.4, 198.51.100.7", "10.0.0.1"}},
+			trustedProxies: true,
+			want:           "198.51.100.7",
+		},
+		{
+			name:           "trusted IPv6 proxy",
+			remoteAddr:     "[2001:db8::1]:4321",
+			header:         http.Header{"X-Forwarded-For": []string{"2001:db9::5"}},
+			trustedProxies: true,
+			want:           "2001:db9::5",
+		},
+		{
+			name:           "trusted proxy with X-Real-Ip only",
+			remoteAddr:     "10.1.2.3:4321",
+			header:         http.Header{"X-Real-Ip": []string{"198.51.100.7"}},
+			trustedProxies: true,
+			want:           "198.51.100.7",
+		},
+		{
+			name:           "trusted proxy without headers",
+			remoteAddr:     "10.1.2.3:4321",
+			header:         http.Header{},
+			trustedProxies: true,
+			want:           "10.1.2.3",
+		},
+		{
+			name:           "untrusted client, X-Forwarded-For ignored",
+			remoteAddr:     "203.0.113.5:4321",
+			header:         http.Header{"X-Forwarded-For": []string{"198.51.100.7"}},
+			trustedProxies: true,
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "untrusted client, X-Real-Ip ignored",
+			remoteAddr:     "192.168.1.2:4321",
+			header:         http.Header{"X-Real-Ip": []string{"198.51.100.7"}},
+			trustedProxies: true,
+			want:           "192.168.1.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: tt.remoteAddr, Header: tt.header}
+			tp := trustedProxies
+			if !tt.trustedProxies {
+				tp = nil
+			}
+			if got := getIP(r, tp); got != tt.want {
+				t.Errorf("getIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTrustedProxies(t *testing.T) {
+	for _, p := range []string{"10.0.0.0/33", "not-an-ip", "300.1.1.1"} {
+		if _, err := parseTrustedProxies([]string{p}); err == nil {
+			t.Errorf("parseTrustedProxies(%v) expected error", p)
+		}
+	}
+}
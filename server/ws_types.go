@@ -24,6 +24,7 @@ type WsAccountInfoReq struct {
 	ContractFilter    string `json:"contractFilter,omitempty"`
 	SecondaryCurrency string `json:"secondaryCurrency,omitempty"`
 	Gap               int    `json:"gap,omitempty"`
+	ChangeGap         int    `json:"changeGap,omitempty"`
 }
 
 type WsBackendInfo struct {
@@ -69,6 +70,7 @@ type WsBalanceHistoryReq struct {
 	To         int64    `json:"to,omitempty"`
 	Currencies []string `json:"currencies,omitempty"`
 	Gap        int      `json:"gap,omitempty"`
+	ChangeGap  int      `json:"changeGap,omitempty"`
 	GroupBy    uint32   `json:"groupBy,omitempty"`
 }
 
@@ -96,7 +98,8 @@ type WsSendTransactionReq struct {
 }
 
 type WsSubscribeAddressesReq struct {
-	Addresses []string `json:"addresses"`
+	Addresses        []string `json:"addresses"`
+	MinConfirmations int      `json:"minConfirmations,omitempty"`
 }
 type WsSubscribeFiatRatesReq struct {
 	Currency string   `json:"currency,omitempty"`
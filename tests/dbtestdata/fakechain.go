@@ -26,7 +26,7 @@ func (c *fakeBlockChain) Initialize() error {
 	return nil
 }
 
-func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) error {
+func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, spendingTxidForOutpoint bchain.SpendingTxidForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
 	return nil
 }
 
@@ -58,6 +58,7 @@ func (c *fakeBlockChain) GetChainInfo() (v *bchain.ChainInfo, err error) {
 		Bestblockhash: GetTestBitcoinTypeBlock2(c.Parser).BlockHeader.Hash,
 		Version:       "001001",
 		Subversion:    c.GetSubversion(),
+		MinRelayTxFee: "1000",
 	}, nil
 }
 
@@ -201,6 +202,16 @@ func (c *fakeBlockChain) EstimateFee(blocks int) (v big.Int, err error) {
 	return
 }
 
+func (c *fakeBlockChain) GetMempoolMinFee() (v *big.Int, err error) {
+	return big.NewInt(2000), nil
+}
+
+func (c *fakeBlockChain) GetMempoolInfo() (v *bchain.MempoolInfo, err error) {
+	v = &bchain.MempoolInfo{}
+	v.MinFeeSat.SetInt64(2000)
+	return v, nil
+}
+
 func (c *fakeBlockChain) SendRawTransaction(tx string) (v string, err error) {
 	if tx == "123456" {
 		return "9876", nil
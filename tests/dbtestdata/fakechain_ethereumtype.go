@@ -1,6 +1,7 @@
 package dbtestdata
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 	"strconv"
@@ -117,6 +118,15 @@ func (c *fakeBlockChainEthereumType) EthereumTypeGetNonce(addrDesc bchain.Addres
 	return uint64(addrDesc[0]), nil
 }
 
+// EthereumTypeGetCode returns a code only for the contracts of the test data
+func (c *fakeBlockChainEthereumType) EthereumTypeGetCode(addrDesc bchain.AddressDescriptor) ([]byte, error) {
+	switch hex.EncodeToString(addrDesc) {
+	case EthAddrContract4a, EthAddrContract0d, EthAddrContract47, EthAddrContractCd, EthAddrContract6f:
+		return []byte{0x60, 0x80, 0x60, 0x40, 0x52}, nil
+	}
+	return nil, nil
+}
+
 func (c *fakeBlockChainEthereumType) GetContractInfo(contractDesc bchain.AddressDescriptor) (*bchain.ContractInfo, error) {
 	addresses, _, _ := c.Parser.GetAddressesFromAddrDesc(contractDesc)
 	return &bchain.ContractInfo{
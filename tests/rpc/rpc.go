@@ -189,6 +189,7 @@ func testGetTransaction(t *testing.T, h *TestHandler) {
 
 func testGetTransactionForMempool(t *testing.T, h *TestHandler) {
 	for txid, want := range h.TestData.TxDetails {
+		wantTime := want.Time
 		// reset fields that are not parsed by BlockChainParser
 		want.Confirmations, want.Blocktime, want.Time, want.CoinSpecificData = 0, 0, 0, nil
 
@@ -197,6 +198,11 @@ func testGetTransactionForMempool(t *testing.T, h *TestHandler) {
 			t.Fatal(err)
 		}
 
+		// coins with timestamp in the transaction parse it from the tx, it must match the test data
+		if got.Time != 0 && wantTime != 0 && got.Time != wantTime {
+			t.Errorf("GetTransactionForMempool() got Time %d, want %d", got.Time, wantTime)
+		}
+
 		normalizeAddresses(want, h.Chain.GetChainParser())
 		normalizeAddresses(got, h.Chain.GetChainParser())
 